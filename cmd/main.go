@@ -9,10 +9,14 @@ import (
 	"travel-agency/internal/auth"
 	"travel-agency/internal/config"
 	"travel-agency/internal/db"
+	"travel-agency/internal/dunning"
+	"travel-agency/internal/fx"
+	"travel-agency/internal/gateways"
 	"travel-agency/internal/handlers"
 	"travel-agency/internal/jobs"
 	"travel-agency/internal/models"
 	"travel-agency/internal/notifications"
+	"travel-agency/internal/suppliers"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
@@ -34,7 +38,7 @@ func main() {
 		log.Fatalf("Failed to enable pgcrypto extension: %v", err)
 	}
 
-	// Auto‑migrate all models except Invoice (created only if missing)
+	// Auto‑migrate all models except Invoice (see db.MigrateInvoices)
 	toMigrate := []interface{}{
 		&models.Tenant{},
 		&models.User{},
@@ -43,30 +47,94 @@ func main() {
 		&models.ItineraryItem{},
 		&models.Booking{},
 		&models.Vendor{},
-		&models.Payment{},
 		&models.Task{},
 		&models.Ticket{},
 		&models.AuditLog{},
+		&models.MarkupRule{},
+		&models.ItineraryVersion{},
+		&models.ItineraryVersionItem{},
+		&models.ItineraryTemplate{},
+		&models.ItineraryTemplateItem{},
+		&models.BookingTransition{},
+		&models.CancellationRule{},
+		&models.CreditNote{},
+		&models.CreditNoteItem{},
+		&models.CreditNoteSequence{},
+		&models.Refund{},
+		&models.Inventory{},
+		&models.InventoryAllocation{},
+		&models.VendorContract{},
+		&models.RateCard{},
+		&models.PaymentRun{},
+		&models.VendorPayment{},
+		&models.ExchangeRate{},
+		&models.AccountingMapping{},
+		&models.AccountingExport{},
+		&models.AccountingExportRecord{},
+		&models.ReminderRule{},
+		&models.InvoiceReminder{},
+		&models.RecurringInvoice{},
+		&models.RecurringInvoiceRun{},
+		&models.TaxRule{},
+		&models.TaxRuleComponent{},
+	}
+	moneyModels := append([]interface{}{&models.Invoice{}, &models.InvoiceItem{}, &models.InvoiceItemTax{},
+		&models.Payment{}, &models.PaymentAllocation{}}, toMigrate...)
+	if err := db.MigrateMoney(database, moneyModels...); err != nil {
+		log.Fatalf("Failed to migrate monetary columns: %v", err)
 	}
 	if err := database.AutoMigrate(toMigrate...); err != nil {
 		log.Fatalf("Auto migration failed: %v", err)
 	}
-	if !database.Migrator().HasTable(&models.Invoice{}) {
-		if err := database.AutoMigrate(&models.Invoice{}); err != nil {
-			log.Fatalf("Failed to create invoices table: %v", err)
+	if err := db.MigrateInvoices(database); err != nil {
+		log.Fatalf("Failed to migrate invoices table: %v", err)
+	}
+	if err := db.MigratePayments(database); err != nil {
+		log.Fatalf("Failed to migrate payments table: %v", err)
+	}
+	if err := db.MigrateLedger(database); err != nil {
+		log.Fatalf("Failed to migrate ledger tables: %v", err)
+	}
+	if cfg.ExchangeRatesFile != "" {
+		n, err := fx.LoadFile(database, cfg.ExchangeRatesFile)
+		if err != nil {
+			log.Fatalf("Failed to load exchange rates from %s: %v", cfg.ExchangeRatesFile, err)
 		}
+		log.Printf("Loaded %d exchange rate(s) from %s", n, cfg.ExchangeRatesFile)
 	}
 
-	// Start background jobs
-	jobs.StartCronJobs(database)
-
 	// Handlers
 	jwtSecret := cfg.JWTSecret
 	authHandler := handlers.NewAuthHandler(database, jwtSecret)
 	smtpSender := notifications.NewSMTPSender(
 		cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword, cfg.SMTPFrom,
 	)
+	reminders := dunning.New(database, smtpSender, jwtSecret, cfg.PublicBaseURL)
+
+	// Start background jobs
+	jobs.StartCronJobs(database, reminders)
+
 	adminHandler := handlers.NewAdminHandler(database, smtpSender)
+	calendarHandler := handlers.NewCalendarHandler(database, jwtSecret, cfg.PublicBaseURL)
+
+	// Supplier connectors, keyed by vendor type. Real integrations register here.
+	supplierRegistry := suppliers.NewRegistry()
+	if cfg.SupplierMock {
+		mockGDS := suppliers.NewMockGDS()
+		for _, vendorType := range []string{"Airline", "Hotel", "Tour Operator"} {
+			supplierRegistry.Register(vendorType, mockGDS)
+		}
+	}
+
+	// Payment gateways, keyed by the name used in webhook URLs.
+	paymentGateways := gateways.NewRegistry()
+	if cfg.StripeSecretKey != "" {
+		paymentGateways.Register("stripe", gateways.NewStripe(cfg.StripeSecretKey, cfg.StripeWebhookSecret))
+	}
+	if cfg.PaymentMock {
+		paymentGateways.Register("mock", gateways.NewMock(cfg.PaymentMockSecret, cfg.PublicBaseURL))
+	}
+	webhookHandler := handlers.NewWebhookHandler(database, paymentGateways)
 
 	r := chi.NewRouter()
 
@@ -88,6 +156,20 @@ func main() {
 	r.Post("/api/auth/login", authHandler.Login)
 	r.Post("/api/auth/refresh", authHandler.RefreshToken)
 
+	// Public calendar subscription feed (authenticated by signed token)
+	r.Get("/api/calendar/{token}/schedule.ics", calendarHandler.UserFeed)
+
+	// Payment gateway webhooks (authenticated by provider signature)
+	r.Post("/api/webhooks/payments/{provider}", webhookHandler.PaymentWebhook)
+	if cfg.PaymentMock {
+		webhookHandler.MountMockCheckout(r)
+	}
+
+	// Public invoice payment links (authenticated by signed token)
+	paymentLinkHandler := handlers.NewPaymentLinkHandler(database, paymentGateways, smtpSender, jwtSecret, cfg.PublicBaseURL)
+	r.Get("/api/pay/{token}", paymentLinkHandler.ShowPaymentLink)
+	r.Post("/api/pay/{token}", paymentLinkHandler.StartLinkPayment)
+
 	// Protected routes
 	r.Group(func(r chi.Router) {
 		r.Use(auth.AuthMiddleware(jwtSecret))
@@ -101,10 +183,25 @@ func main() {
 			r.Delete("/{agentID}", adminHandler.DeleteAgent)
 		})
 
+		// Admin: tenant settings (branding, tax rate, contacts)
+		r.Get("/api/admin/tenant", adminHandler.GetTenant)
+		r.Put("/api/admin/tenant", adminHandler.UpdateTenant)
+
+		// Admin: exchange rates
+		exchangeRateHandler := handlers.NewExchangeRateHandler(database)
+		r.Route("/api/admin/exchange-rates", func(r chi.Router) {
+			r.Get("/", exchangeRateHandler.ListRates)
+			r.Post("/", exchangeRateHandler.ImportRates)
+			r.Delete("/{rateID}", exchangeRateHandler.DeleteRate)
+		})
+		r.Get("/api/exchange-rates/convert", exchangeRateHandler.Convert)
+
 		// User self‑service
 		r.Get("/api/user/profile", authHandler.GetProfile)
 		r.Put("/api/user/profile", authHandler.UpdateProfile)
 		r.Put("/api/user/reset-password", authHandler.ResetPassword)
+		r.Get("/api/user/calendar-url", calendarHandler.SubscriptionURL)
+		r.Post("/api/user/calendar-url/regenerate", calendarHandler.RegenerateSubscriptionURL)
 
 		// Leads
 		leadsHandler := handlers.NewLeadsHandler(database)
@@ -122,43 +219,185 @@ func main() {
 			r.Get("/", itinHandler.ListItineraries)
 			r.Get("/{itineraryID}", itinHandler.GetItinerary)
 			r.Put("/{itineraryID}", itinHandler.UpdateItinerary)
+			r.Get("/{itineraryID}/pricing", itinHandler.GetItineraryPricing)
+			r.Get("/{itineraryID}/pdf", itinHandler.DownloadItineraryPDF)
+			r.Post("/{itineraryID}/clone", itinHandler.CloneItinerary)
+			r.Post("/{itineraryID}/invoice", itinHandler.InvoiceItinerary)
+			r.Get("/{itineraryID}/calendar.ics", calendarHandler.ItineraryCalendar)
+			r.Get("/{itineraryID}/versions", itinHandler.ListVersions)
+			r.Get("/{itineraryID}/versions/diff", itinHandler.DiffVersions)
+			r.Get("/{itineraryID}/versions/{version}", itinHandler.GetVersion)
+			r.Post("/{itineraryID}/versions/{version}/restore", itinHandler.RestoreVersion)
+			r.Post("/{itineraryID}/versions/{version}/sent", itinHandler.MarkVersionSent)
+			r.Post("/{itineraryID}/versions/{version}/accepted", itinHandler.MarkVersionAccepted)
+		})
+
+		// Itinerary templates (reusable packages)
+		templateHandler := handlers.NewItineraryTemplateHandler(database)
+		r.Route("/api/itinerary-templates", func(r chi.Router) {
+			r.Post("/", templateHandler.CreateTemplate)
+			r.Get("/", templateHandler.ListTemplates)
+			r.Get("/{templateID}", templateHandler.GetTemplate)
+			r.Put("/{templateID}", templateHandler.UpdateTemplate)
+			r.Delete("/{templateID}", templateHandler.DeleteTemplate)
+			r.Post("/{templateID}/instantiate", templateHandler.InstantiateTemplate)
+		})
+
+		// Pricing: per-tenant markup rules
+		pricingHandler := handlers.NewPricingHandler(database)
+		r.Route("/api/pricing/markup-rules", func(r chi.Router) {
+			r.Post("/", pricingHandler.CreateMarkupRule)
+			r.Get("/", pricingHandler.ListMarkupRules)
+			r.Put("/{ruleID}", pricingHandler.UpdateMarkupRule)
+			r.Delete("/{ruleID}", pricingHandler.DeleteMarkupRule)
 		})
 
 		// Bookings
-		bookingHandler := handlers.NewBookingHandler(database)
+		bookingHandler := handlers.NewBookingHandler(database, supplierRegistry)
+		taxHandler := handlers.NewTaxHandler(database)
 		r.Route("/api/bookings", func(r chi.Router) {
 			r.Post("/", bookingHandler.CreateBooking)
 			r.Get("/", bookingHandler.ListBookings)
 			r.Get("/{bookingID}", bookingHandler.GetBooking)
 			r.Put("/{bookingID}", bookingHandler.UpdateBooking)
+			r.Delete("/{bookingID}", bookingHandler.DeleteBooking)
+			r.Post("/{bookingID}/status", bookingHandler.ChangeBookingStatus)
+			r.Post("/{bookingID}/cancel", bookingHandler.CancelBooking)
+			r.Get("/{bookingID}/transitions", bookingHandler.ListBookingTransitions)
+			r.Post("/search", bookingHandler.SearchSupplier)
+			r.Post("/{bookingID}/hold", bookingHandler.HoldBooking)
+			r.Post("/{bookingID}/confirm", bookingHandler.ConfirmBooking)
+			r.Get("/{bookingID}/supplier", bookingHandler.GetSupplierReservation)
+			r.Get("/{bookingID}/taxes", taxHandler.BookingTaxes)
 		})
 
 		// Vendors
 		vendorHandler := handlers.NewVendorHandler(database)
+		payablesHandler := handlers.NewPayablesHandler(database)
 		r.Route("/api/vendors", func(r chi.Router) {
 			r.Post("/", vendorHandler.CreateVendor)
 			r.Get("/", vendorHandler.ListVendors)
+			r.Get("/availability", vendorHandler.Availability)
+			r.Get("/scorecards", vendorHandler.VendorReport)
 			r.Get("/{vendorID}", vendorHandler.GetVendor)
 			r.Put("/{vendorID}", vendorHandler.UpdateVendor)
+			r.Get("/{vendorID}/cancellation-policy", vendorHandler.GetCancellationPolicy)
+			r.Put("/{vendorID}/cancellation-policy", vendorHandler.SetCancellationPolicy)
+			r.Get("/{vendorID}/inventory", vendorHandler.ListInventory)
+			r.Post("/{vendorID}/inventory", vendorHandler.SetAllotment)
+			r.Get("/{vendorID}/contracts", vendorHandler.ListContracts)
+			r.Post("/{vendorID}/contracts", vendorHandler.CreateContract)
+			r.Get("/{vendorID}/contracts/{contractID}", vendorHandler.GetContract)
+			r.Put("/{vendorID}/contracts/{contractID}", vendorHandler.UpdateContract)
+			r.Delete("/{vendorID}/contracts/{contractID}", vendorHandler.DeleteContract)
+			r.Get("/{vendorID}/rates", vendorHandler.QuoteRate)
+			r.Get("/{vendorID}/statement", payablesHandler.VendorStatement)
+			r.Get("/{vendorID}/scorecard", vendorHandler.Scorecard)
+		})
+
+		// Vendor payables
+		r.Route("/api/payables", func(r chi.Router) {
+			r.Get("/", payablesHandler.ListPayables)
+			r.Get("/runs", payablesHandler.ListPaymentRuns)
+			r.Post("/runs", payablesHandler.CreatePaymentRun)
+			r.Get("/runs/{runID}", payablesHandler.GetPaymentRun)
+			r.Post("/runs/{runID}/execute", payablesHandler.ExecutePaymentRun)
+			r.Post("/runs/{runID}/cancel", payablesHandler.CancelPaymentRun)
 		})
 
 		// Invoices
 		invoiceHandler := handlers.NewInvoiceHandler(database)
+		creditNoteHandler := handlers.NewCreditNoteHandler(database)
+		reminderHandler := handlers.NewReminderHandler(database, reminders)
 		r.Route("/api/invoices", func(r chi.Router) {
 			r.Post("/", invoiceHandler.CreateInvoice)
 			r.Get("/", invoiceHandler.ListInvoices)
 			r.Get("/{invoiceID}", invoiceHandler.GetInvoice)
 			r.Put("/{invoiceID}", invoiceHandler.UpdateInvoice)
 			r.Get("/{invoiceID}/pdf", invoiceHandler.DownloadInvoicePDF)
+			r.Post("/{invoiceID}/issue", invoiceHandler.IssueInvoice)
+			r.Post("/{invoiceID}/void", invoiceHandler.VoidInvoice)
+			r.Post("/{invoiceID}/credit-notes", creditNoteHandler.CreateCreditNote)
+			r.Post("/{invoiceID}/payment-link", paymentLinkHandler.CreatePaymentLink)
+			r.Get("/{invoiceID}/reminders", reminderHandler.ListInvoiceReminders)
+			r.Put("/{invoiceID}/reminders", reminderHandler.UpdateInvoiceReminders)
+			r.Post("/{invoiceID}/reminders", reminderHandler.SendInvoiceReminder)
+		})
+		r.Route("/api/reminders", func(r chi.Router) {
+			r.Get("/rules", reminderHandler.ListRules)
+			r.Put("/rules", reminderHandler.SaveRules)
+		})
+		recurringInvoiceHandler := handlers.NewRecurringInvoiceHandler(database)
+		r.Route("/api/recurring-invoices", func(r chi.Router) {
+			r.Get("/", recurringInvoiceHandler.ListRecurringInvoices)
+			r.Post("/", recurringInvoiceHandler.CreateRecurringInvoice)
+			r.Get("/{recurringID}", recurringInvoiceHandler.GetRecurringInvoice)
+			r.Put("/{recurringID}", recurringInvoiceHandler.UpdateRecurringInvoice)
+			r.Post("/{recurringID}/pause", recurringInvoiceHandler.PauseRecurringInvoice)
+			r.Post("/{recurringID}/resume", recurringInvoiceHandler.ResumeRecurringInvoice)
+		})
+		r.Route("/api/credit-notes", func(r chi.Router) {
+			r.Get("/", creditNoteHandler.ListCreditNotes)
+			r.Get("/{creditNoteID}", creditNoteHandler.GetCreditNote)
+			r.Get("/{creditNoteID}/pdf", creditNoteHandler.DownloadCreditNotePDF)
+		})
+
+		// Customer statements and receivables aging
+		receivablesHandler := handlers.NewReceivablesHandler(database)
+		r.Get("/api/customers/{customerID}/statement", receivablesHandler.CustomerStatement)
+		r.Get("/api/receivables/aging", receivablesHandler.AgingReport)
+
+		// Tax rules
+		r.Route("/api/tax/rules", func(r chi.Router) {
+			r.Get("/", taxHandler.ListTaxRules)
+			r.Post("/", taxHandler.CreateTaxRule)
+			r.Put("/{ruleID}", taxHandler.UpdateTaxRule)
+			r.Delete("/{ruleID}", taxHandler.DeleteTaxRule)
+		})
+
+		// Ledger
+		ledgerHandler := handlers.NewLedgerHandler(database)
+		r.Route("/api/ledger", func(r chi.Router) {
+			r.Get("/accounts", ledgerHandler.ListAccounts)
+			r.Post("/accounts", ledgerHandler.CreateAccount)
+			r.Get("/entries", ledgerHandler.ListEntries)
+			r.Post("/entries", ledgerHandler.CreateEntry)
+			r.Post("/entries/{entryID}/reverse", ledgerHandler.ReverseEntry)
+			r.Get("/trial-balance", ledgerHandler.TrialBalance)
+			r.Get("/profit-and-loss", ledgerHandler.ProfitAndLoss)
+			r.Post("/backfill", ledgerHandler.Backfill)
+		})
+
+		// Accounting package exports
+		accountingHandler := handlers.NewAccountingHandler(database)
+		r.Route("/api/accounting", func(r chi.Router) {
+			r.Get("/mappings", accountingHandler.ListMappings)
+			r.Put("/mappings", accountingHandler.SaveMappings)
+			r.Delete("/mappings/{mappingID}", accountingHandler.DeleteMapping)
+			r.Get("/exports", accountingHandler.ListExports)
+			r.Post("/exports", accountingHandler.CreateExport)
+			r.Get("/exports/{exportID}/download", accountingHandler.DownloadExport)
 		})
 
 		// Payments
-		paymentHandler := handlers.NewPaymentHandler(database)
+		paymentHandler := handlers.NewPaymentHandler(database, paymentGateways)
 		r.Route("/api/payments", func(r chi.Router) {
 			r.Post("/", paymentHandler.CreatePayment)
 			r.Get("/", paymentHandler.ListPayments)
+			r.Post("/intents", paymentHandler.CreateIntent)
 			r.Get("/{paymentID}", paymentHandler.GetPayment)
 			r.Put("/{paymentID}", paymentHandler.UpdatePayment)
+			r.Post("/{paymentID}/allocations", paymentHandler.AllocatePayment)
+			r.Delete("/{paymentID}/allocations/{allocationID}", paymentHandler.DeleteAllocation)
+			r.Post("/{paymentID}/capture", paymentHandler.CapturePayment)
+			r.Post("/{paymentID}/refund", paymentHandler.RefundPayment)
+		})
+
+		// Refunds paid out by hand, e.g. after a booking is cancelled
+		refundHandler := handlers.NewRefundHandler(database)
+		r.Route("/api/refunds", func(r chi.Router) {
+			r.Get("/", refundHandler.ListRefunds)
+			r.Post("/{refundID}/settle", refundHandler.SettleRefund)
 		})
 
 		// Tasks
@@ -171,6 +410,10 @@ func main() {
 			r.Delete("/{taskID}", taskHandler.DeleteTask)
 		})
 
+		// Reports
+		reportingHandler := handlers.NewReportingHandler(database)
+		r.Get("/api/reports/sales", reportingHandler.GetSalesReport)
+
 		// Tickets
 		ticketHandler := handlers.NewTicketHandler(database)
 		r.Route("/api/tickets", func(r chi.Router) {
@@ -0,0 +1,395 @@
+// internal/accounting/accounting.go
+package accounting
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/receivables"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Account roles that can be mapped to accounts in a package.
+const (
+	AccountReceivable = "receivable"
+	AccountPayable    = "payable"
+	AccountBank       = "bank"
+	AccountSales      = "sales"
+	AccountPurchases  = "purchases"
+	AccountTax        = "tax"       // Output tax without a mapping of its own.
+	AccountInputTax   = "input_tax" // Input tax without a mapping of its own.
+	AccountCustomer   = "customer"  // Customer name for invoices without a mapped customer.
+)
+
+// Types of exported records.
+const (
+	RecordInvoice       = "invoice"
+	RecordBill          = "bill"
+	RecordPayment       = "payment"
+	RecordVendorPayment = "vendor_payment"
+)
+
+// Errors returned when exporting.
+var (
+	ErrUnknownFormat    = errors.New("unknown export format")
+	ErrInvalidMapping   = errors.New("invalid accounting mapping")
+	ErrInvalidPeriod    = errors.New("invalid export period")
+	ErrNothingToExport  = errors.New("nothing new to export in this period")
+	ErrRenderingFailure = errors.New("export could not be rendered")
+)
+
+// defaults are the names used where a tenant has no mapping, per format
+// ("" for all). Xero wants account codes and tax types rather than names.
+var defaults = map[string]map[string]string{
+	"": {
+		models.MappingAccount + ":" + AccountReceivable: "Accounts Receivable",
+		models.MappingAccount + ":" + AccountPayable:    "Accounts Payable",
+		models.MappingAccount + ":" + AccountBank:       "Bank",
+		models.MappingAccount + ":" + AccountSales:      "Sales",
+		models.MappingAccount + ":" + AccountPurchases:  "Cost of Sales",
+		models.MappingAccount + ":" + AccountTax:        "Output Tax",
+		models.MappingAccount + ":" + AccountInputTax:   "Input Tax",
+		models.MappingAccount + ":" + AccountCustomer:   "Cash Customer",
+	},
+	models.ExportXeroCSV: {
+		models.MappingAccount + ":" + AccountSales:     "200",
+		models.MappingAccount + ":" + AccountPurchases: "310",
+		models.MappingAccount + ":" + AccountTax:       "OUTPUT",
+		models.MappingAccount + ":" + AccountInputTax:  "INPUT",
+	},
+}
+
+// ValidFormat reports whether f is an export format.
+func ValidFormat(f string) bool {
+	switch f {
+	case models.ExportQuickBooksIIF, models.ExportXeroCSV, models.ExportTallyXML:
+		return true
+	}
+	return false
+}
+
+// ValidateMapping checks a mapping before it is saved.
+func ValidateMapping(m *models.AccountingMapping) error {
+	m.Format = strings.ToLower(strings.TrimSpace(m.Format))
+	m.Kind = strings.ToLower(strings.TrimSpace(m.Kind))
+	m.Key = strings.TrimSpace(m.Key)
+	m.Value = strings.TrimSpace(m.Value)
+	if m.Format != "" && !ValidFormat(m.Format) {
+		return fmt.Errorf("%w: format must be iif, xero, tally or empty", ErrInvalidMapping)
+	}
+	switch m.Kind {
+	case models.MappingAccount:
+		if _, ok := defaults[""][m.Kind+":"+m.Key]; !ok {
+			return fmt.Errorf("%w: unknown account %q", ErrInvalidMapping, m.Key)
+		}
+	case models.MappingTax:
+	case models.MappingContact:
+		if _, err := uuid.Parse(m.Key); err != nil {
+			return fmt.Errorf("%w: contact key must be a customer ID", ErrInvalidMapping)
+		}
+	default:
+		return fmt.Errorf("%w: kind must be account, tax or contact", ErrInvalidMapping)
+	}
+	if m.Key == "" || m.Value == "" {
+		return fmt.Errorf("%w: key and value are required", ErrInvalidMapping)
+	}
+	return nil
+}
+
+// Mappings resolves names for one format: the tenant's mapping for the
+// format, then its mapping for all formats, then the default.
+type Mappings struct {
+	format string
+	values map[string]string
+}
+
+// LoadMappings loads the tenant's mappings for the format.
+func LoadMappings(db *gorm.DB, tenantID uint, format string) (*Mappings, error) {
+	var rows []models.AccountingMapping
+	if err := db.Where("tenant_id = ? AND format IN ?", tenantID, []string{"", format}).
+		Order("format").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	m := &Mappings{format: format, values: map[string]string{}}
+	for _, r := range rows { // Format-specific rows come last and win.
+		m.values[r.Kind+":"+r.Key] = r.Value
+	}
+	return m, nil
+}
+
+func (m *Mappings) lookup(kind, key string) (string, bool) {
+	if v, ok := m.values[kind+":"+key]; ok {
+		return v, true
+	}
+	if v, ok := defaults[m.format][kind+":"+key]; ok {
+		return v, true
+	}
+	v, ok := defaults[""][kind+":"+key]
+	return v, ok
+}
+
+// Account returns the name (or code) of an account role.
+func (m *Mappings) Account(role string) string {
+	v, _ := m.lookup(models.MappingAccount, role)
+	return v
+}
+
+// Tax returns the tax code, or tax account, for a line's tax components.
+// The components' names joined with "+" (e.g. "CGST+SGST") are tried
+// first, then each name, then the default output or input tax.
+func (m *Mappings) Tax(taxes []models.InvoiceItemTax, purchase bool) string {
+	names := make([]string, 0, len(taxes))
+	for _, t := range taxes {
+		names = append(names, t.Name)
+	}
+	sort.Strings(names)
+	if v, ok := m.values[models.MappingTax+":"+strings.Join(names, "+")]; ok && len(names) > 0 {
+		return v
+	}
+	for _, n := range names {
+		if v, ok := m.values[models.MappingTax+":"+n]; ok {
+			return v
+		}
+	}
+	if purchase {
+		return m.Account(AccountInputTax)
+	}
+	return m.Account(AccountTax)
+}
+
+// Customer returns the name of an invoice's customer.
+func (m *Mappings) Customer(id *uuid.UUID) string {
+	if id != nil {
+		if v, ok := m.values[models.MappingContact+":"+id.String()]; ok {
+			return v
+		}
+	}
+	return m.Account(AccountCustomer)
+}
+
+// Batch is what one export covers.
+type Batch struct {
+	Format         string
+	From, To       time.Time
+	Invoices       []models.Invoice // Sale invoices, with Items.Taxes.
+	Bills          []models.Invoice // Purchase invoices, with Items.Taxes.
+	Payments       []models.Payment
+	VendorPayments []models.VendorPayment
+	Vendors        map[uint]string
+	invoices       map[uuid.UUID]*models.Invoice // Invoices payments were made for.
+}
+
+// Empty reports whether there is nothing in the batch.
+func (b *Batch) Empty() bool {
+	return len(b.Invoices)+len(b.Bills)+len(b.Payments)+len(b.VendorPayments) == 0
+}
+
+// Collect gathers the documents dated from from through to (inclusive
+// days): issued invoices and vendor bills that are not void, and completed
+// customer and vendor payments. Unless includeExported is set, documents
+// already exported in the format are left out.
+func Collect(db *gorm.DB, tenantID uint, format string, from, to time.Time, includeExported bool) (*Batch, error) {
+	if !ValidFormat(format) {
+		return nil, ErrUnknownFormat
+	}
+	if from.IsZero() || to.Before(from) {
+		return nil, ErrInvalidPeriod
+	}
+	end := to.AddDate(0, 0, 1)
+	b := &Batch{Format: format, From: from, To: to, Vendors: map[uint]string{}, invoices: map[uuid.UUID]*models.Invoice{}}
+
+	exported := map[string]map[string]bool{}
+	if !includeExported {
+		var records []models.AccountingExportRecord
+		if err := db.Where("tenant_id = ? AND format = ?", tenantID, format).Find(&records).Error; err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			if exported[r.RecordType] == nil {
+				exported[r.RecordType] = map[string]bool{}
+			}
+			exported[r.RecordType][r.RecordID] = true
+		}
+	}
+
+	var invoices []models.Invoice
+	if err := db.Preload("Items", func(tx *gorm.DB) *gorm.DB { return tx.Order("position, id") }).Preload("Items.Taxes").
+		Where("tenant_id = ? AND issue_date >= ? AND issue_date < ? AND status NOT IN ?",
+			tenantID, from, end, []string{"Draft", "Void", "Canceled", "Cancelled"}).
+		Order("issue_date, created_at").Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	for _, inv := range invoices {
+		switch {
+		case inv.InvoiceType == "purchase" && !exported[RecordBill][inv.ID.String()]:
+			b.Bills = append(b.Bills, inv)
+		case inv.InvoiceType == "sale" && inv.IssuedAt != nil && !exported[RecordInvoice][inv.ID.String()]:
+			b.Invoices = append(b.Invoices, inv)
+		}
+	}
+
+	var payments []models.Payment
+	if err := db.Preload("Allocations").
+		Where("tenant_id = ? AND status = ? AND payment_date >= ? AND payment_date < ?",
+			tenantID, receivables.PaymentCompleted, from, end).
+		Order("payment_date, id").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range payments {
+		if !exported[RecordPayment][fmt.Sprint(p.ID)] {
+			b.Payments = append(b.Payments, p)
+		}
+	}
+
+	var vendorPayments []models.VendorPayment
+	if err := db.Where("tenant_id = ? AND status = ? AND paid_at >= ? AND paid_at < ?",
+		tenantID, "Completed", from, end).
+		Order("paid_at, id").Find(&vendorPayments).Error; err != nil {
+		return nil, err
+	}
+	for _, vp := range vendorPayments {
+		if !exported[RecordVendorPayment][fmt.Sprint(vp.ID)] {
+			b.VendorPayments = append(b.VendorPayments, vp)
+		}
+	}
+
+	return b, b.load(db, tenantID)
+}
+
+// load fetches the vendor names and the invoices that payments refer to.
+func (b *Batch) load(db *gorm.DB, tenantID uint) error {
+	var vendorIDs []uint
+	for _, inv := range b.Bills {
+		if inv.VendorID != nil {
+			vendorIDs = append(vendorIDs, *inv.VendorID)
+		}
+	}
+	var ids []uuid.UUID
+	for _, p := range b.Payments {
+		if id := paidInvoice(p); id != nil {
+			ids = append(ids, *id)
+		}
+	}
+	for _, vp := range b.VendorPayments {
+		vendorIDs = append(vendorIDs, vp.VendorID)
+		ids = append(ids, vp.InvoiceID)
+	}
+	if len(vendorIDs) > 0 {
+		var vendors []models.Vendor
+		if err := db.Where("tenant_id = ? AND id IN ?", tenantID, vendorIDs).Find(&vendors).Error; err != nil {
+			return err
+		}
+		for _, v := range vendors {
+			b.Vendors[v.ID] = v.Name
+		}
+	}
+	if len(ids) > 0 {
+		var invoices []models.Invoice
+		if err := db.Where("tenant_id = ? AND id IN ?", tenantID, ids).Find(&invoices).Error; err != nil {
+			return err
+		}
+		for i := range invoices {
+			b.invoices[invoices[i].ID] = &invoices[i]
+		}
+	}
+	return nil
+}
+
+// paidInvoice is the invoice a payment was made for, or else the first it
+// was allocated to.
+func paidInvoice(p models.Payment) *uuid.UUID {
+	if p.InvoiceID != nil {
+		return p.InvoiceID
+	}
+	if len(p.Allocations) > 0 {
+		return &p.Allocations[0].InvoiceID
+	}
+	return nil
+}
+
+// vendor returns the name of a vendor.
+func (b *Batch) vendor(id *uint) string {
+	if id != nil {
+		if name, ok := b.Vendors[*id]; ok {
+			return name
+		}
+	}
+	return "Unknown vendor"
+}
+
+// Export renders the batch, saves the file and marks its documents as
+// exported in the format.
+func Export(tx *gorm.DB, tenantID, userID uint, b *Batch, m *Mappings) (*models.AccountingExport, error) {
+	if b.Empty() {
+		return nil, ErrNothingToExport
+	}
+	var (
+		content     []byte
+		contentType string
+		ext         string
+		err         error
+	)
+	switch b.Format {
+	case models.ExportQuickBooksIIF:
+		content, err = RenderIIF(b, m)
+		contentType, ext = "text/plain; charset=utf-8", "iif"
+	case models.ExportXeroCSV:
+		content, err = RenderXero(b, m)
+		contentType, ext = "application/zip", "zip"
+	case models.ExportTallyXML:
+		content, err = RenderTally(b, m)
+		contentType, ext = "application/xml", "xml"
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRenderingFailure, err)
+	}
+
+	export := models.AccountingExport{
+		TenantID:    tenantID,
+		Format:      b.Format,
+		From:        b.From,
+		To:          b.To,
+		Invoices:    len(b.Invoices),
+		Bills:       len(b.Bills),
+		Payments:    len(b.Payments) + len(b.VendorPayments),
+		Filename:    fmt.Sprintf("%s-%s-%s.%s", b.Format, b.From.Format("20060102"), b.To.Format("20060102"), ext),
+		ContentType: contentType,
+		Content:     content,
+		CreatedBy:   userID,
+	}
+	if err := tx.Create(&export).Error; err != nil {
+		return nil, err
+	}
+
+	var records []models.AccountingExportRecord
+	add := func(typ, id string) {
+		records = append(records, models.AccountingExportRecord{
+			TenantID: tenantID, Format: b.Format, RecordType: typ, RecordID: id, ExportID: export.ID})
+	}
+	for _, inv := range b.Invoices {
+		add(RecordInvoice, inv.ID.String())
+	}
+	for _, inv := range b.Bills {
+		add(RecordBill, inv.ID.String())
+	}
+	for _, p := range b.Payments {
+		add(RecordPayment, fmt.Sprint(p.ID))
+	}
+	for _, vp := range b.VendorPayments {
+		add(RecordVendorPayment, fmt.Sprint(vp.ID))
+	}
+	// Documents exported again keep their first export.
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(records, 200).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
@@ -0,0 +1,106 @@
+package accounting
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExports(t *testing.T) {
+	db := testutil.DB(t)
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	number, customer := "INV-2026-000001", uuid.New()
+	sale := models.Invoice{TenantID: 1, Number: &number, InvoiceType: "sale", Status: "Outstanding", Currency: "INR",
+		IssueDate: day, DueDate: day.AddDate(0, 0, 15), IssuedAt: &day, CustomerID: &customer,
+		Amount: money.FromInt(1180), TaxTotal: money.FromInt(180), Items: []models.InvoiceItem{{
+			TenantID: 1, Description: "Goa package", Quantity: 2, UnitPrice: money.FromInt(500),
+			Net: money.FromInt(1000), TaxAmount: money.FromInt(180), Total: money.FromInt(1180),
+			Taxes: []models.InvoiceItemTax{{Name: "CGST", Rate: 9, Amount: money.FromInt(90)},
+				{Name: "SGST", Rate: 9, Amount: money.FromInt(90)}}}}}
+	db.Create(&sale)
+	draft := models.Invoice{TenantID: 1, InvoiceType: "sale", Status: "Draft", IssueDate: day, DueDate: day, Amount: money.FromInt(50)}
+	db.Create(&draft)
+	db.Create(&models.Vendor{TenantID: 1, Name: "Sea View Resort"})
+	vendorID := uint(1)
+	bill := models.Invoice{TenantID: 1, InvoiceType: "purchase", Reference: "SV-77", Status: "Outstanding", Currency: "INR",
+		IssueDate: day, DueDate: day, VendorID: &vendorID, Amount: money.FromInt(600)}
+	db.Create(&bill)
+	db.Create(&models.Payment{TenantID: 1, InvoiceID: &sale.ID, PaymentDate: day, Amount: money.FromInt(1180),
+		Currency: "INR", Status: "Completed", Reference: "UTR123"})
+	db.Create(&models.VendorPayment{TenantID: 1, VendorID: 1, InvoiceID: bill.ID, Amount: money.FromInt(600),
+		Currency: "INR", Status: "Completed", PaidAt: &day})
+	db.Create(&models.AccountingMapping{TenantID: 1, Kind: models.MappingTax, Key: "CGST+SGST", Value: "GST Payable"})
+	db.Create(&models.AccountingMapping{TenantID: 1, Kind: models.MappingContact, Key: customer.String(), Value: "Acme Corp"})
+	db.Create(&models.AccountingMapping{TenantID: 1, Format: models.ExportXeroCSV, Kind: models.MappingTax,
+		Key: "CGST+SGST", Value: "GST18"})
+
+	export := func(format string, includeExported bool) (*models.AccountingExport, error) {
+		b, err := Collect(db, 1, format, day, day, includeExported)
+		if err != nil {
+			return nil, err
+		}
+		m, err := LoadMappings(db, 1, format)
+		if err != nil {
+			return nil, err
+		}
+		return Export(db, 1, 1, b, m)
+	}
+
+	// IIF: balanced transactions with mapped names; drafts are left out.
+	iif, err := export(models.ExportQuickBooksIIF, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, iif.Invoices)
+	assert.Equal(t, 1, iif.Bills)
+	assert.Equal(t, 2, iif.Payments)
+	text := string(iif.Content)
+	assert.Contains(t, text, "TRNS\tINVOICE\t03/10/2026\tAccounts Receivable\tAcme Corp\t1180.00\tINV-2026-000001")
+	assert.Contains(t, text, "SPL\tINVOICE\t03/10/2026\tGST Payable\tAcme Corp\t-180.00")
+	assert.Contains(t, text, "TRNS\tBILL\t03/10/2026\tAccounts Payable\tSea View Resort\t-600.00\tSV-77")
+	assert.Contains(t, text, "TRNS\tBILLPMT\t03/10/2026\tBank\tSea View Resort\t-600.00")
+	assert.Equal(t, 4, strings.Count(text, "\nENDTRNS"))
+
+	// Exported documents are not exported again in the same format.
+	_, err = export(models.ExportQuickBooksIIF, false)
+	assert.ErrorIs(t, err, ErrNothingToExport)
+	again, err := export(models.ExportQuickBooksIIF, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, again.Invoices)
+
+	// Xero: a zip of sales invoices, bills and a bank statement.
+	xero, err := export(models.ExportXeroCSV, false)
+	assert.NoError(t, err)
+	zr, err := zip.NewReader(bytes.NewReader(xero.Content), int64(len(xero.Content)))
+	assert.NoError(t, err)
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		var buf bytes.Buffer
+		buf.ReadFrom(rc)
+		rc.Close()
+		files[f.Name] = buf.String()
+	}
+	assert.Contains(t, files["SalesInvoices.csv"], "Acme Corp,INV-2026-000001,,10/03/2026,25/03/2026,Goa package,2,500.00,200,GST18,180.00,INR")
+	assert.Contains(t, files["Bills.csv"], "Sea View Resort,SV-77")
+	assert.Contains(t, files["BankStatement.csv"], "10/03/2026,1180.00,Acme Corp")
+
+	// Tally: vouchers with party ledgers, debits negative.
+	tally, err := export(models.ExportTallyXML, false)
+	assert.NoError(t, err)
+	var env tallyEnvelope
+	assert.NoError(t, xml.Unmarshal(tally.Content, &env))
+	assert.Len(t, env.Message, 4)
+	sales := env.Message[0].Voucher
+	assert.Equal(t, "Sales", sales.Type)
+	assert.Equal(t, tallyEntry{Ledger: "Acme Corp", Positive: "Yes", Amount: "-1180.00"}, sales.Entries[0])
+	assert.Equal(t, tallyEntry{Ledger: "Sales", Positive: "No", Amount: "1000.00"}, sales.Entries[1])
+}
@@ -0,0 +1,49 @@
+// internal/accounting/iif.go
+package accounting
+
+import (
+	"bytes"
+	"strings"
+)
+
+// iifTypes are the QuickBooks transaction types of the record types.
+var iifTypes = map[string]string{
+	RecordInvoice:       "INVOICE",
+	RecordBill:          "BILL",
+	RecordPayment:       "PAYMENT",
+	RecordVendorPayment: "BILLPMT",
+}
+
+// iifField strips the tabs and line breaks IIF uses as separators.
+var iifField = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+
+// RenderIIF renders the batch as a QuickBooks Desktop IIF file: one TRNS
+// line per document followed by its SPL lines. IIF has no currency column,
+// so amounts are in each document's own currency.
+func RenderIIF(b *Batch, m *Mappings) ([]byte, error) {
+	var buf bytes.Buffer
+	header := "TRNSTYPE\tDATE\tACCNT\tNAME\tAMOUNT\tDOCNUM\tMEMO\n"
+	buf.WriteString("!TRNS\t" + header)
+	buf.WriteString("!SPL\t" + header)
+	buf.WriteString("!ENDTRNS\n")
+	for _, v := range b.vouchers(m) {
+		for i, l := range v.Lines {
+			tag := "SPL"
+			if i == 0 {
+				tag = "TRNS"
+			}
+			memo := l.Memo
+			if memo == "" {
+				memo = v.Memo
+			}
+			fields := []string{tag, iifTypes[v.Kind], v.Date.Format("01/02/2006"), l.Account, v.Party,
+				l.Amount.Format(v.Currency), v.Number, memo}
+			for j, f := range fields {
+				fields[j] = iifField.Replace(f)
+			}
+			buf.WriteString(strings.Join(fields, "\t") + "\n")
+		}
+		buf.WriteString("ENDTRNS\n")
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,84 @@
+// internal/accounting/tally.go
+package accounting
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+// tallyTypes are the Tally voucher types of the record types.
+var tallyTypes = map[string]string{
+	RecordInvoice:       "Sales",
+	RecordBill:          "Purchase",
+	RecordPayment:       "Receipt",
+	RecordVendorPayment: "Payment",
+}
+
+type tallyEnvelope struct {
+	XMLName xml.Name       `xml:"ENVELOPE"`
+	Request string         `xml:"HEADER>TALLYREQUEST"`
+	Report  string         `xml:"BODY>IMPORTDATA>REQUESTDESC>REPORTNAME"`
+	Message []tallyMessage `xml:"BODY>IMPORTDATA>REQUESTDATA>TALLYMESSAGE"`
+}
+
+type tallyMessage struct {
+	Voucher tallyVoucher `xml:"VOUCHER"`
+}
+
+type tallyVoucher struct {
+	Type      string       `xml:"VCHTYPE,attr"`
+	Action    string       `xml:"ACTION,attr"`
+	Date      string       `xml:"DATE"`
+	TypeName  string       `xml:"VOUCHERTYPENAME"`
+	Number    string       `xml:"VOUCHERNUMBER,omitempty"`
+	Reference string       `xml:"REFERENCE,omitempty"`
+	Party     string       `xml:"PARTYLEDGERNAME"`
+	Narration string       `xml:"NARRATION,omitempty"`
+	Entries   []tallyEntry `xml:"ALLLEDGERENTRIES.LIST"`
+}
+
+type tallyEntry struct {
+	Ledger   string `xml:"LEDGERNAME"`
+	Positive string `xml:"ISDEEMEDPOSITIVE"`
+	Amount   string `xml:"AMOUNT"`
+}
+
+// RenderTally renders the batch as Tally import XML, one voucher per
+// document. The customer's or vendor's own ledger takes the receivables or
+// payables line, as Tally keeps parties under Sundry Debtors and Creditors.
+// Debits are negative with ISDEEMEDPOSITIVE Yes, as Tally expects.
+func RenderTally(b *Batch, m *Mappings) ([]byte, error) {
+	env := tallyEnvelope{Request: "Import Data", Report: "Vouchers"}
+	for _, v := range b.vouchers(m) {
+		tv := tallyVoucher{
+			Type:      tallyTypes[v.Kind],
+			Action:    "Create",
+			Date:      v.Date.Format("20060102"),
+			TypeName:  tallyTypes[v.Kind],
+			Number:    v.Number,
+			Reference: v.Number,
+			Party:     v.Party,
+			Narration: v.Memo,
+		}
+		for _, l := range v.Lines {
+			e := tallyEntry{Ledger: l.Account, Positive: "No", Amount: (-l.Amount).Format(v.Currency)}
+			if l.Party {
+				e.Ledger = v.Party
+			}
+			if l.Amount > 0 {
+				e.Positive = "Yes"
+			}
+			tv.Entries = append(tv.Entries, e)
+		}
+		env.Message = append(env.Message, tallyMessage{Voucher: tv})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(env); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,128 @@
+// internal/accounting/vouchers.go
+package accounting
+
+import (
+	"fmt"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+)
+
+// posting debits (positive) or credits (negative) one account.
+type posting struct {
+	Account string
+	Party   bool // The customer's or vendor's receivables or payables line.
+	Amount  money.Amount
+	Memo    string
+}
+
+// voucher is a document as a balanced transaction, the shape both IIF and
+// Tally import. The first line is the party's or the bank's.
+type voucher struct {
+	Kind     string // One of the record types.
+	Number   string
+	Date     time.Time
+	Party    string
+	Memo     string
+	Currency string
+	Lines    []posting
+}
+
+func documentNumber(inv *models.Invoice) string {
+	switch {
+	case inv.Number != nil:
+		return *inv.Number
+	case inv.Reference != "":
+		return inv.Reference
+	default:
+		return inv.ID.String()
+	}
+}
+
+// invoiceLines credits sales (or debits purchases) per line and tax per tax
+// account, or the totals when the invoice has no lines.
+func invoiceLines(inv *models.Invoice, m *Mappings) []posting {
+	purchase := inv.InvoiceType == "purchase"
+	sign, account := money.Amount(-1), m.Account(AccountSales)
+	if purchase {
+		sign, account = 1, m.Account(AccountPurchases)
+	}
+	var lines []posting
+	taxes := map[string]money.Amount{}
+	var order []string
+	addTax := func(acc string, a money.Amount) {
+		if a == 0 {
+			return
+		}
+		if _, ok := taxes[acc]; !ok {
+			order = append(order, acc)
+		}
+		taxes[acc] += a
+	}
+	if len(inv.Items) == 0 {
+		lines = append(lines, posting{Account: account, Amount: sign * (inv.Amount - inv.TaxTotal)})
+		addTax(m.Tax(nil, purchase), inv.TaxTotal)
+	}
+	for _, item := range inv.Items {
+		lines = append(lines, posting{Account: account, Amount: sign * item.Net, Memo: item.Description})
+		addTax(m.Tax(item.Taxes, purchase), item.TaxAmount)
+	}
+	for _, acc := range order {
+		lines = append(lines, posting{Account: acc, Amount: sign * taxes[acc], Memo: "Tax"})
+	}
+
+	var total money.Amount
+	for _, l := range lines {
+		total += l.Amount
+	}
+	control := posting{Account: m.Account(AccountReceivable), Party: true, Amount: -total}
+	if purchase {
+		control.Account = m.Account(AccountPayable)
+	}
+	return append([]posting{control}, lines...)
+}
+
+// vouchers turns the batch into balanced transactions: invoices, bills,
+// customer payments and vendor payments, in that order.
+func (b *Batch) vouchers(m *Mappings) []voucher {
+	var out []voucher
+	for i := range b.Invoices {
+		inv := &b.Invoices[i]
+		out = append(out, voucher{Kind: RecordInvoice, Number: documentNumber(inv), Date: inv.IssueDate,
+			Party: m.Customer(inv.CustomerID), Currency: inv.Currency, Lines: invoiceLines(inv, m)})
+	}
+	for i := range b.Bills {
+		inv := &b.Bills[i]
+		out = append(out, voucher{Kind: RecordBill, Number: documentNumber(inv), Date: inv.IssueDate,
+			Party: b.vendor(inv.VendorID), Currency: inv.Currency, Lines: invoiceLines(inv, m)})
+	}
+	for _, p := range b.Payments {
+		v := voucher{Kind: RecordPayment, Number: p.Reference, Date: p.PaymentDate, Party: m.Customer(nil),
+			Memo: fmt.Sprintf("Payment %d %s", p.ID, p.Method), Currency: p.Currency}
+		if id := paidInvoice(p); id != nil {
+			if inv, ok := b.invoices[*id]; ok {
+				v.Party = m.Customer(inv.CustomerID)
+				v.Memo = fmt.Sprintf("Payment for %s", documentNumber(inv))
+			}
+		}
+		v.Lines = []posting{
+			{Account: m.Account(AccountBank), Amount: p.Amount},
+			{Account: m.Account(AccountReceivable), Party: true, Amount: -p.Amount},
+		}
+		out = append(out, v)
+	}
+	for _, vp := range b.VendorPayments {
+		v := voucher{Kind: RecordVendorPayment, Number: fmt.Sprint(vp.ID), Date: *vp.PaidAt,
+			Party: b.vendor(&vp.VendorID), Memo: fmt.Sprintf("Vendor payment %d %s", vp.ID, vp.Method), Currency: vp.Currency}
+		if inv, ok := b.invoices[vp.InvoiceID]; ok {
+			v.Memo = fmt.Sprintf("Payment of %s", documentNumber(inv))
+		}
+		v.Lines = []posting{
+			{Account: m.Account(AccountBank), Amount: -vp.Amount},
+			{Account: m.Account(AccountPayable), Party: true, Amount: vp.Amount},
+		}
+		out = append(out, v)
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+// internal/accounting/xero.go
+package accounting
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+)
+
+// xeroDate is the date format of Xero imports. Xero reads dates in the
+// organisation's regional format; day/month/year is the most common.
+const xeroDate = "02/01/2006"
+
+var xeroInvoiceHeader = []string{"*ContactName", "*InvoiceNumber", "Reference", "*InvoiceDate", "*DueDate",
+	"*Description", "*Quantity", "*UnitAmount", "*AccountCode", "*TaxType", "TaxAmount", "Currency"}
+
+// RenderXero renders the batch as a zip of Xero import files: sales
+// invoices and bills in Xero's invoice template, and customer and vendor
+// payments as a bank statement to reconcile against them.
+func RenderXero(b *Batch, m *Mappings) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(name string, rows [][]string) error {
+		if len(rows) < 2 {
+			return nil
+		}
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		w := csv.NewWriter(f)
+		w.WriteAll(rows)
+		return w.Error()
+	}
+
+	invoices := [][]string{xeroInvoiceHeader}
+	for i := range b.Invoices {
+		invoices = append(invoices, xeroInvoiceRows(&b.Invoices[i], m.Customer(b.Invoices[i].CustomerID), m)...)
+	}
+	if err := write("SalesInvoices.csv", invoices); err != nil {
+		return nil, err
+	}
+	bills := [][]string{xeroInvoiceHeader}
+	for i := range b.Bills {
+		bills = append(bills, xeroInvoiceRows(&b.Bills[i], b.vendor(b.Bills[i].VendorID), m)...)
+	}
+	if err := write("Bills.csv", bills); err != nil {
+		return nil, err
+	}
+
+	statement := [][]string{{"*Date", "*Amount", "Payee", "Description", "Reference"}}
+	for _, v := range b.vouchers(m) {
+		if v.Kind != RecordPayment && v.Kind != RecordVendorPayment {
+			continue
+		}
+		statement = append(statement, []string{v.Date.Format(xeroDate), v.Lines[0].Amount.Format(v.Currency),
+			v.Party, v.Memo, v.Number})
+	}
+	if err := write("BankStatement.csv", statement); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// xeroInvoiceRows are an invoice's lines in Xero's invoice template, with
+// the tax we computed so Xero does not recalculate it. Discounted lines are
+// exported as one unit of their net amount.
+func xeroInvoiceRows(inv *models.Invoice, contact string, m *Mappings) [][]string {
+	purchase := inv.InvoiceType == "purchase"
+	account := m.Account(AccountSales)
+	if purchase {
+		account = m.Account(AccountPurchases)
+	}
+	cur := inv.Currency
+	row := func(desc, qty, unit string, taxes []models.InvoiceItemTax, tax money.Amount) []string {
+		taxType := "NONE" // Xero's type for untaxed lines.
+		if len(taxes) > 0 || tax != 0 {
+			taxType = m.Tax(taxes, purchase)
+		}
+		return []string{contact, documentNumber(inv), inv.Installment, inv.IssueDate.Format(xeroDate),
+			inv.DueDate.Format(xeroDate), desc, qty, unit, account, taxType, tax.Format(cur), cur}
+	}
+	if len(inv.Items) == 0 {
+		return [][]string{row("Invoice "+documentNumber(inv), "1", (inv.Amount - inv.TaxTotal).Format(cur),
+			nil, inv.TaxTotal)}
+	}
+	rows := make([][]string, 0, len(inv.Items))
+	for _, item := range inv.Items {
+		qty, unit, desc := fmt.Sprintf("%g", item.Quantity), item.UnitPrice.String(), item.Description
+		if item.DiscountAmount != 0 || item.Quantity == 0 {
+			qty, unit = "1", item.Net.Format(cur)
+			desc = fmt.Sprintf("%s (%g x %s less %s discount)", item.Description, item.Quantity,
+				item.UnitPrice, item.DiscountAmount.Format(cur))
+		}
+		rows = append(rows, row(desc, qty, unit, item.Taxes, item.TaxAmount))
+	}
+	return rows
+}
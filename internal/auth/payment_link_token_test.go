@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymentLinkToken(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	invoiceID := uuid.New()
+	token := GeneratePaymentLinkToken(3, invoiceID, now.Add(24*time.Hour), "secret")
+
+	tenantID, gotInvoice, err := ParsePaymentLinkToken(token, "secret", now)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), tenantID)
+	assert.Equal(t, invoiceID, gotInvoice)
+
+	parts := strings.Split(token, ".")
+	otherTenant := strings.Join(append([]string{"4"}, parts[1:]...), ".")
+	otherInvoice := strings.Join([]string{parts[0], uuid.NewString(), parts[2], parts[3]}, ".")
+	later := strings.Join([]string{parts[0], parts[1], "9999999999", parts[3]}, ".")
+
+	tests := []struct {
+		name   string
+		token  string
+		secret string
+		at     time.Time
+		err    error
+	}{
+		{"expired", token, "secret", now.Add(25 * time.Hour), ErrPaymentLinkExpired},
+		{"other secret", token, "other", now, nil},
+		{"tenant changed", otherTenant, "secret", now, nil},
+		{"invoice changed", otherInvoice, "secret", now, nil},
+		{"expiry extended", later, "secret", now, nil},
+		{"malformed", "3." + invoiceID.String(), "secret", now, nil},
+		{"empty", "", "secret", now, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParsePaymentLinkToken(tt.token, tt.secret, tt.at)
+			require.Error(t, err)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				assert.NotErrorIs(t, err, ErrPaymentLinkExpired)
+			}
+		})
+	}
+}
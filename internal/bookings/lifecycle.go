@@ -0,0 +1,239 @@
+// internal/bookings/lifecycle.go
+package bookings
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"travel-agency/internal/inventory"
+	"travel-agency/internal/invoicing"
+	"travel-agency/internal/ledger"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/receivables"
+	"travel-agency/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidTransition is returned when a status change is not allowed.
+var ErrInvalidTransition = errors.New("invalid booking status transition")
+
+// transitions lists the states reachable from each state. Completed and
+// Cancelled are terminal.
+var transitions = map[string][]string{
+	models.BookingPending:   {models.BookingConfirmed, models.BookingCancelled},
+	models.BookingConfirmed: {models.BookingTicketed, models.BookingCompleted, models.BookingCancelled},
+	models.BookingTicketed:  {models.BookingCompleted, models.BookingCancelled},
+}
+
+// IsValidStatus reports whether s is a known booking status.
+func IsValidStatus(s string) bool {
+	switch s {
+	case models.BookingPending, models.BookingConfirmed, models.BookingTicketed,
+		models.BookingCompleted, models.BookingCancelled:
+		return true
+	}
+	return false
+}
+
+// CanTransition reports whether a booking may move from one status to another.
+func CanTransition(from, to string) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordTransition stores a transition row and an audit log entry.
+func RecordTransition(tx *gorm.DB, b *models.Booking, from string, actorID uint, reason string) error {
+	t := models.BookingTransition{
+		TenantID:   b.TenantID,
+		BookingID:  b.ID,
+		FromStatus: from,
+		ToStatus:   b.Status,
+		ActorID:    actorID,
+		Reason:     reason,
+		CreatedAt:  time.Now(),
+	}
+	if err := tx.Create(&t).Error; err != nil {
+		return err
+	}
+	details := fmt.Sprintf("Booking %d: %q -> %q", b.ID, from, b.Status)
+	return utils.LogAction(tx, b.TenantID, actorID, "BOOKING_STATUS", "Booking", details)
+}
+
+// Transition moves the booking to a new status (not Cancelled, see Cancel),
+// saves it and records who did it. Confirming a booking may invoice it, see
+// invoicing.OnBookingConfirmed.
+func Transition(tx *gorm.DB, b *models.Booking, to string, actorID uint, reason string) error {
+	if to == models.BookingCancelled {
+		return fmt.Errorf("%w: use Cancel to cancel a booking", ErrInvalidTransition)
+	}
+	if !CanTransition(b.Status, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, b.Status, to)
+	}
+	from := b.Status
+	b.Status = to
+	b.UpdatedAt = time.Now()
+	if err := tx.Save(b).Error; err != nil {
+		return err
+	}
+	if err := RecordTransition(tx, b, from, actorID, reason); err != nil {
+		return err
+	}
+	if to == models.BookingConfirmed {
+		return invoicing.OnBookingConfirmed(tx, b, actorID)
+	}
+	return nil
+}
+
+// ComputePenalty applies a vendor cancellation policy to a booking cancelled
+// at the given time and returns the penalty on the client price and on the
+// vendor cost. Without a policy nothing is charged; if the cancellation is
+// later than every tier allows, the full amount is forfeited.
+func ComputePenalty(rules []models.CancellationRule, b models.Booking, at time.Time) (customer, vendor money.Amount) {
+	if len(rules) == 0 {
+		return 0, 0
+	}
+	daysLeft := int(math.Floor(b.TravelDate.Sub(at).Hours() / 24))
+
+	var tier *models.CancellationRule
+	for i := range rules {
+		r := &rules[i]
+		if r.MinDaysBefore <= daysLeft && (tier == nil || r.MinDaysBefore > tier.MinDaysBefore) {
+			tier = r
+		}
+	}
+	if tier == nil {
+		return b.Price, b.Cost
+	}
+
+	customer = money.Min(b.Price, b.Price.Percent(tier.PenaltyPercent)+tier.FlatFee)
+	vendor = money.Min(b.Cost, b.Cost.Percent(tier.PenaltyPercent)+tier.FlatFee)
+	return customer.Round(b.Currency), vendor.Round(b.CostCurrency)
+}
+
+// CancelResult describes the financial effect of a cancellation.
+type CancelResult struct {
+	Booking    *models.Booking    `json:"booking"`
+	Penalty    money.Amount       `json:"penalty"`
+	Refundable money.Amount       `json:"refundable"`
+	Refund     *models.Refund     `json:"refund,omitempty"`
+	CreditNote *models.CreditNote `json:"creditNote,omitempty"`
+}
+
+// Cancel cancels the booking, returns any allotment it held, applies the
+// vendor's cancellation policy and, if the booking is billed on an invoice,
+// refunds what was paid of the refundable amount and issues a credit note
+// for the part that is still unpaid.
+func Cancel(tx *gorm.DB, b *models.Booking, actorID uint, reason string) (*CancelResult, error) {
+	if !CanTransition(b.Status, models.BookingCancelled) {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, b.Status, models.BookingCancelled)
+	}
+
+	var rules []models.CancellationRule
+	if err := tx.Where("vendor_id = ? AND tenant_id = ?", b.VendorID, b.TenantID).
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	penalty, vendorPenalty := ComputePenalty(rules, *b, now)
+
+	from := b.Status
+	b.Status = models.BookingCancelled
+	b.CancelledAt = &now
+	b.CancellationPenalty = penalty
+	b.VendorPenalty = vendorPenalty
+	b.CancellationReason = reason
+	b.UpdatedAt = now
+	if err := tx.Save(b).Error; err != nil {
+		return nil, err
+	}
+	if err := RecordTransition(tx, b, from, actorID, reason); err != nil {
+		return nil, err
+	}
+	if err := inventory.Return(tx, b.ID); err != nil {
+		return nil, err
+	}
+
+	result := &CancelResult{Booking: b, Penalty: penalty, Refundable: b.Price - penalty}
+	if b.InvoiceID == nil || result.Refundable <= 0 {
+		return result, nil
+	}
+
+	var invoice models.Invoice
+	if err := tx.Where("id = ? AND tenant_id = ?", *b.InvoiceID, b.TenantID).First(&invoice).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return result, nil
+		}
+		return nil, err
+	}
+
+	// Drafts are edited rather than credited or refunded.
+	if invoicing.IsDraft(&invoice) || receivables.IsClosed(invoice.Status) {
+		return result, nil
+	}
+	paid, err := receivables.Paid(tx, invoice)
+	if err != nil {
+		return nil, err
+	}
+	credited, err := receivables.Credited(tx, invoice.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Once the booking is taken off, the invoice is owed its other lines and
+	// the penalty. What was paid beyond that is refunded; the rest of the
+	// refundable amount was never paid and is credited.
+	owed := invoice.Amount - credited - result.Refundable
+	refundAmount := money.Min(result.Refundable, money.Max(0, paid-owed))
+	creditAmount := money.Min(result.Refundable-refundAmount, invoice.Amount-credited)
+
+	note := fmt.Sprintf("Cancellation of booking %d", b.ID)
+	if reason != "" {
+		note += ": " + reason
+	}
+	if refundAmount > 0 {
+		refund := models.Refund{
+			TenantID:  b.TenantID,
+			InvoiceID: invoice.ID,
+			BookingID: b.ID,
+			Amount:    refundAmount,
+			Currency:  invoice.Currency,
+			Status:    "Pending",
+			Reason:    note,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := tx.Create(&refund).Error; err != nil {
+			return nil, err
+		}
+		if err := ledger.PostRefund(tx, &refund); err != nil {
+			return nil, err
+		}
+		result.Refund = &refund
+		if err := utils.LogAction(tx, b.TenantID, actorID, "CREATE_REFUND", "Refund", note); err != nil {
+			return nil, err
+		}
+	}
+	if creditAmount <= 0 {
+		return result, nil
+	}
+
+	credit := models.CreditNote{
+		BookingID: b.ID,
+		IssueDate: now,
+		Amount:    creditAmount,
+		Reason:    note,
+	}
+	if err := invoicing.IssueCreditNote(tx, &invoice, &credit); err != nil {
+		return nil, err
+	}
+	result.CreditNote = &credit
+	return result, utils.LogAction(tx, b.TenantID, actorID, "CREATE_CREDIT_NOTE", "CreditNote", note)
+}
@@ -0,0 +1,109 @@
+package bookings
+
+import (
+	"testing"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanTransition(t *testing.T) {
+	assert.True(t, CanTransition(models.BookingPending, models.BookingConfirmed))
+	assert.True(t, CanTransition(models.BookingConfirmed, models.BookingTicketed))
+	assert.True(t, CanTransition(models.BookingTicketed, models.BookingCancelled))
+	assert.False(t, CanTransition(models.BookingPending, models.BookingTicketed))
+	assert.False(t, CanTransition(models.BookingCancelled, models.BookingConfirmed))
+	assert.False(t, CanTransition(models.BookingCompleted, models.BookingCancelled))
+}
+
+func TestComputePenalty(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	rules := []models.CancellationRule{
+		{MinDaysBefore: 30, PenaltyPercent: 10},
+		{MinDaysBefore: 7, PenaltyPercent: 50, FlatFee: money.FromInt(5)},
+		{MinDaysBefore: 0, PenaltyPercent: 100},
+	}
+	b := models.Booking{Cost: money.FromInt(800), Price: money.FromInt(1000)}
+
+	b.TravelDate = now.AddDate(0, 0, 45)
+	customer, vendor := ComputePenalty(rules, b, now)
+	assert.Equal(t, money.FromInt(100), customer)
+	assert.Equal(t, money.FromInt(80), vendor)
+
+	b.TravelDate = now.AddDate(0, 0, 10)
+	customer, vendor = ComputePenalty(rules, b, now)
+	assert.Equal(t, money.FromInt(505), customer)
+	assert.Equal(t, money.FromInt(405), vendor)
+
+	// Already travelled: no tier applies, everything is forfeited.
+	b.TravelDate = now.AddDate(0, 0, -1)
+	customer, _ = ComputePenalty(rules, b, now)
+	assert.Equal(t, money.FromInt(1000), customer)
+
+	customer, vendor = ComputePenalty(nil, b, now)
+	assert.Zero(t, customer)
+	assert.Zero(t, vendor)
+}
+
+func TestCancelRefundsPaidAndCreditsTheRest(t *testing.T) {
+	db := testutil.DB(t)
+	issued := time.Now()
+	invoice := models.Invoice{
+		ID: uuid.New(), TenantID: 1, InvoiceType: "sale", Status: "Partially Paid",
+		Amount: money.FromInt(100), Currency: "USD", IssueDate: issued, IssuedAt: &issued,
+		DueDate: issued.AddDate(0, 0, 30),
+	}
+	require.NoError(t, db.Create(&invoice).Error)
+	require.NoError(t, db.Create(&models.CancellationRule{TenantID: 1, VendorID: 7, PenaltyPercent: 20}).Error)
+
+	cancel := func(paid int64) *CancelResult {
+		t.Helper()
+		require.NoError(t, db.Where("1 = 1").Delete(&models.PaymentAllocation{}).Error)
+		require.NoError(t, db.Where("1 = 1").Delete(&models.Payment{}).Error)
+		if paid > 0 {
+			payment := models.Payment{TenantID: 1, Amount: money.FromInt(paid), Currency: "USD", Status: "Completed"}
+			require.NoError(t, db.Create(&payment).Error)
+			require.NoError(t, db.Create(&models.PaymentAllocation{
+				TenantID: 1, PaymentID: payment.ID, InvoiceID: invoice.ID, Amount: payment.Amount,
+			}).Error)
+		}
+		require.NoError(t, db.Where("1 = 1").Delete(&models.CreditNote{}).Error)
+		b := models.Booking{
+			TenantID: 1, VendorID: 7, InvoiceID: &invoice.ID, Status: models.BookingConfirmed,
+			TravelDate: time.Now().AddDate(0, 1, 0), Price: money.FromInt(100), Currency: "USD",
+		}
+		require.NoError(t, db.Create(&b).Error)
+		res, err := Cancel(db, &b, 1, "")
+		require.NoError(t, err)
+		assert.Equal(t, money.FromInt(20), res.Penalty)
+		return res
+	}
+
+	tests := []struct {
+		paid           int64
+		refund, credit int64
+	}{
+		{paid: 100, refund: 80},
+		{paid: 50, refund: 30, credit: 50},
+		{paid: 10, credit: 80},
+		{paid: 0, credit: 80},
+	}
+	for _, tt := range tests {
+		res := cancel(tt.paid)
+		var refund, credit money.Amount
+		if res.Refund != nil {
+			refund = res.Refund.Amount
+		}
+		if res.CreditNote != nil {
+			credit = res.CreditNote.Amount
+		}
+		assert.Equal(t, money.FromInt(tt.refund), refund, "refund when %d was paid", tt.paid)
+		assert.Equal(t, money.FromInt(tt.credit), credit, "credit when %d was paid", tt.paid)
+	}
+}
@@ -21,6 +21,15 @@ type Config struct {
 	SMTPUser   string
 	SMTPPassword string
 	SMTPFrom   string
+
+	PublicBaseURL string // Externally reachable base URL, used in links we hand out.
+	SupplierMock  bool   // Route Airline/Hotel/Tour Operator vendors to the in-process mock supplier.
+
+	StripeSecretKey     string // Enables the "stripe" payment provider when set.
+	StripeWebhookSecret string // Required with StripeSecretKey.
+	PaymentMock         bool   // Register the in-process "mock" payment provider; off unless PAYMENT_MOCK is set.
+	PaymentMockSecret   string // Signs the mock provider's webhooks; required with PaymentMock.
+	ExchangeRatesFile   string // CSV of shared exchange rates loaded at startup.
 }
 
 func LoadConfig() *Config {
@@ -58,6 +67,27 @@ func LoadConfig() *Config {
 		log.Fatalf("Invalid SMTP_PORT: %v", err)
 	}
 
+	publicBaseURL := os.Getenv("PUBLIC_BASE_URL")
+	if publicBaseURL == "" {
+		publicBaseURL = "http://localhost:" + port
+	}
+
+	supplierMock, _ := strconv.ParseBool(os.Getenv("SUPPLIER_MOCK"))
+
+	// Webhooks mark invoices paid, so a provider must not run without the
+	// secret that authenticates them.
+	stripeSecretKey := os.Getenv("STRIPE_SECRET_KEY")
+	stripeWebhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
+	if stripeSecretKey != "" && stripeWebhookSecret == "" {
+		log.Fatal("STRIPE_WEBHOOK_SECRET is required when STRIPE_SECRET_KEY is set")
+	}
+
+	paymentMock, _ := strconv.ParseBool(os.Getenv("PAYMENT_MOCK"))
+	paymentMockSecret := os.Getenv("PAYMENT_MOCK_SECRET")
+	if paymentMock && paymentMockSecret == "" {
+		log.Fatal("PAYMENT_MOCK_SECRET is required when PAYMENT_MOCK is set")
+	}
+
 	return &Config{
 		Port:         port,
 		DBHost:       os.Getenv("DB_HOST"),         // e.g., "localhost"
@@ -71,5 +101,14 @@ func LoadConfig() *Config {
 		SMTPUser:     os.Getenv("SMTP_USER"),
 		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
 		SMTPFrom:     os.Getenv("SMTP_FROM"),
+
+		PublicBaseURL: publicBaseURL,
+		SupplierMock:  supplierMock,
+
+		StripeSecretKey:     stripeSecretKey,
+		StripeWebhookSecret: stripeWebhookSecret,
+		PaymentMock:         paymentMock,
+		PaymentMockSecret:   paymentMockSecret,
+		ExchangeRatesFile:   os.Getenv("EXCHANGE_RATES_FILE"),
 	}
 }
@@ -0,0 +1,166 @@
+// internal/db/migrate.go
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// MigrateInvoices brings the invoice tables up to date. Invoice.VendorID
+// used to be a UUID, which can never match a vendor; that column is kept
+// as legacy_vendor_uuid (Invoice.LegacyVendor) and a numeric vendor_id is
+// added.
+func MigrateInvoices(db *gorm.DB) error {
+	m := db.Migrator()
+	if m.HasTable(&models.Invoice{}) {
+		cols, err := m.ColumnTypes(&models.Invoice{})
+		if err != nil {
+			return err
+		}
+		for _, col := range cols {
+			if col.Name() == "vendor_id" && strings.EqualFold(col.DatabaseTypeName(), "uuid") {
+				if err := m.RenameColumn(&models.Invoice{}, "vendor_id", "legacy_vendor_uuid"); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return db.AutoMigrate(&models.Invoice{}, &models.InvoiceItem{}, &models.InvoiceItemTax{}, &models.InvoiceSequence{})
+}
+
+// MigratePayments brings the payment tables up to date. Payment.InvoiceID
+// used to be an integer, which can never match an invoice's UUID; that
+// column is kept as legacy_invoice_id and a UUID invoice_id is added.
+func MigratePayments(db *gorm.DB) error {
+	m := db.Migrator()
+	if m.HasTable(&models.Payment{}) {
+		cols, err := m.ColumnTypes(&models.Payment{})
+		if err != nil {
+			return err
+		}
+		for _, col := range cols {
+			if col.Name() == "invoice_id" && !strings.EqualFold(col.DatabaseTypeName(), "uuid") {
+				if err := m.RenameColumn(&models.Payment{}, "invoice_id", "legacy_invoice_id"); err != nil {
+					return err
+				}
+				// New payments leave the legacy column empty.
+				if db.Dialector.Name() == "postgres" {
+					if err := db.Exec("ALTER TABLE payments ALTER COLUMN legacy_invoice_id DROP NOT NULL").Error; err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return db.AutoMigrate(&models.Payment{}, &models.PaymentAllocation{}, &models.WebhookEvent{})
+}
+
+// MigrateMoney converts the monetary columns of the given models from
+// floating point to exact decimals and rounds what was stored to the
+// currency's minor units: cost columns by cost_currency where the table has
+// one, other columns by currency. Tables without a currency keep four
+// decimals. Columns that are already numeric are left alone, so this only
+// rounds once. It must run before AutoMigrate, which would convert the
+// columns without rounding.
+func MigrateMoney(db *gorm.DB, dst ...interface{}) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	amountType := reflect.TypeOf(money.Amount(0))
+	m := db.Migrator()
+	for _, model := range dst {
+		if !m.HasTable(model) {
+			continue
+		}
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		cols, err := m.ColumnTypes(model)
+		if err != nil {
+			return err
+		}
+		existing := map[string]string{}
+		for _, col := range cols {
+			existing[col.Name()] = strings.ToLower(col.DatabaseTypeName())
+		}
+		table := stmt.Schema.Table
+		for _, f := range stmt.Schema.Fields {
+			typ, ok := existing[f.DBName]
+			if f.FieldType != amountType || !ok || typ == "numeric" {
+				continue
+			}
+			if err := db.Exec(fmt.Sprintf("ALTER TABLE %q ALTER COLUMN %q TYPE numeric(19,4) USING ROUND(%q::numeric, 4)",
+				table, f.DBName, f.DBName)).Error; err != nil {
+				return err
+			}
+			currency := "currency"
+			if _, ok := existing["cost_currency"]; ok && strings.Contains(f.DBName, "cost") {
+				currency = "cost_currency"
+			}
+			if _, ok := existing[currency]; !ok {
+				continue
+			}
+			if err := db.Exec(fmt.Sprintf("UPDATE %q SET %q = ROUND(%q, %s)",
+				table, f.DBName, f.DBName, minorUnitsCase(currency))).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// minorUnitsCase is a SQL expression giving the minor units of the currency
+// in the named column.
+func minorUnitsCase(column string) string {
+	units := money.MinorUnitsMap()
+	codes := make([]string, 0, len(units))
+	for code := range units {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	var b strings.Builder
+	fmt.Fprintf(&b, "CASE UPPER(%q)", column)
+	for _, code := range codes {
+		fmt.Fprintf(&b, " WHEN '%s' THEN %d", code, units[code])
+	}
+	b.WriteString(" ELSE 2 END")
+	return b.String()
+}
+
+// MigrateLedger creates the ledger tables. On Postgres a trigger also
+// rejects changes to posted journal entries and lines made outside GORM.
+func MigrateLedger(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Account{}, &models.JournalEntry{}, &models.JournalLine{}); err != nil {
+		return err
+	}
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	stmts := []string{
+		`CREATE OR REPLACE FUNCTION journal_immutable() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'posted journal entries cannot be changed or deleted';
+END;
+$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS journal_entries_immutable ON journal_entries`,
+		`CREATE TRIGGER journal_entries_immutable BEFORE UPDATE OR DELETE ON journal_entries
+	FOR EACH ROW EXECUTE FUNCTION journal_immutable()`,
+		`DROP TRIGGER IF EXISTS journal_lines_immutable ON journal_lines`,
+		`CREATE TRIGGER journal_lines_immutable BEFORE UPDATE OR DELETE ON journal_lines
+	FOR EACH ROW EXECUTE FUNCTION journal_immutable()`,
+	}
+	for _, s := range stmts {
+		if err := db.Exec(s).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
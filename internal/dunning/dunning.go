@@ -0,0 +1,270 @@
+// internal/dunning/dunning.go
+package dunning
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"net/mail"
+	"strings"
+	"text/template"
+	"time"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/models"
+	"travel-agency/internal/notifications"
+	"travel-agency/internal/receivables"
+	"travel-agency/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Errors returned when reminding.
+var (
+	ErrInvalidRule = errors.New("invalid reminder rule")
+	ErrNoContact   = errors.New("invoice has no contact email")
+	ErrNothingOwed = errors.New("invoice has nothing outstanding")
+)
+
+// linkDays is how long the payment link in a reminder stays valid.
+const linkDays = 14
+
+// DefaultSubject and DefaultBody are used by rules without their own.
+const (
+	DefaultSubject = `{{if gt .DaysOverdue 0}}Overdue: invoice {{.Reference}} was due on {{.DueDate}}` +
+		`{{else if eq .DaysUntilDue 0}}Invoice {{.Reference}} is due today` +
+		`{{else}}Invoice {{.Reference}} is due on {{.DueDate}}{{end}}`
+	DefaultBody = `<p>Hello,</p>
+<p>{{if gt .DaysOverdue 0}}Invoice <strong>{{.Reference}}</strong> was due on {{.DueDate}} and is now {{.DaysOverdue}} day(s) overdue.
+{{- else if eq .DaysUntilDue 0}}Invoice <strong>{{.Reference}}</strong> is due today.
+{{- else}}This is a friendly reminder that invoice <strong>{{.Reference}}</strong> is due on {{.DueDate}}.{{end}}</p>
+<p>Amount outstanding: <strong>{{.Outstanding}} {{.Currency}}</strong></p>
+{{if .PaymentLink}}<p><a href="{{.PaymentLink}}">View and pay your invoice</a></p>
+{{end}}<p>If you have already paid, please disregard this reminder.</p>
+<p>{{.Tenant}}</p>`
+)
+
+// Message is what reminder templates can use.
+type Message struct {
+	Tenant       string
+	Reference    string // Invoice number.
+	Currency     string
+	Amount       string // Invoice total.
+	Outstanding  string
+	IssueDate    string
+	DueDate      string
+	DaysOverdue  int // Zero until the due date has passed.
+	DaysUntilDue int // Zero from the due date on.
+	PaymentLink  string
+}
+
+// ValidateRule checks a rule's templates before it is saved.
+func ValidateRule(r *models.ReminderRule) error {
+	r.Subject = strings.TrimSpace(r.Subject)
+	r.Body = strings.TrimSpace(r.Body)
+	if r.DaysAfterDue < -90 || r.DaysAfterDue > 365 {
+		return fmt.Errorf("%w: daysAfterDue must be between -90 and 365", ErrInvalidRule)
+	}
+	subject, body := r.Subject, r.Body
+	if subject == "" {
+		subject = DefaultSubject
+	}
+	if body == "" {
+		body = DefaultBody
+	}
+	_, _, err := render(subject, body, Message{Reference: "INV-0000-000000", DaysOverdue: 1, PaymentLink: "https://example.com"})
+	return err
+}
+
+// DaysAfterDue counts the days from the due date to now; negative before it.
+func DaysAfterDue(due, now time.Time) int {
+	return int(utils.TruncateDay(now).Sub(utils.TruncateDay(due)).Hours() / 24)
+}
+
+// Reminders sends payment reminders for overdue and soon-due sale invoices.
+// With a BaseURL each reminder carries a payment link signed with Secret.
+type Reminders struct {
+	DB      *gorm.DB
+	Sender  notifications.EmailSender
+	Secret  string
+	BaseURL string
+}
+
+func New(db *gorm.DB, sender notifications.EmailSender, secret, baseURL string) *Reminders {
+	return &Reminders{DB: db, Sender: sender, Secret: secret, BaseURL: baseURL}
+}
+
+// remindable are the statuses of invoices that can still be reminded about.
+func remindable(inv *models.Invoice) bool {
+	return inv.InvoiceType == "sale" && inv.IssuedAt != nil && !receivables.IsClosed(inv.Status) &&
+		inv.Status != "Paid" && inv.Status != "Credited"
+}
+
+// SendDue sends the reminders due under each tenant's schedule and returns
+// how many were sent. An invoice gets the latest step of the schedule it has
+// reached, once; steps it skipped past (e.g. issued late) are not sent.
+// Invoices without a contact email, or with reminders turned off, are left
+// alone, and reminders stop once an invoice is paid or credited.
+func (m *Reminders) SendDue(now time.Time) (int, error) {
+	var rules []models.ReminderRule
+	if err := m.DB.Where("active = ?", true).Order("tenant_id, days_after_due").Find(&rules).Error; err != nil {
+		return 0, err
+	}
+	schedules := map[uint][]models.ReminderRule{}
+	for _, r := range rules {
+		schedules[r.TenantID] = append(schedules[r.TenantID], r)
+	}
+
+	sent := 0
+	for tenantID, schedule := range schedules {
+		var invoices []models.Invoice
+		if err := m.DB.Where("tenant_id = ? AND invoice_type = ? AND issued_at IS NOT NULL AND reminders_off = ? AND contact_email <> '' AND status NOT IN ?",
+			tenantID, "sale", false, []string{"Paid", "Credited", "Draft", "Void", "Canceled", "Cancelled"}).
+			Find(&invoices).Error; err != nil {
+			return sent, err
+		}
+		for i := range invoices {
+			inv := &invoices[i]
+			rule := step(schedule, DaysAfterDue(inv.DueDate, now))
+			if rule == nil {
+				continue
+			}
+			var done int64
+			if err := m.DB.Model(&models.InvoiceReminder{}).
+				Where("invoice_id = ? AND rule_id = ? AND status = ?", inv.ID, rule.ID, models.ReminderSent).
+				Count(&done).Error; err != nil {
+				return sent, err
+			}
+			if done > 0 {
+				continue
+			}
+			switch _, err := m.Send(inv, rule, "", 0, now); {
+			case err == nil:
+				sent++
+			case !errors.Is(err, ErrNothingOwed):
+				log.Printf("Failed to send payment reminder for invoice %s: %v", inv.ID, err)
+			}
+		}
+	}
+	return sent, nil
+}
+
+// step is the latest rule of the schedule (sorted by day) reached days
+// after the due date.
+func step(schedule []models.ReminderRule, days int) *models.ReminderRule {
+	var rule *models.ReminderRule
+	for i := range schedule {
+		if schedule[i].DaysAfterDue <= days {
+			rule = &schedule[i]
+		}
+	}
+	return rule
+}
+
+// Send emails a reminder for the invoice, using the rule's templates (or the
+// defaults when rule is nil), to the given address or else the invoice's
+// contact email, and logs it. A failed email is logged too and returned as
+// an error along with the log entry; sentBy is the user sending by hand.
+func (m *Reminders) Send(inv *models.Invoice, rule *models.ReminderRule, to string, sentBy uint, now time.Time) (*models.InvoiceReminder, error) {
+	if to == "" {
+		to = inv.ContactEmail
+	}
+	if to == "" {
+		return nil, ErrNoContact
+	}
+	if _, err := mail.ParseAddress(to); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoContact, err)
+	}
+	if !remindable(inv) {
+		return nil, ErrNothingOwed
+	}
+	outstanding, err := receivables.Outstanding(m.DB, *inv)
+	if err != nil {
+		return nil, err
+	}
+	if outstanding <= 0 {
+		return nil, ErrNothingOwed
+	}
+
+	var tenant models.Tenant
+	if err := m.DB.Select("id", "name").First(&tenant, inv.TenantID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	days := DaysAfterDue(inv.DueDate, now)
+	msg := Message{
+		Tenant:      tenant.Name,
+		Reference:   inv.ID.String(),
+		Currency:    inv.Currency,
+		Amount:      inv.Amount.Format(inv.Currency),
+		Outstanding: outstanding.Format(inv.Currency),
+		IssueDate:   inv.IssueDate.Format("02 Jan 2006"),
+		DueDate:     inv.DueDate.Format("02 Jan 2006"),
+	}
+	if inv.Number != nil {
+		msg.Reference = *inv.Number
+	}
+	if days > 0 {
+		msg.DaysOverdue = days
+	} else {
+		msg.DaysUntilDue = -days
+	}
+	if m.BaseURL != "" {
+		msg.PaymentLink = m.BaseURL + "/api/pay/" +
+			auth.GeneratePaymentLinkToken(inv.TenantID, inv.ID, now.AddDate(0, 0, linkDays), m.Secret)
+	}
+
+	subjectTmpl, bodyTmpl := DefaultSubject, DefaultBody
+	if rule != nil && rule.Subject != "" {
+		subjectTmpl = rule.Subject
+	}
+	if rule != nil && rule.Body != "" {
+		bodyTmpl = rule.Body
+	}
+	subject, body, err := render(subjectTmpl, bodyTmpl, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	reminder := models.InvoiceReminder{
+		TenantID:     inv.TenantID,
+		InvoiceID:    inv.ID,
+		DaysAfterDue: days,
+		SentTo:       to,
+		Subject:      subject,
+		Status:       models.ReminderSent,
+		SentBy:       sentBy,
+	}
+	if rule != nil {
+		reminder.RuleID = &rule.ID
+	}
+	sendErr := m.Sender.SendEmail(to, subject, body)
+	if sendErr != nil {
+		reminder.Status = models.ReminderFailed
+		reminder.Error = sendErr.Error()
+	}
+	if err := m.DB.Create(&reminder).Error; err != nil {
+		return nil, err
+	}
+	return &reminder, sendErr
+}
+
+func render(subjectTmpl, bodyTmpl string, msg Message) (string, string, error) {
+	st, err := template.New("subject").Parse(subjectTmpl)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: subject: %v", ErrInvalidRule, err)
+	}
+	bt, err := htmltemplate.New("body").Parse(bodyTmpl)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: body: %v", ErrInvalidRule, err)
+	}
+	var subject, body bytes.Buffer
+	if err := st.Execute(&subject, msg); err != nil {
+		return "", "", fmt.Errorf("%w: subject: %v", ErrInvalidRule, err)
+	}
+	if err := bt.Execute(&body, msg); err != nil {
+		return "", "", fmt.Errorf("%w: body: %v", ErrInvalidRule, err)
+	}
+	return strings.TrimSpace(subject.String()), body.String(), nil
+}
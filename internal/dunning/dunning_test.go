@@ -0,0 +1,84 @@
+package dunning
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type outbox struct {
+	sent []string // Subjects.
+	fail bool
+}
+
+func (o *outbox) SendEmail(to, subject, body string) error {
+	if o.fail {
+		return errors.New("smtp down")
+	}
+	o.sent = append(o.sent, subject)
+	return nil
+}
+
+func TestSendDue(t *testing.T) {
+	db := testutil.DB(t)
+	db.Create(&models.Tenant{Name: "Agency"})
+	for _, days := range []int{-3, 0, 7, 14} {
+		db.Create(&models.ReminderRule{TenantID: 1, DaysAfterDue: days, Active: true})
+	}
+	db.Model(&models.ReminderRule{}).Where("days_after_due = ?", 14).
+		Update("subject", "Final notice for {{.Reference}}: {{.Outstanding}} {{.Currency}}")
+
+	now := time.Date(2026, 5, 20, 9, 30, 0, 0, time.UTC)
+	issued := now.AddDate(0, -1, 0)
+	number := "INV-2026-000007"
+	inv := models.Invoice{TenantID: 1, Number: &number, InvoiceType: "sale", Status: "Overdue", Currency: "USD",
+		IssueDate: issued, IssuedAt: &issued, DueDate: now.AddDate(0, 0, -8), Amount: money.FromInt(250),
+		ContactEmail: "billing@example.com"}
+	db.Create(&inv)
+	noEmail := models.Invoice{TenantID: 1, InvoiceType: "sale", Status: "Overdue", IssueDate: issued, IssuedAt: &issued,
+		DueDate: now.AddDate(0, 0, -8), Amount: money.FromInt(100)}
+	db.Create(&noEmail)
+
+	box := &outbox{}
+	r := New(db, box, "secret", "https://agency.example")
+
+	// Eight days overdue: the 7-day reminder, once.
+	sent, err := r.SendDue(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, []string{"Overdue: invoice INV-2026-000007 was due on 12 May 2026"}, box.sent)
+	sent, _ = r.SendDue(now.Add(time.Hour))
+	assert.Equal(t, 0, sent)
+
+	// A failed email is logged and tried again the next time.
+	box.fail = true
+	sent, _ = r.SendDue(now.AddDate(0, 0, 6))
+	assert.Equal(t, 0, sent)
+	var failed models.InvoiceReminder
+	assert.NoError(t, db.Where("status = ?", models.ReminderFailed).First(&failed).Error)
+	assert.Equal(t, "smtp down", failed.Error)
+	box.fail = false
+	sent, _ = r.SendDue(now.AddDate(0, 0, 7))
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, "Final notice for INV-2026-000007: 250.00 USD", box.sent[1])
+
+	// Reminders stop once the invoice is paid.
+	db.Model(&inv).Update("status", "Paid")
+	db.Model(&models.ReminderRule{}).Where("days_after_due = ?", 14).Update("days_after_due", 20)
+	sent, _ = r.SendDue(now.AddDate(0, 0, 20))
+	assert.Equal(t, 0, sent)
+
+	var logged int64
+	db.Model(&models.InvoiceReminder{}).Where("invoice_id = ?", inv.ID).Count(&logged)
+	assert.Equal(t, int64(3), logged)
+
+	// Templates are checked before a rule is saved.
+	assert.ErrorIs(t, ValidateRule(&models.ReminderRule{Subject: "{{.Nope}}"}), ErrInvalidRule)
+	assert.NoError(t, ValidateRule(&models.ReminderRule{DaysAfterDue: -3}))
+}
@@ -0,0 +1,249 @@
+// internal/fx/fx.go
+package fx
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultBaseCurrency is used for tenants that have not chosen one.
+const DefaultBaseCurrency = "USD"
+
+var (
+	// ErrNoRate is returned when no rate is known for a pair on a date.
+	ErrNoRate = errors.New("no exchange rate")
+	// ErrInvalidCurrency is returned for codes that are not three letters.
+	ErrInvalidCurrency = errors.New("invalid currency code")
+	// ErrInvalidRate is returned for malformed rate rows.
+	ErrInvalidRate = errors.New("invalid exchange rate")
+)
+
+// Normalize upper-cases and trims a currency code.
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Valid reports whether code is a three-letter ISO 4217 style code.
+func Valid(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// BaseCurrency returns the tenant's reporting currency.
+func BaseCurrency(db *gorm.DB, tenantID uint) (string, error) {
+	var tenant models.Tenant
+	if err := db.Select("base_currency").First(&tenant, tenantID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return DefaultBaseCurrency, nil
+		}
+		return "", err
+	}
+	if tenant.BaseCurrency == "" {
+		return DefaultBaseCurrency, nil
+	}
+	return tenant.BaseCurrency, nil
+}
+
+// lookup finds the latest rate for the pair on or before the date. The
+// tenant's own rate beats a shared one from the same date.
+func lookup(db *gorm.DB, tenantID uint, from, to string, on time.Time) (float64, bool, error) {
+	var rates []models.ExchangeRate
+	if err := db.Where("tenant_id IN ? AND from_currency = ? AND to_currency = ? AND date <= ?",
+		[]uint{0, tenantID}, from, to, on).
+		Order("date DESC, tenant_id DESC").Limit(1).Find(&rates).Error; err != nil {
+		return 0, false, err
+	}
+	if len(rates) == 0 {
+		return 0, false, nil
+	}
+	return rates[0].Rate, true, nil
+}
+
+// direct tries the pair and its inverse.
+func direct(db *gorm.DB, tenantID uint, from, to string, on time.Time) (float64, bool, error) {
+	rate, ok, err := lookup(db, tenantID, from, to, on)
+	if err != nil || ok {
+		return rate, ok, err
+	}
+	rate, ok, err = lookup(db, tenantID, to, from, on)
+	if err != nil || !ok || rate == 0 {
+		return 0, false, err
+	}
+	return 1 / rate, true, nil
+}
+
+// Rate returns what one unit of from is worth in to on the given date. It
+// uses the pair's latest rate on or before the date, the inverse pair, or
+// failing both a cross rate through the tenant's base currency.
+func Rate(db *gorm.DB, tenantID uint, from, to string, on time.Time) (float64, error) {
+	from, to = Normalize(from), Normalize(to)
+	if from == to {
+		return 1, nil
+	}
+	day := utils.TruncateDay(on).AddDate(0, 0, 1).Add(-time.Nanosecond)
+	rate, ok, err := direct(db, tenantID, from, to, day)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		return rate, nil
+	}
+
+	base, err := BaseCurrency(db, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	if base != from && base != to {
+		toBase, ok1, err := direct(db, tenantID, from, base, day)
+		if err != nil {
+			return 0, err
+		}
+		fromBase, ok2, err := direct(db, tenantID, base, to, day)
+		if err != nil {
+			return 0, err
+		}
+		if ok1 && ok2 {
+			return toBase * fromBase, nil
+		}
+	}
+	return 0, fmt.Errorf("%w for %s->%s on %s", ErrNoRate, from, to, on.Format("2006-01-02"))
+}
+
+// ToBase returns the rate from currency to the tenant's base currency on the
+// date, with the base currency itself.
+func ToBase(db *gorm.DB, tenantID uint, currency string, on time.Time) (rate float64, base string, err error) {
+	base, err = BaseCurrency(db, tenantID)
+	if err != nil {
+		return 0, "", err
+	}
+	if currency == "" {
+		return 1, base, nil
+	}
+	rate, err = Rate(db, tenantID, currency, base, on)
+	return rate, base, err
+}
+
+// Convert converts amount between currencies at the date's rate, rounded to
+// the target currency.
+func Convert(db *gorm.DB, tenantID uint, amount money.Amount, from, to string, on time.Time) (money.Amount, error) {
+	rate, err := Rate(db, tenantID, from, to, on)
+	if err != nil {
+		return 0, err
+	}
+	return amount.Mul(rate).Round(to), nil
+}
+
+// Save stores rates for the tenant (0 for shared rates), replacing any for
+// the same pair and date.
+func Save(db *gorm.DB, tenantID uint, rates []models.ExchangeRate, source string) error {
+	if len(rates) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for i := range rates {
+		r := &rates[i]
+		r.ID = 0
+		r.TenantID = tenantID
+		r.FromCurrency = Normalize(r.FromCurrency)
+		r.ToCurrency = Normalize(r.ToCurrency)
+		r.Date = utils.TruncateDay(r.Date)
+		if r.Source == "" {
+			r.Source = source
+		}
+		r.CreatedAt = now
+		r.UpdatedAt = now
+		if !Valid(r.FromCurrency) || !Valid(r.ToCurrency) || r.FromCurrency == r.ToCurrency {
+			return fmt.Errorf("%w: %q -> %q", ErrInvalidCurrency, r.FromCurrency, r.ToCurrency)
+		}
+		if r.Rate <= 0 || math.IsInf(r.Rate, 0) || math.IsNaN(r.Rate) || r.Date.IsZero() {
+			return fmt.Errorf("%w: %s->%s needs a date and a positive rate", ErrInvalidRate, r.FromCurrency, r.ToCurrency)
+		}
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "from_currency"}, {Name: "to_currency"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+	}).Create(&rates).Error
+}
+
+// ParseCSV reads rates with a "date,from,to,rate" header; dates are
+// YYYY-MM-DD. Blank lines and lines starting with # are skipped.
+func ParseCSV(r io.Reader) ([]models.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidRate)
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "from", "to", "rate"} {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("%w: header needs date, from, to and rate", ErrInvalidRate)
+		}
+	}
+
+	var rates []models.ExchangeRate
+	for line := 2; ; line++ {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRate, err)
+		}
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(rec[cols["date"]]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: bad date", ErrInvalidRate, line)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rec[cols["rate"]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: bad rate", ErrInvalidRate, line)
+		}
+		rates = append(rates, models.ExchangeRate{
+			FromCurrency: Normalize(rec[cols["from"]]),
+			ToCurrency:   Normalize(rec[cols["to"]]),
+			Date:         date,
+			Rate:         rate,
+		})
+	}
+	return rates, nil
+}
+
+// LoadFile loads shared rates from a CSV file (see ParseCSV).
+func LoadFile(db *gorm.DB, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	rates, err := ParseCSV(f)
+	if err != nil {
+		return 0, err
+	}
+	return len(rates), Save(db, 0, rates, filepath.Base(path))
+}
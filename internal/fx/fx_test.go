@@ -0,0 +1,68 @@
+package fx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRate(t *testing.T) {
+	db := testutil.DB(t)
+	db.Create(&models.Tenant{ID: 1, Name: "Agency", BaseCurrency: "INR"})
+
+	rates, err := ParseCSV(strings.NewReader(`date,from,to,rate
+# shared rates
+2024-01-01,usd,inr,83
+2024-02-01,USD,INR,82.5
+2024-01-01,EUR,INR,90
+`))
+	assert.NoError(t, err)
+	assert.Len(t, rates, 3)
+	assert.NoError(t, Save(db, 0, rates, "test"))
+
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	rate := func(from, to, on string) float64 {
+		r, err := Rate(db, 1, from, to, day(on))
+		assert.NoError(t, err)
+		return r
+	}
+
+	assert.Equal(t, 1.0, rate("INR", "INR", "2023-01-01"))
+	assert.Equal(t, 83.0, rate("USD", "INR", "2024-01-15"))            // latest on or before
+	assert.Equal(t, 82.5, rate("USD", "INR", "2024-02-01"))            // same day
+	assert.InDelta(t, 1/82.5, rate("INR", "USD", "2024-03-01"), 1e-9)  // inverse
+	assert.InDelta(t, 83.0/90, rate("USD", "EUR", "2024-01-15"), 1e-9) // cross via INR
+
+	_, err = Rate(db, 1, "USD", "INR", day("2023-12-31"))
+	assert.True(t, errors.Is(err, ErrNoRate))
+
+	// The tenant's own rate beats the shared one; re-saving replaces it.
+	assert.NoError(t, Save(db, 1, []models.ExchangeRate{{FromCurrency: "USD", ToCurrency: "INR", Date: day("2024-01-01"), Rate: 84}}, "test"))
+	assert.NoError(t, Save(db, 1, []models.ExchangeRate{{FromCurrency: "USD", ToCurrency: "INR", Date: day("2024-01-01"), Rate: 84.5}}, "test"))
+	assert.Equal(t, 84.5, rate("USD", "INR", "2024-01-15"))
+	r, err := Rate(db, 2, "USD", "INR", day("2024-01-15"))
+	assert.NoError(t, err)
+	assert.Equal(t, 83.0, r)
+
+	assert.True(t, errors.Is(Save(db, 1, []models.ExchangeRate{{FromCurrency: "USD", ToCurrency: "INR", Date: day("2024-01-01")}}, "test"), ErrInvalidRate))
+	assert.True(t, errors.Is(Save(db, 1, []models.ExchangeRate{{FromCurrency: "US", ToCurrency: "INR", Date: day("2024-01-01"), Rate: 1}}, "test"), ErrInvalidCurrency))
+
+	// Recording a payment defaults its currency and keeps the rate used.
+	p := models.Payment{TenantID: 1, Currency: "eur", PaymentDate: day("2024-01-10")}
+	assert.NoError(t, RecordPayment(db, &p))
+	assert.Equal(t, "EUR", p.Currency)
+	assert.Equal(t, 90.0, p.ExchangeRate)
+	p = models.Payment{TenantID: 1, PaymentDate: day("2024-01-10")}
+	assert.NoError(t, RecordPayment(db, &p))
+	assert.Equal(t, "INR", p.Currency)
+	assert.Equal(t, 1.0, p.ExchangeRate)
+}
@@ -0,0 +1,120 @@
+// internal/fx/record.go
+package fx
+
+import (
+	"fmt"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// currency normalises a code, defaulting to base, and checks it.
+func currency(code, base string) (string, error) {
+	code = Normalize(code)
+	if code == "" {
+		return base, nil
+	}
+	if !Valid(code) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCurrency, code)
+	}
+	return code, nil
+}
+
+func dateOr(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now()
+	}
+	return t
+}
+
+// RecordBooking defaults the booking's currencies (cost to price, price to
+// the tenant's base currency) and records their rates to the base currency
+// on the booking date.
+func RecordBooking(db *gorm.DB, b *models.Booking) error {
+	base, err := BaseCurrency(db, b.TenantID)
+	if err != nil {
+		return err
+	}
+	if b.Currency, err = currency(b.Currency, base); err != nil {
+		return err
+	}
+	if b.CostCurrency, err = currency(b.CostCurrency, b.Currency); err != nil {
+		return err
+	}
+	on := dateOr(b.BookingDate)
+	if b.ExchangeRate, err = Rate(db, b.TenantID, b.Currency, base, on); err != nil {
+		return err
+	}
+	b.CostExchangeRate, err = Rate(db, b.TenantID, b.CostCurrency, base, on)
+	return err
+}
+
+// RecordInvoice defaults the invoice's currency to the tenant's base
+// currency and records its rate on the issue date.
+func RecordInvoice(db *gorm.DB, inv *models.Invoice) error {
+	base, err := BaseCurrency(db, inv.TenantID)
+	if err != nil {
+		return err
+	}
+	if inv.Currency, err = currency(inv.Currency, base); err != nil {
+		return err
+	}
+	inv.ExchangeRate, err = Rate(db, inv.TenantID, inv.Currency, base, dateOr(inv.IssueDate))
+	return err
+}
+
+// RecordPayment defaults the payment's currency to the tenant's base
+// currency and records its rate on the payment date.
+func RecordPayment(db *gorm.DB, p *models.Payment) error {
+	base, err := BaseCurrency(db, p.TenantID)
+	if err != nil {
+		return err
+	}
+	if p.Currency, err = currency(p.Currency, base); err != nil {
+		return err
+	}
+	p.ExchangeRate, err = Rate(db, p.TenantID, p.Currency, base, dateOr(p.PaymentDate))
+	return err
+}
+
+// PriceItems defaults the itinerary's currency to the tenant's base currency
+// and each item's cost currency to the itinerary's, and records the rate
+// from each item's cost currency to the itinerary's on its start date.
+func PriceItems(db *gorm.DB, itin *models.Itinerary, items []models.ItineraryItem) error {
+	base, err := BaseCurrency(db, itin.TenantID)
+	if err != nil {
+		return err
+	}
+	if itin.Currency, err = currency(itin.Currency, base); err != nil {
+		return err
+	}
+	on := dateOr(itin.StartDate)
+	for i := range items {
+		item := &items[i]
+		if item.CostCurrency, err = currency(item.CostCurrency, itin.Currency); err != nil {
+			return err
+		}
+		if item.CostRate, err = Rate(db, itin.TenantID, item.CostCurrency, itin.Currency, on); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rated returns amount times rate, treating a missing rate (records from
+// before rates were kept) as 1.
+func Rated(amount money.Amount, rate float64) money.Amount {
+	if rate == 0 {
+		return amount
+	}
+	return amount.Mul(rate)
+}
+
+// RatedSQL is the SQL counterpart of Rated for the given amount and rate
+// columns.
+func RatedSQL(amount, rate string) string {
+	return fmt.Sprintf("%s * CAST(COALESCE(NULLIF(%s, 0), 1) AS NUMERIC)", amount, rate)
+}
@@ -0,0 +1,76 @@
+package gateways
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"travel-agency/internal/money"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockProviderFlow(t *testing.T) {
+	ctx := context.Background()
+	reg := NewRegistry()
+	reg.Register("Mock", NewMock("secret", "http://localhost"))
+
+	p, err := reg.For(" mock ")
+	assert.NoError(t, err)
+	_, err = reg.For("stripe")
+	assert.ErrorIs(t, err, ErrNoProvider)
+
+	intent, err := p.CreateIntent(ctx, IntentRequest{Amount: money.FromFloat(125.5), Currency: "USD", Reference: "INV-2026-000001"})
+	assert.NoError(t, err)
+	assert.Equal(t, StatusRequiresPayment, intent.Status)
+	assert.Equal(t, "http://localhost/mock-pay/"+intent.ID, intent.URL)
+
+	body, sig, err := p.(*Mock).Pay(intent.ID)
+	assert.NoError(t, err)
+	ev, err := p.ParseWebhook(body, http.Header{MockSignatureHeader: {sig}})
+	assert.NoError(t, err)
+	assert.Equal(t, EventSucceeded, ev.Type)
+	assert.Equal(t, intent.ID, ev.IntentID)
+	assert.Equal(t, money.FromFloat(125.5), ev.Amount)
+
+	_, err = p.ParseWebhook(append(body, ' '), http.Header{MockSignatureHeader: {sig}})
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	refund, err := p.Refund(ctx, intent.ID, 0, "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, money.FromFloat(125.5), refund.Amount)
+
+	unsigned := NewMock("", "http://localhost")
+	_, err = unsigned.ParseWebhook(body, http.Header{MockSignatureHeader: {SignHMAC("", string(body))}})
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestStripeWebhookSignature(t *testing.T) {
+	s := NewStripe("sk_test", "whsec_test")
+	now := time.Unix(1_760_000_000, 0)
+	s.now = func() time.Time { return now }
+
+	payload := []byte(`{"id":"evt_1","type":"payment_intent.succeeded","data":{"object":{"id":"pi_1","amount":4200,"currency":"eur"}}}`)
+	sign := func(ts time.Time, secret string) http.Header {
+		t := fmt.Sprint(ts.Unix())
+		return http.Header{"Stripe-Signature": {"t=" + t + ",v1=" + SignHMAC(secret, t+"."+string(payload))}}
+	}
+
+	ev, err := s.ParseWebhook(payload, sign(now, "whsec_test"))
+	assert.NoError(t, err)
+	assert.Equal(t, Event{ID: "evt_1", Type: EventSucceeded, IntentID: "pi_1", Amount: money.FromInt(42), Currency: "EUR"}, *ev)
+
+	_, err = s.ParseWebhook(payload, sign(now, "whsec_other"))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	_, err = s.ParseWebhook(payload, sign(now.Add(-10*time.Minute), "whsec_test"))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	_, err = s.ParseWebhook(payload, http.Header{})
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	// Without a secret, a signature anyone can compute is still refused.
+	s.WebhookSecret = ""
+	_, err = s.ParseWebhook(payload, sign(now, ""))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
@@ -0,0 +1,168 @@
+// internal/gateways/mock.go
+package gateways
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"travel-agency/internal/money"
+)
+
+// MockSignatureHeader carries the mock provider's webhook signature: the hex
+// HMAC-SHA256 of the body.
+const MockSignatureHeader = "X-Mock-Signature"
+
+// Mock is an in-process payment provider for local development and tests.
+// Intents live in memory; Pay, Fail and RefundEvent produce signed webhook
+// bodies as the real gateway would send them.
+type Mock struct {
+	Secret  string
+	BaseURL string // Prefix for the pretend hosted payment page.
+
+	mu      sync.Mutex
+	intents map[string]*Intent
+}
+
+func NewMock(secret, baseURL string) *Mock {
+	return &Mock{Secret: secret, BaseURL: baseURL, intents: map[string]*Intent{}}
+}
+
+func randomID(prefix string) string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("%s_%x", prefix, b)
+}
+
+func (m *Mock) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("mock: amount must be positive")
+	}
+	in := &Intent{
+		ID:       randomID("pi_mock"),
+		Status:   StatusRequiresPayment,
+		Amount:   fromMinor(toMinor(req.Amount, req.Currency), req.Currency),
+		Currency: req.Currency,
+	}
+	in.URL = m.BaseURL + "/mock-pay/" + in.ID
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.intents[in.ID] = in
+	copy := *in
+	return &copy, nil
+}
+
+func (m *Mock) intent(id string) (*Intent, error) {
+	in, ok := m.intents[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return in, nil
+}
+
+// Capture takes an authorised payment. Capturing twice is harmless.
+func (m *Mock) Capture(ctx context.Context, intentID string) (*Intent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	in, err := m.intent(intentID)
+	if err != nil {
+		return nil, err
+	}
+	if in.Status == StatusRequiresCapture {
+		in.Status = StatusSucceeded
+	}
+	copy := *in
+	return &copy, nil
+}
+
+func (m *Mock) Refund(ctx context.Context, intentID string, amount money.Amount, currency string) (*Refund, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	in, err := m.intent(intentID)
+	if err != nil {
+		return nil, err
+	}
+	if in.Status != StatusSucceeded && in.Status != StatusRefunded {
+		return nil, fmt.Errorf("mock: intent %s is %s, not paid", intentID, in.Status)
+	}
+	if amount <= 0 || amount > in.Amount {
+		amount = in.Amount
+	}
+	if amount == in.Amount {
+		in.Status = StatusRefunded
+	}
+	return &Refund{ID: randomID("re_mock"), IntentID: intentID, Status: StatusSucceeded, Amount: amount}, nil
+}
+
+// ParseWebhook checks the X-Mock-Signature header and decodes the event,
+// which is already in the normalised form. Without a secret every webhook
+// is rejected.
+func (m *Mock) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	if m.Secret == "" {
+		return nil, fmt.Errorf("%w: no webhook secret configured", ErrInvalidSignature)
+	}
+	if !hmac.Equal([]byte(header.Get(MockSignatureHeader)), []byte(SignHMAC(m.Secret, string(payload)))) {
+		return nil, ErrInvalidSignature
+	}
+	var ev Event
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+// event marks the intent and returns a signed webhook body and signature.
+func (m *Mock) event(intentID, eventType, status string) ([]byte, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	in, err := m.intent(intentID)
+	if err != nil {
+		return nil, "", err
+	}
+	in.Status = status
+	body, err := json.Marshal(Event{
+		ID:       randomID("evt_mock"),
+		Type:     eventType,
+		IntentID: in.ID,
+		Amount:   in.Amount,
+		Currency: in.Currency,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return body, SignHMAC(m.Secret, string(body)), nil
+}
+
+// Pay simulates the customer paying: the intent succeeds and the returned
+// body and signature are what the webhook endpoint would receive.
+func (m *Mock) Pay(intentID string) ([]byte, string, error) {
+	return m.event(intentID, EventSucceeded, StatusSucceeded)
+}
+
+// Authorize simulates the customer paying an intent created with manual
+// capture: the money is held until Capture.
+func (m *Mock) Authorize(intentID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	in, err := m.intent(intentID)
+	if err != nil {
+		return err
+	}
+	in.Status = StatusRequiresCapture
+	return nil
+}
+
+// Fail simulates a declined payment.
+func (m *Mock) Fail(intentID string) ([]byte, string, error) {
+	return m.event(intentID, EventFailed, StatusFailed)
+}
+
+// RefundEvent simulates the provider reporting a full refund.
+func (m *Mock) RefundEvent(intentID string) ([]byte, string, error) {
+	return m.event(intentID, EventRefunded, StatusRefunded)
+}
@@ -0,0 +1,145 @@
+// internal/gateways/provider.go
+package gateways
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"travel-agency/internal/money"
+)
+
+// Intent states, normalised across providers.
+const (
+	StatusRequiresPayment = "requires_payment"
+	StatusRequiresCapture = "requires_capture" // Authorised, waiting for Capture.
+	StatusSucceeded       = "succeeded"
+	StatusFailed          = "failed"
+	StatusRefunded        = "refunded"
+)
+
+// Webhook event types, normalised across providers.
+const (
+	EventSucceeded = "payment.succeeded"
+	EventFailed    = "payment.failed"
+	EventRefunded  = "payment.refunded"
+)
+
+var (
+	// ErrNoProvider is returned when no provider is registered under a name.
+	ErrNoProvider = errors.New("no payment provider")
+	// ErrNotFound is returned for unknown intents.
+	ErrNotFound = errors.New("payment intent not found")
+	// ErrInvalidSignature is returned when a webhook fails verification.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// IntentRequest asks a provider to collect an amount.
+type IntentRequest struct {
+	Amount      money.Amount `json:"amount"`
+	Currency    string       `json:"currency"`
+	Reference   string       `json:"reference"` // Our reference, echoed back in webhooks (invoice number or ID).
+	Description string       `json:"description"`
+	ReturnURL   string       `json:"returnUrl,omitempty"`
+	// ManualCapture authorises only; call Capture to take the money.
+	ManualCapture bool `json:"manualCapture"`
+}
+
+// Intent is the provider's record of a payment being collected.
+type Intent struct {
+	ID       string       `json:"id"`
+	Status   string       `json:"status"`
+	Amount   money.Amount `json:"amount"`
+	Currency string       `json:"currency"`
+	URL      string       `json:"url,omitempty"` // Hosted page or link where the customer pays.
+}
+
+// Refund is the provider's record of money returned.
+type Refund struct {
+	ID       string       `json:"id"`
+	IntentID string       `json:"intentId"`
+	Status   string       `json:"status"`
+	Amount   money.Amount `json:"amount"`
+}
+
+// Event is a verified webhook notification.
+type Event struct {
+	ID       string       `json:"id"` // Provider's event ID; used for idempotency.
+	Type     string       `json:"type"`
+	IntentID string       `json:"intentId"`
+	Amount   money.Amount `json:"amount"`
+	Currency string       `json:"currency"`
+}
+
+// PaymentProvider is implemented by each payment gateway integration.
+type PaymentProvider interface {
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	Capture(ctx context.Context, intentID string) (*Intent, error)
+	// Refund returns amount (in currency) of a paid intent, or all of it
+	// when amount is zero.
+	Refund(ctx context.Context, intentID string, amount money.Amount, currency string) (*Refund, error)
+	// ParseWebhook verifies the request signature and decodes the event.
+	// Events the integration does not act on come back with an empty Type.
+	ParseWebhook(payload []byte, header http.Header) (*Event, error)
+}
+
+// Registry maps provider names (as used in webhook URLs) to providers.
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]PaymentProvider
+}
+
+func NewRegistry() *Registry {
+	return &Registry{providers: map[string]PaymentProvider{}}
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Register installs the provider under a name, replacing any previous one.
+func (r *Registry) Register(name string, p PaymentProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[normalizeName(name)] = p
+}
+
+// For returns the provider registered under name, or ErrNoProvider.
+func (r *Registry) For(name string) (PaymentProvider, error) {
+	if r == nil {
+		return nil, ErrNoProvider
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.providers[normalizeName(name)]
+	if !ok {
+		return nil, ErrNoProvider
+	}
+	return p, nil
+}
+
+// Names lists the registered providers.
+func (r *Registry) Names() []string {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// toMinor converts an amount to the currency's minor units (cents, paise),
+// as providers' APIs take them.
+func toMinor(amount money.Amount, currency string) int64 {
+	return amount.Minor(currency)
+}
+
+func fromMinor(amount int64, currency string) money.Amount {
+	return money.FromMinor(amount, currency)
+}
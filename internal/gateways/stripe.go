@@ -0,0 +1,223 @@
+// internal/gateways/stripe.go
+package gateways
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"travel-agency/internal/money"
+)
+
+// Stripe talks to the Stripe Payment Intents API. Hosted checkout is left
+// to the client: the intent's client secret is returned as its URL.
+type Stripe struct {
+	SecretKey     string
+	WebhookSecret string
+	BaseURL       string        // Defaults to https://api.stripe.com.
+	Tolerance     time.Duration // Maximum webhook age; defaults to 5 minutes.
+	Client        *http.Client
+
+	now func() time.Time
+}
+
+func NewStripe(secretKey, webhookSecret string) *Stripe {
+	return &Stripe{
+		SecretKey:     secretKey,
+		WebhookSecret: webhookSecret,
+		BaseURL:       "https://api.stripe.com",
+		Tolerance:     5 * time.Minute,
+		Client:        &http.Client{Timeout: 30 * time.Second},
+		now:           time.Now,
+	}
+}
+
+// stripeIntent is the subset of a PaymentIntent we read.
+type stripeIntent struct {
+	ID           string `json:"id"`
+	Object       string `json:"object"`
+	Status       string `json:"status"`
+	Amount       int64  `json:"amount"`
+	Currency     string `json:"currency"`
+	ClientSecret string `json:"client_secret"`
+	// On charge objects (refund events).
+	PaymentIntent  string `json:"payment_intent"`
+	AmountRefunded int64  `json:"amount_refunded"`
+}
+
+func (s *Stripe) post(ctx context.Context, path string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(s.BaseURL, "/")+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.SecretKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(body, &e)
+		return fmt.Errorf("stripe: %s (HTTP %d)", e.Error.Message, resp.StatusCode)
+	}
+	return json.Unmarshal(body, out)
+}
+
+func stripeStatus(status string) string {
+	switch status {
+	case "succeeded":
+		return StatusSucceeded
+	case "requires_capture":
+		return StatusRequiresCapture
+	case "canceled":
+		return StatusFailed
+	default:
+		return StatusRequiresPayment
+	}
+}
+
+func (s *Stripe) toIntent(pi stripeIntent) *Intent {
+	return &Intent{
+		ID:       pi.ID,
+		Status:   stripeStatus(pi.Status),
+		Amount:   fromMinor(pi.Amount, pi.Currency),
+		Currency: strings.ToUpper(pi.Currency),
+		URL:      pi.ClientSecret,
+	}
+}
+
+func (s *Stripe) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(toMinor(req.Amount, req.Currency), 10))
+	form.Set("currency", strings.ToLower(req.Currency))
+	form.Set("description", req.Description)
+	form.Set("metadata[reference]", req.Reference)
+	form.Set("automatic_payment_methods[enabled]", "true")
+	if req.ManualCapture {
+		form.Set("capture_method", "manual")
+	}
+	var pi stripeIntent
+	if err := s.post(ctx, "/v1/payment_intents", form, &pi); err != nil {
+		return nil, err
+	}
+	return s.toIntent(pi), nil
+}
+
+func (s *Stripe) Capture(ctx context.Context, intentID string) (*Intent, error) {
+	var pi stripeIntent
+	if err := s.post(ctx, "/v1/payment_intents/"+url.PathEscape(intentID)+"/capture", url.Values{}, &pi); err != nil {
+		return nil, err
+	}
+	return s.toIntent(pi), nil
+}
+
+func (s *Stripe) Refund(ctx context.Context, intentID string, amount money.Amount, currency string) (*Refund, error) {
+	form := url.Values{}
+	form.Set("payment_intent", intentID)
+	if amount > 0 {
+		form.Set("amount", strconv.FormatInt(toMinor(amount, currency), 10))
+	}
+	var out struct {
+		ID       string `json:"id"`
+		Status   string `json:"status"`
+		Amount   int64  `json:"amount"`
+		Currency string `json:"currency"`
+	}
+	if err := s.post(ctx, "/v1/refunds", form, &out); err != nil {
+		return nil, err
+	}
+	return &Refund{ID: out.ID, IntentID: intentID, Status: out.Status, Amount: fromMinor(out.Amount, out.Currency)}, nil
+}
+
+// ParseWebhook checks the Stripe-Signature header (t=timestamp,v1=HMAC of
+// "timestamp.payload") and maps payment intent and refund events. Without a
+// webhook secret anyone could sign events, so every webhook is rejected.
+func (s *Stripe) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	if s.WebhookSecret == "" {
+		return nil, fmt.Errorf("%w: no webhook secret configured", ErrInvalidSignature)
+	}
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header.Get("Stripe-Signature"), ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			sigs = append(sigs, kv[1])
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return nil, ErrInvalidSignature
+	}
+	if age := s.now().Sub(time.Unix(unix, 0)); age > s.Tolerance || age < -s.Tolerance {
+		return nil, fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+	expected := SignHMAC(s.WebhookSecret, ts+"."+string(payload))
+	valid := false
+	for _, sig := range sigs {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, ErrInvalidSignature
+	}
+
+	var raw struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object stripeIntent `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, err
+	}
+	obj := raw.Data.Object
+	ev := &Event{ID: raw.ID, IntentID: obj.ID, Amount: fromMinor(obj.Amount, obj.Currency), Currency: strings.ToUpper(obj.Currency)}
+	switch raw.Type {
+	case "payment_intent.succeeded":
+		ev.Type = EventSucceeded
+	case "payment_intent.payment_failed", "payment_intent.canceled":
+		ev.Type = EventFailed
+	case "charge.refunded":
+		ev.Type = EventRefunded
+		ev.IntentID = obj.PaymentIntent
+		ev.Amount = fromMinor(obj.AmountRefunded, obj.Currency)
+	}
+	return ev, nil
+}
+
+// SignHMAC returns the hex HMAC-SHA256 of message under secret.
+func SignHMAC(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
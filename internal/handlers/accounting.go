@@ -0,0 +1,258 @@
+// handlers/accounting.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"travel-agency/internal/accounting"
+	"travel-agency/internal/auth"
+	"travel-agency/internal/models"
+	"travel-agency/internal/utils"
+)
+
+// AccountingHandler exports invoices, vendor bills and payments to
+// accounting packages and manages how our accounts map onto theirs.
+type AccountingHandler struct {
+	DB *gorm.DB
+}
+
+func NewAccountingHandler(db *gorm.DB) *AccountingHandler {
+	return &AccountingHandler{DB: db}
+}
+
+// writeAccountingError maps export errors to status codes.
+func writeAccountingError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Export not found", http.StatusNotFound)
+	case errors.Is(err, accounting.ErrUnknownFormat), errors.Is(err, accounting.ErrInvalidMapping),
+		errors.Is(err, accounting.ErrInvalidPeriod):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, accounting.ErrNothingToExport):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// ListMappings handles GET /api/accounting/mappings?format=.
+func (h *AccountingHandler) ListMappings(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	query := h.DB.Where("tenant_id = ?", claims.TenantID)
+	if f := r.URL.Query().Get("format"); f != "" {
+		query = query.Where("format IN ?", []string{"", strings.ToLower(f)})
+	}
+	var mappings []models.AccountingMapping
+	if err := query.Order("format, kind, key").Find(&mappings).Error; err != nil {
+		http.Error(w, "Unable to fetch mappings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mappings)
+}
+
+// SaveMappings handles PUT /api/accounting/mappings with {"mappings":
+// [{"format", "kind", "key", "value"}]}. A mapping replaces any the tenant
+// has for the same format, kind and key.
+func (h *AccountingHandler) SaveMappings(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Mappings []models.AccountingMapping `json:"mappings"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if len(input.Mappings) == 0 {
+		http.Error(w, "No mappings given", http.StatusBadRequest)
+		return
+	}
+	for i := range input.Mappings {
+		m := &input.Mappings[i]
+		if err := accounting.ValidateMapping(m); err != nil {
+			writeAccountingError(w, err, "Invalid mapping")
+			return
+		}
+		m.ID = 0
+		m.TenantID = claims.TenantID
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "format"}, {Name: "kind"}, {Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+		}).Create(&input.Mappings).Error; err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "UPDATE_ACCOUNTING_MAPPINGS", "AccountingMapping",
+			fmt.Sprintf("Saved %d accounting mapping(s)", len(input.Mappings)))
+	}); err != nil {
+		http.Error(w, "Failed to save mappings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"saved": len(input.Mappings)})
+}
+
+// DeleteMapping handles DELETE /api/accounting/mappings/{mappingID}; the
+// default applies again.
+func (h *AccountingHandler) DeleteMapping(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	mappingID, err := strconv.Atoi(chi.URLParam(r, "mappingID"))
+	if err != nil {
+		http.Error(w, "Invalid mapping ID", http.StatusBadRequest)
+		return
+	}
+	var deleted int64
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ? AND tenant_id = ?", mappingID, claims.TenantID).Delete(&models.AccountingMapping{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if deleted = res.RowsAffected; deleted == 0 {
+			return nil
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "DELETE_ACCOUNTING_MAPPING", "AccountingMapping",
+			fmt.Sprintf("Accounting mapping %d deleted", mappingID))
+	}); err != nil {
+		http.Error(w, "Failed to delete mapping", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "Mapping not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateExport handles POST /api/accounting/exports with {"format": "iif",
+// "xero" or "tally", "from", "to" (YYYY-MM-DD), "includeExported"}.
+// Documents already exported in the format are left out unless
+// includeExported is set.
+func (h *AccountingHandler) CreateExport(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Format          string `json:"format"`
+		From            string `json:"from"`
+		To              string `json:"to"`
+		IncludeExported bool   `json:"includeExported"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	from, err := time.Parse("2006-01-02", input.From)
+	if err != nil {
+		http.Error(w, "Invalid from date, use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	to, err := time.Parse("2006-01-02", input.To)
+	if err != nil {
+		http.Error(w, "Invalid to date, use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	format := strings.ToLower(strings.TrimSpace(input.Format))
+
+	var export *models.AccountingExport
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		batch, err := accounting.Collect(tx, claims.TenantID, format, from, to, input.IncludeExported)
+		if err != nil {
+			return err
+		}
+		mappings, err := accounting.LoadMappings(tx, claims.TenantID, format)
+		if err != nil {
+			return err
+		}
+		if export, err = accounting.Export(tx, claims.TenantID, claims.UserID, batch, mappings); err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "CREATE_ACCOUNTING_EXPORT", "AccountingExport",
+			fmt.Sprintf("Exported %d invoice(s), %d bill(s) and %d payment(s) for %s to %s as %s",
+				export.Invoices, export.Bills, export.Payments, input.From, input.To, format))
+	}); err != nil {
+		writeAccountingError(w, err, "Failed to create export")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(export)
+}
+
+// ListExports handles GET /api/accounting/exports?format=, newest first.
+func (h *AccountingHandler) ListExports(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	query := h.DB.Omit("content").Where("tenant_id = ?", claims.TenantID)
+	if f := r.URL.Query().Get("format"); f != "" {
+		query = query.Where("format = ?", strings.ToLower(f))
+	}
+	var exports []models.AccountingExport
+	if err := query.Order("created_at DESC, id DESC").Find(&exports).Error; err != nil {
+		http.Error(w, "Unable to fetch exports", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exports)
+}
+
+// DownloadExport handles GET /api/accounting/exports/{exportID}/download.
+func (h *AccountingHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	exportID, err := strconv.Atoi(chi.URLParam(r, "exportID"))
+	if err != nil {
+		http.Error(w, "Invalid export ID", http.StatusBadRequest)
+		return
+	}
+	var export models.AccountingExport
+	if err := h.DB.Where("id = ? AND tenant_id = ?", exportID, claims.TenantID).First(&export).Error; err != nil {
+		writeAccountingError(w, err, "Unable to fetch export")
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename))
+	w.Write(export.Content)
+}
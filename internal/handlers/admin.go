@@ -3,15 +3,20 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"travel-agency/internal/auth"
+	"travel-agency/internal/fx"
 	"travel-agency/internal/models"
 	"travel-agency/internal/notifications"
+	"travel-agency/internal/tax"
+	"travel-agency/internal/utils"
 
 	"github.com/go-chi/chi/v5"
 	"golang.org/x/crypto/bcrypt"
@@ -183,6 +188,97 @@ func (h *AdminHandler) DeleteAgent(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// GetTenant returns the current tenant's settings (branding, tax rate, contacts).
+func (h *AdminHandler) GetTenant(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+
+	var tenant models.Tenant
+	if err := h.DB.First(&tenant, claims.TenantID).Error; err != nil {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tenant)
+}
+
+// UpdateTenant modifies the current tenant's settings.
+func (h *AdminHandler) UpdateTenant(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+
+	var tenant models.Tenant
+	if err := h.DB.First(&tenant, claims.TenantID).Error; err != nil {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	var payload models.Tenant
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	tenant.Name = payload.Name
+	tenant.Address = payload.Address
+	tenant.TaxRate = payload.TaxRate
+	tenant.LogoPath = payload.LogoPath
+	tenant.PrimaryColor = payload.PrimaryColor
+	tenant.SecondaryColor = payload.SecondaryColor
+	tenant.FooterText = payload.FooterText
+	tenant.Phone = payload.Phone
+	tenant.Email = payload.Email
+	tenant.EmergencyPhone = payload.EmergencyPhone
+	tenant.AutoInvoiceOnConfirm = payload.AutoInvoiceOnConfirm
+	tenant.DepositPercent = payload.DepositPercent
+	tenant.BalanceDaysBeforeStart = payload.BalanceDaysBeforeStart
+	tenant.TaxEngine = payload.TaxEngine
+	tenant.UpdatedAt = time.Now()
+
+	if tenant.DepositPercent < 0 || tenant.DepositPercent >= 100 || tenant.BalanceDaysBeforeStart < 0 {
+		http.Error(w, "DepositPercent must be 0-99 and BalanceDaysBeforeStart non-negative", http.StatusBadRequest)
+		return
+	}
+	if tenant.LogoPath != "" {
+		if _, err := utils.AssetPath(tenant.LogoPath); err != nil {
+			http.Error(w, fmt.Sprintf("LogoPath must be a file in the %s directory", utils.AssetsDir), http.StatusBadRequest)
+			return
+		}
+	}
+	if !tax.Known(tenant.TaxEngine) {
+		http.Error(w, fmt.Sprintf("TaxEngine must be empty or one of: %s", strings.Join(tax.Engines(), ", ")), http.StatusBadRequest)
+		return
+	}
+
+	if base := fx.Normalize(payload.BaseCurrency); base != "" && base != tenant.BaseCurrency {
+		if !fx.Valid(base) {
+			http.Error(w, "BaseCurrency must be a three-letter currency code", http.StatusBadRequest)
+			return
+		}
+		// Recorded rates are to the old base currency, so it is fixed once
+		// anything has been booked, invoiced or paid.
+		var used int64
+		for _, model := range []interface{}{&models.Booking{}, &models.Invoice{}, &models.Payment{}} {
+			var n int64
+			if err := h.DB.Model(model).Where("tenant_id = ? AND exchange_rate > 0", tenant.ID).Count(&n).Error; err != nil {
+				http.Error(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+			used += n
+		}
+		if used > 0 {
+			http.Error(w, "BaseCurrency cannot change once bookings, invoices or payments have recorded exchange rates", http.StatusConflict)
+			return
+		}
+		tenant.BaseCurrency = base
+	}
+
+	if err := h.DB.Save(&tenant).Error; err != nil {
+		http.Error(w, "Failed to update tenant", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tenant)
+}
+
 // generateTempPassword produces a random alphanumeric string of length n.
 func generateTempPassword(n int) string {
 	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
@@ -4,45 +4,116 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"travel-agency/internal/auth"
+	"travel-agency/internal/bookings"
+	"travel-agency/internal/fx"
+	"travel-agency/internal/inventory"
+	"travel-agency/internal/invoicing"
 	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/pricing"
+	"travel-agency/internal/suppliers"
+	"travel-agency/internal/utils"
 )
 
 type BookingHandler struct {
-	DB *gorm.DB
+	DB        *gorm.DB
+	Suppliers *suppliers.Registry
 }
 
-func NewBookingHandler(db *gorm.DB) *BookingHandler {
-	return &BookingHandler{DB: db}
+func NewBookingHandler(db *gorm.DB, registry *suppliers.Registry) *BookingHandler {
+	return &BookingHandler{DB: db, Suppliers: registry}
 }
 
 // createBookingInput defines the fields clients may submit when creating.
 type createBookingInput struct {
-	ItineraryID uint      `json:"itineraryID"`
-	VendorID    uint      `json:"vendorID"`
-	BookingRef  string    `json:"bookingRef"`
-	Status      string    `json:"status"`
-	BookingDate time.Time `json:"bookingDate"`
-	TravelDate  time.Time `json:"travelDate"`
-	Cost        float64   `json:"cost"`
-	Price       float64   `json:"price"`
+	ItineraryID uint         `json:"itineraryID"`
+	VendorID    uint         `json:"vendorID"`
+	InvoiceID   *uuid.UUID   `json:"invoiceID,omitempty"`
+	BookingRef  string       `json:"bookingRef"`
+	OfferID     string       `json:"offerID,omitempty"` // From a supplier search; confirm via /confirm.
+	Status      string       `json:"status"`            // Pending (default) or Confirmed
+	BookingDate time.Time    `json:"bookingDate"`
+	TravelDate  time.Time    `json:"travelDate"`
+	Cost        money.Amount `json:"cost"`
+	Price       money.Amount `json:"price"`
+
+	// Currency of Price defaults to the tenant's base currency; CostCurrency
+	// (of Cost) to Currency.
+	Currency     string `json:"currency,omitempty"`
+	CostCurrency string `json:"costCurrency,omitempty"`
+
+	// Set Product to price the booking from the vendor contract and draw from
+	// the vendor's allotment on TravelDate. Cost defaults to the contracted
+	// cost (rate card, else allotment) when not given.
+	Product   string `json:"product,omitempty"`
+	Nights    int    `json:"nights,omitempty"`    // Consecutive dates to reserve; default 1.
+	Units     int    `json:"units,omitempty"`     // Rooms/seats per date; default 1.
+	Occupancy int    `json:"occupancy,omitempty"` // Guests per unit, for the rate lookup.
+	MealPlan  string `json:"mealPlan,omitempty"`  // For the rate lookup.
 }
 
 // updateBookingInput defines the fields clients may submit when updating.
+// A status change goes through the booking lifecycle rules.
 type updateBookingInput struct {
-	BookingRef  *string    `json:"bookingRef,omitempty"`
-	Status      *string    `json:"status,omitempty"`
-	BookingDate *time.Time `json:"bookingDate,omitempty"`
-	TravelDate  *time.Time `json:"travelDate,omitempty"`
-	Cost        *float64   `json:"cost,omitempty"`
-	Price       *float64   `json:"price,omitempty"`
+	InvoiceID   *uuid.UUID    `json:"invoiceID,omitempty"`
+	BookingRef  *string       `json:"bookingRef,omitempty"`
+	Status      *string       `json:"status,omitempty"`
+	Reason      string        `json:"reason,omitempty"` // Recorded with a status change.
+	BookingDate *time.Time    `json:"bookingDate,omitempty"`
+	TravelDate  *time.Time    `json:"travelDate,omitempty"`
+	Cost        *money.Amount `json:"cost,omitempty"`
+	Price       *money.Amount `json:"price,omitempty"`
+
+	Currency     *string `json:"currency,omitempty"`
+	CostCurrency *string `json:"costCurrency,omitempty"`
+}
+
+// writeBookingError maps lifecycle errors to 409, currency errors to 400 and
+// everything else to 500.
+func writeBookingError(w http.ResponseWriter, err error, msg string) {
+	if errors.Is(err, bookings.ErrInvalidTransition) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if isFXError(err) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, msg, http.StatusInternalServerError)
+}
+
+// findBooking loads a booking by URL param, scoped to the tenant. It writes
+// the error response itself and returns nil on failure.
+func (h *BookingHandler) findBooking(w http.ResponseWriter, r *http.Request, tenantID uint) *models.Booking {
+	id, err := strconv.Atoi(chi.URLParam(r, "bookingID"))
+	if err != nil {
+		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+		return nil
+	}
+
+	var booking models.Booking
+	if err := h.DB.
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		First(&booking).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Booking not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return nil
+	}
+	return &booking
 }
 
 // CreateBooking handles POST /bookings
@@ -59,23 +130,96 @@ func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// New bookings start as Pending, or Confirmed when recorded after the fact.
+	if input.Status == "" {
+		input.Status = models.BookingPending
+	}
+	if input.Status != models.BookingPending && input.Status != models.BookingConfirmed {
+		http.Error(w, "status must be Pending or Confirmed for a new booking", http.StatusBadRequest)
+		return
+	}
+	if input.Product != "" && input.TravelDate.IsZero() {
+		http.Error(w, "travelDate is required to book from inventory", http.StatusBadRequest)
+		return
+	}
+	if input.OfferID != "" && input.Status != models.BookingPending {
+		http.Error(w, "Supplier bookings start as Pending and are confirmed through the supplier", http.StatusBadRequest)
+		return
+	}
+
 	booking := models.Booking{
 		ItineraryID: input.ItineraryID,
 		VendorID:    input.VendorID,
+		InvoiceID:   input.InvoiceID,
 		BookingRef:  input.BookingRef,
+		OfferID:     input.OfferID,
 		Status:      input.Status,
 		BookingDate: input.BookingDate,
 		TravelDate:  input.TravelDate,
 		Cost:        input.Cost,
 		Price:       input.Price,
 
+		Currency:     input.Currency,
+		CostCurrency: input.CostCurrency,
+
 		TenantID:  claims.TenantID,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	if err := h.DB.Create(&booking).Error; err != nil {
-		http.Error(w, "Failed to create booking", http.StatusInternalServerError)
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		contracted := false
+		if input.Product != "" {
+			cost, found, err := pricing.ContractCost(tx, claims.TenantID, booking.VendorID, input.Product,
+				booking.TravelDate, input.Nights, input.Units, input.Occupancy, input.MealPlan)
+			if err != nil {
+				return err
+			}
+			if found {
+				contracted = true
+				booking.ContractCost = cost
+				if booking.Cost == 0 {
+					booking.Cost = cost
+				} else if pricing.CostDiffers(booking.Cost, cost) {
+					booking.Warnings = append(booking.Warnings, pricing.CostWarning(input.Product, booking.Cost, cost))
+				}
+			}
+		}
+		if err := fx.RecordBooking(tx, &booking); err != nil {
+			return err
+		}
+		if err := tx.Create(&booking).Error; err != nil {
+			return err
+		}
+		if input.Product != "" {
+			cost, err := inventory.Reserve(tx, &booking, input.Product, booking.TravelDate, input.Nights, input.Units)
+			if errors.Is(err, inventory.ErrNoAllotment) && contracted {
+				err = nil // Contracted on free sale; nothing to draw down.
+			}
+			if err != nil {
+				return err
+			}
+			if booking.Cost == 0 && cost != 0 {
+				booking.Cost = cost
+				if err := tx.Model(&booking).Update("cost", cost).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if err := bookings.RecordTransition(tx, &booking, "", claims.UserID, "Created"); err != nil {
+			return err
+		}
+		// Recorded as Confirmed: invoice it as a confirmation would.
+		if booking.Status == models.BookingConfirmed {
+			return invoicing.OnBookingConfirmed(tx, &booking, claims.UserID)
+		}
+		return nil
+	}); err != nil {
+		if errors.Is(err, inventory.ErrInsufficient) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		writeBookingError(w, err, "Failed to create booking")
 		return
 	}
 
@@ -141,21 +285,8 @@ func (h *BookingHandler) UpdateBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := strconv.Atoi(chi.URLParam(r, "bookingID"))
-	if err != nil {
-		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
-		return
-	}
-
-	var booking models.Booking
-	if err := h.DB.
-		Where("id = ? AND tenant_id = ?", id, claims.TenantID).
-		First(&booking).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			http.Error(w, "Booking not found", http.StatusNotFound)
-		} else {
-			http.Error(w, "Database error", http.StatusInternalServerError)
-		}
+	booking := h.findBooking(w, r, claims.TenantID)
+	if booking == nil {
 		return
 	}
 
@@ -164,14 +295,23 @@ func (h *BookingHandler) UpdateBooking(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid payload", http.StatusBadRequest)
 		return
 	}
+	if input.Status != nil && !bookings.IsValidStatus(*input.Status) {
+		http.Error(w, "Unknown booking status", http.StatusBadRequest)
+		return
+	}
+	if input.Status != nil && booking.OfferID != "" &&
+		(*input.Status == models.BookingConfirmed || *input.Status == models.BookingCancelled) {
+		http.Error(w, "Supplier bookings are confirmed and cancelled through their endpoints", http.StatusConflict)
+		return
+	}
 
 	// Only update fields the user provided
+	if input.InvoiceID != nil {
+		booking.InvoiceID = input.InvoiceID
+	}
 	if input.BookingRef != nil {
 		booking.BookingRef = *input.BookingRef
 	}
-	if input.Status != nil {
-		booking.Status = *input.Status
-	}
 	if input.BookingDate != nil {
 		booking.BookingDate = *input.BookingDate
 	}
@@ -180,14 +320,40 @@ func (h *BookingHandler) UpdateBooking(w http.ResponseWriter, r *http.Request) {
 	}
 	if input.Cost != nil {
 		booking.Cost = *input.Cost
+		if booking.ContractCost != 0 && pricing.CostDiffers(booking.Cost, booking.ContractCost) {
+			booking.Warnings = append(booking.Warnings, pricing.CostWarning("Booking", booking.Cost, booking.ContractCost))
+		}
 	}
 	if input.Price != nil {
 		booking.Price = *input.Price
 	}
+	if input.Currency != nil {
+		booking.Currency = *input.Currency
+	}
+	if input.CostCurrency != nil {
+		booking.CostCurrency = *input.CostCurrency
+	}
 	booking.UpdatedAt = time.Now()
 
-	if err := h.DB.Save(&booking).Error; err != nil {
-		http.Error(w, "Failed to update booking", http.StatusInternalServerError)
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if input.Currency != nil || input.CostCurrency != nil || input.BookingDate != nil {
+			if err := fx.RecordBooking(tx, booking); err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(booking).Error; err != nil {
+			return err
+		}
+		if input.Status == nil || *input.Status == booking.Status {
+			return nil
+		}
+		if *input.Status == models.BookingCancelled {
+			_, err := bookings.Cancel(tx, booking, claims.UserID, input.Reason)
+			return err
+		}
+		return bookings.Transition(tx, booking, *input.Status, claims.UserID, input.Reason)
+	}); err != nil {
+		writeBookingError(w, err, "Failed to update booking")
 		return
 	}
 
@@ -195,23 +361,164 @@ func (h *BookingHandler) UpdateBooking(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(booking)
 }
 
-// DeleteBooking handles DELETE /bookings/{bookingID}
-func (h *BookingHandler) DeleteBooking(w http.ResponseWriter, r *http.Request) {
+// ChangeBookingStatus handles POST /bookings/{bookingID}/status
+func (h *BookingHandler) ChangeBookingStatus(w http.ResponseWriter, r *http.Request) {
 	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
 	if !ok {
 		http.Error(w, "Unauthorized: missing tenant info", http.StatusUnauthorized)
 		return
 	}
 
-	id, err := strconv.Atoi(chi.URLParam(r, "bookingID"))
-	if err != nil {
-		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+	booking := h.findBooking(w, r, claims.TenantID)
+	if booking == nil {
 		return
 	}
 
+	var input struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if input.Status == models.BookingCancelled {
+		http.Error(w, "Use the cancel endpoint to cancel a booking", http.StatusBadRequest)
+		return
+	}
+	if input.Status == models.BookingConfirmed && booking.OfferID != "" {
+		http.Error(w, "Use the confirm endpoint for supplier bookings", http.StatusConflict)
+		return
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		return bookings.Transition(tx, booking, input.Status, claims.UserID, input.Reason)
+	}); err != nil {
+		writeBookingError(w, err, "Failed to change booking status")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(booking)
+}
+
+// CancelBooking handles POST /bookings/{bookingID}/cancel. It applies the
+// vendor's cancellation policy and returns the penalty and any refund or
+// credit note that was issued.
+func (h *BookingHandler) CancelBooking(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized: missing tenant info", http.StatusUnauthorized)
+		return
+	}
+
+	booking := h.findBooking(w, r, claims.TenantID)
+	if booking == nil {
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !bookings.CanTransition(booking.Status, models.BookingCancelled) {
+		writeBookingError(w, fmt.Errorf("%w: %s -> %s", bookings.ErrInvalidTransition, booking.Status, models.BookingCancelled), "")
+		return
+	}
+	// Release the supplier reservation first so we never cancel locally
+	// while the supplier still holds (and bills) the seat.
+	if booking.OfferID != "" && booking.BookingRef != "" {
+		if err := h.cancelWithSupplier(r.Context(), booking); err != nil {
+			writeSupplierError(w, err)
+			return
+		}
+	}
+
+	var result *bookings.CancelResult
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// Reload it locked: it may have been cancelled or changed since it
+		// was loaded above. Cancel checks the status again.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND tenant_id = ?", booking.ID, claims.TenantID).
+			First(booking).Error; err != nil {
+			return err
+		}
+		var err error
+		result, err = bookings.Cancel(tx, booking, claims.UserID, input.Reason)
+		return err
+	}); err != nil {
+		writeBookingError(w, err, "Failed to cancel booking")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ListBookingTransitions handles GET /bookings/{bookingID}/transitions
+func (h *BookingHandler) ListBookingTransitions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized: missing tenant info", http.StatusUnauthorized)
+		return
+	}
+
+	booking := h.findBooking(w, r, claims.TenantID)
+	if booking == nil {
+		return
+	}
+
+	var list []models.BookingTransition
 	if err := h.DB.
-		Where("id = ? AND tenant_id = ?", id, claims.TenantID).
-		Delete(&models.Booking{}).Error; err != nil {
+		Where("booking_id = ? AND tenant_id = ?", booking.ID, claims.TenantID).
+		Order("created_at, id").
+		Find(&list).Error; err != nil {
+		http.Error(w, "Failed to fetch transitions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// DeleteBooking handles DELETE /bookings/{bookingID}. Only bookings that are
+// still Pending can be deleted; anything further along must be cancelled so
+// its history and financial effects are kept.
+func (h *BookingHandler) DeleteBooking(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized: missing tenant info", http.StatusUnauthorized)
+		return
+	}
+
+	booking := h.findBooking(w, r, claims.TenantID)
+	if booking == nil {
+		return
+	}
+	if booking.Status != models.BookingPending {
+		http.Error(w, "Only pending bookings can be deleted; cancel it instead", http.StatusConflict)
+		return
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := inventory.Return(tx, booking.ID); err != nil {
+			return err
+		}
+		if err := tx.Where("booking_id = ?", booking.ID).Delete(&models.BookingTransition{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(booking).Error; err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "DELETE_BOOKING", "Booking",
+			fmt.Sprintf("Deleted pending booking %d", booking.ID))
+	}); err != nil {
 		http.Error(w, "Failed to delete booking", http.StatusInternalServerError)
 		return
 	}
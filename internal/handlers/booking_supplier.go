@@ -0,0 +1,258 @@
+// internal/handlers/booking_supplier.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/bookings"
+	"travel-agency/internal/models"
+	"travel-agency/internal/suppliers"
+	"travel-agency/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// connectorFor returns the supplier connector for the vendor's type.
+func (h *BookingHandler) connectorFor(tenantID, vendorID uint) (suppliers.SupplierConnector, error) {
+	var vendor models.Vendor
+	if err := h.DB.Where("id = ? AND tenant_id = ?", vendorID, tenantID).First(&vendor).Error; err != nil {
+		return nil, err
+	}
+	return h.Suppliers.For(vendor.Type)
+}
+
+// writeSupplierError maps connector lookup and supplier errors to responses.
+func writeSupplierError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Vendor not found", http.StatusNotFound)
+	case errors.Is(err, suppliers.ErrNoConnector):
+		http.Error(w, "No supplier connector for this vendor type", http.StatusUnprocessableEntity)
+	case errors.Is(err, suppliers.ErrNotFound):
+		http.Error(w, "Supplier has no record of this offer or PNR", http.StatusNotFound)
+	case errors.Is(err, suppliers.ErrHoldExpired):
+		http.Error(w, "Supplier hold expired; search and hold again", http.StatusConflict)
+	default:
+		http.Error(w, "Supplier error: "+err.Error(), http.StatusBadGateway)
+	}
+}
+
+func (h *BookingHandler) cancelWithSupplier(ctx context.Context, b *models.Booking) error {
+	conn, err := h.connectorFor(b.TenantID, b.VendorID)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Cancel(ctx, b.BookingRef)
+	return err
+}
+
+// SearchSupplier handles POST /bookings/search and returns the vendor's
+// offers. Pass an offer's ID as offerID when creating the booking.
+func (h *BookingHandler) SearchSupplier(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized: missing tenant info", http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		VendorID uint `json:"vendorID"`
+		suppliers.SearchRequest
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := h.connectorFor(claims.TenantID, input.VendorID)
+	if err != nil {
+		writeSupplierError(w, err)
+		return
+	}
+	offers, err := conn.Search(r.Context(), input.SearchRequest)
+	if err != nil {
+		writeSupplierError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(offers)
+}
+
+// HoldBooking handles POST /bookings/{bookingID}/hold. It places the booking's
+// offer on hold with the supplier and stores the PNR; the booking stays Pending.
+func (h *BookingHandler) HoldBooking(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized: missing tenant info", http.StatusUnauthorized)
+		return
+	}
+
+	booking := h.findBooking(w, r, claims.TenantID)
+	if booking == nil {
+		return
+	}
+	if booking.OfferID == "" {
+		http.Error(w, "Booking has no supplier offer", http.StatusBadRequest)
+		return
+	}
+	if booking.Status != models.BookingPending || booking.BookingRef != "" {
+		http.Error(w, "Booking is already held or confirmed", http.StatusConflict)
+		return
+	}
+
+	var input struct {
+		Pax int `json:"pax"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+	}
+
+	res, err := h.hold(r.Context(), booking, input.Pax)
+	if err != nil {
+		writeSupplierError(w, err)
+		return
+	}
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(booking).Error; err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "SUPPLIER_HOLD", "Booking",
+			fmt.Sprintf("Booking %d held with supplier, PNR %s", booking.ID, res.PNR))
+	}); err != nil {
+		http.Error(w, "Failed to save booking", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"booking": booking, "reservation": res})
+}
+
+// hold places the offer on hold and copies the PNR and cost to the booking
+// (without saving it).
+func (h *BookingHandler) hold(ctx context.Context, b *models.Booking, pax int) (*suppliers.Reservation, error) {
+	conn, err := h.connectorFor(b.TenantID, b.VendorID)
+	if err != nil {
+		return nil, err
+	}
+	if pax < 1 {
+		pax = 1
+	}
+	res, err := conn.Hold(ctx, b.OfferID, pax)
+	if err != nil {
+		return nil, err
+	}
+	b.BookingRef = res.PNR
+	b.Cost = res.Cost
+	if b.TravelDate.IsZero() {
+		b.TravelDate = res.TravelDate
+	}
+	b.UpdatedAt = time.Now()
+	return res, nil
+}
+
+// ConfirmBooking handles POST /bookings/{bookingID}/confirm. The supplier
+// confirms the held reservation (holding it first if needed), the returned
+// PNR is stored in BookingRef and the booking moves to Confirmed.
+func (h *BookingHandler) ConfirmBooking(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized: missing tenant info", http.StatusUnauthorized)
+		return
+	}
+
+	booking := h.findBooking(w, r, claims.TenantID)
+	if booking == nil {
+		return
+	}
+	if booking.OfferID == "" {
+		http.Error(w, "Booking has no supplier offer; change its status instead", http.StatusBadRequest)
+		return
+	}
+	if !bookings.CanTransition(booking.Status, models.BookingConfirmed) {
+		writeBookingError(w, fmt.Errorf("%w: %s -> %s", bookings.ErrInvalidTransition, booking.Status, models.BookingConfirmed), "")
+		return
+	}
+
+	var input struct {
+		Pax int `json:"pax"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if booking.BookingRef == "" {
+		if _, err := h.hold(r.Context(), booking, input.Pax); err != nil {
+			writeSupplierError(w, err)
+			return
+		}
+	}
+	conn, err := h.connectorFor(claims.TenantID, booking.VendorID)
+	if err != nil {
+		writeSupplierError(w, err)
+		return
+	}
+	res, err := conn.Confirm(r.Context(), booking.BookingRef)
+	if err != nil {
+		writeSupplierError(w, err)
+		return
+	}
+	booking.BookingRef = res.PNR
+	booking.Cost = res.Cost
+	booking.BookingDate = time.Now()
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		return bookings.Transition(tx, booking, models.BookingConfirmed, claims.UserID, "Confirmed by supplier, PNR "+res.PNR)
+	}); err != nil {
+		writeBookingError(w, err, "Supplier confirmed but saving the booking failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"booking": booking, "reservation": res})
+}
+
+// GetSupplierReservation handles GET /bookings/{bookingID}/supplier and
+// returns the supplier's current view of the reservation.
+func (h *BookingHandler) GetSupplierReservation(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized: missing tenant info", http.StatusUnauthorized)
+		return
+	}
+
+	booking := h.findBooking(w, r, claims.TenantID)
+	if booking == nil {
+		return
+	}
+	if booking.OfferID == "" || booking.BookingRef == "" {
+		http.Error(w, "Booking has no supplier reservation", http.StatusNotFound)
+		return
+	}
+
+	conn, err := h.connectorFor(claims.TenantID, booking.VendorID)
+	if err != nil {
+		writeSupplierError(w, err)
+		return
+	}
+	res, err := conn.Retrieve(r.Context(), booking.BookingRef)
+	if err != nil {
+		writeSupplierError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateConfirmedBookingInvoicesIt(t *testing.T) {
+	db := testutil.DB(t)
+	require.NoError(t, db.Create(&models.Tenant{ID: 1, Name: "Agency", AutoInvoiceOnConfirm: true}).Error)
+	h := NewBookingHandler(db, nil)
+
+	rr := serveAs(1, h.CreateBooking, "POST", "/bookings", "/bookings",
+		`{"status": "Confirmed", "bookingRef": "AB12", "price": "250", "currency": "USD", "travelDate": "2026-12-01T00:00:00Z"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var booking models.Booking
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &booking))
+	require.NotNil(t, booking.InvoiceID)
+
+	var invoice models.Invoice
+	require.NoError(t, db.First(&invoice, "id = ?", *booking.InvoiceID).Error)
+	assert.Equal(t, "USD", invoice.Currency)
+	assert.Equal(t, booking.Price, invoice.Amount)
+}
@@ -0,0 +1,262 @@
+// internal/handlers/calendar.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/models"
+	"travel-agency/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// CalendarHandler serves iCalendar (.ics) feeds for itineraries and agents.
+type CalendarHandler struct {
+	DB      *gorm.DB
+	Secret  string
+	BaseURL string
+}
+
+func NewCalendarHandler(db *gorm.DB, secret, baseURL string) *CalendarHandler {
+	return &CalendarHandler{DB: db, Secret: secret, BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// itineraryEvents builds one all-day event per itinerary item (on its day)
+// and one event per booking on its travel date.
+func itineraryEvents(itin models.Itinerary, bookings []models.Booking, vendors map[uint]models.Vendor) []utils.CalendarEvent {
+	var events []utils.CalendarEvent
+	perDay := map[int]int{}
+	for _, item := range itin.Items {
+		perDay[item.Day]++
+		date := itin.StartDate.AddDate(0, 0, item.Day-1)
+		summary := item.Type
+		if v, ok := vendors[item.VendorID]; ok {
+			summary += " - " + v.Name
+		}
+		events = append(events, utils.CalendarEvent{
+			// Items are re-created on save, so key the UID on day and position.
+			UID:         utils.ICalUID("itinerary", itin.ID, "day", item.Day, perDay[item.Day]),
+			Summary:     summary,
+			Description: item.Description,
+			Location:    itin.Destination,
+			Start:       date,
+			End:         date,
+			AllDay:      true,
+		})
+	}
+	for _, b := range bookings {
+		if b.TravelDate.IsZero() {
+			continue
+		}
+		summary := "Booking"
+		if v, ok := vendors[b.VendorID]; ok {
+			summary += " - " + v.Name
+		}
+		if b.BookingRef != "" {
+			summary += " (" + b.BookingRef + ")"
+		}
+		events = append(events, utils.CalendarEvent{
+			UID:         utils.ICalUID("booking", b.ID),
+			Summary:     summary,
+			Description: fmt.Sprintf("%s - status: %s", itin.Name, b.Status),
+			Location:    itin.Destination,
+			Start:       b.TravelDate,
+			End:         b.TravelDate,
+			AllDay:      utils.IsDateOnly(b.TravelDate),
+		})
+	}
+	return events
+}
+
+// loadVendors fetches the tenant's vendors with the given IDs, keyed by ID.
+func loadVendors(db *gorm.DB, tenantID uint, ids []uint) (map[uint]models.Vendor, error) {
+	vendors := map[uint]models.Vendor{}
+	if len(ids) == 0 {
+		return vendors, nil
+	}
+	var list []models.Vendor
+	if err := db.Where("id IN ? AND tenant_id = ?", ids, tenantID).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for _, v := range list {
+		vendors[v.ID] = v
+	}
+	return vendors, nil
+}
+
+func writeICS(w http.ResponseWriter, filename string, body []byte) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", "inline; filename="+filename)
+	w.Write(body)
+}
+
+// ItineraryCalendar handles GET /itineraries/{itineraryID}/calendar.ics
+func (h *CalendarHandler) ItineraryCalendar(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var itin models.Itinerary
+	if err := h.DB.Preload("Items").
+		Where("id = ? AND tenant_id = ?", chi.URLParam(r, "itineraryID"), claims.TenantID).
+		First(&itin).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Itinerary not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var bookings []models.Booking
+	if err := h.DB.
+		Where("itinerary_id = ? AND tenant_id = ?", itin.ID, claims.TenantID).
+		Find(&bookings).Error; err != nil {
+		http.Error(w, "Failed to fetch bookings", http.StatusInternalServerError)
+		return
+	}
+
+	var vendorIDs []uint
+	for _, item := range itin.Items {
+		vendorIDs = append(vendorIDs, item.VendorID)
+	}
+	for _, b := range bookings {
+		vendorIDs = append(vendorIDs, b.VendorID)
+	}
+	vendors, err := loadVendors(h.DB, claims.TenantID, vendorIDs)
+	if err != nil {
+		http.Error(w, "Failed to fetch vendors", http.StatusInternalServerError)
+		return
+	}
+
+	body := utils.GenerateICS(itin.Name, itineraryEvents(itin, bookings, vendors))
+	writeICS(w, fmt.Sprintf("itinerary_%d.ics", itin.ID), body)
+}
+
+// SubscriptionURL handles GET /user/calendar-url and returns the caller's
+// signed, read-only feed URL for calendar apps.
+func (h *CalendarHandler) SubscriptionURL(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Where("id = ? AND tenant_id = ?", claims.UserID, claims.TenantID).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+	h.writeSubscriptionURL(w, user)
+}
+
+// RegenerateSubscriptionURL handles POST /user/calendar-url/regenerate. The
+// caller's previous feed URLs stop working and a new one is returned.
+func (h *CalendarHandler) RegenerateSubscriptionURL(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ? AND tenant_id = ?", claims.UserID, claims.TenantID).
+			Update("calendar_token_version", gorm.Expr("calendar_token_version + 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ? AND tenant_id = ?", claims.UserID, claims.TenantID).First(&user).Error; err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "REGENERATE_CALENDAR_URL", "User",
+			fmt.Sprintf("Calendar feed URL of user %d regenerated", user.ID))
+	}); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to regenerate calendar URL", http.StatusInternalServerError)
+		}
+		return
+	}
+	h.writeSubscriptionURL(w, user)
+}
+
+func (h *CalendarHandler) writeSubscriptionURL(w http.ResponseWriter, user models.User) {
+	token := auth.GenerateCalendarToken(user.ID, user.TenantID, user.CalendarTokenVersion, h.Secret)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"url": h.BaseURL + "/api/calendar/" + token + "/schedule.ics",
+	})
+}
+
+// UserFeed handles GET /calendar/{token}/schedule.ics (public, token-signed).
+// It lists the user's task due dates and the trips assigned to them. Tokens
+// issued before the user last regenerated their URL are refused.
+func (h *CalendarHandler) UserFeed(w http.ResponseWriter, r *http.Request) {
+	userID, tenantID, version, err := auth.ParseCalendarToken(chi.URLParam(r, "token"), h.Secret)
+	if err != nil {
+		http.Error(w, "Invalid calendar token", http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Where("id = ? AND tenant_id = ? AND is_active = ? AND calendar_token_version = ?",
+		userID, tenantID, true, version).First(&user).Error; err != nil {
+		http.Error(w, "Invalid calendar token", http.StatusUnauthorized)
+		return
+	}
+
+	var tasks []models.Task
+	if err := h.DB.
+		Where("tenant_id = ? AND assigned_to = ? AND status <> ?", tenantID, userID, "Completed").
+		Find(&tasks).Error; err != nil {
+		http.Error(w, "Failed to fetch tasks", http.StatusInternalServerError)
+		return
+	}
+
+	var trips []models.Itinerary
+	if err := h.DB.
+		Where("tenant_id = ? AND assigned_to = ?", tenantID, userID).
+		Find(&trips).Error; err != nil {
+		http.Error(w, "Failed to fetch itineraries", http.StatusInternalServerError)
+		return
+	}
+
+	var events []utils.CalendarEvent
+	for _, t := range tasks {
+		if t.DueDate.IsZero() {
+			continue
+		}
+		events = append(events, utils.CalendarEvent{
+			UID:         utils.ICalUID("task", t.ID),
+			Summary:     "Task due: " + t.Title,
+			Description: strings.TrimSpace(fmt.Sprintf("%s\nPriority: %s, status: %s", t.Description, t.Priority, t.Status)),
+			Start:       t.DueDate,
+			End:         t.DueDate,
+			AllDay:      utils.IsDateOnly(t.DueDate),
+		})
+	}
+	for _, trip := range trips {
+		events = append(events, utils.CalendarEvent{
+			UID:      utils.ICalUID("itinerary", trip.ID),
+			Summary:  "Trip: " + trip.Name,
+			Location: trip.Destination,
+			Start:    trip.StartDate,
+			End:      trip.EndDate,
+			AllDay:   true,
+		})
+	}
+
+	writeICS(w, "schedule.ics", utils.GenerateICS(user.Name+" - schedule", events))
+}
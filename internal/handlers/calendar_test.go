@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegenerateCalendarURL(t *testing.T) {
+	db := testutil.DB(t)
+	user := models.User{TenantID: 1, Name: "Agent", Email: "agent@example.com", PasswordHash: "x", Role: "agent", IsActive: true}
+	require.NoError(t, db.Create(&user).Error)
+	h := NewCalendarHandler(db, "calendar-secret", "http://agency.test")
+
+	feedURL := func(rr *httptest.ResponseRecorder) string {
+		t.Helper()
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var body map[string]string
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		u, err := url.Parse(body["url"])
+		require.NoError(t, err)
+		return u.Path
+	}
+	feed := func(path string) int {
+		r := chi.NewRouter()
+		r.Get("/api/calendar/{token}/schedule.ics", h.UserFeed)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr.Code
+	}
+	serveUser := func(method string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		return serveAs(user.TenantID, handler, method, "/user/calendar-url", "/user/calendar-url", "")
+	}
+
+	old := feedURL(serveUser("GET", h.SubscriptionURL))
+	assert.Equal(t, http.StatusOK, feed(old))
+
+	renewed := feedURL(serveUser("POST", h.RegenerateSubscriptionURL))
+	assert.NotEqual(t, old, renewed)
+	assert.Equal(t, http.StatusUnauthorized, feed(old))
+	assert.Equal(t, http.StatusOK, feed(renewed))
+	assert.Equal(t, renewed, feedURL(serveUser("GET", h.SubscriptionURL)))
+}
@@ -0,0 +1,195 @@
+// handlers/credit_note.go
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/invoicing"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/utils"
+)
+
+type CreditNoteHandler struct {
+	DB *gorm.DB
+}
+
+func NewCreditNoteHandler(db *gorm.DB) *CreditNoteHandler {
+	return &CreditNoteHandler{DB: db}
+}
+
+// creditNotePayload is the body of a create request: either lines crediting
+// some of the invoice's lines, or a plain amount.
+type creditNotePayload struct {
+	IssueDate time.Time    `json:"issueDate"`
+	Amount    money.Amount `json:"amount"`
+	Reason    string       `json:"reason"`
+	Items     []struct {
+		InvoiceItemID uint    `json:"invoiceItemId"`
+		Quantity      float64 `json:"quantity"` // 0 credits what is left of the line.
+		Description   string  `json:"description,omitempty"`
+	} `json:"items,omitempty"`
+}
+
+// CreateCreditNote handles POST /invoices/{invoiceID}/credit-notes.
+func (h *CreditNoteHandler) CreateCreditNote(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "invoiceID"))
+	if err != nil {
+		jsonError(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		jsonError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var payload creditNotePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		jsonError(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(payload.Reason) == "" {
+		jsonError(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+
+	note := models.CreditNote{
+		IssueDate: payload.IssueDate,
+		Amount:    payload.Amount,
+		Reason:    strings.TrimSpace(payload.Reason),
+	}
+	for _, item := range payload.Items {
+		note.Items = append(note.Items, models.CreditNoteItem{
+			InvoiceItemID: item.InvoiceItemID,
+			Quantity:      item.Quantity,
+			Description:   item.Description,
+		})
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var invoice models.Invoice
+		if err := tx.Where("id = ? AND tenant_id = ?", id, claims.TenantID).First(&invoice).Error; err != nil {
+			return err
+		}
+		if err := invoicing.IssueCreditNote(tx, &invoice, &note); err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "CREATE_CREDIT_NOTE", "CreditNote",
+			fmt.Sprintf("Credited %s %s on invoice %s: %s", note.Amount, note.Currency, invoiceReference(invoice), note.Reason))
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			jsonError(w, "Invoice not found", http.StatusNotFound)
+			return
+		}
+		writeInvoicingError(w, err, "Failed to create credit note")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(note)
+}
+
+// ListCreditNotes handles GET /credit-notes, optionally ?invoiceId=.
+func (h *CreditNoteHandler) ListCreditNotes(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		jsonError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := h.DB.Preload("Items").Where("tenant_id = ?", claims.TenantID)
+	if raw := r.URL.Query().Get("invoiceId"); raw != "" {
+		invoiceID, err := uuid.Parse(raw)
+		if err != nil {
+			jsonError(w, "Invalid invoice ID", http.StatusBadRequest)
+			return
+		}
+		q = q.Where("invoice_id = ?", invoiceID)
+	}
+	var notes []models.CreditNote
+	if err := q.Order("issue_date DESC, created_at DESC").Find(&notes).Error; err != nil {
+		jsonError(w, "Unable to fetch credit notes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(notes)
+}
+
+// creditNote loads a credit note of the caller's tenant. It writes the error
+// response itself and returns false on failure.
+func (h *CreditNoteHandler) creditNote(w http.ResponseWriter, r *http.Request, note *models.CreditNote) bool {
+	id, err := uuid.Parse(chi.URLParam(r, "creditNoteID"))
+	if err != nil {
+		jsonError(w, "Invalid credit note ID", http.StatusBadRequest)
+		return false
+	}
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		jsonError(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	if err := h.DB.Preload("Items").
+		Where("id = ? AND tenant_id = ?", id, claims.TenantID).
+		First(note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			jsonError(w, "Credit note not found", http.StatusNotFound)
+		} else {
+			jsonError(w, "Database error", http.StatusInternalServerError)
+		}
+		return false
+	}
+	return true
+}
+
+// GetCreditNote handles GET /credit-notes/{creditNoteID}
+func (h *CreditNoteHandler) GetCreditNote(w http.ResponseWriter, r *http.Request) {
+	var note models.CreditNote
+	if !h.creditNote(w, r, &note) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(note)
+}
+
+// DownloadCreditNotePDF handles GET /credit-notes/{creditNoteID}/pdf
+func (h *CreditNoteHandler) DownloadCreditNotePDF(w http.ResponseWriter, r *http.Request) {
+	var note models.CreditNote
+	if !h.creditNote(w, r, &note) {
+		return
+	}
+	var invoice models.Invoice
+	if err := h.DB.Where("id = ?", note.InvoiceID).First(&invoice).Error; err != nil {
+		jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	pdfBytes, err := utils.GenerateCreditNotePDF(note, invoice)
+	if err != nil {
+		jsonError(w, "Failed to generate PDF", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("credit_note_%s.pdf", note.ID)
+	if note.Number != nil {
+		filename = *note.Number + ".pdf"
+	}
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.Header().Set("Content-Type", "application/pdf")
+	_, _ = io.Copy(w, bytes.NewReader(pdfBytes))
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"travel-agency/internal/auth"
+	"travel-agency/internal/fx"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/utils"
+)
+
+// isFXError reports whether err is a currency or missing-rate error, which
+// the client can fix.
+func isFXError(err error) bool {
+	return errors.Is(err, fx.ErrNoRate) || errors.Is(err, fx.ErrInvalidCurrency) || errors.Is(err, fx.ErrInvalidRate)
+}
+
+type ExchangeRateHandler struct {
+	DB *gorm.DB
+}
+
+func NewExchangeRateHandler(db *gorm.DB) *ExchangeRateHandler {
+	return &ExchangeRateHandler{DB: db}
+}
+
+// parseDay reads an optional YYYY-MM-DD query parameter, defaulting to today.
+func parseDay(r *http.Request, name string) (time.Time, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return utils.TruncateDay(time.Now()), nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// ListRates handles GET /admin/exchange-rates?from=&to=. It lists the
+// tenant's own and the shared rates, newest first.
+func (h *ExchangeRateHandler) ListRates(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	query := h.DB.Where("tenant_id IN ?", []uint{0, claims.TenantID})
+	if from := fx.Normalize(r.URL.Query().Get("from")); from != "" {
+		query = query.Where("from_currency = ?", from)
+	}
+	if to := fx.Normalize(r.URL.Query().Get("to")); to != "" {
+		query = query.Where("to_currency = ?", to)
+	}
+
+	var rates []models.ExchangeRate
+	if err := query.Order("date DESC, from_currency, to_currency, tenant_id DESC").Find(&rates).Error; err != nil {
+		http.Error(w, "Unable to fetch exchange rates", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rates)
+}
+
+// ImportRates handles POST /admin/exchange-rates. The body is either JSON,
+// {"rates": [{"from", "to", "date", "rate"}]}, or CSV (Content-Type
+// text/csv) with a date,from,to,rate header. Rates are the tenant's own and
+// replace any it has for the same pair and date.
+func (h *ExchangeRateHandler) ImportRates(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var rates []models.ExchangeRate
+	source := "api"
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+		var err error
+		if rates, err = fx.ParseCSV(http.MaxBytesReader(w, r.Body, 5<<20)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		source = "csv upload"
+	} else {
+		var input struct {
+			Rates []models.ExchangeRate `json:"rates"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+		rates = input.Rates
+	}
+	if len(rates) == 0 {
+		http.Error(w, "No rates given", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := fx.Save(tx, claims.TenantID, rates, source); err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "IMPORT_EXCHANGE_RATES", "ExchangeRate",
+			fmt.Sprintf("Imported %d exchange rate(s) via %s", len(rates), source))
+	}); err != nil {
+		if isFXError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to save exchange rates", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int{"imported": len(rates)})
+}
+
+// DeleteRate handles DELETE /admin/exchange-rates/{rateID}. Only the
+// tenant's own rates can be deleted; amounts already recorded keep the rate
+// they used.
+func (h *ExchangeRateHandler) DeleteRate(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	rateID, err := strconv.Atoi(chi.URLParam(r, "rateID"))
+	if err != nil {
+		http.Error(w, "Invalid rate ID", http.StatusBadRequest)
+		return
+	}
+	res := h.DB.Where("id = ? AND tenant_id = ?", rateID, claims.TenantID).Delete(&models.ExchangeRate{})
+	if res.Error != nil {
+		http.Error(w, "Failed to delete exchange rate", http.StatusInternalServerError)
+		return
+	}
+	if res.RowsAffected == 0 {
+		http.Error(w, "Exchange rate not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Convert handles GET /exchange-rates/convert?amount=&from=&to=&date=. to
+// defaults to the tenant's base currency and date to today.
+func (h *ExchangeRateHandler) Convert(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	amount, err := money.Parse(q.Get("amount"))
+	if err != nil {
+		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		return
+	}
+	date, err := parseDay(r, "date")
+	if err != nil {
+		http.Error(w, "Invalid date (use YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+	from, to := fx.Normalize(q.Get("from")), fx.Normalize(q.Get("to"))
+	if to == "" {
+		if to, err = fx.BaseCurrency(h.DB, claims.TenantID); err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if !fx.Valid(from) || !fx.Valid(to) {
+		http.Error(w, "from and to must be currency codes", http.StatusBadRequest)
+		return
+	}
+
+	rate, err := fx.Rate(h.DB, claims.TenantID, from, to, date)
+	if err != nil {
+		if isFXError(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"amount":    amount,
+		"from":      from,
+		"to":        to,
+		"date":      date.Format("2006-01-02"),
+		"rate":      rate,
+		"converted": amount.Mul(rate).Round(to),
+	})
+}
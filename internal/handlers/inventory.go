@@ -0,0 +1,226 @@
+// internal/handlers/inventory.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/inventory"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// inventorySlot is an inventory row with its remaining availability.
+type inventorySlot struct {
+	models.Inventory
+	Available int `json:"available"`
+}
+
+func toSlots(rows []models.Inventory) []inventorySlot {
+	slots := make([]inventorySlot, 0, len(rows))
+	for _, row := range rows {
+		slots = append(slots, inventorySlot{Inventory: row, Available: max(row.Available(), 0)})
+	}
+	return slots
+}
+
+// parseDateRange reads ?from=&to= (YYYY-MM-DD). from defaults to today and
+// to to 30 days after from.
+func parseDateRange(r *http.Request) (from, to time.Time, ok bool) {
+	from = inventory.Day(time.Now())
+	if s := r.URL.Query().Get("from"); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return from, to, false
+		}
+		from = t
+	}
+	to = from.AddDate(0, 0, 30)
+	if s := r.URL.Query().Get("to"); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return from, to, false
+		}
+		to = t
+	}
+	return from, to, !to.Before(from)
+}
+
+// ListInventory handles GET /vendors/{vendorID}/inventory?product=&from=&to=
+func (h *VendorHandler) ListInventory(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	vendor := h.findVendor(w, r, claims.TenantID)
+	if vendor == nil {
+		return
+	}
+	from, to, ok := parseDateRange(r)
+	if !ok {
+		http.Error(w, "Invalid date range", http.StatusBadRequest)
+		return
+	}
+
+	query := h.DB.Where("tenant_id = ? AND vendor_id = ? AND date BETWEEN ? AND ?", claims.TenantID, vendor.ID, from, to)
+	if product := r.URL.Query().Get("product"); product != "" {
+		query = query.Where("product = ?", product)
+	}
+	var rows []models.Inventory
+	if err := query.Order("product, date").Find(&rows).Error; err != nil {
+		http.Error(w, "Unable to fetch inventory", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toSlots(rows))
+}
+
+// maxAllotmentDays caps how many dates one SetAllotment request may write.
+const maxAllotmentDays = 366
+
+// SetAllotment handles POST /vendors/{vendorID}/inventory. It creates or
+// updates one inventory row per date in [from, to] for the product. Each
+// date is released releaseDaysBefore days ahead of it.
+func (h *VendorHandler) SetAllotment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	vendor := h.findVendor(w, r, claims.TenantID)
+	if vendor == nil {
+		return
+	}
+
+	var input struct {
+		Product           string       `json:"product"`
+		From              string       `json:"from"`
+		To                string       `json:"to"`
+		Capacity          int          `json:"capacity"`
+		ReleaseDaysBefore int          `json:"releaseDaysBefore"`
+		ContractedCost    money.Amount `json:"contractedCost"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	from, err := time.Parse("2006-01-02", input.From)
+	if err != nil {
+		http.Error(w, "Invalid from date", http.StatusBadRequest)
+		return
+	}
+	to := from
+	if input.To != "" {
+		if to, err = time.Parse("2006-01-02", input.To); err != nil || to.Before(from) {
+			http.Error(w, "Invalid to date", http.StatusBadRequest)
+			return
+		}
+	}
+	if days := int(to.Sub(from).Hours()/24) + 1; days > maxAllotmentDays {
+		http.Error(w, fmt.Sprintf("from..to may span at most %d days", maxAllotmentDays), http.StatusBadRequest)
+		return
+	}
+	if input.Product == "" || input.Capacity < 0 || input.ReleaseDaysBefore < 0 || input.ContractedCost < 0 {
+		http.Error(w, "product is required; capacity, releaseDaysBefore and contractedCost must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	var rows []models.Inventory
+	var conflict error
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+			var slot models.Inventory
+			res := tx.Where("tenant_id = ? AND vendor_id = ? AND product = ? AND date = ?",
+				claims.TenantID, vendor.ID, input.Product, d).Limit(1).Find(&slot)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected > 0 && input.Capacity < slot.Booked {
+				conflict = fmt.Errorf("capacity on %s is below the %d units already booked", d.Format("2006-01-02"), slot.Booked)
+				return conflict
+			}
+			if res.RowsAffected == 0 {
+				slot = models.Inventory{
+					TenantID:  claims.TenantID,
+					VendorID:  vendor.ID,
+					Product:   input.Product,
+					Date:      d,
+					CreatedAt: now,
+				}
+			}
+			slot.Capacity = input.Capacity
+			slot.ContractedCost = input.ContractedCost
+			slot.ReleaseDate = d.AddDate(0, 0, -input.ReleaseDaysBefore)
+			slot.UpdatedAt = now
+			if err := tx.Save(&slot).Error; err != nil {
+				return err
+			}
+			rows = append(rows, slot)
+		}
+		return nil
+	}); err != nil {
+		if err == conflict {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Unable to save allotment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toSlots(rows))
+}
+
+// Availability handles GET /vendors/availability?from=&to=&product=&vendorId=&units=
+// and lists open allotment across the tenant's vendors. Only dates with at
+// least units (default 1) available are returned.
+func (h *VendorHandler) Availability(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	from, to, ok := parseDateRange(r)
+	if !ok {
+		http.Error(w, "Invalid date range", http.StatusBadRequest)
+		return
+	}
+	units := 1
+	if s := r.URL.Query().Get("units"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid units", http.StatusBadRequest)
+			return
+		}
+		units = n
+	}
+
+	query := h.DB.Where("tenant_id = ? AND date BETWEEN ? AND ? AND released_at IS NULL", claims.TenantID, from, to).
+		Where("capacity - booked - released >= ?", units)
+	if product := r.URL.Query().Get("product"); product != "" {
+		query = query.Where("product = ?", product)
+	}
+	if vendorID := r.URL.Query().Get("vendorId"); vendorID != "" {
+		query = query.Where("vendor_id = ?", vendorID)
+	}
+	var rows []models.Inventory
+	if err := query.Order("date, vendor_id, product").Find(&rows).Error; err != nil {
+		http.Error(w, "Unable to fetch availability", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toSlots(rows))
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAllotmentCapsTheRange(t *testing.T) {
+	db := testutil.DB(t)
+	vendor := models.Vendor{TenantID: 1, Name: "Hotel"}
+	require.NoError(t, db.Create(&vendor).Error)
+	set := func(to string) int {
+		rr := serveAs(1, NewVendorHandler(db).SetAllotment, "POST", "/vendors/{vendorID}/inventory",
+			fmt.Sprintf("/vendors/%d/inventory", vendor.ID),
+			fmt.Sprintf(`{"product": "Deluxe Room", "from": "2030-01-01", "to": %q, "capacity": 5}`, to))
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, set("2031-01-02"))
+	assert.Equal(t, http.StatusOK, set("2030-12-31"))
+	var rows int64
+	db.Model(&models.Inventory{}).Count(&rows)
+	assert.Equal(t, int64(365), rows)
+}
@@ -4,17 +4,24 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/mail"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"travel-agency/internal/auth"
+	"travel-agency/internal/fx"
+	"travel-agency/internal/invoicing"
 	"travel-agency/internal/models"
+	"travel-agency/internal/money"
 	"travel-agency/internal/utils"
 )
 
@@ -33,6 +40,77 @@ func NewInvoiceHandler(db *gorm.DB) *InvoiceHandler {
 	return &InvoiceHandler{DB: db}
 }
 
+// invoicePayload is the body of create and update requests. With items the
+// amount is computed from them; without, the entered amount is used.
+type invoicePayload struct {
+	InvoiceType string               `json:"invoiceType"`
+	IssueDate   time.Time            `json:"issueDate"`
+	DueDate     time.Time            `json:"dueDate"`
+	Status      string               `json:"status"`
+	Amount      money.Amount         `json:"amount"`
+	Currency    string               `json:"currency"`
+	CustomerID  *uuid.UUID           `json:"customerId,omitempty"`
+	VendorID    invoiceVendor        `json:"vendorId"`
+	Reference   string               `json:"reference,omitempty"`
+	Email       string               `json:"contactEmail,omitempty"` // Where payment reminders are sent.
+	Items       []models.InvoiceItem `json:"items,omitempty"`
+
+	// Matched by the tenant's tax rules for lines given no taxes.
+	PlaceOfSupply string `json:"placeOfSupply,omitempty"`
+	CustomerType  string `json:"customerType,omitempty"`
+}
+
+// invoiceVendor is the payload's vendorId: a vendor's numeric ID or, as
+// clients sent it before vendors had numeric IDs on invoices, a UUID. A UUID
+// matches no vendor and is kept as the invoice's legacy vendor.
+type invoiceVendor struct {
+	ID     *uint
+	Legacy *uuid.UUID
+}
+
+func (v *invoiceVendor) UnmarshalJSON(data []byte) error {
+	*v = invoiceVendor{}
+	if string(data) == "null" {
+		return nil
+	}
+	var id uint
+	if err := json.Unmarshal(data, &id); err == nil {
+		v.ID = &id
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("vendorId must be a vendor ID")
+	}
+	legacy, err := uuid.Parse(s)
+	if err != nil {
+		return fmt.Errorf("vendorId must be a vendor ID")
+	}
+	v.Legacy = &legacy
+	return nil
+}
+
+// writeInvoicingError maps invoicing errors to responses.
+func writeInvoicingError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, invoicing.ErrInvalidLine), errors.Is(err, invoicing.ErrSourceNotFound), isFXError(err):
+		jsonError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, invoicing.ErrInvalidCredit):
+		jsonError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, invoicing.ErrAlreadyIssued), errors.Is(err, invoicing.ErrLocked),
+		errors.Is(err, invoicing.ErrNotIssued), errors.Is(err, invoicing.ErrOverCredited),
+		errors.Is(err, invoicing.ErrCannotVoid):
+		jsonError(w, err.Error(), http.StatusConflict)
+	default:
+		jsonError(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// issues reports whether saving an invoice with this status should issue it.
+func issues(status string) bool {
+	return status != "" && status != invoicing.Draft
+}
+
 // CreateInvoice handles POST /invoices
 func (h *InvoiceHandler) CreateInvoice(w http.ResponseWriter, r *http.Request) {
 	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
@@ -41,16 +119,7 @@ func (h *InvoiceHandler) CreateInvoice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var payload struct {
-		InvoiceType string     `json:"invoiceType"`
-		IssueDate   time.Time  `json:"issueDate"`
-		DueDate     time.Time  `json:"dueDate"`
-		Status      string     `json:"status"`
-		Amount      float64    `json:"amount"`
-		Currency    string     `json:"currency"`
-		CustomerID  *uuid.UUID `json:"customerId,omitempty"`
-		VendorID    *uuid.UUID `json:"vendorId,omitempty"`
-	}
+	var payload invoicePayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		jsonError(w, "Invalid payload", http.StatusBadRequest)
 		return
@@ -69,28 +138,75 @@ func (h *InvoiceHandler) CreateInvoice(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, "amount must be non-negative", http.StatusBadRequest)
 		return
 	}
+	if payload.Email != "" {
+		if _, err := mail.ParseAddress(payload.Email); err != nil {
+			jsonError(w, "Invalid contact email", http.StatusBadRequest)
+			return
+		}
+	}
 
 	invoice := models.Invoice{
-		TenantID:    claims.TenantID,
-		InvoiceType: payload.InvoiceType,
-		IssueDate:   payload.IssueDate,
-		DueDate:     payload.DueDate,
-		Status:      payload.Status,
-		Amount:      payload.Amount,
-		Currency:    payload.Currency,
-		CustomerID:  payload.CustomerID,
-		VendorID:    payload.VendorID,
+		TenantID:     claims.TenantID,
+		InvoiceType:  payload.InvoiceType,
+		IssueDate:    payload.IssueDate,
+		DueDate:      payload.DueDate,
+		Status:       payload.Status,
+		Amount:       payload.Amount,
+		Currency:     payload.Currency,
+		CustomerID:   payload.CustomerID,
+		VendorID:     payload.VendorID.ID,
+		LegacyVendor: payload.VendorID.Legacy,
+		Reference:    payload.Reference,
+		ContactEmail: payload.Email,
+		Items:        payload.Items,
+
+		PlaceOfSupply: payload.PlaceOfSupply,
+		CustomerType:  payload.CustomerType,
+	}
+	if err := invoicing.ResolveSources(h.DB, claims.TenantID, invoice.Items); err != nil {
+		writeInvoicingError(w, err, "Failed to create invoice")
+		return
+	}
+	if err := invoicing.ApplyTaxes(h.DB, &invoice); err != nil {
+		writeInvoicingError(w, err, "Failed to create invoice")
+		return
+	}
+	if err := invoicing.ComputeTotals(&invoice); err != nil {
+		writeInvoicingError(w, err, "Failed to create invoice")
+		return
+	}
+	for i := range invoice.Items {
+		invoice.Items[i].ID = 0
+		for j := range invoice.Items[i].Taxes {
+			invoice.Items[i].Taxes[j].ID = 0
+		}
 	}
 
-	// Wrap creation + audit log in a transaction
-	tx := h.DB.Begin()
-	if err := tx.Create(&invoice).Error; err != nil {
-		tx.Rollback()
-		jsonError(w, "Failed to create invoice", http.StatusInternalServerError)
+	// Create the invoice and its lines, number it if it is not a draft, and
+	// audit, all in one transaction.
+	status := invoice.Status
+	invoice.Status = invoicing.Draft
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := fx.RecordInvoice(tx, &invoice); err != nil {
+			return err
+		}
+		if err := tx.Create(&invoice).Error; err != nil {
+			return err
+		}
+		if err := invoicing.LinkBookings(tx, &invoice); err != nil {
+			return err
+		}
+		if issues(status) {
+			invoice.Status = status
+			if err := invoicing.Issue(tx, &invoice); err != nil {
+				return err
+			}
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "CREATE_INVOICE", "Invoice", "Created invoice")
+	}); err != nil {
+		writeInvoicingError(w, err, "Failed to create invoice")
 		return
 	}
-	_ = utils.LogAction(tx, claims.TenantID, claims.UserID, "CREATE_INVOICE", "Invoice", "Created invoice")
-	tx.Commit()
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -106,7 +222,7 @@ func (h *InvoiceHandler) ListInvoices(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var invoices []models.Invoice
-	if err := h.DB.
+	if err := h.DB.Preload("Items.Taxes").
 		Where("tenant_id = ?", claims.TenantID).
 		Find(&invoices).Error; err != nil {
 		jsonError(w, "Unable to fetch invoices", http.StatusInternalServerError)
@@ -117,7 +233,8 @@ func (h *InvoiceHandler) ListInvoices(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(invoices)
 }
 
-// GetInvoice handles GET /invoices/{invoiceID}
+// GetInvoice handles GET /invoices/{invoiceID}, with the payment reminders
+// sent for it.
 func (h *InvoiceHandler) GetInvoice(w http.ResponseWriter, r *http.Request) {
 	rawID := chi.URLParam(r, "invoiceID")
 	id, err := uuid.Parse(rawID)
@@ -133,7 +250,8 @@ func (h *InvoiceHandler) GetInvoice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var invoice models.Invoice
-	if err := h.DB.
+	if err := h.DB.Preload("Items.Taxes").
+		Preload("Reminders", func(db *gorm.DB) *gorm.DB { return db.Order("created_at DESC, id DESC") }).
 		Where("id = ? AND tenant_id = ?", id, claims.TenantID).
 		First(&invoice).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -148,7 +266,8 @@ func (h *InvoiceHandler) GetInvoice(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(invoice)
 }
 
-// UpdateInvoice handles PUT /invoices/{invoiceID}
+// UpdateInvoice handles PUT /invoices/{invoiceID}. Only drafts can be
+// edited; issued invoices are locked (409).
 func (h *InvoiceHandler) UpdateInvoice(w http.ResponseWriter, r *http.Request) {
 	rawID := chi.URLParam(r, "invoiceID")
 	id, err := uuid.Parse(rawID)
@@ -175,17 +294,12 @@ func (h *InvoiceHandler) UpdateInvoice(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-
-	var payload struct {
-		InvoiceType string     `json:"invoiceType"`
-		IssueDate   time.Time  `json:"issueDate"`
-		DueDate     time.Time  `json:"dueDate"`
-		Status      string     `json:"status"`
-		Amount      float64    `json:"amount"`
-		Currency    string     `json:"currency"`
-		CustomerID  *uuid.UUID `json:"customerId,omitempty"`
-		VendorID    *uuid.UUID `json:"vendorId,omitempty"`
+	if !invoicing.IsDraft(&invoice) {
+		writeInvoicingError(w, invoicing.ErrLocked, "Failed to update invoice")
+		return
 	}
+
+	var payload invoicePayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		jsonError(w, "Invalid payload", http.StatusBadRequest)
 		return
@@ -204,26 +318,201 @@ func (h *InvoiceHandler) UpdateInvoice(w http.ResponseWriter, r *http.Request) {
 		jsonError(w, "amount must be non-negative", http.StatusBadRequest)
 		return
 	}
+	if payload.Email != "" {
+		if _, err := mail.ParseAddress(payload.Email); err != nil {
+			jsonError(w, "Invalid contact email", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Totals are recomputed from the new lines, or from the stored ones
+	// when the payload has none.
+	if payload.Items != nil {
+		if err := invoicing.ResolveSources(h.DB, claims.TenantID, payload.Items); err != nil {
+			writeInvoicingError(w, err, "Failed to update invoice")
+			return
+		}
+		invoice.Items = payload.Items
+		invoice.InvoiceType = payload.InvoiceType
+		invoice.IssueDate = payload.IssueDate
+		invoice.PlaceOfSupply = payload.PlaceOfSupply
+		invoice.CustomerType = payload.CustomerType
+		if err := invoicing.ApplyTaxes(h.DB, &invoice); err != nil {
+			writeInvoicingError(w, err, "Failed to update invoice")
+			return
+		}
+	} else if err := h.DB.Preload("Taxes").Where("invoice_id = ?", invoice.ID).
+		Order("position").Find(&invoice.Items).Error; err != nil {
+		jsonError(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	invoice.Amount = payload.Amount
+	if err := invoicing.ComputeTotals(&invoice); err != nil {
+		writeInvoicingError(w, err, "Failed to update invoice")
+		return
+	}
+	invoice.Currency = payload.Currency
+	invoice.IssueDate = payload.IssueDate
+	if err := fx.RecordInvoice(h.DB, &invoice); err != nil {
+		writeInvoicingError(w, err, "Failed to update invoice")
+		return
+	}
 
 	// Perform a partial update
 	updates := map[string]interface{}{
-		"invoice_type": payload.InvoiceType,
-		"issue_date":   payload.IssueDate,
-		"due_date":     payload.DueDate,
-		"status":       payload.Status,
-		"amount":       payload.Amount,
-		"currency":     payload.Currency,
-		"customer_id":  payload.CustomerID,
-		"vendor_id":    payload.VendorID,
-		"updated_at":   time.Now(),
+		"invoice_type":       payload.InvoiceType,
+		"issue_date":         payload.IssueDate,
+		"due_date":           payload.DueDate,
+		"subtotal":           invoice.Subtotal,
+		"discount_total":     invoice.DiscountTotal,
+		"tax_total":          invoice.TaxTotal,
+		"amount":             invoice.Amount,
+		"currency":           invoice.Currency,
+		"exchange_rate":      invoice.ExchangeRate,
+		"customer_id":        payload.CustomerID,
+		"vendor_id":          payload.VendorID.ID,
+		"legacy_vendor_uuid": payload.VendorID.Legacy,
+		"reference":          payload.Reference,
+		"contact_email":      payload.Email,
+		"place_of_supply":    payload.PlaceOfSupply,
+		"customer_type":      payload.CustomerType,
+		"updated_at":         time.Now(),
+	}
+	// A draft given another status is issued (and numbered) instead; one
+	// given none keeps its own.
+	issue := issues(payload.Status)
+	if !issue && payload.Status != "" {
+		updates["status"] = payload.Status
 	}
-	if err := h.DB.Model(&invoice).Updates(updates).Error; err != nil {
-		jsonError(w, "Failed to update invoice", http.StatusInternalServerError)
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// It may have been issued since it was loaded above.
+		var current models.Invoice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", invoice.ID).First(&current).Error; err != nil {
+			return err
+		}
+		if !invoicing.IsDraft(&current) {
+			return invoicing.ErrLocked
+		}
+		if err := tx.Model(&models.Invoice{}).Where("id = ?", invoice.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if payload.Items != nil {
+			if err := invoicing.ReplaceItems(tx, &invoice); err != nil {
+				return err
+			}
+		}
+		if err := invoicing.LinkBookings(tx, &invoice); err != nil {
+			return err
+		}
+		if issue {
+			invoice.Status = payload.Status
+			if err := invoicing.Issue(tx, &invoice); err != nil {
+				return err
+			}
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID,
+			"UPDATE_INVOICE", "Invoice", "Updated invoice")
+	}); err != nil {
+		writeInvoicingError(w, err, "Failed to update invoice")
 		return
 	}
+	h.DB.Preload("Items.Taxes").First(&invoice, "id = ?", invoice.ID)
 
-	_ = utils.LogAction(h.DB, claims.TenantID, claims.UserID,
-		"UPDATE_INVOICE", "Invoice", "Updated invoice")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(invoice)
+}
+
+// IssueInvoice handles POST /invoices/{invoiceID}/issue. A sale invoice gets
+// the tenant's next number for its issue year; drafts become Outstanding.
+func (h *InvoiceHandler) IssueInvoice(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "invoiceID"))
+	if err != nil {
+		jsonError(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		jsonError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var invoice models.Invoice
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// Locked so that two requests cannot both issue the draft.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items.Taxes").
+			Where("id = ? AND tenant_id = ?", id, claims.TenantID).
+			First(&invoice).Error; err != nil {
+			return err
+		}
+		if err := invoicing.Issue(tx, &invoice); err != nil {
+			return err
+		}
+		number := "(purchase)"
+		if invoice.Number != nil {
+			number = *invoice.Number
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "ISSUE_INVOICE", "Invoice",
+			fmt.Sprintf("Issued invoice %s as %s", invoice.ID, number))
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			jsonError(w, "Invoice not found", http.StatusNotFound)
+			return
+		}
+		writeInvoicingError(w, err, "Failed to issue invoice")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(invoice)
+}
+
+// VoidInvoice handles POST /invoices/{invoiceID}/void with {"reason": "..."}.
+// Issued invoices without payments can be voided; their number stays used.
+func (h *InvoiceHandler) VoidInvoice(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "invoiceID"))
+	if err != nil {
+		jsonError(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		jsonError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		jsonError(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(input.Reason) == "" {
+		jsonError(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+
+	var invoice models.Invoice
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND tenant_id = ?", id, claims.TenantID).First(&invoice).Error; err != nil {
+			return err
+		}
+		if err := invoicing.VoidInvoice(tx, &invoice, input.Reason); err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "VOID_INVOICE", "Invoice",
+			fmt.Sprintf("Voided invoice %s: %s", invoiceReference(invoice), invoice.VoidReason))
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			jsonError(w, "Invoice not found", http.StatusNotFound)
+			return
+		}
+		writeInvoicingError(w, err, "Failed to void invoice")
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(invoice)
@@ -240,10 +529,24 @@ func (h *InvoiceHandler) DeleteInvoice(w http.ResponseWriter, r *http.Request) {
 
 	claims, _ := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
 
-	// Delete in one statement with tenant check
-	if err := h.DB.
-		Where("id = ? AND tenant_id = ?", id, claims.TenantID).
-		Delete(&models.Invoice{}).Error; err != nil {
+	// Numbered invoices stay, so the sequence has no gaps.
+	var numbered int64
+	h.DB.Model(&models.Invoice{}).
+		Where("id = ? AND tenant_id = ? AND number IS NOT NULL", id, claims.TenantID).
+		Count(&numbered)
+	if numbered > 0 {
+		jsonError(w, "Issued invoices cannot be deleted", http.StatusConflict)
+		return
+	}
+
+	// Delete with tenant check, along with the lines.
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ? AND tenant_id = ?", id, claims.TenantID).Delete(&models.Invoice{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		return invoicing.DeleteItems(tx, id)
+	}); err != nil {
 		jsonError(w, "Failed to delete invoice", http.StatusInternalServerError)
 		return
 	}
@@ -263,7 +566,8 @@ func (h *InvoiceHandler) DownloadInvoicePDF(w http.ResponseWriter, r *http.Reque
 	claims, _ := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
 
 	var invoice models.Invoice
-	if err := h.DB.
+	if err := h.DB.Preload("Items.Taxes").
+		Preload("Reminders", func(db *gorm.DB) *gorm.DB { return db.Order("created_at DESC, id DESC") }).
 		Where("id = ? AND tenant_id = ?", id, claims.TenantID).
 		First(&invoice).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -281,6 +585,9 @@ func (h *InvoiceHandler) DownloadInvoicePDF(w http.ResponseWriter, r *http.Reque
 	}
 
 	filename := fmt.Sprintf("invoice_%s.pdf", invoice.ID)
+	if invoice.Number != nil {
+		filename = *invoice.Number + ".pdf"
+	}
 	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
 	w.Header().Set("Content-Type", "application/pdf")
 	_, _ = io.Copy(w, bytes.NewReader(pdfBytes))
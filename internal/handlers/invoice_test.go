@@ -11,8 +11,11 @@ import (
 	"time"
 
 	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/testutil"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -43,7 +46,7 @@ func TestUpdateInvoice(t *testing.T) {
 		IssueDate:   time.Now().Add(-24 * time.Hour),
 		DueDate:     time.Now().Add(24 * time.Hour),
 		Status:      "Draft",
-		Amount:      1000,
+		Amount:      money.FromInt(1000),
 		Currency:    "USD",
 		CreatedAt:   time.Now().Add(-24 * time.Hour),
 		UpdatedAt:   time.Now().Add(-24 * time.Hour),
@@ -94,7 +97,7 @@ func TestUpdateInvoice(t *testing.T) {
 	err := json.Unmarshal(rr.Body.Bytes(), &updatedInvoice)
 	assert.NoError(t, err)
 	assert.Equal(t, "Sent", updatedInvoice.Status)
-	assert.Equal(t, float64(1100), updatedInvoice.Amount)
+	assert.Equal(t, money.FromInt(1100), updatedInvoice.Amount)
 
 	// Optionally, check that audit log was created.
 	var audit models.AuditLog
@@ -106,3 +109,87 @@ func TestUpdateInvoice(t *testing.T) {
 func contextWithDummyClaims(ctx context.Context, claims interface{}) context.Context {
 	return context.WithValue(ctx, claimsKey, claims)
 }
+
+func updateInvoiceAs(db *gorm.DB, invoice models.Invoice, payload map[string]interface{}) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	return serveAs(invoice.TenantID, NewInvoiceHandler(db).UpdateInvoice, http.MethodPut,
+		"/invoices/{invoiceID}", "/invoices/"+invoice.ID.String(), string(body))
+}
+
+func TestUpdateInvoiceKeepsStatus(t *testing.T) {
+	db := testutil.DB(t)
+	invoice := models.Invoice{
+		ID:          uuid.New(),
+		TenantID:    1,
+		InvoiceType: "sale",
+		IssueDate:   time.Now(),
+		DueDate:     time.Now().AddDate(0, 0, 30),
+		Status:      "Draft",
+		Amount:      money.FromInt(1000),
+		Currency:    "USD",
+	}
+	assert.NoError(t, db.Create(&invoice).Error)
+
+	rr := updateInvoiceAs(db, invoice, map[string]interface{}{
+		"invoiceType": "sale",
+		"issueDate":   invoice.IssueDate,
+		"dueDate":     invoice.DueDate,
+		"amount":      money.FromInt(1200),
+		"currency":    "USD",
+	})
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var stored models.Invoice
+	assert.NoError(t, db.First(&stored, "id = ?", invoice.ID).Error)
+	assert.Equal(t, "Draft", stored.Status)
+	assert.Equal(t, money.FromInt(1200), stored.Amount)
+
+	// Once issued it can no longer be edited.
+	now := time.Now()
+	assert.NoError(t, db.Model(&stored).Updates(map[string]interface{}{"status": "Outstanding", "issued_at": now}).Error)
+	rr = updateInvoiceAs(db, invoice, map[string]interface{}{
+		"invoiceType": "sale",
+		"issueDate":   invoice.IssueDate,
+		"dueDate":     invoice.DueDate,
+		"amount":      money.FromInt(1500),
+		"currency":    "USD",
+	})
+	assert.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+}
+
+func TestCreateInvoiceVendorShapes(t *testing.T) {
+	db := testutil.DB(t)
+	h := NewInvoiceHandler(db)
+	vendorID, legacy := uint(7), uuid.New()
+
+	for _, tt := range []struct {
+		vendorID interface{}
+		code     int
+		id       *uint
+		legacy   *uuid.UUID
+	}{
+		{vendorID: vendorID, code: http.StatusCreated, id: &vendorID},
+		{vendorID: legacy.String(), code: http.StatusCreated, legacy: &legacy}, // As sent before vendor IDs were numeric.
+		{vendorID: nil, code: http.StatusCreated},
+		{vendorID: "supplier-7", code: http.StatusBadRequest},
+	} {
+		body, _ := json.Marshal(map[string]interface{}{
+			"invoiceType": "purchase",
+			"issueDate":   time.Now(),
+			"dueDate":     time.Now().AddDate(0, 0, 30),
+			"amount":      money.FromInt(100),
+			"currency":    "USD",
+			"vendorId":    tt.vendorID,
+		})
+		rr := serveAs(1, h.CreateInvoice, http.MethodPost, "/invoices", "/invoices", string(body))
+		if !assert.Equal(t, tt.code, rr.Code, "vendorId %v: %s", tt.vendorID, rr.Body.String()) || tt.code != http.StatusCreated {
+			continue
+		}
+		var created models.Invoice
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+		var stored models.Invoice
+		assert.NoError(t, db.First(&stored, "id = ?", created.ID).Error)
+		assert.Equal(t, tt.id, stored.VendorID, "vendorId %v", tt.vendorID)
+		assert.Equal(t, tt.legacy, stored.LegacyVendor, "vendorId %v", tt.vendorID)
+	}
+}
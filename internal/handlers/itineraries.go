@@ -2,12 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"travel-agency/internal/auth"
 	"travel-agency/internal/models"
+	"travel-agency/internal/pricing"
+	"travel-agency/internal/utils"
 
 	"github.com/go-chi/chi/v5"
 	"gorm.io/gorm"
@@ -21,6 +24,34 @@ func NewItineraryHandler(db *gorm.DB) *ItineraryHandler {
 	return &ItineraryHandler{DB: db}
 }
 
+// createItinerary prices the items, inserts the itinerary with its items and
+// records the first version, all in a single transaction.
+func createItinerary(db *gorm.DB, itin *models.Itinerary, items []models.ItineraryItem, userID uint) error {
+	if itin.AssignedTo == 0 {
+		itin.AssignedTo = userID
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if _, err := pricing.PriceItinerary(tx, itin, items); err != nil {
+			return err
+		}
+		if err := tx.Create(itin).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			item := items[i]
+			item.ID = 0
+			item.ItineraryID = itin.ID
+			item.CreatedAt = time.Now()
+			item.UpdatedAt = time.Now()
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+		}
+		_, err := snapshotItinerary(tx, itin, userID, 0)
+		return err
+	})
+}
+
 // CreateItinerary accepts a payload with both itinerary and its items,
 // enforces tenant scope, and wraps in a single transaction.
 func (h *ItineraryHandler) CreateItinerary(w http.ResponseWriter, r *http.Request) {
@@ -30,14 +61,16 @@ func (h *ItineraryHandler) CreateItinerary(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// we expect camelCase JSON from the client
+	// we expect camelCase JSON from the client; totals are computed server-side
 	var payload struct {
-		Name       string                 `json:"name"`
-		StartDate  string                 `json:"startDate"`
-		EndDate    string                 `json:"endDate"`
-		Status     string                 `json:"status"`
-		TotalPrice float64                `json:"totalPrice"`
-		Items      []models.ItineraryItem `json:"items"`
+		Name        string                 `json:"name"`
+		CustomerID  uint                   `json:"customerId"`
+		StartDate   string                 `json:"startDate"`
+		EndDate     string                 `json:"endDate"`
+		Status      string                 `json:"status"`
+		Destination string                 `json:"destination"`
+		Currency    string                 `json:"currency"` // Defaults to the tenant's base currency.
+		Items       []models.ItineraryItem `json:"items"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
@@ -59,32 +92,23 @@ func (h *ItineraryHandler) CreateItinerary(w http.ResponseWriter, r *http.Reques
 
 	// Build parent record
 	itin := models.Itinerary{
-		TenantID:   claims.TenantID,
-		Name:       payload.Name,
-		StartDate:  startDate,
-		EndDate:    endDate,
-		Status:     payload.Status,
-		TotalPrice: payload.TotalPrice,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		TenantID:    claims.TenantID,
+		CustomerID:  payload.CustomerID,
+		Name:        payload.Name,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		Status:      payload.Status,
+		Destination: payload.Destination,
+		Currency:    payload.Currency,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
 	}
 
-	// Run in transaction: insert parent + items
-	if err := h.DB.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(&itin).Error; err != nil {
-			return err
+	if err := createItinerary(h.DB, &itin, payload.Items, claims.UserID); err != nil {
+		if isFXError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
-		for i := range payload.Items {
-			item := payload.Items[i]
-			item.ItineraryID = itin.ID
-			item.CreatedAt = time.Now()
-			item.UpdatedAt = time.Now()
-			if err := tx.Create(&item).Error; err != nil {
-				return err
-			}
-		}
-		return nil
-	}); err != nil {
 		http.Error(w, "Failed to create itinerary and items", http.StatusInternalServerError)
 		return
 	}
@@ -101,20 +125,27 @@ func (h *ItineraryHandler) CreateItinerary(w http.ResponseWriter, r *http.Reques
 
 // UpdateItinerary updates both parent and child items in one transaction.
 func (h *ItineraryHandler) UpdateItinerary(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
 	id64, err := strconv.Atoi(chi.URLParam(r, "itineraryID"))
 	if err != nil {
 		http.Error(w, "Invalid itinerary ID", http.StatusBadRequest)
 		return
 	}
 
-	// Decode update payload
+	// Decode update payload; totals are computed server-side
 	var payload struct {
-		Name       string                 `json:"name"`
-		StartDate  time.Time              `json:"startDate"`
-		EndDate    time.Time              `json:"endDate"`
-		Status     string                 `json:"status"`
-		TotalPrice float64                `json:"totalPrice"`
-		Items      []models.ItineraryItem `json:"items"`
+		Name        string                 `json:"name"`
+		StartDate   time.Time              `json:"startDate"`
+		EndDate     time.Time              `json:"endDate"`
+		Status      string                 `json:"status"`
+		Destination string                 `json:"destination"`
+		Currency    string                 `json:"currency"` // Unchanged when empty.
+		Items       []models.ItineraryItem `json:"items"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		http.Error(w, "Invalid payload", http.StatusBadRequest)
@@ -122,7 +153,9 @@ func (h *ItineraryHandler) UpdateItinerary(w http.ResponseWriter, r *http.Reques
 	}
 
 	var itin models.Itinerary
-	if err := h.DB.Preload("Items").First(&itin, id64).Error; err != nil {
+	if err := h.DB.Preload("Items").
+		Where("id = ? AND tenant_id = ?", id64, claims.TenantID).
+		First(&itin).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			http.Error(w, "Itinerary not found", http.StatusNotFound)
 		} else {
@@ -131,14 +164,21 @@ func (h *ItineraryHandler) UpdateItinerary(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Transaction: update parent, delete old items, create new ones
+	// Transaction: reprice, update parent, replace items, snapshot a new version
 	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		itin.Items = nil
 		itin.Name = payload.Name
 		itin.StartDate = payload.StartDate
 		itin.EndDate = payload.EndDate
 		itin.Status = payload.Status
-		itin.TotalPrice = payload.TotalPrice
+		itin.Destination = payload.Destination
+		if payload.Currency != "" {
+			itin.Currency = payload.Currency
+		}
 		itin.UpdatedAt = time.Now()
+		if _, err := pricing.PriceItinerary(tx, &itin, payload.Items); err != nil {
+			return err
+		}
 		if err := tx.Save(&itin).Error; err != nil {
 			return err
 		}
@@ -158,8 +198,14 @@ func (h *ItineraryHandler) UpdateItinerary(w http.ResponseWriter, r *http.Reques
 				return err
 			}
 		}
-		return nil
+		// keep the previous proposal: every save becomes a new version
+		_, err := snapshotItinerary(tx, &itin, claims.UserID, 0)
+		return err
 	}); err != nil {
+		if isFXError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to update itinerary and items", http.StatusInternalServerError)
 		return
 	}
@@ -223,6 +269,196 @@ func (h *ItineraryHandler) GetItinerary(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(itin)
 }
 
+// GetItineraryPricing returns the server-side price breakdown of an itinerary
+// using the tenant's current markup rules. Nothing is persisted.
+func (h *ItineraryHandler) GetItineraryPricing(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	id64, err := strconv.Atoi(chi.URLParam(r, "itineraryID"))
+	if err != nil {
+		http.Error(w, "Invalid itinerary ID", http.StatusBadRequest)
+		return
+	}
+
+	var itin models.Itinerary
+	if err := h.DB.Preload("Items").
+		Where("id = ? AND tenant_id = ?", id64, claims.TenantID).
+		First(&itin).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Itinerary not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	breakdown, err := pricing.PriceItinerary(h.DB, &itin, itin.Items)
+	if err != nil {
+		if isFXError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to price itinerary", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(breakdown)
+}
+
+// CloneItinerary handles POST /itineraries/{itineraryID}/clone. The body is
+// optional: name, customerId and startDate override the source values, and the
+// trip keeps its length when moved to a new start date.
+func (h *ItineraryHandler) CloneItinerary(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+	src := h.tenantItinerary(w, r, claims)
+	if src == nil {
+		return
+	}
+
+	var payload struct {
+		Name       string `json:"name"`
+		CustomerID *uint  `json:"customerId"`
+		StartDate  string `json:"startDate"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+	}
+
+	clone := models.Itinerary{
+		TenantID:    claims.TenantID,
+		CustomerID:  src.CustomerID,
+		Name:        src.Name + " (copy)",
+		StartDate:   src.StartDate,
+		EndDate:     src.EndDate,
+		Status:      "Planned",
+		Destination: src.Destination,
+		Currency:    src.Currency,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if payload.Name != "" {
+		clone.Name = payload.Name
+	}
+	if payload.CustomerID != nil {
+		clone.CustomerID = *payload.CustomerID
+	}
+	if payload.StartDate != "" {
+		startDate, err := time.Parse("2006-01-02", payload.StartDate)
+		if err != nil {
+			http.Error(w, "Invalid start date format", http.StatusBadRequest)
+			return
+		}
+		clone.StartDate = startDate
+		clone.EndDate = startDate.Add(src.EndDate.Sub(src.StartDate))
+	}
+
+	// Item days are relative to the start date, so they carry over unchanged.
+	// Costs taken from a contract are looked up again for the new dates.
+	items := make([]models.ItineraryItem, 0, len(src.Items))
+	for _, item := range src.Items {
+		cost := item.Cost
+		if item.ContractCost != 0 && !pricing.CostDiffers(item.Cost, item.ContractCost) {
+			cost = 0
+		}
+		items = append(items, models.ItineraryItem{
+			Day:          item.Day,
+			Type:         item.Type,
+			Description:  item.Description,
+			VendorID:     item.VendorID,
+			Product:      item.Product,
+			Occupancy:    item.Occupancy,
+			MealPlan:     item.MealPlan,
+			Cost:         cost,
+			CostCurrency: item.CostCurrency,
+			Price:        item.Price,
+			Status:       "Pending",
+		})
+	}
+
+	if err := createItinerary(h.DB, &clone, items, claims.UserID); err != nil {
+		if isFXError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to clone itinerary", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.DB.Preload("Items").First(&clone, clone.ID).Error; err != nil {
+		http.Error(w, "Failed to load cloned itinerary", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(clone)
+}
+
+// DownloadItineraryPDF handles GET /itineraries/{itineraryID}/pdf and renders
+// the branded day-by-day travel document.
+func (h *ItineraryHandler) DownloadItineraryPDF(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+	itin := h.tenantItinerary(w, r, claims)
+	if itin == nil {
+		return
+	}
+
+	var tenant models.Tenant
+	if err := h.DB.First(&tenant, claims.TenantID).Error; err != nil && err != gorm.ErrRecordNotFound {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var bookings []models.Booking
+	if err := h.DB.
+		Where("itinerary_id = ? AND tenant_id = ?", itin.ID, claims.TenantID).
+		Order("travel_date").
+		Find(&bookings).Error; err != nil {
+		http.Error(w, "Failed to fetch bookings", http.StatusInternalServerError)
+		return
+	}
+
+	var vendorIDs []uint
+	for _, item := range itin.Items {
+		vendorIDs = append(vendorIDs, item.VendorID)
+	}
+	for _, b := range bookings {
+		vendorIDs = append(vendorIDs, b.VendorID)
+	}
+	vendors, err := loadVendors(h.DB, claims.TenantID, vendorIDs)
+	if err != nil {
+		http.Error(w, "Failed to fetch vendors", http.StatusInternalServerError)
+		return
+	}
+
+	pdfBytes, err := utils.GenerateItineraryPDF(tenant, *itin, bookings, vendors)
+	if err != nil {
+		http.Error(w, "Failed to generate PDF", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("itinerary_%d.pdf", itin.ID)
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Write(pdfBytes)
+}
+
 // DeleteItinerary removes an itinerary and all its items in one transaction.
 func (h *ItineraryHandler) DeleteItinerary(w http.ResponseWriter, r *http.Request) {
 	id64, err := strconv.Atoi(chi.URLParam(r, "itineraryID"))
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloneItineraryWithoutRate(t *testing.T) {
+	db := testutil.DB(t)
+	require.NoError(t, db.Create(&models.Tenant{ID: 1, Name: "Agency", BaseCurrency: "USD"}).Error)
+	itin := models.Itinerary{
+		TenantID: 1, Name: "Lisbon", Currency: "EUR",
+		StartDate: time.Now().AddDate(0, 1, 0), EndDate: time.Now().AddDate(0, 1, 3),
+		Items: []models.ItineraryItem{{Day: 1, Type: "Hotel", Cost: money.FromInt(300), CostCurrency: "GBP"}},
+	}
+	require.NoError(t, db.Create(&itin).Error)
+
+	// No GBP to EUR rate is on file, which the client can fix.
+	rr := serveAs(1, NewItineraryHandler(db).CloneItinerary, "POST", "/itineraries/{itineraryID}/clone",
+		fmt.Sprintf("/itineraries/%d/clone", itin.ID), "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+}
+
+func TestCloneItinerary(t *testing.T) {
+	db := testutil.DB(t)
+	require.NoError(t, db.Create(&models.Tenant{ID: 1, Name: "Agency", BaseCurrency: "INR"}).Error)
+	src := models.Itinerary{TenantID: 1, CustomerID: 4, Name: "Rajasthan", Destination: "Jaipur", Status: "Confirmed", Currency: "INR",
+		StartDate: isoDay("2026-03-10"), EndDate: isoDay("2026-03-14")}
+	items := []models.ItineraryItem{
+		{Day: 1, Type: "Hotel", Description: "Jaipur", Cost: money.FromInt(100), Price: money.FromInt(130), Status: "Booked"},
+		{Day: 4, Type: "Transfer", Description: "Airport", Cost: money.FromInt(20), Price: money.FromInt(25), Status: "Booked"},
+	}
+	require.NoError(t, createItinerary(db, &src, items, 1))
+
+	h := NewItineraryHandler(db)
+	clone := func(body string) (int, models.Itinerary) {
+		rr := serveAs(1, h.CloneItinerary, "POST", "/itineraries/{itineraryID}/clone",
+			fmt.Sprintf("/itineraries/%d/clone", src.ID), body)
+		var itin models.Itinerary
+		if rr.Code == http.StatusCreated {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &itin))
+		}
+		return rr.Code, itin
+	}
+
+	code, copied := clone("")
+	require.Equal(t, http.StatusCreated, code)
+	assert.NotEqual(t, src.ID, copied.ID)
+	assert.Equal(t, "Rajasthan (copy)", copied.Name)
+	assert.Equal(t, uint(4), copied.CustomerID)
+	assert.Equal(t, "Planned", copied.Status)
+	assert.True(t, copied.StartDate.Equal(src.StartDate))
+	require.Len(t, copied.Items, 2)
+	for _, item := range copied.Items {
+		assert.Equal(t, "Pending", item.Status, "bookings are not copied")
+	}
+
+	// A new start date moves the trip and keeps its length; item days are
+	// relative to the start, so they stay.
+	code, moved := clone(`{"name": "Rajasthan again", "customerId": 5, "startDate": "2026-11-02"}`)
+	require.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "Rajasthan again", moved.Name)
+	assert.Equal(t, uint(5), moved.CustomerID)
+	assert.True(t, moved.StartDate.Equal(isoDay("2026-11-02")))
+	assert.True(t, moved.EndDate.Equal(isoDay("2026-11-06")))
+	days := []int{}
+	for _, item := range moved.Items {
+		days = append(days, item.Day)
+	}
+	assert.ElementsMatch(t, []int{1, 4}, days)
+
+	// The source is untouched.
+	var reloaded models.Itinerary
+	require.NoError(t, db.Preload("Items").First(&reloaded, src.ID).Error)
+	assert.Equal(t, "Confirmed", reloaded.Status)
+	assert.Len(t, reloaded.Items, 2)
+
+	code, _ = clone(`{"startDate": "next week"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,89 @@
+// internal/handlers/itinerary_invoices.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/invoicing"
+	"travel-agency/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// InvoiceItinerary handles POST /itineraries/{itineraryID}/invoice. It bills
+// the itinerary's priced items as sale invoices, one per installment of the
+// schedule (default: the tenant's deposit/balance split). The body is
+// optional: {"schedule": [{"label", "percent", "daysBeforeStart" | "dueInDays"}],
+// "issueDate": "YYYY-MM-DD", "draft": false, "placeOfSupply", "customerType"};
+// the last two pick the tenant's tax rules.
+func (h *ItineraryHandler) InvoiceItinerary(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	id64, err := strconv.Atoi(chi.URLParam(r, "itineraryID"))
+	if err != nil {
+		http.Error(w, "Invalid itinerary ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Schedule  []invoicing.Installment `json:"schedule"`
+		IssueDate string                  `json:"issueDate"`
+		Draft     bool                    `json:"draft"`
+		Place     string                  `json:"placeOfSupply"`
+		Customer  string                  `json:"customerType"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+	}
+	opts := invoicing.Options{Schedule: input.Schedule, Draft: input.Draft, ActorID: claims.UserID,
+		PlaceOfSupply: input.Place, CustomerType: input.Customer}
+	if input.IssueDate != "" {
+		t, err := time.Parse("2006-01-02", input.IssueDate)
+		if err != nil {
+			http.Error(w, "issueDate must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		opts.IssueDate = t
+	}
+
+	var invoices []models.Invoice
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var itin models.Itinerary
+		if err := tx.Preload("Items").
+			Where("id = ? AND tenant_id = ?", id64, claims.TenantID).
+			First(&itin).Error; err != nil {
+			return err
+		}
+		invoices, err = invoicing.FromItinerary(tx, &itin, opts)
+		return err
+	}); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			http.Error(w, "Itinerary not found", http.StatusNotFound)
+		case errors.Is(err, invoicing.ErrAlreadyInvoiced):
+			http.Error(w, "Itinerary is already invoiced", http.StatusConflict)
+		case errors.Is(err, invoicing.ErrInvalidSchedule), errors.Is(err, invoicing.ErrNothingToBill):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to generate invoices", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invoices)
+}
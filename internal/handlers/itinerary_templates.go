@@ -0,0 +1,308 @@
+// internal/handlers/itinerary_templates.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type ItineraryTemplateHandler struct {
+	DB *gorm.DB
+}
+
+func NewItineraryTemplateHandler(db *gorm.DB) *ItineraryTemplateHandler {
+	return &ItineraryTemplateHandler{DB: db}
+}
+
+// validTemplate checks that every item falls within the template's duration.
+func validTemplate(tmpl models.ItineraryTemplate) string {
+	if tmpl.Name == "" {
+		return "name is required"
+	}
+	if tmpl.DurationDays < 1 {
+		return "durationDays must be at least 1"
+	}
+	for _, item := range tmpl.Items {
+		if item.DayOffset < 0 || item.DayOffset >= tmpl.DurationDays {
+			return "item dayOffset must be between 0 and durationDays-1"
+		}
+	}
+	return ""
+}
+
+// findTemplate loads a template (with items) by URL param, scoped to the tenant.
+func (h *ItineraryTemplateHandler) findTemplate(r *http.Request, tenantID uint) (*models.ItineraryTemplate, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, "templateID"))
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var tmpl models.ItineraryTemplate
+	if err := h.DB.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("day_offset, id")
+	}).Where("id = ? AND tenant_id = ?", id, tenantID).First(&tmpl).Error; err != nil {
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+// CreateTemplate handles POST /itinerary-templates
+func (h *ItineraryTemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var tmpl models.ItineraryTemplate
+	if err := json.NewDecoder(r.Body).Decode(&tmpl); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if msg := validTemplate(tmpl); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	tmpl.ID = 0
+	tmpl.TenantID = claims.TenantID
+	tmpl.CreatedAt = time.Now()
+	tmpl.UpdatedAt = time.Now()
+	for i := range tmpl.Items {
+		tmpl.Items[i].ID = 0
+	}
+
+	// Items are created together with the template.
+	if err := h.DB.Create(&tmpl).Error; err != nil {
+		http.Error(w, "Failed to create template", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tmpl)
+}
+
+// ListTemplates handles GET /itinerary-templates
+func (h *ItineraryTemplateHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var list []models.ItineraryTemplate
+	if err := h.DB.
+		Where("tenant_id = ?", claims.TenantID).
+		Preload("Items").
+		Find(&list).Error; err != nil {
+		http.Error(w, "Failed to fetch templates", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetTemplate handles GET /itinerary-templates/{templateID}
+func (h *ItineraryTemplateHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	tmpl, err := h.findTemplate(r, claims.TenantID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Template not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tmpl)
+}
+
+// UpdateTemplate handles PUT /itinerary-templates/{templateID}; items are replaced.
+func (h *ItineraryTemplateHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	tmpl, err := h.findTemplate(r, claims.TenantID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Template not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var updated models.ItineraryTemplate
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if msg := validTemplate(updated); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		tmpl.Name = updated.Name
+		tmpl.Description = updated.Description
+		tmpl.Destination = updated.Destination
+		tmpl.DurationDays = updated.DurationDays
+		tmpl.UpdatedAt = time.Now()
+		tmpl.Items = nil
+		if err := tx.Save(tmpl).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("template_id = ?", tmpl.ID).Delete(&models.ItineraryTemplateItem{}).Error; err != nil {
+			return err
+		}
+		for _, item := range updated.Items {
+			item.ID = 0
+			item.TemplateID = tmpl.ID
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		http.Error(w, "Failed to update template", http.StatusInternalServerError)
+		return
+	}
+
+	if tmpl, err = h.findTemplate(r, claims.TenantID); err != nil {
+		http.Error(w, "Failed to load updated template", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tmpl)
+}
+
+// DeleteTemplate handles DELETE /itinerary-templates/{templateID}
+func (h *ItineraryTemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	tmpl, err := h.findTemplate(r, claims.TenantID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Template not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("template_id = ?", tmpl.ID).Delete(&models.ItineraryTemplateItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.ItineraryTemplate{}, tmpl.ID).Error
+	}); err != nil {
+		http.Error(w, "Failed to delete template", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// InstantiateTemplate handles POST /itinerary-templates/{templateID}/instantiate.
+// It creates a new itinerary for a customer starting on the given date; each
+// item's day offset becomes a day number relative to that start date.
+func (h *ItineraryTemplateHandler) InstantiateTemplate(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	tmpl, err := h.findTemplate(r, claims.TenantID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Template not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	var payload struct {
+		Name       string `json:"name"`
+		CustomerID uint   `json:"customerId"`
+		StartDate  string `json:"startDate"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	startDate, err := time.Parse("2006-01-02", payload.StartDate)
+	if err != nil {
+		http.Error(w, "Invalid start date format", http.StatusBadRequest)
+		return
+	}
+	if payload.Name == "" {
+		payload.Name = tmpl.Name
+	}
+
+	itin := models.Itinerary{
+		TenantID:    claims.TenantID,
+		CustomerID:  payload.CustomerID,
+		Name:        payload.Name,
+		StartDate:   startDate,
+		EndDate:     startDate.AddDate(0, 0, tmpl.DurationDays-1),
+		Status:      "Planned",
+		Destination: tmpl.Destination,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	items := make([]models.ItineraryItem, 0, len(tmpl.Items))
+	for _, t := range tmpl.Items {
+		items = append(items, models.ItineraryItem{
+			Day:         t.DayOffset + 1,
+			Type:        t.Type,
+			Description: t.Description,
+			VendorID:    t.VendorID,
+			Product:     t.Product,
+			Occupancy:   t.Occupancy,
+			MealPlan:    t.MealPlan,
+			Cost:        t.Cost,
+			Price:       t.Price,
+			Status:      "Pending",
+		})
+	}
+
+	if err := createItinerary(h.DB, &itin, items, claims.UserID); err != nil {
+		http.Error(w, "Failed to create itinerary from template", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.DB.Preload("Items").First(&itin, itin.ID).Error; err != nil {
+		http.Error(w, "Failed to load created itinerary", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(itin)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func isoDay(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+func TestInstantiateTemplate(t *testing.T) {
+	db := testutil.DB(t)
+	require.NoError(t, db.Create(&models.Tenant{ID: 1, Name: "Agency", BaseCurrency: "INR"}).Error)
+	tmpl := models.ItineraryTemplate{TenantID: 1, Name: "Kerala backwaters", Destination: "Kerala", DurationDays: 4,
+		Items: []models.ItineraryTemplateItem{
+			{DayOffset: 0, Type: "Hotel", Description: "Kochi", Cost: money.FromInt(100), Price: money.FromInt(130)},
+			{DayOffset: 2, Type: "Houseboat", Description: "Alleppey", Cost: money.FromInt(200), Price: money.FromInt(260)},
+		}}
+	require.NoError(t, db.Create(&tmpl).Error)
+
+	h := NewItineraryTemplateHandler(db)
+	instantiate := func(body string) (int, models.Itinerary) {
+		rr := serveAs(1, h.InstantiateTemplate, "POST", "/itinerary-templates/{templateID}/instantiate",
+			fmt.Sprintf("/itinerary-templates/%d/instantiate", tmpl.ID), body)
+		var itin models.Itinerary
+		if rr.Code == http.StatusCreated {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &itin))
+		}
+		return rr.Code, itin
+	}
+
+	code, itin := instantiate(`{"customerId": 9, "startDate": "2026-07-01"}`)
+	require.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "Kerala backwaters", itin.Name)
+	assert.Equal(t, uint(9), itin.CustomerID)
+	assert.Equal(t, "Kerala", itin.Destination)
+	assert.Equal(t, "Planned", itin.Status)
+	assert.True(t, itin.StartDate.Equal(isoDay("2026-07-01")))
+	assert.True(t, itin.EndDate.Equal(isoDay("2026-07-04")), "a 4-day trip ends on its 4th day")
+	require.Len(t, itin.Items, 2)
+	days := map[string]int{}
+	for _, item := range itin.Items {
+		days[item.Description] = item.Day
+		assert.Equal(t, "Pending", item.Status)
+	}
+	assert.Equal(t, map[string]int{"Kochi": 1, "Alleppey": 3}, days)
+
+	var versions int64
+	db.Model(&models.ItineraryVersion{}).Where("itinerary_id = ?", itin.ID).Count(&versions)
+	assert.Equal(t, int64(1), versions, "the new itinerary starts its version history")
+
+	code, itin = instantiate(`{"name": "Honeymoon", "startDate": "2026-08-01"}`)
+	require.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "Honeymoon", itin.Name)
+
+	code, _ = instantiate(`{"startDate": "01/07/2026"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,444 @@
+// internal/handlers/itinerary_versions.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// snapshotItinerary stores an immutable copy of the itinerary and its saved
+// items as the next version, and records it as the current version.
+func snapshotItinerary(tx *gorm.DB, itin *models.Itinerary, userID uint, restoredFrom int) (*models.ItineraryVersion, error) {
+	var items []models.ItineraryItem
+	if err := tx.Where("itinerary_id = ?", itin.ID).Order("day, id").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	var last int
+	if err := tx.Model(&models.ItineraryVersion{}).
+		Where("itinerary_id = ?", itin.ID).
+		Select("COALESCE(MAX(version), 0)").Row().Scan(&last); err != nil {
+		return nil, err
+	}
+
+	version := models.ItineraryVersion{
+		TenantID:     itin.TenantID,
+		ItineraryID:  itin.ID,
+		Version:      last + 1,
+		Name:         itin.Name,
+		StartDate:    itin.StartDate,
+		EndDate:      itin.EndDate,
+		Status:       itin.Status,
+		Destination:  itin.Destination,
+		Currency:     itin.Currency,
+		TotalCost:    itin.TotalCost,
+		Subtotal:     itin.Subtotal,
+		Margin:       itin.Margin,
+		TaxAmount:    itin.TaxAmount,
+		TotalPrice:   itin.TotalPrice,
+		RestoredFrom: restoredFrom,
+		CreatedBy:    userID,
+		CreatedAt:    time.Now(),
+	}
+	for _, item := range items {
+		version.Items = append(version.Items, models.ItineraryVersionItem{
+			Day:          item.Day,
+			Type:         item.Type,
+			Description:  item.Description,
+			VendorID:     item.VendorID,
+			Product:      item.Product,
+			Occupancy:    item.Occupancy,
+			MealPlan:     item.MealPlan,
+			Cost:         item.Cost,
+			ContractCost: item.ContractCost,
+			CostCurrency: item.CostCurrency,
+			CostRate:     item.CostRate,
+			Price:        item.Price,
+			Status:       item.Status,
+		})
+	}
+	if err := tx.Create(&version).Error; err != nil {
+		return nil, err
+	}
+
+	itin.CurrentVersion = version.Version
+	if err := tx.Model(itin).Update("current_version", version.Version).Error; err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// tenantItinerary loads an itinerary by URL param, scoped to the caller's tenant.
+// It writes the error response itself and returns nil on failure.
+func (h *ItineraryHandler) tenantItinerary(w http.ResponseWriter, r *http.Request, claims *auth.Claims) *models.Itinerary {
+	id64, err := strconv.Atoi(chi.URLParam(r, "itineraryID"))
+	if err != nil {
+		http.Error(w, "Invalid itinerary ID", http.StatusBadRequest)
+		return nil
+	}
+
+	var itin models.Itinerary
+	if err := h.DB.Preload("Items").
+		Where("id = ? AND tenant_id = ?", id64, claims.TenantID).
+		First(&itin).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Itinerary not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return nil
+	}
+	return &itin
+}
+
+// findVersion loads a single version (with items) of the given itinerary.
+func (h *ItineraryHandler) findVersion(itineraryID uint, number int) (*models.ItineraryVersion, error) {
+	var version models.ItineraryVersion
+	err := h.DB.Preload("Items").
+		Where("itinerary_id = ? AND version = ?", itineraryID, number).
+		First(&version).Error
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// ListVersions handles GET /itineraries/{itineraryID}/versions
+func (h *ItineraryHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+	itin := h.tenantItinerary(w, r, claims)
+	if itin == nil {
+		return
+	}
+
+	var versions []models.ItineraryVersion
+	if err := h.DB.Preload("Items").
+		Where("itinerary_id = ?", itin.ID).
+		Order("version DESC").
+		Find(&versions).Error; err != nil {
+		http.Error(w, "Failed to fetch versions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+// GetVersion handles GET /itineraries/{itineraryID}/versions/{version}
+func (h *ItineraryHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+	itin := h.tenantItinerary(w, r, claims)
+	if itin == nil {
+		return
+	}
+
+	number, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+	version, err := h.findVersion(itin.ID, number)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Version not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(version)
+}
+
+// FieldChange is a changed itinerary-level field between two versions.
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// ItemChange is an item present in both versions whose values differ.
+type ItemChange struct {
+	From models.ItineraryVersionItem `json:"from"`
+	To   models.ItineraryVersionItem `json:"to"`
+}
+
+// VersionDiff describes what changed between two itinerary versions.
+type VersionDiff struct {
+	From    int                           `json:"from"`
+	To      int                           `json:"to"`
+	Fields  map[string]FieldChange        `json:"fields"`
+	Added   []models.ItineraryVersionItem `json:"added"`
+	Removed []models.ItineraryVersionItem `json:"removed"`
+	Changed []ItemChange                  `json:"changed"`
+}
+
+// itemKey identifies "the same" item across versions. Item rows are re-created
+// on every save, so IDs cannot be used.
+func itemKey(item models.ItineraryVersionItem) string {
+	return fmt.Sprintf("%d|%s|%d|%s", item.Day, item.Type, item.VendorID, item.Description)
+}
+
+// diffVersions compares two versions field by field and item by item.
+func diffVersions(from, to *models.ItineraryVersion) VersionDiff {
+	diff := VersionDiff{
+		From:    from.Version,
+		To:      to.Version,
+		Fields:  map[string]FieldChange{},
+		Added:   []models.ItineraryVersionItem{},
+		Removed: []models.ItineraryVersionItem{},
+		Changed: []ItemChange{},
+	}
+
+	compare := func(name string, a, b interface{}) {
+		if a != b {
+			diff.Fields[name] = FieldChange{From: a, To: b}
+		}
+	}
+	compare("name", from.Name, to.Name)
+	compare("startDate", from.StartDate.Format("2006-01-02"), to.StartDate.Format("2006-01-02"))
+	compare("endDate", from.EndDate.Format("2006-01-02"), to.EndDate.Format("2006-01-02"))
+	compare("status", from.Status, to.Status)
+	compare("destination", from.Destination, to.Destination)
+	compare("currency", from.Currency, to.Currency)
+	compare("totalCost", from.TotalCost, to.TotalCost)
+	compare("subtotal", from.Subtotal, to.Subtotal)
+	compare("margin", from.Margin, to.Margin)
+	compare("taxAmount", from.TaxAmount, to.TaxAmount)
+	compare("totalPrice", from.TotalPrice, to.TotalPrice)
+
+	// Match items by key; duplicates are matched in order.
+	remaining := map[string][]models.ItineraryVersionItem{}
+	for _, item := range from.Items {
+		k := itemKey(item)
+		remaining[k] = append(remaining[k], item)
+	}
+	for _, item := range to.Items {
+		k := itemKey(item)
+		if prev := remaining[k]; len(prev) > 0 {
+			old := prev[0]
+			remaining[k] = prev[1:]
+			if old.Cost != item.Cost || old.Price != item.Price || old.Status != item.Status ||
+				old.Product != item.Product || old.Occupancy != item.Occupancy || old.MealPlan != item.MealPlan {
+				diff.Changed = append(diff.Changed, ItemChange{From: old, To: item})
+			}
+			continue
+		}
+		diff.Added = append(diff.Added, item)
+	}
+	for _, item := range from.Items {
+		k := itemKey(item)
+		if prev := remaining[k]; len(prev) > 0 && prev[0].ID == item.ID {
+			diff.Removed = append(diff.Removed, item)
+			remaining[k] = prev[1:]
+		}
+	}
+	return diff
+}
+
+// DiffVersions handles GET /itineraries/{itineraryID}/versions/diff?from=1&to=2
+func (h *ItineraryHandler) DiffVersions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+	itin := h.tenantItinerary(w, r, claims)
+	if itin == nil {
+		return
+	}
+
+	fromNum, err1 := strconv.Atoi(r.URL.Query().Get("from"))
+	toNum, err2 := strconv.Atoi(r.URL.Query().Get("to"))
+	if err1 != nil || err2 != nil {
+		http.Error(w, "from and to must be version numbers", http.StatusBadRequest)
+		return
+	}
+
+	from, err := h.findVersion(itin.ID, fromNum)
+	if err == nil {
+		var to *models.ItineraryVersion
+		if to, err = h.findVersion(itin.ID, toNum); err == nil {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(diffVersions(from, to))
+			return
+		}
+	}
+	if err == gorm.ErrRecordNotFound {
+		http.Error(w, "Version not found", http.StatusNotFound)
+	} else {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+	}
+}
+
+// RestoreVersion handles POST /itineraries/{itineraryID}/versions/{version}/restore.
+// The itinerary and its items are reset to the stored version (prices included)
+// and a new version is recorded, so history is never rewritten.
+func (h *ItineraryHandler) RestoreVersion(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+	itin := h.tenantItinerary(w, r, claims)
+	if itin == nil {
+		return
+	}
+
+	number, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+	version, err := h.findVersion(itin.ID, number)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Version not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		itin.Name = version.Name
+		itin.StartDate = version.StartDate
+		itin.EndDate = version.EndDate
+		itin.Status = version.Status
+		itin.Destination = version.Destination
+		itin.Currency = version.Currency
+		itin.TotalCost = version.TotalCost
+		itin.Subtotal = version.Subtotal
+		itin.Margin = version.Margin
+		itin.TaxAmount = version.TaxAmount
+		itin.TotalPrice = version.TotalPrice
+		itin.UpdatedAt = time.Now()
+		itin.Items = nil
+		if err := tx.Save(itin).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("itinerary_id = ?", itin.ID).Delete(&models.ItineraryItem{}).Error; err != nil {
+			return err
+		}
+		for _, v := range version.Items {
+			item := models.ItineraryItem{
+				ItineraryID:  itin.ID,
+				Day:          v.Day,
+				Type:         v.Type,
+				Description:  v.Description,
+				VendorID:     v.VendorID,
+				Product:      v.Product,
+				Occupancy:    v.Occupancy,
+				MealPlan:     v.MealPlan,
+				Cost:         v.Cost,
+				ContractCost: v.ContractCost,
+				CostCurrency: v.CostCurrency,
+				CostRate:     v.CostRate,
+				Price:        v.Price,
+				Status:       v.Status,
+				CreatedAt:    time.Now(),
+				UpdatedAt:    time.Now(),
+			}
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+		}
+
+		_, err := snapshotItinerary(tx, itin, claims.UserID, version.Version)
+		return err
+	}); err != nil {
+		http.Error(w, "Failed to restore version", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.DB.Preload("Items").First(itin, itin.ID).Error; err != nil {
+		http.Error(w, "Failed to load restored itinerary", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(itin)
+}
+
+// MarkVersionSent handles POST /itineraries/{itineraryID}/versions/{version}/sent
+func (h *ItineraryHandler) MarkVersionSent(w http.ResponseWriter, r *http.Request) {
+	h.markVersion(w, r, "sent")
+}
+
+// MarkVersionAccepted handles POST /itineraries/{itineraryID}/versions/{version}/accepted
+func (h *ItineraryHandler) MarkVersionAccepted(w http.ResponseWriter, r *http.Request) {
+	h.markVersion(w, r, "accepted")
+}
+
+// markVersion stamps a version as sent to or accepted by the customer and
+// records it on the itinerary.
+func (h *ItineraryHandler) markVersion(w http.ResponseWriter, r *http.Request, state string) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+	itin := h.tenantItinerary(w, r, claims)
+	if itin == nil {
+		return
+	}
+
+	number, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+	version, err := h.findVersion(itin.ID, number)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Version not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	now := time.Now()
+	versionColumn, itinColumn := "sent_at", "sent_version"
+	if state == "accepted" {
+		versionColumn, itinColumn = "accepted_at", "accepted_version"
+		version.AcceptedAt = &now
+	} else {
+		version.SentAt = &now
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ItineraryVersion{}).
+			Where("id = ?", version.ID).
+			Update(versionColumn, now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Itinerary{}).
+			Where("id = ?", itin.ID).
+			Updates(map[string]interface{}{itinColumn: version.Version, "updated_at": now}).Error
+	}); err != nil {
+		http.Error(w, "Failed to mark version as "+state, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(version)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffVersions(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	hotel := models.ItineraryVersionItem{ID: 1, Day: 1, Type: "Hotel", VendorID: 3, Description: "Sea view", Cost: money.FromInt(100), Price: money.FromInt(120)}
+	transfer := models.ItineraryVersionItem{ID: 2, Day: 1, Type: "Transfer", VendorID: 4, Description: "Airport", Cost: money.FromInt(20), Price: money.FromInt(25)}
+	tour := models.ItineraryVersionItem{ID: 3, Day: 2, Type: "Activity", VendorID: 5, Description: "City tour", Cost: money.FromInt(40), Price: money.FromInt(50)}
+	from := &models.ItineraryVersion{Version: 1, Name: "Goa", StartDate: start, EndDate: start.AddDate(0, 0, 3), Status: "Planned",
+		Currency: "INR", Margin: money.FromInt(35), TotalPrice: money.FromInt(195), Items: []models.ItineraryVersionItem{hotel, transfer, tour}}
+
+	// Version 2 reprices the hotel, books the tour for more guests, drops the
+	// transfer, adds a second city tour and moves the trip a week later.
+	// Items are re-created on save.
+	hotel2, tour2, extra := hotel, tour, tour
+	hotel2.ID, hotel2.Price = 11, money.FromInt(130)
+	tour2.ID, tour2.Occupancy = 13, 4
+	extra.ID, extra.Day = 14, 3
+	to := &models.ItineraryVersion{Version: 2, Name: "Goa", StartDate: start.AddDate(0, 0, 7), EndDate: start.AddDate(0, 0, 10), Status: "Planned",
+		Currency: "INR", Margin: money.FromInt(60), TotalPrice: money.FromInt(230), Items: []models.ItineraryVersionItem{hotel2, tour2, extra}}
+
+	diff := diffVersions(from, to)
+	assert.Equal(t, 1, diff.From)
+	assert.Equal(t, 2, diff.To)
+	assert.Equal(t, map[string]FieldChange{
+		"startDate":  {From: "2026-06-01", To: "2026-06-08"},
+		"endDate":    {From: "2026-06-04", To: "2026-06-11"},
+		"margin":     {From: money.FromInt(35), To: money.FromInt(60)},
+		"totalPrice": {From: money.FromInt(195), To: money.FromInt(230)},
+	}, diff.Fields)
+	assert.Equal(t, []ItemChange{{From: hotel, To: hotel2}, {From: tour, To: tour2}}, diff.Changed)
+	assert.Equal(t, []models.ItineraryVersionItem{transfer}, diff.Removed)
+	assert.Equal(t, []models.ItineraryVersionItem{extra}, diff.Added)
+
+	same := diffVersions(from, from)
+	assert.Empty(t, same.Fields)
+	assert.Empty(t, same.Added)
+	assert.Empty(t, same.Removed)
+	assert.Empty(t, same.Changed)
+}
+
+func TestDiffVersionsDuplicateItems(t *testing.T) {
+	night := models.ItineraryVersionItem{Day: 1, Type: "Hotel", Description: "Night", Price: money.FromInt(100)}
+	a, b, c := night, night, night
+	a.ID, b.ID, c.ID = 1, 2, 3
+	from := &models.ItineraryVersion{Version: 1, Items: []models.ItineraryVersionItem{a, b, c}}
+	d := night
+	d.ID = 4
+	to := &models.ItineraryVersion{Version: 2, Items: []models.ItineraryVersionItem{d}}
+
+	// Identical items are matched in order, so two of the three went.
+	diff := diffVersions(from, to)
+	assert.Empty(t, diff.Changed)
+	assert.Empty(t, diff.Added)
+	assert.Equal(t, []models.ItineraryVersionItem{b, c}, diff.Removed)
+}
+
+func TestDiffVersionsHandler(t *testing.T) {
+	db := testutil.DB(t)
+	itin := models.Itinerary{TenantID: 1, Name: "Goa", Currency: "INR", Status: "Planned",
+		StartDate: time.Now().AddDate(0, 1, 0), EndDate: time.Now().AddDate(0, 1, 3)}
+	items := []models.ItineraryItem{{Day: 1, Type: "Hotel", Description: "Sea view", Cost: money.FromInt(100), CostCurrency: "INR"}}
+	require.NoError(t, createItinerary(db, &itin, items, 1))
+	require.NoError(t, db.Model(&itin).Update("name", "Goa and Hampi").Error)
+	_, err := snapshotItinerary(db, &itin, 1, 0)
+	require.NoError(t, err)
+
+	h := NewItineraryHandler(db)
+	diff := func(query string) (int, VersionDiff) {
+		rr := serveAs(1, h.DiffVersions, "GET", "/itineraries/{itineraryID}/versions/diff",
+			fmt.Sprintf("/itineraries/%d/versions/diff?%s", itin.ID, query), "")
+		var d VersionDiff
+		if rr.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &d))
+		}
+		return rr.Code, d
+	}
+
+	code, d := diff("from=1&to=2")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, FieldChange{From: "Goa", To: "Goa and Hampi"}, d.Fields["name"])
+	assert.Len(t, d.Fields, 1)
+
+	code, _ = diff("from=1&to=3")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = diff("from=1")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
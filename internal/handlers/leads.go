@@ -69,6 +69,12 @@ func (h *LeadsHandler) ListLeads(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *LeadsHandler) GetLead(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
 	leadID, err := strconv.Atoi(chi.URLParam(r, "leadID"))
 	if err != nil {
 		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
@@ -76,7 +82,7 @@ func (h *LeadsHandler) GetLead(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var lead models.Lead
-	if err := h.DB.First(&lead, leadID).Error; err != nil {
+	if err := h.DB.Where("id = ? AND tenant_id = ?", leadID, claims.TenantID).First(&lead).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			http.Error(w, "Lead not found", http.StatusNotFound)
 			return
@@ -85,12 +91,17 @@ func (h *LeadsHandler) GetLead(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Optionally: Verify that lead.TenantID matches the authenticated TenantID.
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(lead)
 }
 
 func (h *LeadsHandler) UpdateLead(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
 	leadID, err := strconv.Atoi(chi.URLParam(r, "leadID"))
 	if err != nil {
 		http.Error(w, "Invalid lead ID", http.StatusBadRequest)
@@ -98,7 +109,7 @@ func (h *LeadsHandler) UpdateLead(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var lead models.Lead
-	if err := h.DB.First(&lead, leadID).Error; err != nil {
+	if err := h.DB.Where("id = ? AND tenant_id = ?", leadID, claims.TenantID).First(&lead).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			http.Error(w, "Lead not found", http.StatusNotFound)
 			return
@@ -0,0 +1,361 @@
+// handlers/ledger.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/ledger"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/utils"
+)
+
+var errDuplicateAccount = errors.New("an account with this code already exists")
+
+type LedgerHandler struct {
+	DB *gorm.DB
+}
+
+func NewLedgerHandler(db *gorm.DB) *LedgerHandler {
+	return &LedgerHandler{DB: db}
+}
+
+// writeLedgerError maps ledger errors to status codes.
+func writeLedgerError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Journal entry not found", http.StatusNotFound)
+	case errors.Is(err, ledger.ErrInvalidEntry), errors.Is(err, ledger.ErrUnbalanced):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ledger.ErrCannotReverse), errors.Is(err, errDuplicateAccount):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// ListAccounts handles GET /api/ledger/accounts, the tenant's chart of
+// accounts.
+func (h *LedgerHandler) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	accounts, err := ledger.Accounts(h.DB, claims.TenantID)
+	if err != nil {
+		http.Error(w, "Unable to fetch accounts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(accounts)
+}
+
+// CreateAccount handles POST /api/ledger/accounts with {"code", "name",
+// "type"}.
+func (h *LedgerHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var account models.Account
+	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	account.Code = strings.TrimSpace(account.Code)
+	account.Name = strings.TrimSpace(account.Name)
+	account.Type = strings.ToLower(strings.TrimSpace(account.Type))
+	if account.Code == "" || account.Name == "" {
+		http.Error(w, "Code and name are required", http.StatusBadRequest)
+		return
+	}
+	if !ledger.ValidType(account.Type) {
+		http.Error(w, "Type must be asset, liability, equity, income or expense", http.StatusBadRequest)
+		return
+	}
+	account.ID = 0
+	account.TenantID = claims.TenantID
+	account.System = false
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ledger.EnsureChart(tx, claims.TenantID); err != nil {
+			return err
+		}
+		var taken int64
+		if err := tx.Model(&models.Account{}).Where("tenant_id = ? AND code = ?", claims.TenantID, account.Code).
+			Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return errDuplicateAccount
+		}
+		if err := tx.Create(&account).Error; err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "CREATE_ACCOUNT", "Account",
+			fmt.Sprintf("Account %s %s created", account.Code, account.Name))
+	}); err != nil {
+		writeLedgerError(w, err, "Failed to create account")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(account)
+}
+
+// ListEntries handles GET /api/ledger/entries?from=&to=&sourceType=&sourceId=,
+// newest first.
+func (h *LedgerHandler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	query := h.DB.Preload("Lines.Account").Where("tenant_id = ?", claims.TenantID)
+	if q.Get("from") != "" {
+		from, err := parseDay(r, "from")
+		if err != nil {
+			http.Error(w, "Invalid from date, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("date >= ?", from)
+	}
+	if q.Get("to") != "" {
+		to, err := parseDay(r, "to")
+		if err != nil {
+			http.Error(w, "Invalid to date, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("date < ?", to.AddDate(0, 0, 1))
+	}
+	if s := q.Get("sourceType"); s != "" {
+		query = query.Where("source_type = ?", s)
+	}
+	if s := q.Get("sourceId"); s != "" {
+		query = query.Where("source_id = ?", s)
+	}
+
+	var entries []models.JournalEntry
+	if err := query.Order("date DESC, id DESC").Find(&entries).Error; err != nil {
+		http.Error(w, "Unable to fetch journal entries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// CreateEntry handles POST /api/ledger/entries, a manual entry. Lines name
+// their account by accountId or accountCode.
+func (h *LedgerHandler) CreateEntry(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Date  time.Time `json:"date"`
+		Memo  string    `json:"memo"`
+		Lines []struct {
+			AccountID   uint         `json:"accountId"`
+			AccountCode string       `json:"accountCode"`
+			Debit       money.Amount `json:"debit"`
+			Credit      money.Amount `json:"credit"`
+		} `json:"lines"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(input.Memo) == "" {
+		http.Error(w, "A memo is required", http.StatusBadRequest)
+		return
+	}
+
+	entry := models.JournalEntry{
+		TenantID:    claims.TenantID,
+		Date:        input.Date,
+		Memo:        strings.TrimSpace(input.Memo),
+		SourceType:  ledger.Manual,
+		CreatedByID: claims.UserID,
+	}
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		accounts, err := ledger.Accounts(tx, claims.TenantID)
+		if err != nil {
+			return err
+		}
+		byCode := make(map[string]uint, len(accounts))
+		for _, a := range accounts {
+			byCode[a.Code] = a.ID
+		}
+		for i, l := range input.Lines {
+			id := l.AccountID
+			if id == 0 {
+				if id, ok = byCode[strings.TrimSpace(l.AccountCode)]; !ok {
+					return fmt.Errorf("%w: line %d has an unknown account", ledger.ErrInvalidEntry, i+1)
+				}
+			}
+			entry.Lines = append(entry.Lines, models.JournalLine{AccountID: id, Debit: l.Debit, Credit: l.Credit})
+		}
+		if err := ledger.Post(tx, &entry); err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "CREATE_JOURNAL_ENTRY", "JournalEntry",
+			fmt.Sprintf("Journal entry %d posted: %s", entry.ID, entry.Memo))
+	}); err != nil {
+		writeLedgerError(w, err, "Failed to post journal entry")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// ReverseEntry handles POST /api/ledger/entries/{entryID}/reverse with an
+// optional {"date"}. Posted entries are never edited; a manual entry made in
+// error is undone by posting its reversal.
+func (h *LedgerHandler) ReverseEntry(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	entryID, err := strconv.Atoi(chi.URLParam(r, "entryID"))
+	if err != nil {
+		http.Error(w, "Invalid entry ID", http.StatusBadRequest)
+		return
+	}
+	var input struct {
+		Date time.Time `json:"date"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var reversal *models.JournalEntry
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if reversal, err = ledger.Reverse(tx, claims.TenantID, uint(entryID), claims.UserID, input.Date); err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "REVERSE_JOURNAL_ENTRY", "JournalEntry",
+			fmt.Sprintf("Journal entry %d reversed by entry %d", entryID, reversal.ID))
+	}); err != nil {
+		writeLedgerError(w, err, "Failed to reverse journal entry")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reversal)
+}
+
+// TrialBalance handles GET /api/ledger/trial-balance?asOf=YYYY-MM-DD
+// (default today).
+func (h *LedgerHandler) TrialBalance(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	asOf, err := parseDay(r, "asOf")
+	if err != nil {
+		http.Error(w, "Invalid asOf date, use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	tb, err := ledger.BuildTrialBalance(h.DB, claims.TenantID, asOf)
+	if err != nil {
+		http.Error(w, "Unable to build trial balance", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tb)
+}
+
+// ProfitAndLoss handles GET /api/ledger/profit-and-loss?from=&to=. The
+// period defaults to the current month to date.
+func (h *LedgerHandler) ProfitAndLoss(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	to, err := parseDay(r, "to")
+	if err != nil {
+		http.Error(w, "Invalid to date, use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	from := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, to.Location())
+	if r.URL.Query().Get("from") != "" {
+		if from, err = parseDay(r, "from"); err != nil {
+			http.Error(w, "Invalid from date, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if to.Before(from) {
+		http.Error(w, "The period ends before it starts", http.StatusBadRequest)
+		return
+	}
+	pl, err := ledger.BuildProfitAndLoss(h.DB, claims.TenantID, from, to)
+	if err != nil {
+		http.Error(w, "Unable to build profit and loss", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pl)
+}
+
+// Backfill handles POST /api/ledger/backfill. It posts the tenant's
+// documents from before the ledger existed; running it again posts nothing
+// new.
+func (h *LedgerHandler) Backfill(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var n int
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if n, err = ledger.Backfill(tx, claims.TenantID); err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "BACKFILL_LEDGER", "JournalEntry",
+			fmt.Sprintf("Ledger backfilled from %d document(s)", n))
+	}); err != nil {
+		writeLedgerError(w, err, "Failed to backfill the ledger")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"documents": n})
+}
@@ -0,0 +1,97 @@
+//go:build dev
+
+// internal/handlers/mock_checkout.go
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"travel-agency/internal/gateways"
+	"travel-agency/internal/models"
+	"travel-agency/internal/receivables"
+)
+
+// The mock provider's hosted payment pages settle intents without any
+// authentication, so they are only compiled into dev builds (-tags dev).
+
+// MountMockCheckout serves the mock provider's payment pages under
+// /mock-pay/{intentID}.
+func (h *WebhookHandler) MountMockCheckout(r chi.Router) {
+	r.Get("/mock-pay/{intentID}", h.MockCheckout)
+	r.Post("/mock-pay/{intentID}", h.CompleteMockCheckout)
+}
+
+var mockCheckoutPage = template.Must(template.New("mock").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Mock payment</title></head>
+<body>
+<h1>Mock payment provider</h1>
+<p>Payment {{.Intent}}: {{.Status}}</p>
+{{if .Open}}<form method="post">
+<button type="submit" name="outcome" value="pay">Pay</button>
+<button type="submit" name="outcome" value="fail">Decline</button>
+</form>{{end}}
+</body></html>`))
+
+// mockProvider returns the registered mock provider, or writes 404.
+func (h *WebhookHandler) mockProvider(w http.ResponseWriter, r *http.Request) *gateways.Mock {
+	p, err := h.Gateways.For("mock")
+	mock, ok := p.(*gateways.Mock)
+	if err != nil || !ok {
+		http.NotFound(w, r)
+		return nil
+	}
+	return mock
+}
+
+// MockCheckout handles GET /mock-pay/{intentID}: the mock provider's hosted
+// payment page, for local development.
+func (h *WebhookHandler) MockCheckout(w http.ResponseWriter, r *http.Request) {
+	if h.mockProvider(w, r) == nil {
+		return
+	}
+	var payment models.Payment
+	if err := h.DB.Where("provider = ? AND provider_ref = ?", "mock", chi.URLParam(r, "intentID")).
+		First(&payment).Error; err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	mockCheckoutPage.Execute(w, map[string]interface{}{
+		"Intent": payment.ProviderRef,
+		"Status": payment.Status,
+		"Open":   payment.Status == receivables.PaymentPending,
+	})
+}
+
+// CompleteMockCheckout handles POST /mock-pay/{intentID}: the customer pays
+// or is declined, and the mock's webhook is delivered as if by the provider.
+func (h *WebhookHandler) CompleteMockCheckout(w http.ResponseWriter, r *http.Request) {
+	mock := h.mockProvider(w, r)
+	if mock == nil {
+		return
+	}
+	intentID := chi.URLParam(r, "intentID")
+	outcome := mock.Pay
+	if r.FormValue("outcome") == "fail" {
+		outcome = mock.Fail
+	}
+	body, sig, err := outcome(intentID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	event, err := mock.ParseWebhook(body, http.Header{gateways.MockSignatureHeader: {sig}})
+	if err != nil {
+		http.Error(w, "Invalid event", http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.apply("mock", event); err != nil {
+		log.Printf("payments: handling mock event %s failed: %v", event.ID, err)
+		http.Error(w, "Failed to process event", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+}
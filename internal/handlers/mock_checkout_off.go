@@ -0,0 +1,16 @@
+//go:build !dev
+
+// internal/handlers/mock_checkout_off.go
+package handlers
+
+import (
+	"log"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// MountMockCheckout does nothing outside dev builds: the mock provider's
+// payment pages settle intents without authentication.
+func (h *WebhookHandler) MountMockCheckout(r chi.Router) {
+	log.Println("payments: mock checkout pages are only served by dev builds (-tags dev)")
+}
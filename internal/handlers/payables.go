@@ -0,0 +1,286 @@
+// internal/handlers/payables.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/models"
+	"travel-agency/internal/payables"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PayablesHandler exposes what we owe vendors and the runs that pay them.
+type PayablesHandler struct {
+	DB *gorm.DB
+}
+
+func NewPayablesHandler(db *gorm.DB) *PayablesHandler {
+	return &PayablesHandler{DB: db}
+}
+
+// ListPayables handles GET /payables?vendorId=&asOf=YYYY-MM-DD and returns
+// open purchase invoices with their aging, plus totals per vendor.
+func (h *PayablesHandler) ListPayables(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	asOf := time.Now()
+	if s := r.URL.Query().Get("asOf"); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			http.Error(w, "Invalid asOf date", http.StatusBadRequest)
+			return
+		}
+		asOf = t
+	}
+	var vendorID uint
+	if s := r.URL.Query().Get("vendorId"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, "Invalid vendorId", http.StatusBadRequest)
+			return
+		}
+		vendorID = uint(id)
+	}
+
+	list, err := payables.Open(h.DB, claims.TenantID, vendorID, asOf)
+	if err != nil {
+		http.Error(w, "Unable to compute payables", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"asOf":     asOf.Format("2006-01-02"),
+		"payables": list,
+		"aging":    payables.Summarize(list),
+	})
+}
+
+// CreatePaymentRun handles POST /payables/runs. Everything due by dueBy
+// (default: scheduledFor) and not already scheduled is batched into vendor
+// payments that are executed on scheduledFor.
+func (h *PayablesHandler) CreatePaymentRun(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		ScheduledFor string `json:"scheduledFor"`
+		DueBy        string `json:"dueBy"`
+		VendorID     uint   `json:"vendorId"`
+		Method       string `json:"method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	scheduledFor, err := time.Parse("2006-01-02", input.ScheduledFor)
+	if err != nil {
+		http.Error(w, "Invalid scheduledFor date", http.StatusBadRequest)
+		return
+	}
+	dueBy := scheduledFor
+	if input.DueBy != "" {
+		if dueBy, err = time.Parse("2006-01-02", input.DueBy); err != nil {
+			http.Error(w, "Invalid dueBy date", http.StatusBadRequest)
+			return
+		}
+	}
+	if input.Method == "" {
+		input.Method = "Bank Transfer"
+	}
+
+	var run *models.PaymentRun
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		run, err = payables.PlanRun(tx, claims.TenantID, input.VendorID, claims.UserID, scheduledFor, dueBy, input.Method)
+		return err
+	}); err != nil {
+		if errors.Is(err, payables.ErrNothingDue) {
+			http.Error(w, "Nothing is due for payment", http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, "Unable to create payment run", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(run)
+}
+
+// ListPaymentRuns handles GET /payables/runs
+func (h *PayablesHandler) ListPaymentRuns(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var runs []models.PaymentRun
+	if err := h.DB.Where("tenant_id = ?", claims.TenantID).
+		Order("scheduled_for DESC, id DESC").
+		Find(&runs).Error; err != nil {
+		http.Error(w, "Unable to fetch payment runs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+// findRun loads a payment run (with payments) by URL param. It writes the
+// error response itself and returns nil on failure.
+func (h *PayablesHandler) findRun(w http.ResponseWriter, r *http.Request, tenantID uint) *models.PaymentRun {
+	id, err := strconv.Atoi(chi.URLParam(r, "runID"))
+	if err != nil {
+		http.Error(w, "Invalid payment run ID", http.StatusBadRequest)
+		return nil
+	}
+	var run models.PaymentRun
+	if err := h.DB.Preload("Payments").
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		First(&run).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Payment run not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return nil
+	}
+	return &run
+}
+
+// GetPaymentRun handles GET /payables/runs/{runID}
+func (h *PayablesHandler) GetPaymentRun(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	run := h.findRun(w, r, claims.TenantID)
+	if run == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// ExecutePaymentRun handles POST /payables/runs/{runID}/execute and pays the
+// run now instead of waiting for its scheduled date.
+func (h *PayablesHandler) ExecutePaymentRun(w http.ResponseWriter, r *http.Request) {
+	h.closeRun(w, r, payables.ExecuteRun)
+}
+
+// CancelPaymentRun handles POST /payables/runs/{runID}/cancel
+func (h *PayablesHandler) CancelPaymentRun(w http.ResponseWriter, r *http.Request) {
+	h.closeRun(w, r, payables.CancelRun)
+}
+
+func (h *PayablesHandler) closeRun(w http.ResponseWriter, r *http.Request, fn func(*gorm.DB, *models.PaymentRun, uint) error) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	run := h.findRun(w, r, claims.TenantID)
+	if run == nil {
+		return
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// Reload it locked: the scheduled job may have paid it since.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Payments").
+			Where("id = ? AND tenant_id = ?", run.ID, claims.TenantID).
+			First(run).Error; err != nil {
+			return err
+		}
+		return fn(tx, run, claims.UserID)
+	}); err != nil {
+		if errors.Is(err, payables.ErrRunClosed) {
+			http.Error(w, "Payment run is already "+run.Status, http.StatusConflict)
+			return
+		}
+		http.Error(w, "Unable to update payment run", http.StatusInternalServerError)
+		return
+	}
+
+	if run = h.findRun(w, r, claims.TenantID); run == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// VendorStatement handles GET /vendors/{vendorID}/statement?from=&to=&format=csv.
+// The statement reconciles purchase invoices against booking costs.
+func (h *PayablesHandler) VendorStatement(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var vendor models.Vendor
+	if err := h.DB.Where("id = ? AND tenant_id = ?", chi.URLParam(r, "vendorID"), claims.TenantID).
+		First(&vendor).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Vendor not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, -1)
+	var err error
+	if s := r.URL.Query().Get("from"); s != "" {
+		if from, err = time.Parse("2006-01-02", s); err != nil {
+			http.Error(w, "Invalid from date", http.StatusBadRequest)
+			return
+		}
+	}
+	if s := r.URL.Query().Get("to"); s != "" {
+		if to, err = time.Parse("2006-01-02", s); err != nil || to.Before(from) {
+			http.Error(w, "Invalid to date", http.StatusBadRequest)
+			return
+		}
+	}
+
+	st, err := payables.BuildStatement(h.DB, vendor, from, to)
+	if err != nil {
+		http.Error(w, "Unable to build statement", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=vendor_%d_statement_%s_%s.csv",
+			vendor.ID, from.Format("20060102"), to.Format("20060102")))
+		st.WriteCSV(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
+}
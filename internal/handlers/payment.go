@@ -2,24 +2,64 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"travel-agency/internal/auth"
+	"travel-agency/internal/fx"
+	"travel-agency/internal/gateways"
+	"travel-agency/internal/ledger"
 	"travel-agency/internal/models"
-	"gorm.io/gorm"
+	"travel-agency/internal/receivables"
+	"travel-agency/internal/utils"
 )
 
 type PaymentHandler struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	Gateways *gateways.Registry // Payment providers for online payments; may be nil.
 }
 
-func NewPaymentHandler(db *gorm.DB) *PaymentHandler {
-	return &PaymentHandler{DB: db}
+func NewPaymentHandler(db *gorm.DB, registry *gateways.Registry) *PaymentHandler {
+	return &PaymentHandler{DB: db, Gateways: registry}
 }
 
+// writePaymentError maps allocation and payment provider errors to responses.
+func writePaymentError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, receivables.ErrInvalidAllocation), isFXError(err):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, receivables.ErrOverAllocated), errors.Is(err, receivables.ErrInvoiceNotOpen):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, gateways.ErrNoProvider):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errGateway):
+		http.Error(w, "Payment provider error", http.StatusBadGateway)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+func validPaymentStatus(s string) bool {
+	switch s {
+	case receivables.PaymentPending, receivables.PaymentCompleted, receivables.PaymentFailed, receivables.PaymentRefunded:
+		return true
+	}
+	return false
+}
+
+// CreatePayment handles POST /payments. The payment is spread over the
+// given allocations, or applied to invoiceId as far as it is owed; anything
+// left over is kept as credit. Recorded payments default to Completed.
 func (h *PaymentHandler) CreatePayment(w http.ResponseWriter, r *http.Request) {
 	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
 	if !ok {
@@ -27,25 +67,66 @@ func (h *PaymentHandler) CreatePayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var payment models.Payment
-	if err := json.NewDecoder(r.Body).Decode(&payment); err != nil {
+	var input struct {
+		models.Payment
+		Allocations []models.PaymentAllocation `json:"allocations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		http.Error(w, "Invalid payload", http.StatusBadRequest)
 		return
 	}
 
+	payment := input.Payment
+	payment.ID = 0
 	payment.TenantID = claims.TenantID
+	payment.Allocations = nil
+	if payment.Status == "" {
+		payment.Status = receivables.PaymentCompleted
+	}
+	if payment.PaymentDate.IsZero() {
+		payment.PaymentDate = time.Now()
+	}
+	if payment.Amount <= 0 || !validPaymentStatus(payment.Status) {
+		http.Error(w, "amount must be positive and status Pending, Completed, Failed or Refunded", http.StatusBadRequest)
+		return
+	}
+	payment.Unallocated = payment.Amount
 	payment.CreatedAt = time.Now()
 	payment.UpdatedAt = time.Now()
 
-	if err := h.DB.Create(&payment).Error; err != nil {
-		http.Error(w, "Failed to create payment", http.StatusInternalServerError)
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := fx.RecordPayment(tx, &payment); err != nil {
+			return err
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		if err := ledger.PostPayment(tx, &payment); err != nil {
+			return err
+		}
+		if err := utils.LogAction(tx, claims.TenantID, claims.UserID, "CREATE_PAYMENT", "Payment",
+			fmt.Sprintf("Recorded payment %d of %s %s", payment.ID, payment.Amount, payment.Currency)); err != nil {
+			return err
+		}
+		if len(input.Allocations) > 0 {
+			return receivables.Allocate(tx, &payment, input.Allocations, claims.UserID)
+		}
+		if payment.InvoiceID != nil {
+			return receivables.ApplyToInvoice(tx, &payment, *payment.InvoiceID, claims.UserID)
+		}
+		return nil
+	}); err != nil {
+		writePaymentError(w, err, "Failed to create payment")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(payment)
 }
 
+// ListPayments handles GET /payments?invoiceId=&credit=true. credit=true
+// lists only payments with an unallocated credit balance.
 func (h *PaymentHandler) ListPayments(w http.ResponseWriter, r *http.Request) {
 	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
 	if !ok {
@@ -53,8 +134,22 @@ func (h *PaymentHandler) ListPayments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	query := h.DB.Preload("Allocations").Where("tenant_id = ?", claims.TenantID)
+	if s := r.URL.Query().Get("invoiceId"); s != "" {
+		invoiceID, err := uuid.Parse(s)
+		if err != nil {
+			http.Error(w, "Invalid invoiceId", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("id IN (?)", h.DB.Model(&models.PaymentAllocation{}).
+			Select("payment_id").Where("invoice_id = ?", invoiceID))
+	}
+	if r.URL.Query().Get("credit") == "true" {
+		query = query.Where("unallocated > 0 AND status NOT IN ?", []string{receivables.PaymentFailed, receivables.PaymentRefunded})
+	}
+
 	var payments []models.Payment
-	if err := h.DB.Where("tenant_id = ?", claims.TenantID).Find(&payments).Error; err != nil {
+	if err := query.Order("payment_date DESC, id DESC").Find(&payments).Error; err != nil {
 		http.Error(w, "Unable to fetch payments", http.StatusInternalServerError)
 		return
 	}
@@ -63,20 +158,38 @@ func (h *PaymentHandler) ListPayments(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(payments)
 }
 
-func (h *PaymentHandler) GetPayment(w http.ResponseWriter, r *http.Request) {
+// findPayment loads the payment named by the URL, scoped to the tenant. It
+// writes the error response itself and returns nil on failure.
+func (h *PaymentHandler) findPayment(w http.ResponseWriter, r *http.Request, tenantID uint) *models.Payment {
 	paymentID, err := strconv.Atoi(chi.URLParam(r, "paymentID"))
 	if err != nil {
 		http.Error(w, "Invalid payment ID", http.StatusBadRequest)
-		return
+		return nil
 	}
 
 	var payment models.Payment
-	if err := h.DB.First(&payment, paymentID).Error; err != nil {
+	if err := h.DB.Preload("Allocations").
+		Where("id = ? AND tenant_id = ?", paymentID, tenantID).
+		First(&payment).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			http.Error(w, "Payment not found", http.StatusNotFound)
-			return
+			return nil
 		}
 		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil
+	}
+	return &payment
+}
+
+func (h *PaymentHandler) GetPayment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	payment := h.findPayment(w, r, claims.TenantID)
+	if payment == nil {
 		return
 	}
 
@@ -84,20 +197,18 @@ func (h *PaymentHandler) GetPayment(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(payment)
 }
 
+// UpdatePayment handles PUT /payments/{paymentID}. Allocations are changed
+// through the allocation endpoints; a new amount may not drop below what is
+// allocated, and a status change updates the invoices paid.
 func (h *PaymentHandler) UpdatePayment(w http.ResponseWriter, r *http.Request) {
-	paymentID, err := strconv.Atoi(chi.URLParam(r, "paymentID"))
-	if err != nil {
-		http.Error(w, "Invalid payment ID", http.StatusBadRequest)
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
 		return
 	}
 
-	var payment models.Payment
-	if err := h.DB.First(&payment, paymentID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			http.Error(w, "Payment not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Database error", http.StatusInternalServerError)
+	payment := h.findPayment(w, r, claims.TenantID)
+	if payment == nil {
 		return
 	}
 
@@ -106,20 +217,122 @@ func (h *PaymentHandler) UpdatePayment(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid payload", http.StatusBadRequest)
 		return
 	}
+	if updated.Amount <= 0 || !validPaymentStatus(updated.Status) {
+		http.Error(w, "amount must be positive and status Pending, Completed, Failed or Refunded", http.StatusBadRequest)
+		return
+	}
 
 	// Update permitted fields only.
-	payment.InvoiceID = updated.InvoiceID
-	payment.PaymentDate = updated.PaymentDate
+	before := fmt.Sprintf("%s %s", payment.Amount, payment.Status)
+	if !updated.PaymentDate.IsZero() {
+		payment.PaymentDate = updated.PaymentDate
+	}
 	payment.Amount = updated.Amount
+	if updated.Currency != "" {
+		payment.Currency = updated.Currency
+	}
 	payment.Method = updated.Method
+	payment.Reference = updated.Reference
 	payment.Status = updated.Status
 	payment.UpdatedAt = time.Now()
 
-	if err := h.DB.Save(&payment).Error; err != nil {
-		http.Error(w, "Failed to update payment", http.StatusInternalServerError)
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := fx.RecordPayment(tx, payment); err != nil {
+			return err
+		}
+		if err := tx.Omit("Allocations").Save(payment).Error; err != nil {
+			return err
+		}
+		if err := receivables.Recalculate(tx, payment); err != nil {
+			return err
+		}
+		if err := ledger.PostPayment(tx, payment); err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "UPDATE_PAYMENT", "Payment",
+			fmt.Sprintf("Payment %d: %s -> %s %s", payment.ID, before, payment.Amount, payment.Status))
+	}); err != nil {
+		writePaymentError(w, err, "Failed to update payment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payment)
+}
+
+// AllocatePayment handles POST /payments/{paymentID}/allocations and applies
+// the payment's credit to invoices: {"allocations": [{"invoiceId", "amount"}]}.
+func (h *PaymentHandler) AllocatePayment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Allocations []models.PaymentAllocation `json:"allocations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || len(input.Allocations) == 0 {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	payment := h.findPayment(w, r, claims.TenantID)
+	if payment == nil {
+		return
+	}
+	if payment.Status == receivables.PaymentFailed || payment.Status == receivables.PaymentRefunded {
+		http.Error(w, "Payment "+strings.ToLower(payment.Status)+"; nothing to allocate", http.StatusConflict)
+		return
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// Re-read the credit balance under lock so concurrent allocations
+		// cannot spend it twice.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("unallocated").First(payment, payment.ID).Error; err != nil {
+			return err
+		}
+		return receivables.Allocate(tx, payment, input.Allocations, claims.UserID)
+	}); err != nil {
+		writePaymentError(w, err, "Failed to allocate payment")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(payment)
 }
+
+// DeleteAllocation handles DELETE /payments/{paymentID}/allocations/{allocationID}.
+// The amount returns to the payment's credit.
+func (h *PaymentHandler) DeleteAllocation(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	allocationID, err := strconv.Atoi(chi.URLParam(r, "allocationID"))
+	if err != nil {
+		http.Error(w, "Invalid allocation ID", http.StatusBadRequest)
+		return
+	}
+
+	payment := h.findPayment(w, r, claims.TenantID)
+	if payment == nil {
+		return
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("unallocated").First(payment, payment.ID).Error; err != nil {
+			return err
+		}
+		return receivables.Unallocate(tx, payment, uint(allocationID), claims.UserID)
+	}); err != nil {
+		writePaymentError(w, err, "Failed to remove allocation")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,531 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"travel-agency/internal/auth"
+	"travel-agency/internal/fx"
+	"travel-agency/internal/gateways"
+	"travel-agency/internal/ledger"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/receivables"
+	"travel-agency/internal/utils"
+)
+
+// gatewayStatus maps a provider's intent status to a payment status. Intents
+// still waiting for the customer or for capture stay Pending.
+func gatewayStatus(status string) string {
+	switch status {
+	case gateways.StatusSucceeded:
+		return receivables.PaymentCompleted
+	case gateways.StatusFailed:
+		return receivables.PaymentFailed
+	case gateways.StatusRefunded:
+		return receivables.PaymentRefunded
+	default:
+		return receivables.PaymentPending
+	}
+}
+
+// setPaymentStatus moves a payment to a new status and brings its invoices
+// in line. A refunded payment stays refunded. A failed payment that comes
+// back only takes what its invoices still need; the rest becomes credit.
+func setPaymentStatus(tx *gorm.DB, p *models.Payment, status string, actorID uint, source string) error {
+	if p.Status == status || p.Status == receivables.PaymentRefunded {
+		return nil
+	}
+	before := p.Status
+	p.Status = status
+	p.UpdatedAt = time.Now()
+	if err := tx.Model(&models.Payment{}).Where("id = ?", p.ID).
+		Updates(map[string]interface{}{"status": status, "updated_at": p.UpdatedAt}).Error; err != nil {
+		return err
+	}
+	var err error
+	if before == receivables.PaymentFailed && status != receivables.PaymentRefunded {
+		err = receivables.Reallocate(tx, p, actorID)
+	} else {
+		err = receivables.Recalculate(tx, p)
+	}
+	if err != nil {
+		return err
+	}
+	if err := ledger.PostPayment(tx, p); err != nil {
+		return err
+	}
+	return utils.LogAction(tx, p.TenantID, actorID, "UPDATE_PAYMENT", "Payment",
+		fmt.Sprintf("Payment %d: %s -> %s (%s)", p.ID, before, status, source))
+}
+
+// errGateway wraps failures reported by a payment provider.
+var errGateway = errors.New("payment provider error")
+
+// startPayment asks the named provider to collect amount on the invoice, or
+// all that is outstanding when amount is zero, and records a Pending payment
+// allocated to the invoice. The payment completes when the provider's
+// webhook arrives.
+func startPayment(ctx context.Context, db *gorm.DB, registry *gateways.Registry, invoice models.Invoice,
+	name string, amount money.Amount, req gateways.IntentRequest, actorID uint) (*models.Payment, *gateways.Intent, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	provider, err := registry.For(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w %q", err, name)
+	}
+	if receivables.IsClosed(invoice.Status) {
+		return nil, nil, fmt.Errorf("%w: invoice is %s", receivables.ErrInvoiceNotOpen, invoice.Status)
+	}
+	outstanding, err := receivables.Outstanding(db, invoice)
+	if err != nil {
+		return nil, nil, err
+	}
+	if amount == 0 {
+		amount = outstanding
+	}
+	amount = amount.Round(invoice.Currency)
+	if amount <= 0 || amount > outstanding {
+		return nil, nil, fmt.Errorf("%w: invoice has %s left to collect", receivables.ErrOverAllocated, outstanding)
+	}
+
+	reference := invoice.ID.String()
+	if invoice.Number != nil {
+		reference = *invoice.Number
+	}
+	req.Amount = amount
+	req.Currency = invoice.Currency
+	req.Reference = reference
+	req.Description = "Invoice " + reference
+	intent, err := provider.CreateIntent(ctx, req)
+	if err != nil {
+		log.Printf("payments: %s intent for invoice %s failed: %v", name, invoice.ID, err)
+		return nil, nil, fmt.Errorf("%w: %v", errGateway, err)
+	}
+
+	invoiceID := invoice.ID
+	payment := models.Payment{
+		TenantID:    invoice.TenantID,
+		InvoiceID:   &invoiceID,
+		PaymentDate: time.Now(),
+		Amount:      amount,
+		Currency:    invoice.Currency,
+		Method:      "Online",
+		Reference:   intent.ID,
+		Status:      receivables.PaymentPending,
+		Unallocated: amount,
+		Provider:    name,
+		ProviderRef: intent.ID,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := fx.RecordPayment(tx, &payment); err != nil {
+			return err
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		if err := receivables.ApplyToInvoice(tx, &payment, invoiceID, actorID); err != nil {
+			return err
+		}
+		return utils.LogAction(tx, invoice.TenantID, actorID, "CREATE_PAYMENT_INTENT", "Payment",
+			fmt.Sprintf("Requested %s %s for invoice %s via %s (%s)", amount, payment.Currency, reference, name, intent.ID))
+	}); err != nil {
+		return nil, nil, err
+	}
+	return &payment, intent, nil
+}
+
+// CreateIntent handles POST /payments/intents: {"invoiceId", "provider",
+// "amount", "manualCapture", "returnUrl"}. It starts an online payment of
+// the invoice, or part of it, and returns the provider's intent.
+func (h *PaymentHandler) CreateIntent(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		InvoiceID     uuid.UUID    `json:"invoiceId"`
+		Provider      string       `json:"provider"`
+		Amount        money.Amount `json:"amount"` // Defaults to what is outstanding.
+		ManualCapture bool         `json:"manualCapture"`
+		ReturnURL     string       `json:"returnUrl"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.InvoiceID == uuid.Nil || input.Amount < 0 {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	var invoice models.Invoice
+	if err := h.DB.Where("id = ? AND tenant_id = ?", input.InvoiceID, claims.TenantID).First(&invoice).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	payment, intent, err := startPayment(r.Context(), h.DB, h.Gateways, invoice, input.Provider, input.Amount,
+		gateways.IntentRequest{ManualCapture: input.ManualCapture, ReturnURL: input.ReturnURL}, claims.UserID)
+	if err != nil {
+		writePaymentError(w, err, "Failed to record payment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"payment": payment, "intent": intent})
+}
+
+// gatewayPayment loads the URL's payment and its provider. It writes the
+// error response itself and returns nil on failure.
+func (h *PaymentHandler) gatewayPayment(w http.ResponseWriter, r *http.Request, tenantID uint) (*models.Payment, gateways.PaymentProvider) {
+	payment := h.findPayment(w, r, tenantID)
+	if payment == nil {
+		return nil, nil
+	}
+	if payment.Provider == "" || payment.ProviderRef == "" {
+		http.Error(w, "Payment was not taken through a payment provider", http.StatusConflict)
+		return nil, nil
+	}
+	provider, err := h.Gateways.For(payment.Provider)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Payment provider %q is not configured", payment.Provider), http.StatusServiceUnavailable)
+		return nil, nil
+	}
+	return payment, provider
+}
+
+// CapturePayment handles POST /payments/{paymentID}/capture for payments
+// authorised with manual capture.
+func (h *PaymentHandler) CapturePayment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	payment, provider := h.gatewayPayment(w, r, claims.TenantID)
+	if payment == nil {
+		return
+	}
+	if payment.Status != receivables.PaymentPending {
+		http.Error(w, "Only pending payments can be captured", http.StatusConflict)
+		return
+	}
+
+	intent, err := provider.Capture(r.Context(), payment.ProviderRef)
+	if err != nil {
+		log.Printf("payments: capture of payment %d failed: %v", payment.ID, err)
+		http.Error(w, "Payment provider error", http.StatusBadGateway)
+		return
+	}
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		return setPaymentStatus(tx, payment, gatewayStatus(intent.Status), claims.UserID, "captured")
+	}); err != nil {
+		writePaymentError(w, err, "Failed to update payment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payment)
+}
+
+// RefundPayment handles POST /payments/{paymentID}/refund: {"amount", "reason"}.
+// What is left of the payment is refunded when no amount is given. A refund
+// record is kept against the payment's invoice; a partial refund is taken
+// off the invoices it paid and a full one releases them.
+func (h *PaymentHandler) RefundPayment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Amount money.Amount `json:"amount"`
+		Reason string       `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	payment, provider := h.gatewayPayment(w, r, claims.TenantID)
+	if payment == nil {
+		return
+	}
+	if payment.Status != receivables.PaymentCompleted {
+		http.Error(w, "Only completed payments can be refunded", http.StatusConflict)
+		return
+	}
+	refunded, err := receivables.Refunded(h.DB, payment.ID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	left := payment.Amount - refunded
+	if input.Amount < 0 || input.Amount > left {
+		http.Error(w, fmt.Sprintf("Refund must be between 0 and %s", left), http.StatusBadRequest)
+		return
+	}
+	if input.Amount == 0 {
+		input.Amount = left
+	}
+
+	refund, err := provider.Refund(r.Context(), payment.ProviderRef, input.Amount, payment.Currency)
+	if err != nil {
+		log.Printf("payments: refund of payment %d failed: %v", payment.ID, err)
+		http.Error(w, "Payment provider error", http.StatusBadGateway)
+		return
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		status := receivables.PaymentPending
+		if refund.Status == gateways.StatusSucceeded {
+			status = receivables.PaymentCompleted
+		}
+		return recordRefund(tx, payment, refund.Amount, status, input.Reason, claims.UserID,
+			fmt.Sprintf("%s refund %s", payment.Provider, refund.ID))
+	}); err != nil {
+		writePaymentError(w, err, "Failed to record refund")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"payment": payment, "refund": refund})
+}
+
+// recordRefund keeps a refund of a gateway payment against the invoice it
+// paid. Once the refunds add up to the whole payment it is Refunded;
+// completed partial refunds are taken off its credit and allocations.
+func recordRefund(tx *gorm.DB, p *models.Payment, amount money.Amount, status, reason string, actorID uint, source string) error {
+	invoiceID := p.InvoiceID
+	if invoiceID == nil {
+		var a models.PaymentAllocation
+		if err := tx.Where("payment_id = ?", p.ID).Order("id").First(&a).Error; err == nil {
+			invoiceID = &a.InvoiceID
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+	if invoiceID != nil {
+		record := models.Refund{
+			TenantID:  p.TenantID,
+			InvoiceID: *invoiceID,
+			PaymentID: &p.ID,
+			Amount:    amount,
+			Currency:  p.Currency,
+			Method:    p.Provider,
+			Status:    status,
+			Reason:    reason,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		if err := ledger.PostRefund(tx, &record); err != nil {
+			return err
+		}
+	}
+	if err := utils.LogAction(tx, p.TenantID, actorID, "REFUND_PAYMENT", "Payment",
+		fmt.Sprintf("Refunded %s of payment %d (%s)", amount, p.ID, source)); err != nil {
+		return err
+	}
+
+	refunded, err := receivables.Refunded(tx, p.ID)
+	if err != nil {
+		return err
+	}
+	if status != receivables.PaymentCompleted {
+		refunded += amount
+	}
+	if refunded >= p.Amount {
+		return setPaymentStatus(tx, p, receivables.PaymentRefunded, actorID, source)
+	}
+	if status != receivables.PaymentCompleted {
+		return nil
+	}
+	return receivables.ApplyRefunds(tx, p, actorID)
+}
+
+// gatewayRefunded brings a payment in line with the total the provider says
+// has been refunded of it. Refunds we asked for are already recorded, so
+// only what was refunded elsewhere (e.g. the provider's dashboard) is added.
+func gatewayRefunded(tx *gorm.DB, p *models.Payment, total money.Amount, source string) error {
+	// The provider has now completed the refunds that were pending.
+	var pending []models.Refund
+	if err := tx.Where("payment_id = ? AND status = ?", p.ID, receivables.PaymentPending).Find(&pending).Error; err != nil {
+		return err
+	}
+	for i := range pending {
+		pending[i].Status = receivables.PaymentCompleted
+		pending[i].UpdatedAt = time.Now()
+		if err := tx.Model(&pending[i]).Updates(map[string]interface{}{
+			"status": pending[i].Status, "updated_at": pending[i].UpdatedAt}).Error; err != nil {
+			return err
+		}
+		if err := ledger.PostRefund(tx, &pending[i]); err != nil {
+			return err
+		}
+	}
+
+	refunded, err := receivables.Refunded(tx, p.ID)
+	if err != nil {
+		return err
+	}
+	if total > refunded {
+		return recordRefund(tx, p, total-refunded, receivables.PaymentCompleted, "Refunded at the provider", 0, source)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	if refunded >= p.Amount {
+		return setPaymentStatus(tx, p, receivables.PaymentRefunded, 0, source)
+	}
+	return receivables.ApplyRefunds(tx, p, 0)
+}
+
+// eventMismatch says how a success event differs from the payment it is
+// for, or returns "" when it is for the payment's amount and currency.
+func eventMismatch(p *models.Payment, ev *gateways.Event) string {
+	if !strings.EqualFold(ev.Currency, p.Currency) {
+		return fmt.Sprintf("event is in %q, payment in %s", ev.Currency, p.Currency)
+	}
+	if ev.Amount.Round(p.Currency) != p.Amount.Round(p.Currency) {
+		return fmt.Sprintf("event is for %s, payment for %s", ev.Amount, p.Amount)
+	}
+	return ""
+}
+
+// WebhookHandler receives payment gateway notifications. It is public: each
+// request is authenticated by the provider's signature.
+type WebhookHandler struct {
+	DB       *gorm.DB
+	Gateways *gateways.Registry
+}
+
+func NewWebhookHandler(db *gorm.DB, registry *gateways.Registry) *WebhookHandler {
+	return &WebhookHandler{DB: db, Gateways: registry}
+}
+
+// maxWebhookBody bounds what we read from a webhook request.
+const maxWebhookBody = 1 << 20
+
+// PaymentWebhook handles POST /api/webhooks/payments/{provider}. Each event
+// is applied once: redeliveries of an event already seen are acknowledged
+// and ignored. Events for payments we do not know are recorded and
+// acknowledged so the provider stops retrying.
+func (h *WebhookHandler) PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	name := strings.ToLower(chi.URLParam(r, "provider"))
+	provider, err := h.Gateways.For(name)
+	if err != nil {
+		http.Error(w, "Unknown payment provider", http.StatusNotFound)
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "Unable to read body", http.StatusBadRequest)
+		return
+	}
+	event, err := provider.ParseWebhook(payload, r.Header)
+	if err != nil {
+		if errors.Is(err, gateways.ErrInvalidSignature) {
+			http.Error(w, "Invalid signature", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if event.ID == "" {
+		http.Error(w, "Event has no ID", http.StatusBadRequest)
+		return
+	}
+
+	duplicate, err := h.apply(name, event)
+	if err != nil {
+		log.Printf("payments: handling %s event %s failed: %v", name, event.ID, err)
+		http.Error(w, "Failed to process event", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if duplicate {
+		json.NewEncoder(w).Encode(map[string]string{"status": "already processed"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "processed"})
+}
+
+// apply records a verified event and updates the payment it is about. It
+// reports whether the event had already been handled.
+func (h *WebhookHandler) apply(name string, event *gateways.Event) (bool, error) {
+	duplicate := false
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		record := models.WebhookEvent{Provider: name, EventID: event.ID, Type: event.Type, CreatedAt: time.Now()}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			duplicate = true
+			return nil
+		}
+		if event.Type == "" || event.IntentID == "" {
+			return nil
+		}
+
+		var payment models.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("provider = ? AND provider_ref = ?", name, event.IntentID).
+			First(&payment).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("payments: %s event %s for unknown intent %s", name, event.ID, event.IntentID)
+				return nil
+			}
+			return err
+		}
+		if err := tx.Model(&record).Update("payment_id", payment.ID).Error; err != nil {
+			return err
+		}
+
+		source := fmt.Sprintf("%s event %s", name, event.ID)
+		switch event.Type {
+		case gateways.EventSucceeded:
+			if mismatch := eventMismatch(&payment, event); mismatch != "" {
+				// Left pending for someone to look at rather than counting
+				// money we did not receive.
+				log.Printf("payments: %s event %s not applied to payment %d: %s", name, event.ID, payment.ID, mismatch)
+				return utils.LogAction(tx, payment.TenantID, 0, "PAYMENT_MISMATCH", "Payment",
+					fmt.Sprintf("Payment %d not completed by %s: %s", payment.ID, source, mismatch))
+			}
+			return setPaymentStatus(tx, &payment, receivables.PaymentCompleted, 0, source)
+		case gateways.EventFailed:
+			return setPaymentStatus(tx, &payment, receivables.PaymentFailed, 0, source)
+		case gateways.EventRefunded:
+			// The event carries the total refunded so far.
+			if event.Amount < payment.Amount {
+				return gatewayRefunded(tx, &payment, event.Amount, source)
+			}
+			return setPaymentStatus(tx, &payment, receivables.PaymentRefunded, 0, source)
+		}
+		return nil
+	})
+	return duplicate, err
+}
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"log"
+	"net/http"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"travel-agency/internal/auth"
+	"travel-agency/internal/gateways"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/notifications"
+	"travel-agency/internal/receivables"
+	"travel-agency/internal/utils"
+)
+
+// PaymentLinkHandler hands out signed, expiring links that let a customer
+// without an account see an invoice and pay it online.
+type PaymentLinkHandler struct {
+	DB          *gorm.DB
+	Gateways    *gateways.Registry
+	EmailSender notifications.EmailSender
+	Secret      string // Signs link tokens.
+	BaseURL     string // Externally reachable base URL for the links.
+}
+
+func NewPaymentLinkHandler(db *gorm.DB, registry *gateways.Registry, sender notifications.EmailSender, secret, baseURL string) *PaymentLinkHandler {
+	return &PaymentLinkHandler{DB: db, Gateways: registry, EmailSender: sender, Secret: secret, BaseURL: baseURL}
+}
+
+const (
+	defaultPaymentLinkDays = 7
+	maxPaymentLinkDays     = 90
+)
+
+// CreatePaymentLink handles POST /invoices/{invoiceID}/payment-link:
+// {"email", "expiresInDays"}. It returns the link and, when an email address
+// is given, sends it to the customer.
+func (h *PaymentLinkHandler) CreatePaymentLink(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	invoiceID, err := uuid.Parse(chi.URLParam(r, "invoiceID"))
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Email         string `json:"email"`
+		ExpiresInDays int    `json:"expiresInDays"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+	}
+	if input.ExpiresInDays == 0 {
+		input.ExpiresInDays = defaultPaymentLinkDays
+	}
+	if input.ExpiresInDays < 0 || input.ExpiresInDays > maxPaymentLinkDays {
+		http.Error(w, fmt.Sprintf("expiresInDays must be between 1 and %d", maxPaymentLinkDays), http.StatusBadRequest)
+		return
+	}
+	if input.Email != "" {
+		if _, err := mail.ParseAddress(input.Email); err != nil {
+			http.Error(w, "Invalid email address", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var invoice models.Invoice
+	if err := h.DB.Where("id = ? AND tenant_id = ?", invoiceID, claims.TenantID).First(&invoice).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if invoice.InvoiceType != "sale" || receivables.IsClosed(invoice.Status) {
+		http.Error(w, "Only issued sale invoices can be paid online", http.StatusConflict)
+		return
+	}
+	outstanding, err := receivables.Outstanding(h.DB, invoice)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if outstanding <= 0 {
+		http.Error(w, "Invoice has nothing left to pay", http.StatusConflict)
+		return
+	}
+
+	expires := time.Now().AddDate(0, 0, input.ExpiresInDays)
+	link := h.BaseURL + "/api/pay/" + auth.GeneratePaymentLinkToken(claims.TenantID, invoice.ID, expires, h.Secret)
+	reference := invoiceReference(invoice)
+
+	emailed := false
+	if input.Email != "" {
+		var tenant models.Tenant
+		h.DB.First(&tenant, claims.TenantID)
+		subject := fmt.Sprintf("Invoice %s from %s", reference, tenant.Name)
+		body := "Hello,<br/><br/>" +
+			fmt.Sprintf("Invoice <strong>%s</strong> has <strong>%s %s</strong> outstanding",
+				html.EscapeString(reference), outstanding.Format(invoice.Currency), html.EscapeString(invoice.Currency))
+		if !invoice.DueDate.IsZero() {
+			body += ", due on " + invoice.DueDate.Format("02 Jan 2006")
+		}
+		body += ".<br/><br/>" +
+			`<a href="` + html.EscapeString(link) + `">View and pay your invoice</a><br/><br/>` +
+			"This link expires on " + expires.Format("02 Jan 2006") + "."
+		if err := h.EmailSender.SendEmail(input.Email, subject, body); err != nil {
+			log.Printf("Warning: failed to send payment link to %s: %v", input.Email, err)
+		} else {
+			emailed = true
+		}
+	}
+
+	details := fmt.Sprintf("Created payment link for invoice %s, expires %s", reference, expires.Format("2006-01-02"))
+	if emailed {
+		details += ", emailed to " + input.Email
+	}
+	if err := utils.LogAction(h.DB, claims.TenantID, claims.UserID, "CREATE_PAYMENT_LINK", "Invoice", details); err != nil {
+		log.Printf("Warning: failed to log payment link for invoice %s: %v", invoice.ID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"url":       link,
+		"expiresAt": expires,
+		"emailed":   emailed,
+	})
+}
+
+// invoiceReference is how an invoice is named to customers.
+func invoiceReference(inv models.Invoice) string {
+	if inv.Number != nil {
+		return *inv.Number
+	}
+	return inv.ID.String()
+}
+
+// linkInvoice resolves a payment link token to its invoice. It writes the
+// error response itself and returns nil on failure.
+func (h *PaymentLinkHandler) linkInvoice(w http.ResponseWriter, r *http.Request) *models.Invoice {
+	tenantID, invoiceID, err := auth.ParsePaymentLinkToken(chi.URLParam(r, "token"), h.Secret, time.Now())
+	if err != nil {
+		if errors.Is(err, auth.ErrPaymentLinkExpired) {
+			http.Error(w, "This payment link has expired. Please ask us for a new one.", http.StatusGone)
+			return nil
+		}
+		http.Error(w, "Invalid payment link", http.StatusNotFound)
+		return nil
+	}
+
+	var invoice models.Invoice
+	if err := h.DB.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Where("id = ? AND tenant_id = ?", invoiceID, tenantID).First(&invoice).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Invalid payment link", http.StatusNotFound)
+			return nil
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil
+	}
+	return &invoice
+}
+
+var paymentPage = template.Must(template.New("pay").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Invoice {{.Reference}}</title></head>
+<body>
+<h1>{{.Tenant}}</h1>
+<h2>Invoice {{.Reference}}</h2>
+<p>Issued {{.Invoice.IssueDate.Format "02 Jan 2006"}}, due {{.Invoice.DueDate.Format "02 Jan 2006"}}</p>
+{{if .Invoice.Items}}<table>
+<tr><th>Description</th><th>Amount</th></tr>
+{{range .Invoice.Items}}<tr><td>{{.Description}}</td><td>{{.Total.Format $.Invoice.Currency}}</td></tr>
+{{end}}</table>{{end}}
+<p>Total: {{.Invoice.Amount.Format .Invoice.Currency}} {{.Invoice.Currency}}<br>
+{{if .Credited}}Credited: {{.Credited.Format .Invoice.Currency}} {{.Invoice.Currency}}<br>
+{{end}}Paid: {{.Paid.Format .Invoice.Currency}} {{.Invoice.Currency}}<br>
+<strong>Outstanding: {{.Outstanding.Format .Invoice.Currency}} {{.Invoice.Currency}}</strong></p>
+{{if .Message}}<p>{{.Message}}</p>
+{{else}}<form method="post">
+{{range .Providers}}<button type="submit" name="provider" value="{{.}}">Pay with {{.}}</button>
+{{end}}</form>{{end}}
+</body></html>`))
+
+// ShowPaymentLink handles GET /pay/{token} (public, token-signed): the
+// invoice summary and a way to pay what is outstanding.
+func (h *PaymentLinkHandler) ShowPaymentLink(w http.ResponseWriter, r *http.Request) {
+	invoice := h.linkInvoice(w, r)
+	if invoice == nil {
+		return
+	}
+
+	paid, err := receivables.Paid(h.DB, *invoice)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	credited, err := receivables.Credited(h.DB, invoice.ID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	outstanding := money.Max(0, invoice.Amount-credited-paid)
+	var tenant models.Tenant
+	h.DB.First(&tenant, invoice.TenantID)
+
+	providers := h.Gateways.Names()
+	sort.Strings(providers)
+	var message string
+	switch {
+	case receivables.IsClosed(invoice.Status):
+		message = "This invoice is no longer open for payment."
+	case outstanding <= 0:
+		message = "This invoice has been paid. Thank you."
+	case len(providers) == 0:
+		message = "Online payment is not available at the moment. Please contact us to pay."
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := paymentPage.Execute(w, map[string]interface{}{
+		"Tenant":      tenant.Name,
+		"Reference":   invoiceReference(*invoice),
+		"Invoice":     invoice,
+		"Paid":        paid,
+		"Credited":    credited,
+		"Outstanding": outstanding,
+		"Providers":   providers,
+		"Message":     message,
+	}); err != nil {
+		log.Printf("payment link page for invoice %s: %v", invoice.ID, err)
+	}
+}
+
+// supersedePending fails the invoice's online payments that were started but
+// never completed, e.g. a checkout the customer abandoned, so the amount can
+// be collected again. Should one still succeed later, its webhook completes
+// it, and what the invoice no longer needs stays with the customer as
+// credit (see setPaymentStatus).
+func (h *PaymentLinkHandler) supersedePending(invoice *models.Invoice) error {
+	return h.DB.Transaction(func(tx *gorm.DB) error {
+		var pending []models.Payment
+		if err := tx.Where("tenant_id = ? AND invoice_id = ? AND provider <> '' AND status = ?",
+			invoice.TenantID, invoice.ID, receivables.PaymentPending).Find(&pending).Error; err != nil {
+			return err
+		}
+		for i := range pending {
+			if err := setPaymentStatus(tx, &pending[i], receivables.PaymentFailed, 0, "superseded by a new payment link checkout"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// StartLinkPayment handles POST /pay/{token} with a "provider" form field.
+// It starts a payment of what is outstanding and sends the customer to the
+// provider's payment page; providers paid from the client (e.g. Stripe
+// Elements) get the intent back as JSON instead. The invoice is reconciled
+// when the provider's webhook reports the payment.
+func (h *PaymentLinkHandler) StartLinkPayment(w http.ResponseWriter, r *http.Request) {
+	invoice := h.linkInvoice(w, r)
+	if invoice == nil {
+		return
+	}
+
+	if err := h.supersedePending(invoice); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	returnURL := h.BaseURL + r.URL.Path
+	_, intent, err := startPayment(r.Context(), h.DB, h.Gateways, *invoice, r.FormValue("provider"), 0,
+		gateways.IntentRequest{ReturnURL: returnURL}, 0)
+	if err != nil {
+		writePaymentError(w, err, "Unable to start payment")
+		return
+	}
+
+	if strings.HasPrefix(intent.URL, "http://") || strings.HasPrefix(intent.URL, "https://") {
+		http.Redirect(w, r, intent.URL, http.StatusSeeOther)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(intent)
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/gateways"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/receivables"
+	"travel-agency/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const testLinkSecret = "link-secret"
+
+// paymentLinkServer serves the public payment link and webhook routes with
+// the mock provider.
+func paymentLinkServer(db *gorm.DB) (*chi.Mux, *gateways.Mock) {
+	mock := gateways.NewMock("mock-secret", "http://pay.test")
+	registry := gateways.NewRegistry()
+	registry.Register("mock", mock)
+
+	links := NewPaymentLinkHandler(db, registry, nil, testLinkSecret, "http://agency.test")
+	webhooks := NewWebhookHandler(db, registry)
+	r := chi.NewRouter()
+	r.Get("/pay/{token}", links.ShowPaymentLink)
+	r.Post("/pay/{token}", links.StartLinkPayment)
+	r.Post("/webhooks/payments/{provider}", webhooks.PaymentWebhook)
+	return r, mock
+}
+
+func openInvoice(t *testing.T, db *gorm.DB, tenantID uint, amount int64) models.Invoice {
+	t.Helper()
+	inv := models.Invoice{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		InvoiceType: "sale",
+		Status:      "Outstanding",
+		Currency:    "USD",
+		Amount:      money.FromInt(amount),
+		IssueDate:   time.Now(),
+		DueDate:     time.Now().AddDate(0, 0, 30),
+	}
+	require.NoError(t, db.Create(&inv).Error)
+	return inv
+}
+
+func linkToken(tenantID uint, invoiceID uuid.UUID) string {
+	return auth.GeneratePaymentLinkToken(tenantID, invoiceID, time.Now().Add(time.Hour), testLinkSecret)
+}
+
+// startCheckout posts the link's form and returns the mock intent the
+// customer was sent to.
+func startCheckout(t *testing.T, r http.Handler, token string) string {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/pay/"+token, strings.NewReader(url.Values{"provider": {"mock"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusSeeOther, rr.Code, rr.Body.String())
+	return path.Base(rr.Header().Get("Location"))
+}
+
+func deliverWebhook(t *testing.T, r http.Handler, body []byte, sig string) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/webhooks/payments/mock", strings.NewReader(string(body)))
+	req.Header.Set(gateways.MockSignatureHeader, sig)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+}
+
+func TestSupersededCheckoutSucceedsLate(t *testing.T) {
+	db := testutil.DB(t)
+	r, mock := paymentLinkServer(db)
+	inv := openInvoice(t, db, 1, 100)
+	token := linkToken(1, inv.ID)
+
+	// The customer abandons a checkout, starts another and pays it.
+	abandoned := startCheckout(t, r, token)
+	paid := startCheckout(t, r, token)
+	body, sig, err := mock.Pay(paid)
+	require.NoError(t, err)
+	deliverWebhook(t, r, body, sig)
+
+	var first models.Payment
+	require.NoError(t, db.Where("provider_ref = ?", abandoned).First(&first).Error)
+	assert.Equal(t, receivables.PaymentFailed, first.Status)
+	db.First(&inv, "id = ?", inv.ID)
+	assert.Equal(t, "Paid", inv.Status)
+
+	// The abandoned checkout goes through after all.
+	body, sig, err = mock.Pay(abandoned)
+	require.NoError(t, err)
+	deliverWebhook(t, r, body, sig)
+
+	require.NoError(t, db.Preload("Allocations").First(&first, first.ID).Error)
+	assert.Equal(t, receivables.PaymentCompleted, first.Status)
+	assert.Empty(t, first.Allocations)
+	assert.Equal(t, money.FromInt(100), first.Unallocated)
+
+	db.First(&inv, "id = ?", inv.ID)
+	assert.Equal(t, "Paid", inv.Status)
+	got, err := receivables.Paid(db, inv)
+	require.NoError(t, err)
+	assert.Equal(t, money.FromInt(100), got)
+}
+
+func TestPaymentLinkScopedToItsInvoice(t *testing.T) {
+	db := testutil.DB(t)
+	r, _ := paymentLinkServer(db)
+	inv := openInvoice(t, db, 1, 100)
+
+	get := func(token string) int {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", "/pay/"+token, nil))
+		return rr.Code
+	}
+	tests := []struct {
+		name  string
+		token string
+		code  int
+	}{
+		{"its invoice", linkToken(1, inv.ID), http.StatusOK},
+		{"another tenant's invoice", linkToken(2, inv.ID), http.StatusNotFound},
+		{"an unknown invoice", linkToken(1, uuid.New()), http.StatusNotFound},
+		{"signed with another secret", auth.GeneratePaymentLinkToken(1, inv.ID, time.Now().Add(time.Hour), "other"), http.StatusNotFound},
+		{"expired", auth.GeneratePaymentLinkToken(1, inv.ID, time.Now().Add(-time.Minute), testLinkSecret), http.StatusGone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.code, get(tt.token))
+		})
+	}
+}
+
+func TestStartLinkPaymentOnPaidInvoice(t *testing.T) {
+	db := testutil.DB(t)
+	r, mock := paymentLinkServer(db)
+	inv := openInvoice(t, db, 1, 100)
+	token := linkToken(1, inv.ID)
+
+	intent := startCheckout(t, r, token)
+	body, sig, err := mock.Pay(intent)
+	require.NoError(t, err)
+	deliverWebhook(t, r, body, sig)
+
+	req := httptest.NewRequest("POST", "/pay/"+token, strings.NewReader(url.Values{"provider": {"mock"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+
+	var payments int64
+	db.Model(&models.Payment{}).Where("invoice_id = ?", inv.ID).Count(&payments)
+	assert.Equal(t, int64(1), payments)
+	var paid models.Payment
+	require.NoError(t, db.Where("provider_ref = ?", intent).First(&paid).Error)
+	assert.Equal(t, receivables.PaymentCompleted, paid.Status, "a completed payment is not superseded")
+}
+
+func TestWebhookForAnotherAmountLeavesPaymentPending(t *testing.T) {
+	db := testutil.DB(t)
+	r, _ := paymentLinkServer(db)
+	inv := openInvoice(t, db, 1, 100)
+	intent := startCheckout(t, r, linkToken(1, inv.ID))
+
+	for i, ev := range []gateways.Event{
+		{Amount: money.FromInt(1), Currency: "USD"},
+		{Amount: money.FromInt(100), Currency: "EUR"},
+	} {
+		ev.ID, ev.Type, ev.IntentID = fmt.Sprintf("evt_%d", i), gateways.EventSucceeded, intent
+		body, err := json.Marshal(ev)
+		require.NoError(t, err)
+		deliverWebhook(t, r, body, gateways.SignHMAC("mock-secret", string(body)))
+	}
+
+	var payment models.Payment
+	require.NoError(t, db.Where("provider_ref = ?", intent).First(&payment).Error)
+	assert.Equal(t, receivables.PaymentPending, payment.Status)
+	db.First(&inv, "id = ?", inv.ID)
+	assert.Equal(t, "Outstanding", inv.Status)
+	var flagged int64
+	db.Model(&models.AuditLog{}).Where("action = ?", "PAYMENT_MISMATCH").Count(&flagged)
+	assert.Equal(t, int64(2), flagged)
+}
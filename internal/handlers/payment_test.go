@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdatePaymentKeepsCurrency(t *testing.T) {
+	db := testutil.DB(t)
+	require.NoError(t, db.Create(&models.Tenant{ID: 1, Name: "Agency", BaseCurrency: "EUR"}).Error)
+	payment := models.Payment{TenantID: 1, PaymentDate: time.Now(), Amount: money.FromInt(100), Currency: "EUR",
+		Unallocated: money.FromInt(100), Status: "Completed"}
+	require.NoError(t, db.Create(&payment).Error)
+
+	path := fmt.Sprintf("/payments/%d", payment.ID)
+	rr := serveAs(1, NewPaymentHandler(db, nil).UpdatePayment, "PUT", "/payments/{paymentID}", path,
+		`{"Amount": "120", "Status": "Completed", "Reference": "TX-9"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// Clients read the payment by its field names.
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "EUR", body["Currency"])
+	assert.Equal(t, "TX-9", body["Reference"])
+
+	require.NoError(t, db.First(&payment, payment.ID).Error)
+	assert.Equal(t, "EUR", payment.Currency)
+	assert.Equal(t, money.FromInt(120), payment.Amount)
+}
@@ -0,0 +1,171 @@
+// internal/handlers/pricing.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/models"
+	"travel-agency/internal/pricing"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type PricingHandler struct {
+	DB *gorm.DB
+}
+
+func NewPricingHandler(db *gorm.DB) *PricingHandler {
+	return &PricingHandler{DB: db}
+}
+
+// validMarkupRule checks the fields the pricing engine relies on.
+func validMarkupRule(rule models.MarkupRule) string {
+	if rule.MarkupType != pricing.MarkupPercent && rule.MarkupType != pricing.MarkupFixed {
+		return "markupType must be 'percent' or 'fixed'"
+	}
+	if rule.MarkupValue < 0 || rule.MinMargin < 0 {
+		return "markupValue and minMargin must be non-negative"
+	}
+	return ""
+}
+
+// CreateMarkupRule handles POST /pricing/markup-rules
+func (h *PricingHandler) CreateMarkupRule(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var rule models.MarkupRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if msg := validMarkupRule(rule); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	rule.ID = 0
+	rule.TenantID = claims.TenantID
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = time.Now()
+
+	if err := h.DB.Create(&rule).Error; err != nil {
+		http.Error(w, "Failed to create markup rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// ListMarkupRules handles GET /pricing/markup-rules
+func (h *PricingHandler) ListMarkupRules(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var rules []models.MarkupRule
+	if err := h.DB.Where("tenant_id = ?", claims.TenantID).
+		Order("priority DESC, id").
+		Find(&rules).Error; err != nil {
+		http.Error(w, "Unable to fetch markup rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// UpdateMarkupRule handles PUT /pricing/markup-rules/{ruleID}
+func (h *PricingHandler) UpdateMarkupRule(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	ruleID, err := strconv.Atoi(chi.URLParam(r, "ruleID"))
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	var rule models.MarkupRule
+	if err := h.DB.Where("id = ? AND tenant_id = ?", ruleID, claims.TenantID).First(&rule).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Markup rule not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var updated models.MarkupRule
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if msg := validMarkupRule(updated); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	// Update allowed fields.
+	rule.Name = updated.Name
+	rule.VendorID = updated.VendorID
+	rule.ItemType = updated.ItemType
+	rule.Destination = updated.Destination
+	rule.MarkupType = updated.MarkupType
+	rule.MarkupValue = updated.MarkupValue
+	rule.MinMargin = updated.MinMargin
+	rule.Priority = updated.Priority
+	rule.Disabled = updated.Disabled
+	rule.UpdatedAt = time.Now()
+
+	if err := h.DB.Save(&rule).Error; err != nil {
+		http.Error(w, "Unable to update markup rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// DeleteMarkupRule handles DELETE /pricing/markup-rules/{ruleID}
+func (h *PricingHandler) DeleteMarkupRule(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	ruleID, err := strconv.Atoi(chi.URLParam(r, "ruleID"))
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	res := h.DB.
+		Where("id = ? AND tenant_id = ?", ruleID, claims.TenantID).
+		Delete(&models.MarkupRule{})
+	if res.Error != nil {
+		http.Error(w, "Failed to delete markup rule", http.StatusInternalServerError)
+		return
+	}
+	if res.RowsAffected == 0 {
+		http.Error(w, "Markup rule not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
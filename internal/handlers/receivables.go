@@ -0,0 +1,121 @@
+// handlers/receivables.go
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/receivables"
+)
+
+// ReceivablesHandler reports what customers owe us.
+type ReceivablesHandler struct {
+	DB *gorm.DB
+}
+
+func NewReceivablesHandler(db *gorm.DB) *ReceivablesHandler {
+	return &ReceivablesHandler{DB: db}
+}
+
+// CustomerStatement handles GET /api/customers/{customerID}/statement
+// ?from=&to=YYYY-MM-DD&currency=&format=pdf. The period defaults to the
+// current month and the currency to that of the customer's latest invoice.
+func (h *ReceivablesHandler) CustomerStatement(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+	customerID, err := uuid.Parse(chi.URLParam(r, "customerID"))
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, -1)
+	if s := r.URL.Query().Get("from"); s != "" {
+		if from, err = time.Parse("2006-01-02", s); err != nil {
+			http.Error(w, "Invalid from date", http.StatusBadRequest)
+			return
+		}
+	}
+	if s := r.URL.Query().Get("to"); s != "" {
+		if to, err = time.Parse("2006-01-02", s); err != nil {
+			http.Error(w, "Invalid to date", http.StatusBadRequest)
+			return
+		}
+	}
+	if to.Before(from) {
+		http.Error(w, "Invalid to date", http.StatusBadRequest)
+		return
+	}
+	currency := strings.ToUpper(r.URL.Query().Get("currency"))
+
+	st, err := receivables.BuildStatement(h.DB, claims.TenantID, customerID, currency, from, to)
+	if err != nil {
+		if errors.Is(err, receivables.ErrNoActivity) {
+			http.Error(w, "Customer not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Unable to build statement", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "pdf" {
+		var buf bytes.Buffer
+		if err := st.WritePDF(&buf); err != nil {
+			http.Error(w, "Failed to generate PDF", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=statement_%s_%s_%s.pdf",
+			customerID, from.Format("20060102"), to.Format("20060102")))
+		w.Write(buf.Bytes())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
+}
+
+// AgingReport handles GET /api/receivables/aging?asOf=YYYY-MM-DD (default
+// today): open sale invoices by customer in current, 1-30, 31-60, 61-90 and
+// 90+ days overdue buckets, with totals per currency.
+func (h *ReceivablesHandler) AgingReport(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	asOf := time.Now()
+	if s := r.URL.Query().Get("asOf"); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			http.Error(w, "Invalid asOf date", http.StatusBadRequest)
+			return
+		}
+		asOf = t
+	}
+
+	report, err := receivables.BuildAging(h.DB, claims.TenantID, asOf)
+	if err != nil {
+		http.Error(w, "Unable to compute aging", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,257 @@
+// handlers/recurring_invoices.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/invoicing"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/utils"
+)
+
+// RecurringInvoiceHandler manages invoices billed on a schedule; the cron
+// job generates them.
+type RecurringInvoiceHandler struct {
+	DB *gorm.DB
+}
+
+func NewRecurringInvoiceHandler(db *gorm.DB) *RecurringInvoiceHandler {
+	return &RecurringInvoiceHandler{DB: db}
+}
+
+type recurringInvoicePayload struct {
+	Name         string       `json:"name"`
+	CustomerID   *uuid.UUID   `json:"customerId"`
+	ContactEmail string       `json:"contactEmail"`
+	Description  string       `json:"description"`
+	Amount       money.Amount `json:"amount"`
+	Currency     string       `json:"currency"`
+	TaxName      string       `json:"taxName"`
+	TaxRate      float64      `json:"taxRate"`
+	Cadence      string       `json:"cadence"`  // monthly, quarterly or cron
+	CronSpec     string       `json:"cronSpec"` // e.g. "0 0 1 */2 *" for every other month
+	DueInDays    int          `json:"dueInDays"`
+	Draft        bool         `json:"draft"`
+	StartDate    string       `json:"startDate"`         // YYYY-MM-DD
+	EndDate      string       `json:"endDate,omitempty"` // YYYY-MM-DD; empty runs until paused
+}
+
+// apply copies the payload onto ri and validates it.
+func (p *recurringInvoicePayload) apply(ri *models.RecurringInvoice) error {
+	start, err := time.Parse("2006-01-02", p.StartDate)
+	if err != nil {
+		return fmt.Errorf("%w: startDate must be YYYY-MM-DD", invoicing.ErrInvalidRecurring)
+	}
+	ri.EndDate = nil
+	if p.EndDate != "" {
+		end, err := time.Parse("2006-01-02", p.EndDate)
+		if err != nil {
+			return fmt.Errorf("%w: endDate must be YYYY-MM-DD", invoicing.ErrInvalidRecurring)
+		}
+		ri.EndDate = &end
+	}
+	ri.Name, ri.CustomerID, ri.ContactEmail, ri.Description = p.Name, p.CustomerID, p.ContactEmail, p.Description
+	ri.Amount, ri.Currency, ri.TaxName, ri.TaxRate = p.Amount, p.Currency, p.TaxName, p.TaxRate
+	ri.Cadence, ri.CronSpec, ri.DueInDays, ri.Draft, ri.StartDate = p.Cadence, p.CronSpec, p.DueInDays, p.Draft, start
+	return invoicing.ValidateRecurring(ri)
+}
+
+// load fetches the tenant's recurring invoice named in the URL. It writes
+// the error response itself and returns nil on failure.
+func (h *RecurringInvoiceHandler) load(w http.ResponseWriter, r *http.Request, tx *gorm.DB, tenantID uint) *models.RecurringInvoice {
+	id, err := strconv.Atoi(chi.URLParam(r, "recurringID"))
+	if err != nil {
+		http.Error(w, "Invalid recurring invoice ID", http.StatusBadRequest)
+		return nil
+	}
+	var ri models.RecurringInvoice
+	if err := tx.Where("id = ? AND tenant_id = ?", id, tenantID).First(&ri).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Recurring invoice not found", http.StatusNotFound)
+			return nil
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil
+	}
+	return &ri
+}
+
+// ListRecurringInvoices handles GET /api/recurring-invoices?status=.
+func (h *RecurringInvoiceHandler) ListRecurringInvoices(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	q := h.DB.Where("tenant_id = ?", claims.TenantID)
+	if status := r.URL.Query().Get("status"); status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var list []models.RecurringInvoice
+	if err := q.Order("name, id").Find(&list).Error; err != nil {
+		http.Error(w, "Unable to fetch recurring invoices", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// CreateRecurringInvoice handles POST /api/recurring-invoices. A start date
+// in the past bills the periods since then on the job's next run.
+func (h *RecurringInvoiceHandler) CreateRecurringInvoice(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var payload recurringInvoicePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	ri := models.RecurringInvoice{TenantID: claims.TenantID, CreatedBy: claims.UserID}
+	if err := payload.apply(&ri); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := invoicing.Reschedule(tx, &ri, time.Time{}); err != nil {
+			return err
+		}
+		if err := tx.Create(&ri).Error; err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "CREATE_RECURRING_INVOICE", "RecurringInvoice",
+			fmt.Sprintf("Recurring invoice %q: %s %s %s from %s", ri.Name, ri.Amount.Format(ri.Currency), ri.Currency,
+				ri.Cadence, ri.StartDate.Format("2006-01-02")))
+	}); err != nil {
+		http.Error(w, "Failed to create recurring invoice", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ri)
+}
+
+// GetRecurringInvoice handles GET /api/recurring-invoices/{recurringID},
+// with the periods billed so far, newest first.
+func (h *RecurringInvoiceHandler) GetRecurringInvoice(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+	ri := h.load(w, r, h.DB.Preload("Runs", func(db *gorm.DB) *gorm.DB {
+		return db.Order("period_start DESC")
+	}), claims.TenantID)
+	if ri == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ri)
+}
+
+// UpdateRecurringInvoice handles PUT /api/recurring-invoices/{recurringID}
+// with the full schedule. Invoices already generated are left as they are;
+// the schedule picks up after the last period billed, and extending the end
+// date of a completed schedule restarts it.
+func (h *RecurringInvoiceHandler) UpdateRecurringInvoice(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+	ri := h.load(w, r, h.DB, claims.TenantID)
+	if ri == nil {
+		return
+	}
+
+	var payload recurringInvoicePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if err := payload.apply(ri); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.save(w, claims, ri, time.Time{}, "UPDATE_RECURRING_INVOICE", "updated")
+}
+
+// PauseRecurringInvoice handles POST /api/recurring-invoices/{recurringID}/pause.
+func (h *RecurringInvoiceHandler) PauseRecurringInvoice(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+	ri := h.load(w, r, h.DB, claims.TenantID)
+	if ri == nil {
+		return
+	}
+	if ri.Status != models.RecurringActive {
+		http.Error(w, fmt.Sprintf("Recurring invoice is %s", ri.Status), http.StatusConflict)
+		return
+	}
+	ri.Status = models.RecurringPaused
+	h.save(w, claims, ri, time.Time{}, "PAUSE_RECURRING_INVOICE", "paused")
+}
+
+// ResumeRecurringInvoice handles POST /api/recurring-invoices/{recurringID}/resume.
+// Billing restarts with the period starting today or next; periods that
+// started while paused are not billed.
+func (h *RecurringInvoiceHandler) ResumeRecurringInvoice(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+	ri := h.load(w, r, h.DB, claims.TenantID)
+	if ri == nil {
+		return
+	}
+	if ri.Status != models.RecurringPaused {
+		http.Error(w, fmt.Sprintf("Recurring invoice is %s", ri.Status), http.StatusConflict)
+		return
+	}
+	ri.Status = models.RecurringActive
+	h.save(w, claims, ri, utils.TruncateDay(time.Now()), "RESUME_RECURRING_INVOICE", "resumed")
+}
+
+// save reschedules and saves the recurring invoice, then writes it out.
+func (h *RecurringInvoiceHandler) save(w http.ResponseWriter, claims *auth.Claims, ri *models.RecurringInvoice, notBefore time.Time, action, verb string) {
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := invoicing.Reschedule(tx, ri, notBefore); err != nil {
+			return err
+		}
+		ri.UpdatedAt = time.Now()
+		if err := tx.Save(ri).Error; err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, action, "RecurringInvoice",
+			fmt.Sprintf("Recurring invoice %q %s", ri.Name, verb))
+	}); err != nil {
+		http.Error(w, "Failed to save recurring invoice", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ri)
+}
@@ -0,0 +1,137 @@
+// handlers/refund.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/ledger"
+	"travel-agency/internal/models"
+	"travel-agency/internal/receivables"
+	"travel-agency/internal/utils"
+)
+
+// RefundHandler lists refunds and settles the ones paid out by hand, such as
+// what is owed back after a booking is cancelled. Refunds through a
+// payment's gateway are settled by the gateway's webhooks instead.
+type RefundHandler struct {
+	DB *gorm.DB
+}
+
+// Errors returned when a refund cannot be settled by hand.
+var (
+	errRefundNotPending = errors.New("refund is not pending")
+	errGatewayRefund    = errors.New("refunds through a payment provider are settled by the provider")
+)
+
+func NewRefundHandler(db *gorm.DB) *RefundHandler {
+	return &RefundHandler{DB: db}
+}
+
+// ListRefunds handles GET /refunds, optionally ?status= and ?invoiceId=.
+func (h *RefundHandler) ListRefunds(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := h.DB.Where("tenant_id = ?", claims.TenantID)
+	if status := r.URL.Query().Get("status"); status != "" {
+		q = q.Where("status = ?", status)
+	}
+	if invoiceID := r.URL.Query().Get("invoiceId"); invoiceID != "" {
+		q = q.Where("invoice_id = ?", invoiceID)
+	}
+	var refunds []models.Refund
+	if err := q.Order("created_at DESC").Find(&refunds).Error; err != nil {
+		http.Error(w, "Failed to fetch refunds", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(refunds)
+}
+
+// SettleRefund handles POST /refunds/{refundID}/settle with
+// {"status": "Completed" or "Failed", "method": "Bank transfer"}. Only
+// pending refunds that are not through a payment's gateway can be settled.
+func (h *RefundHandler) SettleRefund(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(chi.URLParam(r, "refundID"))
+	if err != nil {
+		http.Error(w, "Invalid refund ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Status string `json:"status"`
+		Method string `json:"method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if input.Status != receivables.PaymentCompleted && input.Status != receivables.PaymentFailed {
+		http.Error(w, "status must be Completed or Failed", http.StatusBadRequest)
+		return
+	}
+
+	var refund models.Refund
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND tenant_id = ?", id, claims.TenantID).
+			First(&refund).Error; err != nil {
+			return err
+		}
+		if refund.PaymentID != nil {
+			return errGatewayRefund
+		}
+		if refund.Status != receivables.PaymentPending {
+			return fmt.Errorf("%w: it is %s", errRefundNotPending, refund.Status)
+		}
+
+		refund.Status = input.Status
+		if method := strings.TrimSpace(input.Method); method != "" {
+			refund.Method = method
+		}
+		refund.UpdatedAt = time.Now()
+		if err := tx.Model(&models.Refund{}).Where("id = ?", refund.ID).Updates(map[string]interface{}{
+			"status": refund.Status, "method": refund.Method, "updated_at": refund.UpdatedAt,
+		}).Error; err != nil {
+			return err
+		}
+		if err := ledger.PostRefund(tx, &refund); err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "SETTLE_REFUND", "Refund",
+			fmt.Sprintf("Refund %d of %s %s: %s", refund.ID, refund.Amount, refund.Currency, refund.Status))
+	}); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			http.Error(w, "Refund not found", http.StatusNotFound)
+		case errors.Is(err, errGatewayRefund), errors.Is(err, errRefundNotPending):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to settle refund", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(refund)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettleRefund(t *testing.T) {
+	db := testutil.DB(t)
+	inv := openInvoice(t, db, 1, 100)
+	h := NewRefundHandler(db)
+	settle := func(id uint, body string) int {
+		path := fmt.Sprintf("/refunds/%d/settle", id)
+		rr := serveAs(1, h.SettleRefund, "POST", "/refunds/{refundID}/settle", path, body)
+		return rr.Code
+	}
+
+	// What a cancellation owes back is paid out by hand.
+	cancelled := models.Refund{TenantID: 1, InvoiceID: inv.ID, BookingID: 1, Amount: money.FromInt(80),
+		Currency: "USD", Status: "Pending", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(&cancelled).Error)
+	assert.Equal(t, http.StatusBadRequest, settle(cancelled.ID, `{"status": "Pending"}`))
+	assert.Equal(t, http.StatusOK, settle(cancelled.ID, `{"status": "Completed", "method": "Bank transfer"}`))
+	require.NoError(t, db.First(&cancelled, cancelled.ID).Error)
+	assert.Equal(t, "Completed", cancelled.Status)
+	assert.Equal(t, "Bank transfer", cancelled.Method)
+	assert.Equal(t, http.StatusConflict, settle(cancelled.ID, `{"status": "Failed"}`))
+
+	// Gateway refunds are left to the provider's webhooks.
+	payment := models.Payment{TenantID: 1, Amount: money.FromInt(100), Currency: "USD", Status: "Completed"}
+	require.NoError(t, db.Create(&payment).Error)
+	gateway := models.Refund{TenantID: 1, InvoiceID: inv.ID, PaymentID: &payment.ID, Amount: money.FromInt(10),
+		Currency: "USD", Status: "Pending", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(&gateway).Error)
+	assert.Equal(t, http.StatusConflict, settle(gateway.ID, `{"status": "Completed"}`))
+}
@@ -0,0 +1,282 @@
+// handlers/reminders.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/dunning"
+	"travel-agency/internal/models"
+	"travel-agency/internal/utils"
+)
+
+// ReminderHandler manages tenants' payment reminder schedules and the
+// reminders sent for invoices.
+type ReminderHandler struct {
+	DB        *gorm.DB
+	Reminders *dunning.Reminders
+}
+
+func NewReminderHandler(db *gorm.DB, reminders *dunning.Reminders) *ReminderHandler {
+	return &ReminderHandler{DB: db, Reminders: reminders}
+}
+
+// ListRules handles GET /api/reminders/rules, the tenant's schedule by day.
+func (h *ReminderHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var rules []models.ReminderRule
+	if err := h.DB.Where("tenant_id = ?", claims.TenantID).Order("days_after_due").Find(&rules).Error; err != nil {
+		http.Error(w, "Unable to fetch reminder rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// SaveRules handles PUT /api/reminders/rules with {"rules": [{"daysAfterDue",
+// "subject", "body", "active"}]}, e.g. days -3, 0, 7 and 14. It replaces the
+// tenant's schedule; rules for the same day keep their history, so a
+// reminder already sent under one is not sent again. An empty list turns
+// reminders off.
+func (h *ReminderHandler) SaveRules(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var input struct {
+		Rules []struct {
+			DaysAfterDue int    `json:"daysAfterDue"`
+			Subject      string `json:"subject"`
+			Body         string `json:"body"`
+			Active       *bool  `json:"active"` // Defaults to true.
+		} `json:"rules"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	wanted := make([]models.ReminderRule, 0, len(input.Rules))
+	seen := map[int]bool{}
+	for _, in := range input.Rules {
+		rule := models.ReminderRule{DaysAfterDue: in.DaysAfterDue, Subject: in.Subject, Body: in.Body, Active: true}
+		if in.Active != nil {
+			rule.Active = *in.Active
+		}
+		if err := dunning.ValidateRule(&rule); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if seen[rule.DaysAfterDue] {
+			http.Error(w, fmt.Sprintf("More than one rule for day %d", rule.DaysAfterDue), http.StatusBadRequest)
+			return
+		}
+		seen[rule.DaysAfterDue] = true
+		wanted = append(wanted, rule)
+	}
+
+	var rules []models.ReminderRule
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var existing []models.ReminderRule
+		if err := tx.Where("tenant_id = ?", claims.TenantID).Find(&existing).Error; err != nil {
+			return err
+		}
+		byDay := map[int]models.ReminderRule{}
+		for _, e := range existing {
+			byDay[e.DaysAfterDue] = e
+		}
+		now := time.Now()
+		for _, in := range wanted {
+			rule, ok := byDay[in.DaysAfterDue]
+			delete(byDay, in.DaysAfterDue)
+			if !ok {
+				rule = models.ReminderRule{TenantID: claims.TenantID, DaysAfterDue: in.DaysAfterDue}
+			}
+			rule.Subject, rule.Body, rule.Active, rule.UpdatedAt = in.Subject, in.Body, in.Active, now
+			if err := tx.Save(&rule).Error; err != nil {
+				return err
+			}
+		}
+		for _, gone := range byDay {
+			if err := tx.Delete(&gone).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("tenant_id = ?", claims.TenantID).Order("days_after_due").Find(&rules).Error; err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "UPDATE_REMINDER_RULES", "ReminderRule",
+			fmt.Sprintf("Payment reminder schedule set to %d rule(s)", len(rules)))
+	}); err != nil {
+		http.Error(w, "Failed to save reminder rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// invoice loads the tenant's invoice named in the URL. It writes the error
+// response itself and returns nil on failure.
+func (h *ReminderHandler) invoice(w http.ResponseWriter, r *http.Request, tenantID uint) *models.Invoice {
+	invoiceID, err := uuid.Parse(chi.URLParam(r, "invoiceID"))
+	if err != nil {
+		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		return nil
+	}
+	var invoice models.Invoice
+	if err := h.DB.Where("id = ? AND tenant_id = ?", invoiceID, tenantID).First(&invoice).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+			return nil
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil
+	}
+	return &invoice
+}
+
+// ListInvoiceReminders handles GET /api/invoices/{invoiceID}/reminders,
+// newest first.
+func (h *ReminderHandler) ListInvoiceReminders(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+	invoice := h.invoice(w, r, claims.TenantID)
+	if invoice == nil {
+		return
+	}
+
+	var reminders []models.InvoiceReminder
+	if err := h.DB.Where("invoice_id = ?", invoice.ID).Order("created_at DESC, id DESC").
+		Find(&reminders).Error; err != nil {
+		http.Error(w, "Unable to fetch reminders", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reminders)
+}
+
+// UpdateInvoiceReminders handles PUT /api/invoices/{invoiceID}/reminders
+// with {"contactEmail", "remindersOff"}. Unlike the rest of an invoice these
+// can be changed after it is issued.
+func (h *ReminderHandler) UpdateInvoiceReminders(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+	invoice := h.invoice(w, r, claims.TenantID)
+	if invoice == nil {
+		return
+	}
+
+	var input struct {
+		ContactEmail *string `json:"contactEmail"`
+		RemindersOff *bool   `json:"remindersOff"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	updates := map[string]interface{}{"updated_at": time.Now()}
+	if input.ContactEmail != nil {
+		if *input.ContactEmail != "" {
+			if _, err := mail.ParseAddress(*input.ContactEmail); err != nil {
+				http.Error(w, "Invalid contact email", http.StatusBadRequest)
+				return
+			}
+		}
+		invoice.ContactEmail = *input.ContactEmail
+		updates["contact_email"] = invoice.ContactEmail
+	}
+	if input.RemindersOff != nil {
+		invoice.RemindersOff = *input.RemindersOff
+		updates["reminders_off"] = invoice.RemindersOff
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Invoice{}).Where("id = ?", invoice.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "UPDATE_INVOICE_REMINDERS", "Invoice",
+			fmt.Sprintf("Reminders for invoice %s: contact %q, off %t", invoiceReference(*invoice),
+				invoice.ContactEmail, invoice.RemindersOff))
+	}); err != nil {
+		http.Error(w, "Failed to update invoice", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invoice)
+}
+
+// SendInvoiceReminder handles POST /api/invoices/{invoiceID}/reminders with
+// an optional {"email"}: it sends a reminder now, in the default wording,
+// to the email given or the invoice's contact.
+func (h *ReminderHandler) SendInvoiceReminder(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+	invoice := h.invoice(w, r, claims.TenantID)
+	if invoice == nil {
+		return
+	}
+
+	var input struct {
+		Email string `json:"email"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+	}
+
+	reminder, err := h.Reminders.Send(invoice, nil, input.Email, claims.UserID, time.Now())
+	switch {
+	case errors.Is(err, dunning.ErrNoContact):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, dunning.ErrNothingOwed):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case reminder == nil:
+		http.Error(w, "Failed to send reminder", http.StatusInternalServerError)
+		return
+	}
+	if err := utils.LogAction(h.DB, claims.TenantID, claims.UserID, "SEND_PAYMENT_REMINDER", "Invoice",
+		fmt.Sprintf("Payment reminder for invoice %s to %s: %s", invoiceReference(*invoice), reminder.SentTo, reminder.Status)); err != nil {
+		log.Printf("Warning: failed to log reminder for invoice %s: %v", invoice.ID, err)
+	}
+
+	status := http.StatusCreated
+	if err != nil {
+		status = http.StatusBadGateway // The email could not be sent; the attempt is logged.
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(reminder)
+}
@@ -6,6 +6,9 @@ import (
 	"net/http"
 
 	"gorm.io/gorm"
+	"travel-agency/internal/auth"
+	"travel-agency/internal/fx"
+	"travel-agency/internal/money"
 )
 
 type ReportingHandler struct {
@@ -18,20 +21,34 @@ func NewReportingHandler(db *gorm.DB) *ReportingHandler {
 
 // SalesReport represents aggregated sales data.
 type SalesReport struct {
-	TotalRevenue float64 `json:"total_revenue"`
-	TotalTrips   int     `json:"total_trips"`
+	TotalRevenue money.Amount `json:"total_revenue"`
+	TotalTrips   int          `json:"total_trips"`
+	Currency     string       `json:"currency"` // The tenant's base currency.
 }
 
-// GetSalesReport returns a simple aggregated report.
+// GetSalesReport returns a simple aggregated report. Invoices in other
+// currencies are converted at the rate recorded when they were issued.
 func (h *ReportingHandler) GetSalesReport(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
 	var report SalesReport
-	// Example raw query: adjust per your schema.
-	row := h.DB.Raw(`SELECT COALESCE(SUM(amount), 0) as total_revenue, COUNT(*) as total_trips FROM invoices WHERE status = 'Paid' AND tenant_id = ?`,
-		r.Context().Value("tenantID")).Row()
+	var err error
+	if report.Currency, err = fx.BaseCurrency(h.DB, claims.TenantID); err != nil {
+		http.Error(w, "Failed to generate report", http.StatusInternalServerError)
+		return
+	}
+	row := h.DB.Raw(`SELECT COALESCE(SUM(`+fx.RatedSQL("amount", "exchange_rate")+`), 0) as total_revenue, COUNT(*) as total_trips
+		FROM invoices WHERE status = 'Paid' AND invoice_type = 'sale' AND tenant_id = ?`,
+		claims.TenantID).Row()
 	if err := row.Scan(&report.TotalRevenue, &report.TotalTrips); err != nil {
 		http.Error(w, "Failed to generate report", http.StatusInternalServerError)
 		return
 	}
+	report.TotalRevenue = report.TotalRevenue.Round(report.Currency)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(report)
@@ -0,0 +1,247 @@
+// internal/handlers/tax_rules.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/invoicing"
+	"travel-agency/internal/models"
+	"travel-agency/internal/tax"
+	"travel-agency/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type TaxHandler struct {
+	DB *gorm.DB
+}
+
+func NewTaxHandler(db *gorm.DB) *TaxHandler {
+	return &TaxHandler{DB: db}
+}
+
+// ListTaxRules handles GET /tax/rules
+func (h *TaxHandler) ListTaxRules(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var rules []models.TaxRule
+	if err := h.DB.Preload("Components", func(tx *gorm.DB) *gorm.DB { return tx.Order("position, id") }).
+		Where("tenant_id = ?", claims.TenantID).Order("priority DESC, id").Find(&rules).Error; err != nil {
+		http.Error(w, "Unable to fetch tax rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// CreateTaxRule handles POST /tax/rules, e.g. {"name": "GST hotels intra-state",
+// "serviceType": "Hotel", "placeOfSupply": "intra-state", "components":
+// [{"name": "CGST", "rate": 6}, {"name": "SGST", "rate": 6}]}.
+func (h *TaxHandler) CreateTaxRule(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	var rule models.TaxRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if err := tax.ValidateRule(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule.ID = 0
+	rule.TenantID = claims.TenantID
+	for i := range rule.Components {
+		rule.Components[i].ID = 0
+		rule.Components[i].TaxRuleID = 0
+	}
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&rule).Error; err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "CREATE_TAX_RULE", "TaxRule", rule.Name)
+	}); err != nil {
+		http.Error(w, "Failed to create tax rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// UpdateTaxRule handles PUT /tax/rules/{ruleID}. The components given replace
+// the rule's.
+func (h *TaxHandler) UpdateTaxRule(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	ruleID, err := strconv.Atoi(chi.URLParam(r, "ruleID"))
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	var rule models.TaxRule
+	if err := h.DB.Where("id = ? AND tenant_id = ?", ruleID, claims.TenantID).First(&rule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Tax rule not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var updated models.TaxRule
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if err := tax.ValidateRule(&updated); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule.Name = updated.Name
+	rule.ServiceType = updated.ServiceType
+	rule.PlaceOfSupply = updated.PlaceOfSupply
+	rule.CustomerType = updated.CustomerType
+	rule.Priority = updated.Priority
+	rule.Disabled = updated.Disabled
+	rule.UpdatedAt = time.Now()
+	rule.Components = updated.Components
+	for i := range rule.Components {
+		rule.Components[i].ID = 0
+		rule.Components[i].TaxRuleID = rule.ID
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Components").Save(&rule).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tax_rule_id = ?", rule.ID).Delete(&models.TaxRuleComponent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&rule.Components).Error; err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "UPDATE_TAX_RULE", "TaxRule", rule.Name)
+	}); err != nil {
+		http.Error(w, "Unable to update tax rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// DeleteTaxRule handles DELETE /tax/rules/{ruleID}. Invoices keep the taxes
+// already worked out from it.
+func (h *TaxHandler) DeleteTaxRule(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	ruleID, err := strconv.Atoi(chi.URLParam(r, "ruleID"))
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	var rule models.TaxRule
+	if err := h.DB.Where("id = ? AND tenant_id = ?", ruleID, claims.TenantID).First(&rule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Tax rule not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tax_rule_id = ?", rule.ID).Delete(&models.TaxRuleComponent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&rule).Error; err != nil {
+			return err
+		}
+		return utils.LogAction(tx, claims.TenantID, claims.UserID, "DELETE_TAX_RULE", "TaxRule", rule.Name)
+	}); err != nil {
+		http.Error(w, "Failed to delete tax rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BookingTaxes handles GET /bookings/{bookingID}/taxes?placeOfSupply=&customerType=:
+// the taxes the tenant's engine would charge on the booking, worked out as
+// the line of a sale invoice would be.
+func (h *TaxHandler) BookingTaxes(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	bookingID, err := strconv.Atoi(chi.URLParam(r, "bookingID"))
+	if err != nil {
+		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+		return
+	}
+	var booking models.Booking
+	if err := h.DB.Where("id = ? AND tenant_id = ?", bookingID, claims.TenantID).First(&booking).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Booking not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	id := booking.ID
+	inv := models.Invoice{
+		TenantID:      claims.TenantID,
+		InvoiceType:   "sale",
+		IssueDate:     utils.TruncateDay(time.Now()),
+		Currency:      booking.Currency,
+		PlaceOfSupply: r.URL.Query().Get("placeOfSupply"),
+		CustomerType:  r.URL.Query().Get("customerType"),
+		Items:         []models.InvoiceItem{{BookingID: &id}},
+	}
+	if err := invoicing.ResolveSources(h.DB, claims.TenantID, inv.Items); err != nil {
+		http.Error(w, "Unable to price booking", http.StatusInternalServerError)
+		return
+	}
+	if err := invoicing.ApplyTaxes(h.DB, &inv); err != nil {
+		http.Error(w, "Unable to work out taxes", http.StatusInternalServerError)
+		return
+	}
+	if err := invoicing.ComputeTotals(&inv); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inv.Items[0])
+}
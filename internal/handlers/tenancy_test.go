@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"travel-agency/internal/accounting"
+	"travel-agency/internal/auth"
+	"travel-agency/internal/gateways"
+	"travel-agency/internal/ledger"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/receivables"
+	"travel-agency/internal/suppliers"
+	"travel-agency/internal/testutil"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// serveAs serves one request to h, mounted at pattern, as a user of the
+// tenant.
+func serveAs(tenantID uint, h http.HandlerFunc, method, pattern, path, body string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.MethodFunc(method, pattern, h)
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), auth.ContextKeyClaims, &auth.Claims{TenantID: tenantID, UserID: 1}))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	return rr
+}
+
+// scopedRoute is a route that looks a resource up by ID. seed creates the
+// resource for tenant 2 and returns the handler and the request path and
+// body that reach it.
+type scopedRoute struct {
+	name    string
+	method  string
+	pattern string
+	seed    func(t *testing.T, db *gorm.DB) (h http.HandlerFunc, path, body string)
+}
+
+var scopedRoutes = []scopedRoute{
+	{"vendor scorecard", "GET", "/vendors/{vendorID}/scorecard", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		vendor := models.Vendor{TenantID: 2, Name: "Other agency's hotel"}
+		require.NoError(t, db.Create(&vendor).Error)
+		return NewVendorHandler(db).Scorecard, fmt.Sprintf("/vendors/%d/scorecard", vendor.ID), ""
+	}},
+	{"markup rule", "DELETE", "/pricing/markup-rules/{ruleID}", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		rule := models.MarkupRule{TenantID: 2, Name: "Other agency's hotels", MarkupType: "percent", MarkupValue: 10}
+		require.NoError(t, db.Create(&rule).Error)
+		return NewPricingHandler(db).DeleteMarkupRule, fmt.Sprintf("/pricing/markup-rules/%d", rule.ID), ""
+	}},
+	{"itinerary version", "GET", "/itineraries/{itineraryID}/versions/{version}", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		itin := otherTenantItinerary(t, db, nil)
+		return NewItineraryHandler(db).GetVersion, fmt.Sprintf("/itineraries/%d/versions/1", itin.ID), ""
+	}},
+	{"itinerary update", "PUT", "/itineraries/{itineraryID}", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		itin := otherTenantItinerary(t, db, nil)
+		return NewItineraryHandler(db).UpdateItinerary, fmt.Sprintf("/itineraries/%d", itin.ID),
+			`{"name": "Renamed", "status": "Draft", "items": []}`
+	}},
+	{"itinerary travel document", "GET", "/itineraries/{itineraryID}/pdf", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		itin := otherTenantItinerary(t, db, nil)
+		return NewItineraryHandler(db).DownloadItineraryPDF, fmt.Sprintf("/itineraries/%d/pdf", itin.ID), ""
+	}},
+	{"itinerary template", "GET", "/itinerary-templates/{templateID}", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		tmpl := models.ItineraryTemplate{TenantID: 2, Name: "Other agency's tour", DurationDays: 3}
+		require.NoError(t, db.Create(&tmpl).Error)
+		return NewItineraryTemplateHandler(db).GetTemplate, fmt.Sprintf("/itinerary-templates/%d", tmpl.ID), ""
+	}},
+	{"itinerary clone", "POST", "/itineraries/{itineraryID}/clone", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		itin := otherTenantItinerary(t, db, nil)
+		return NewItineraryHandler(db).CloneItinerary, fmt.Sprintf("/itineraries/%d/clone", itin.ID), "{}"
+	}},
+	{"itinerary calendar", "GET", "/itineraries/{itineraryID}/calendar.ics", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		itin := otherTenantItinerary(t, db, nil)
+		h := NewCalendarHandler(db, "calendar-secret", "http://agency.test")
+		return h.ItineraryCalendar, fmt.Sprintf("/itineraries/%d/calendar.ics", itin.ID), ""
+	}},
+	{"booking cancellation", "POST", "/bookings/{bookingID}/cancel", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		vendor := models.Vendor{TenantID: 2, Name: "Other agency's hotel"}
+		require.NoError(t, db.Create(&vendor).Error)
+		booking := models.Booking{TenantID: 2, VendorID: vendor.ID, Status: "Pending", Currency: "USD", CostCurrency: "USD"}
+		require.NoError(t, db.Create(&booking).Error)
+		return NewBookingHandler(db, nil).CancelBooking, fmt.Sprintf("/bookings/%d/cancel", booking.ID), "{}"
+	}},
+	{"refund settlement", "POST", "/refunds/{refundID}/settle", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		inv := openInvoice(t, db, 2, 100)
+		refund := models.Refund{TenantID: 2, InvoiceID: inv.ID, Amount: money.FromInt(20), Currency: "USD", Status: "Pending"}
+		require.NoError(t, db.Create(&refund).Error)
+		return NewRefundHandler(db).SettleRefund, fmt.Sprintf("/refunds/%d/settle", refund.ID), `{"status": "Completed"}`
+	}},
+	{"supplier reservation", "GET", "/bookings/{bookingID}/supplier", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		gds := suppliers.NewMockGDS()
+		registry := suppliers.NewRegistry()
+		registry.Register("Flight", gds)
+		offers, err := gds.Search(context.Background(), suppliers.SearchRequest{Product: "DEL-BOM", Pax: 1})
+		require.NoError(t, err)
+		res, err := gds.Hold(context.Background(), offers[0].OfferID, 1)
+		require.NoError(t, err)
+
+		vendor := models.Vendor{TenantID: 2, Name: "Other agency's airline", Type: "Flight"}
+		require.NoError(t, db.Create(&vendor).Error)
+		booking := models.Booking{TenantID: 2, VendorID: vendor.ID, Status: "Pending", OfferID: res.OfferID, BookingRef: res.PNR}
+		require.NoError(t, db.Create(&booking).Error)
+		return NewBookingHandler(db, registry).GetSupplierReservation, fmt.Sprintf("/bookings/%d/supplier", booking.ID), ""
+	}},
+	{"vendor allotment", "POST", "/vendors/{vendorID}/inventory", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		vendor := models.Vendor{TenantID: 2, Name: "Other agency's hotel"}
+		require.NoError(t, db.Create(&vendor).Error)
+		return NewVendorHandler(db).SetAllotment, fmt.Sprintf("/vendors/%d/inventory", vendor.ID),
+			`{"product": "Deluxe Room", "from": "2030-01-01", "to": "2030-01-03", "capacity": 5}`
+	}},
+	{"vendor inventory", "GET", "/vendors/{vendorID}/inventory", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		vendor := models.Vendor{TenantID: 2, Name: "Other agency's hotel"}
+		require.NoError(t, db.Create(&vendor).Error)
+		return NewVendorHandler(db).ListInventory, fmt.Sprintf("/vendors/%d/inventory?from=2030-01-01", vendor.ID), ""
+	}},
+	{"vendor contract", "GET", "/vendors/{vendorID}/contracts/{contractID}", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		vendor := models.Vendor{TenantID: 2, Name: "Other agency's hotel"}
+		require.NoError(t, db.Create(&vendor).Error)
+		contract := models.VendorContract{TenantID: 2, VendorID: vendor.ID, Reference: "2030 rates", Currency: "USD"}
+		require.NoError(t, db.Create(&contract).Error)
+		return NewVendorHandler(db).GetContract, fmt.Sprintf("/vendors/%d/contracts/%d", vendor.ID, contract.ID), ""
+	}},
+	{"payment run", "POST", "/payables/runs/{runID}/cancel", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		run := models.PaymentRun{TenantID: 2, Method: "Bank Transfer", Status: models.PaymentRunScheduled}
+		require.NoError(t, db.Create(&run).Error)
+		return NewPayablesHandler(db).CancelPaymentRun, fmt.Sprintf("/payables/runs/%d/cancel", run.ID), ""
+	}},
+	{"invoice issue", "POST", "/invoices/{invoiceID}/issue", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		inv := models.Invoice{ID: uuid.New(), TenantID: 2, InvoiceType: "sale", Status: "Draft", Currency: "USD",
+			Items: []models.InvoiceItem{{Description: "Tour package", Quantity: 1, UnitPrice: money.FromInt(100)}}}
+		require.NoError(t, db.Create(&inv).Error)
+		return NewInvoiceHandler(db).IssueInvoice, fmt.Sprintf("/invoices/%s/issue", inv.ID), ""
+	}},
+	{"itinerary invoicing", "POST", "/itineraries/{itineraryID}/invoice", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		itin := otherTenantItinerary(t, db, []models.ItineraryItem{{Day: 1, Type: "Hotel", Description: "Beach resort",
+			Cost: money.FromInt(100), CostCurrency: "USD", Price: money.FromInt(130)}})
+		return NewItineraryHandler(db).InvoiceItinerary, fmt.Sprintf("/itineraries/%d/invoice", itin.ID), `{"draft": true}`
+	}},
+	{"payment allocation", "POST", "/payments/{paymentID}/allocations", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		inv := openInvoice(t, db, 2, 100)
+		payment := models.Payment{TenantID: 2, Amount: money.FromInt(100), Unallocated: money.FromInt(100), Currency: "USD",
+			Status: receivables.PaymentCompleted}
+		require.NoError(t, db.Create(&payment).Error)
+		return NewPaymentHandler(db, nil).AllocatePayment, fmt.Sprintf("/payments/%d/allocations", payment.ID),
+			fmt.Sprintf(`{"allocations": [{"invoiceId": %q, "amount": 100}]}`, inv.ID)
+	}},
+	{"payment capture", "POST", "/payments/{paymentID}/capture", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		mock := gateways.NewMock("mock-secret", "http://pay.test")
+		registry := gateways.NewRegistry()
+		registry.Register("mock", mock)
+		intent, err := mock.CreateIntent(context.Background(), gateways.IntentRequest{Amount: money.FromInt(100), Currency: "USD", ManualCapture: true})
+		require.NoError(t, err)
+		require.NoError(t, mock.Authorize(intent.ID))
+
+		payment := models.Payment{TenantID: 2, Amount: money.FromInt(100), Currency: "USD", Status: receivables.PaymentPending,
+			Provider: "mock", ProviderRef: intent.ID}
+		require.NoError(t, db.Create(&payment).Error)
+		return NewPaymentHandler(db, registry).CapturePayment, fmt.Sprintf("/payments/%d/capture", payment.ID), ""
+	}},
+	{"payment link", "POST", "/invoices/{invoiceID}/payment-link", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		inv := openInvoice(t, db, 2, 100)
+		h := NewPaymentLinkHandler(db, gateways.NewRegistry(), nil, testLinkSecret, "http://agency.test")
+		return h.CreatePaymentLink, fmt.Sprintf("/invoices/%s/payment-link", inv.ID), ""
+	}},
+	{"exchange rate", "DELETE", "/exchange-rates/{rateID}", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		rate := models.ExchangeRate{TenantID: 2, FromCurrency: "USD", ToCurrency: "INR", Rate: 83}
+		require.NoError(t, db.Create(&rate).Error)
+		return NewExchangeRateHandler(db).DeleteRate, fmt.Sprintf("/exchange-rates/%d", rate.ID), ""
+	}},
+	{"lead", "GET", "/leads/{leadID}", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		lead := models.Lead{TenantID: 2, CustomerName: "Other agency's customer", Budget: money.FromInt(5000), Currency: "USD"}
+		require.NoError(t, db.Create(&lead).Error)
+		return NewLeadsHandler(db).GetLead, fmt.Sprintf("/leads/%d", lead.ID), ""
+	}},
+	{"lead update", "PUT", "/leads/{leadID}", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		lead := models.Lead{TenantID: 2, CustomerName: "Other agency's customer", Budget: money.FromInt(5000), Currency: "USD"}
+		require.NoError(t, db.Create(&lead).Error)
+		return NewLeadsHandler(db).UpdateLead, fmt.Sprintf("/leads/%d", lead.ID), `{"name": "Renamed", "budget": 6000}`
+	}},
+	{"credit note", "GET", "/credit-notes/{creditNoteID}", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		inv := openInvoice(t, db, 2, 100)
+		note := models.CreditNote{ID: uuid.New(), TenantID: 2, InvoiceID: inv.ID, IssueDate: inv.IssueDate,
+			Amount: money.FromInt(20), Currency: "USD", Reason: "Room downgrade"}
+		require.NoError(t, db.Create(&note).Error)
+		return NewCreditNoteHandler(db).GetCreditNote, fmt.Sprintf("/credit-notes/%s", note.ID), ""
+	}},
+	{"invoice void", "POST", "/invoices/{invoiceID}/void", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		inv := openInvoice(t, db, 2, 100)
+		return NewInvoiceHandler(db).VoidInvoice, fmt.Sprintf("/invoices/%s/void", inv.ID), `{"reason": "Trip cancelled"}`
+	}},
+	{"journal entry reversal", "POST", "/ledger/entries/{entryID}/reverse", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		accounts, err := ledger.Accounts(db, 2)
+		require.NoError(t, err)
+		id := map[string]uint{}
+		for _, a := range accounts {
+			id[a.Code] = a.ID
+		}
+		entry := models.JournalEntry{TenantID: 2, SourceType: ledger.Manual, Memo: "Capital", Lines: []models.JournalLine{
+			{AccountID: id[ledger.Cash], Debit: money.FromInt(100)}, {AccountID: id[ledger.Equity], Credit: money.FromInt(100)}}}
+		require.NoError(t, ledger.Post(db, &entry))
+		return NewLedgerHandler(db).ReverseEntry, fmt.Sprintf("/ledger/entries/%d/reverse", entry.ID), ""
+	}},
+	{"accounting export", "GET", "/accounting/exports/{exportID}/download", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		export := models.AccountingExport{TenantID: 2, Format: models.ExportXeroCSV, Filename: "export.csv", ContentType: "text/csv", Content: []byte("a,b\n")}
+		require.NoError(t, db.Create(&export).Error)
+		return NewAccountingHandler(db).DownloadExport, fmt.Sprintf("/accounting/exports/%d/download", export.ID), ""
+	}},
+	{"accounting mapping", "DELETE", "/accounting/mappings/{mappingID}", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		mapping := models.AccountingMapping{TenantID: 2, Kind: models.MappingAccount, Key: accounting.AccountSales, Value: "4000"}
+		require.NoError(t, db.Create(&mapping).Error)
+		return NewAccountingHandler(db).DeleteMapping, fmt.Sprintf("/accounting/mappings/%d", mapping.ID), ""
+	}},
+	{"invoice reminders", "PUT", "/invoices/{invoiceID}/reminders", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		inv := openInvoice(t, db, 2, 100)
+		return NewReminderHandler(db, nil).UpdateInvoiceReminders, fmt.Sprintf("/invoices/%s/reminders", inv.ID), `{"remindersOff": true}`
+	}},
+	{"recurring invoice", "POST", "/recurring-invoices/{recurringID}/pause", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		ri := models.RecurringInvoice{TenantID: 2, Name: "Other agency's retainer", Description: "Travel desk retainer",
+			Amount: money.FromInt(1000), Currency: "USD", Cadence: models.CadenceMonthly, Status: models.RecurringActive}
+		require.NoError(t, db.Create(&ri).Error)
+		return NewRecurringInvoiceHandler(db).PauseRecurringInvoice, fmt.Sprintf("/recurring-invoices/%d/pause", ri.ID), ""
+	}},
+	{"customer statement", "GET", "/customers/{customerID}/statement", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		customerID := uuid.New()
+		inv := openInvoice(t, db, 2, 100)
+		require.NoError(t, db.Model(&inv).Updates(map[string]interface{}{"customer_id": customerID, "issued_at": inv.IssueDate}).Error)
+		return NewReceivablesHandler(db).CustomerStatement, fmt.Sprintf("/customers/%s/statement", customerID), ""
+	}},
+	{"tax rule update", "PUT", "/tax/rules/{ruleID}", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		rule := models.TaxRule{TenantID: 2, Name: "Other agency's GST", Components: []models.TaxRuleComponent{{Name: "GST", Rate: 5}}}
+		require.NoError(t, db.Create(&rule).Error)
+		return NewTaxHandler(db).UpdateTaxRule, fmt.Sprintf("/tax/rules/%d", rule.ID), `{"name": "GST", "components": [{"name": "GST", "rate": 12}]}`
+	}},
+	{"tax rule", "DELETE", "/tax/rules/{ruleID}", func(t *testing.T, db *gorm.DB) (http.HandlerFunc, string, string) {
+		rule := models.TaxRule{TenantID: 2, Name: "Other agency's GST", Components: []models.TaxRuleComponent{{Name: "GST", Rate: 5}}}
+		require.NoError(t, db.Create(&rule).Error)
+		return NewTaxHandler(db).DeleteTaxRule, fmt.Sprintf("/tax/rules/%d", rule.ID), ""
+	}},
+}
+
+// TestTenantScoped checks that a resource of tenant 2 is not found by
+// tenant 1, and is found by tenant 2 itself.
+func TestTenantScoped(t *testing.T) {
+	for _, route := range scopedRoutes {
+		t.Run(route.name, func(t *testing.T) {
+			db := testutil.DB(t)
+			h, path, body := route.seed(t, db)
+			rr := serveAs(1, h, route.method, route.pattern, path, body)
+			assert.Equal(t, http.StatusNotFound, rr.Code, "%s %s as another tenant: %s", route.method, path, rr.Body.String())
+			rr = serveAs(2, h, route.method, route.pattern, path, body)
+			assert.NotEqual(t, http.StatusNotFound, rr.Code, "%s %s as its tenant: %s", route.method, path, rr.Body.String())
+		})
+	}
+}
+
+// otherTenantItinerary creates an itinerary of tenant 2.
+func otherTenantItinerary(t *testing.T, db *gorm.DB, items []models.ItineraryItem) models.Itinerary {
+	t.Helper()
+	itin := models.Itinerary{TenantID: 2, Name: "Other agency's trip", Currency: "USD", Status: "Planned"}
+	require.NoError(t, createItinerary(db, &itin, items, 1))
+	return itin
+}
+
+func TestAllocatePaymentToAnotherTenantsInvoice(t *testing.T) {
+	db := testutil.DB(t)
+	inv := openInvoice(t, db, 2, 100)
+	payment := models.Payment{TenantID: 1, Amount: money.FromInt(100), Unallocated: money.FromInt(100), Currency: "USD",
+		Status: receivables.PaymentCompleted}
+	require.NoError(t, db.Create(&payment).Error)
+
+	body := fmt.Sprintf(`{"allocations": [{"invoiceId": %q, "amount": 100}]}`, inv.ID)
+	rr := serveAs(1, NewPaymentHandler(db, nil).AllocatePayment, "POST", "/payments/{paymentID}/allocations",
+		fmt.Sprintf("/payments/%d/allocations", payment.ID), body)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+}
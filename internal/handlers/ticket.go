@@ -39,11 +39,17 @@ func (h *TicketHandler) CreateTicket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.BookingID != nil && !h.tenantBooking(*req.BookingID, claims.TenantID) {
+		http.Error(w, "Booking not found", http.StatusBadRequest)
+		return
+	}
+
 	ticket := models.Ticket{
 		TenantID:    claims.TenantID,
 		Subject:     req.Subject,
 		Description: req.Description,
 		CustomerID:  req.CustomerID,
+		BookingID:   req.BookingID,
 		AssignedTo:  claims.UserID, // assign to creator by default
 		Priority:    req.Priority,
 	}
@@ -131,6 +137,13 @@ func (h *TicketHandler) UpdateTicket(w http.ResponseWriter, r *http.Request) {
 	if req.AssignedTo != nil {
 		updates["assigned_to"] = *req.AssignedTo
 	}
+	if req.BookingID != nil {
+		if !h.tenantBooking(*req.BookingID, claims.TenantID) {
+			http.Error(w, "Booking not found", http.StatusBadRequest)
+			return
+		}
+		updates["booking_id"] = *req.BookingID
+	}
 	if req.Status != nil {
 		updates["status"] = *req.Status
 	}
@@ -169,3 +182,10 @@ func (h *TicketHandler) DeleteTicket(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// tenantBooking reports whether the booking exists within the tenant.
+func (h *TicketHandler) tenantBooking(bookingID, tenantID uint) bool {
+	var count int64
+	h.DB.Model(&models.Booking{}).Where("id = ? AND tenant_id = ?", bookingID, tenantID).Count(&count)
+	return count > 0
+}
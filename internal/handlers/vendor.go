@@ -124,3 +124,106 @@ func (h *VendorHandler) UpdateVendor(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(vendor)
 }
+
+// GetCancellationPolicy returns the vendor's cancellation tiers.
+func (h *VendorHandler) GetCancellationPolicy(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	vendorID, err := strconv.Atoi(chi.URLParam(r, "vendorID"))
+	if err != nil {
+		http.Error(w, "Invalid vendor ID", http.StatusBadRequest)
+		return
+	}
+
+	var rules []models.CancellationRule
+	if err := h.DB.Where("vendor_id = ? AND tenant_id = ?", vendorID, claims.TenantID).
+		Order("min_days_before DESC").
+		Find(&rules).Error; err != nil {
+		http.Error(w, "Unable to fetch cancellation policy", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// SetCancellationPolicy replaces the vendor's cancellation tiers.
+func (h *VendorHandler) SetCancellationPolicy(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	vendorID, err := strconv.Atoi(chi.URLParam(r, "vendorID"))
+	if err != nil {
+		http.Error(w, "Invalid vendor ID", http.StatusBadRequest)
+		return
+	}
+
+	var vendor models.Vendor
+	if err := h.DB.Where("id = ? AND tenant_id = ?", vendorID, claims.TenantID).First(&vendor).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Vendor not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var rules []models.CancellationRule
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	for i := range rules {
+		if rules[i].PenaltyPercent < 0 || rules[i].PenaltyPercent > 100 || rules[i].FlatFee < 0 {
+			http.Error(w, "penaltyPercent must be 0-100 and flatFee non-negative", http.StatusBadRequest)
+			return
+		}
+		rules[i].ID = 0
+		rules[i].TenantID = claims.TenantID
+		rules[i].VendorID = vendor.ID
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("vendor_id = ?", vendor.ID).Delete(&models.CancellationRule{}).Error; err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+		return tx.Create(&rules).Error
+	}); err != nil {
+		http.Error(w, "Unable to update cancellation policy", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// findVendor loads the vendor named by the URL, scoped to the tenant. It
+// writes the error response itself and returns nil on failure.
+func (h *VendorHandler) findVendor(w http.ResponseWriter, r *http.Request, tenantID uint) *models.Vendor {
+	vendorID, err := strconv.Atoi(chi.URLParam(r, "vendorID"))
+	if err != nil {
+		http.Error(w, "Invalid vendor ID", http.StatusBadRequest)
+		return nil
+	}
+
+	var vendor models.Vendor
+	if err := h.DB.Where("id = ? AND tenant_id = ?", vendorID, tenantID).First(&vendor).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Vendor not found", http.StatusNotFound)
+			return nil
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil
+	}
+	return &vendor
+}
@@ -0,0 +1,298 @@
+// internal/handlers/vendor_contracts.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/inventory"
+	"travel-agency/internal/models"
+	"travel-agency/internal/pricing"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// validContract checks the validity period and that every rate card season
+// is well formed.
+func validContract(c models.VendorContract) string {
+	if c.ValidFrom.IsZero() || c.ValidTo.IsZero() || c.ValidTo.Before(c.ValidFrom) {
+		return "validFrom and validTo are required and validTo must not be before validFrom"
+	}
+	for _, rate := range c.Rates {
+		if rate.Product == "" {
+			return "every rate needs a product"
+		}
+		if rate.StartDate.IsZero() || rate.EndDate.IsZero() || rate.EndDate.Before(rate.StartDate) {
+			return "rate startDate and endDate are required and endDate must not be before startDate"
+		}
+		if rate.Cost < 0 || rate.Occupancy < 0 {
+			return "rate cost and occupancy must not be negative"
+		}
+	}
+	return ""
+}
+
+// normalizeContract pins the contract and its rates to the tenant and vendor
+// and stores dates as whole days.
+func normalizeContract(c *models.VendorContract, tenantID, vendorID uint) {
+	c.TenantID = tenantID
+	c.VendorID = vendorID
+	c.ValidFrom = inventory.Day(c.ValidFrom)
+	c.ValidTo = inventory.Day(c.ValidTo)
+	for i := range c.Rates {
+		rate := &c.Rates[i]
+		rate.ID = 0
+		rate.ContractID = c.ID
+		rate.TenantID = tenantID
+		rate.VendorID = vendorID
+		rate.StartDate = inventory.Day(rate.StartDate)
+		rate.EndDate = inventory.Day(rate.EndDate)
+	}
+}
+
+// findContract loads a vendor contract (with rates) by URL param. It writes
+// the error response itself and returns nil on failure.
+func (h *VendorHandler) findContract(w http.ResponseWriter, r *http.Request, vendor *models.Vendor) *models.VendorContract {
+	id, err := strconv.Atoi(chi.URLParam(r, "contractID"))
+	if err != nil {
+		http.Error(w, "Invalid contract ID", http.StatusBadRequest)
+		return nil
+	}
+	var contract models.VendorContract
+	if err := h.DB.Preload("Rates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("product, start_date")
+	}).Where("id = ? AND vendor_id = ? AND tenant_id = ?", id, vendor.ID, vendor.TenantID).
+		First(&contract).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Contract not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return nil
+	}
+	return &contract
+}
+
+// ListContracts handles GET /vendors/{vendorID}/contracts
+func (h *VendorHandler) ListContracts(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	vendor := h.findVendor(w, r, claims.TenantID)
+	if vendor == nil {
+		return
+	}
+
+	var contracts []models.VendorContract
+	if err := h.DB.Preload("Rates").
+		Where("vendor_id = ? AND tenant_id = ?", vendor.ID, claims.TenantID).
+		Order("valid_from DESC").
+		Find(&contracts).Error; err != nil {
+		http.Error(w, "Unable to fetch contracts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contracts)
+}
+
+// CreateContract handles POST /vendors/{vendorID}/contracts; rates are
+// created with the contract.
+func (h *VendorHandler) CreateContract(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	vendor := h.findVendor(w, r, claims.TenantID)
+	if vendor == nil {
+		return
+	}
+
+	var contract models.VendorContract
+	if err := json.NewDecoder(r.Body).Decode(&contract); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if msg := validContract(contract); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	contract.ID = 0
+	normalizeContract(&contract, claims.TenantID, vendor.ID)
+	contract.CreatedAt = time.Now()
+	contract.UpdatedAt = time.Now()
+
+	if err := h.DB.Create(&contract).Error; err != nil {
+		http.Error(w, "Unable to create contract", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(contract)
+}
+
+// GetContract handles GET /vendors/{vendorID}/contracts/{contractID}
+func (h *VendorHandler) GetContract(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	vendor := h.findVendor(w, r, claims.TenantID)
+	if vendor == nil {
+		return
+	}
+	contract := h.findContract(w, r, vendor)
+	if contract == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contract)
+}
+
+// UpdateContract handles PUT /vendors/{vendorID}/contracts/{contractID};
+// the rate cards are replaced.
+func (h *VendorHandler) UpdateContract(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	vendor := h.findVendor(w, r, claims.TenantID)
+	if vendor == nil {
+		return
+	}
+	contract := h.findContract(w, r, vendor)
+	if contract == nil {
+		return
+	}
+
+	var updated models.VendorContract
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if msg := validContract(updated); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	updated.ID = contract.ID
+	normalizeContract(&updated, claims.TenantID, vendor.ID)
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		contract.Reference = updated.Reference
+		contract.ValidFrom = updated.ValidFrom
+		contract.ValidTo = updated.ValidTo
+		contract.PaymentTerms = updated.PaymentTerms
+		contract.Currency = updated.Currency
+		contract.Notes = updated.Notes
+		contract.UpdatedAt = time.Now()
+		contract.Rates = nil
+		if err := tx.Save(contract).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("contract_id = ?", contract.ID).Delete(&models.RateCard{}).Error; err != nil {
+			return err
+		}
+		if len(updated.Rates) == 0 {
+			return nil
+		}
+		return tx.Create(&updated.Rates).Error
+	}); err != nil {
+		http.Error(w, "Unable to update contract", http.StatusInternalServerError)
+		return
+	}
+	contract.Rates = updated.Rates
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contract)
+}
+
+// DeleteContract handles DELETE /vendors/{vendorID}/contracts/{contractID}
+func (h *VendorHandler) DeleteContract(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	vendor := h.findVendor(w, r, claims.TenantID)
+	if vendor == nil {
+		return
+	}
+	contract := h.findContract(w, r, vendor)
+	if contract == nil {
+		return
+	}
+
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("contract_id = ?", contract.ID).Delete(&models.RateCard{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.VendorContract{}, contract.ID).Error
+	}); err != nil {
+		http.Error(w, "Unable to delete contract", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// QuoteRate handles GET /vendors/{vendorID}/rates?product=&date=&nights=&units=&occupancy=&mealPlan=
+// and returns the contracted cost agents should expect.
+func (h *VendorHandler) QuoteRate(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	vendor := h.findVendor(w, r, claims.TenantID)
+	if vendor == nil {
+		return
+	}
+
+	q := r.URL.Query()
+	product := q.Get("product")
+	date, err := time.Parse("2006-01-02", q.Get("date"))
+	if product == "" || err != nil {
+		http.Error(w, "product and date (YYYY-MM-DD) are required", http.StatusBadRequest)
+		return
+	}
+	atoi := func(key string) int {
+		n, _ := strconv.Atoi(q.Get(key))
+		return n
+	}
+	nights, units, occupancy := atoi("nights"), atoi("units"), atoi("occupancy")
+
+	cost, found, err := pricing.ContractCost(h.DB, claims.TenantID, vendor.ID, product, date, nights, units, occupancy, q.Get("mealPlan"))
+	if err != nil {
+		http.Error(w, "Unable to look up rates", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "No contracted rate for these dates", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"vendorId": vendor.ID,
+		"product":  product,
+		"date":     date.Format("2006-01-02"),
+		"cost":     cost,
+	})
+}
@@ -0,0 +1,111 @@
+// internal/handlers/vendor_scorecards.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"travel-agency/internal/auth"
+	"travel-agency/internal/scorecards"
+)
+
+// scorecardPeriod reads the optional ?from=&to= (YYYY-MM-DD) filters.
+func scorecardPeriod(r *http.Request) (scorecards.Period, bool) {
+	var p scorecards.Period
+	for _, f := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &p.From}, {"to", &p.To}} {
+		s := r.URL.Query().Get(f.name)
+		if s == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return p, false
+		}
+		*f.dst = t
+	}
+	return p, p.From.IsZero() || p.To.IsZero() || !p.To.Before(p.From)
+}
+
+// Scorecard handles GET /vendors/{vendorID}/scorecard?from=&to=
+func (h *VendorHandler) Scorecard(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	vendor := h.findVendor(w, r, claims.TenantID)
+	if vendor == nil {
+		return
+	}
+	period, ok := scorecardPeriod(r)
+	if !ok {
+		http.Error(w, "Invalid date range", http.StatusBadRequest)
+		return
+	}
+
+	cards, err := scorecards.Compute(h.DB, claims.TenantID, vendor.ID, period)
+	if err != nil || len(cards) != 1 {
+		http.Error(w, "Unable to compute scorecard", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cards[0])
+}
+
+// VendorReport handles GET /vendors/scorecards?from=&to=&sort=&limit= and
+// returns every vendor's scorecard, best first. sort is one of score
+// (default), bookings, margin, marginPercent, cancellationRate, ticketRate
+// or onTimeRate.
+func (h *VendorHandler) VendorReport(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.ContextKeyClaims).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Missing tenant information", http.StatusUnauthorized)
+		return
+	}
+
+	period, ok := scorecardPeriod(r)
+	if !ok {
+		http.Error(w, "Invalid date range", http.StatusBadRequest)
+		return
+	}
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy == "" {
+		sortBy = "score"
+	}
+	if !scorecards.ValidSort(sortBy) {
+		http.Error(w, "Invalid sort key", http.StatusBadRequest)
+		return
+	}
+	limit := 0
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	cards, err := scorecards.Compute(h.DB, claims.TenantID, 0, period)
+	if err != nil {
+		http.Error(w, "Unable to compute scorecards", http.StatusInternalServerError)
+		return
+	}
+	scorecards.Rank(cards, sortBy)
+	if limit > 0 && len(cards) > limit {
+		cards = cards[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sort":    sortBy,
+		"vendors": cards,
+	})
+}
@@ -0,0 +1,149 @@
+// internal/inventory/inventory.go
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInsufficient is returned when an allotment cannot cover a booking.
+	ErrInsufficient = errors.New("insufficient inventory")
+	// ErrNoAllotment is returned when the vendor has no allotment of the
+	// product on any of the requested dates. It wraps ErrInsufficient.
+	ErrNoAllotment = fmt.Errorf("%w: no allotment", ErrInsufficient)
+)
+
+// Day truncates t to midnight UTC, the key used for inventory dates.
+func Day(t time.Time) time.Time {
+	return utils.TruncateDay(t)
+}
+
+// Reserve takes units of product from the vendor's allotment for each of the
+// given nights starting at from, and records the allocations against the
+// booking. Rows are locked (SELECT ... FOR UPDATE) in date order so two
+// agents cannot sell the same last room. It returns the total contracted cost.
+func Reserve(tx *gorm.DB, b *models.Booking, product string, from time.Time, nights, units int) (money.Amount, error) {
+	if nights < 1 {
+		nights = 1
+	}
+	if units < 1 {
+		units = 1
+	}
+	start := Day(from)
+	end := start.AddDate(0, 0, nights-1)
+
+	var slots []models.Inventory
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tenant_id = ? AND vendor_id = ? AND product = ? AND date BETWEEN ? AND ?",
+			b.TenantID, b.VendorID, product, start, end).
+		Order("date").
+		Find(&slots).Error; err != nil {
+		return 0, err
+	}
+	if len(slots) == 0 {
+		return 0, ErrNoAllotment
+	}
+	if len(slots) != nights {
+		return 0, fmt.Errorf("%w: no allotment of %q for every night from %s", ErrInsufficient, product, start.Format("2006-01-02"))
+	}
+
+	var cost money.Amount
+	now := time.Now()
+	for _, slot := range slots {
+		if slot.ReleasedAt != nil || slot.Available() < units {
+			return 0, fmt.Errorf("%w: %d of %q left on %s", ErrInsufficient, max(slot.Available(), 0), product, slot.Date.Format("2006-01-02"))
+		}
+		if err := tx.Model(&models.Inventory{}).Where("id = ?", slot.ID).Updates(map[string]interface{}{
+			"booked":     gorm.Expr("booked + ?", units),
+			"updated_at": now,
+		}).Error; err != nil {
+			return 0, err
+		}
+		alloc := models.InventoryAllocation{
+			TenantID:    b.TenantID,
+			BookingID:   b.ID,
+			InventoryID: slot.ID,
+			Units:       units,
+			CreatedAt:   now,
+		}
+		if err := tx.Create(&alloc).Error; err != nil {
+			return 0, err
+		}
+		cost += slot.ContractedCost * money.Amount(units)
+	}
+	return cost, nil
+}
+
+// Return gives a booking's units back. Units of an allotment that has
+// already been released go back to the vendor rather than on sale again.
+func Return(tx *gorm.DB, bookingID uint) error {
+	var allocs []models.InventoryAllocation
+	if err := tx.Where("booking_id = ?", bookingID).Order("inventory_id").Find(&allocs).Error; err != nil {
+		return err
+	}
+	for _, a := range allocs {
+		var slot models.Inventory
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&slot, a.InventoryID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return err
+		}
+		updates := map[string]interface{}{
+			"booked":     gorm.Expr("booked - ?", a.Units),
+			"updated_at": time.Now(),
+		}
+		if slot.ReleasedAt != nil {
+			updates["released"] = gorm.Expr("released + ?", a.Units)
+		}
+		if err := tx.Model(&slot).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Where("booking_id = ?", bookingID).Delete(&models.InventoryAllocation{}).Error
+}
+
+// Release hands unsold units of every allotment whose release date has
+// passed back to the vendor. It returns the number of allotments released.
+func Release(db *gorm.DB, now time.Time) (int, error) {
+	var ids []uint
+	if err := db.Model(&models.Inventory{}).
+		Where("release_date <= ? AND released_at IS NULL", now).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, id := range ids {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var slot models.Inventory
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ? AND released_at IS NULL", id).
+				First(&slot).Error; err != nil {
+				return err
+			}
+			return tx.Model(&slot).Updates(map[string]interface{}{
+				"released":    max(slot.Capacity-slot.Booked, 0),
+				"released_at": now,
+				"updated_at":  now,
+			}).Error
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue // Released concurrently.
+		}
+		if err != nil {
+			return released, err
+		}
+		released++
+	}
+	return released, nil
+}
@@ -0,0 +1,70 @@
+package inventory
+
+import (
+	"testing"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReserveAndReturn(t *testing.T) {
+	db := testutil.DB(t)
+	start := time.Date(2026, 12, 20, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		db.Create(&models.Inventory{TenantID: 1, VendorID: 7, Product: "Deluxe", Date: start.AddDate(0, 0, i),
+			Capacity: 2, ContractedCost: money.FromInt(100), ReleaseDate: start.AddDate(0, 0, i-14)})
+	}
+	b := models.Booking{ID: 1, TenantID: 1, VendorID: 7}
+
+	cost, err := Reserve(db, &b, "Deluxe", start, 3, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, money.FromInt(600), cost)
+
+	other := models.Booking{ID: 2, TenantID: 1, VendorID: 7}
+	_, err = Reserve(db, &other, "Deluxe", start.AddDate(0, 0, 1), 1, 1)
+	assert.ErrorIs(t, err, ErrInsufficient)
+	_, err = Reserve(db, &other, "Deluxe", start, 4, 1)
+	assert.ErrorIs(t, err, ErrInsufficient)
+
+	assert.NoError(t, Return(db, b.ID))
+	var slot models.Inventory
+	db.First(&slot, 1)
+	assert.Equal(t, 2, slot.Available())
+
+	var n int64
+	db.Model(&models.InventoryAllocation{}).Count(&n)
+	assert.Zero(t, n)
+}
+
+func TestRelease(t *testing.T) {
+	db := testutil.DB(t)
+	now := time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)
+	db.Create(&models.Inventory{TenantID: 1, VendorID: 7, Product: "Seat", Date: now.AddDate(0, 0, 5),
+		Capacity: 10, Booked: 4, ReleaseDate: now.AddDate(0, 0, -1)})
+	db.Create(&models.Inventory{TenantID: 1, VendorID: 7, Product: "Seat", Date: now.AddDate(0, 0, 40),
+		Capacity: 10, ReleaseDate: now.AddDate(0, 0, 10)})
+
+	n, err := Release(db, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	var slot models.Inventory
+	db.First(&slot, 1)
+	assert.Equal(t, 6, slot.Released)
+	assert.Equal(t, 0, slot.Available())
+	assert.NotNil(t, slot.ReleasedAt)
+
+	// A cancelled booking on a released allotment goes back to the vendor.
+	db.Create(&models.InventoryAllocation{TenantID: 1, BookingID: 9, InventoryID: 1, Units: 2})
+	assert.NoError(t, Return(db, 9))
+	db.First(&slot, 1)
+	assert.Equal(t, 0, slot.Available())
+	assert.Equal(t, 8, slot.Released)
+
+	n, _ = Release(db, now)
+	assert.Equal(t, 0, n)
+}
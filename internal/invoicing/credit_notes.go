@@ -0,0 +1,257 @@
+// internal/invoicing/credit_notes.go
+package invoicing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"travel-agency/internal/ledger"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/receivables"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Errors returned when crediting or voiding invoices.
+var (
+	ErrInvalidCredit = errors.New("invalid credit note")
+	ErrOverCredited  = errors.New("credit exceeds what is left on the invoice")
+	ErrNotIssued     = errors.New("invoice is not issued")
+	ErrCannotVoid    = errors.New("invoice cannot be voided")
+)
+
+// Void is the status of a voided invoice.
+const Void = "Void"
+
+// FormatCreditNoteNumber renders a credit note number, e.g. CN-2026-000042.
+func FormatCreditNoteNumber(year, seq int) string {
+	return fmt.Sprintf("CN-%d-%06d", year, seq)
+}
+
+// NextCreditNoteNumber takes the tenant's next credit note number for the
+// year, with the same locking as NextNumber.
+func NextCreditNoteNumber(tx *gorm.DB, tenantID uint, year int) (string, error) {
+	seq, err := next(tx, "credit_note_sequences", tenantID, year)
+	if err != nil {
+		return "", err
+	}
+	return FormatCreditNoteNumber(year, seq), nil
+}
+
+// IsDraft reports whether an invoice has not been issued yet. Only drafts
+// can be edited or deleted; issued invoices are corrected with credit notes
+// or voided.
+func IsDraft(inv *models.Invoice) bool {
+	return inv.IssuedAt == nil && (inv.Status == "" || inv.Status == Draft)
+}
+
+// creditable checks that an invoice can take a credit note.
+func creditable(inv *models.Invoice) error {
+	if IsDraft(inv) {
+		return fmt.Errorf("%w: drafts can be edited instead", ErrNotIssued)
+	}
+	if receivables.IsClosed(inv.Status) {
+		return fmt.Errorf("%w: invoice is %s", ErrInvalidCredit, inv.Status)
+	}
+	return nil
+}
+
+// IssueCreditNote credits an issued invoice. With items, each credits a
+// quantity of one of the invoice's lines (all of what is left of it when the
+// quantity is 0), with the line's discount and tax in proportion; without,
+// cn.Amount is credited as is. Across its credit notes an invoice, and each
+// of its lines, can be credited at most in full. Credit notes against sale
+// invoices are numbered from the tenant's credit note sequence.
+func IssueCreditNote(tx *gorm.DB, inv *models.Invoice, cn *models.CreditNote) error {
+	// Reload the invoice locked, so that credit notes and allocations made
+	// at the same time each see what the other took off it.
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", inv.ID).First(inv).Error; err != nil {
+		return err
+	}
+	if err := creditable(inv); err != nil {
+		return err
+	}
+	round := func(a money.Amount) money.Amount { return a.Round(inv.Currency) }
+
+	if len(cn.Items) > 0 {
+		if err := creditLines(tx, inv, cn); err != nil {
+			return err
+		}
+	} else {
+		cn.Amount = round(cn.Amount)
+		if cn.Amount <= 0 {
+			return fmt.Errorf("%w: amount must be positive", ErrInvalidCredit)
+		}
+		cn.Subtotal = cn.Amount
+		cn.TaxTotal = 0
+	}
+
+	credited, err := receivables.Credited(tx, inv.ID)
+	if err != nil {
+		return err
+	}
+	if cn.Amount > inv.Amount-credited {
+		return fmt.Errorf("%w: invoice %s has %s left to credit", ErrOverCredited, inv.ID, money.Max(0, inv.Amount-credited))
+	}
+
+	now := time.Now()
+	cn.TenantID = inv.TenantID
+	cn.InvoiceID = inv.ID
+	cn.Currency = inv.Currency
+	cn.Status = receivables.CreditNoteIssued
+	if cn.IssueDate.IsZero() {
+		cn.IssueDate = now
+	}
+	if inv.InvoiceType == "sale" {
+		number, err := NextCreditNoteNumber(tx, inv.TenantID, cn.IssueDate.Year())
+		if err != nil {
+			return err
+		}
+		cn.Number = &number
+	}
+	cn.CreatedAt = now
+	cn.UpdatedAt = now
+	if err := tx.Create(cn).Error; err != nil {
+		return err
+	}
+	if err := ledger.PostCreditNote(tx, inv, cn); err != nil {
+		return err
+	}
+	return receivables.RefreshStatus(tx, inv, now)
+}
+
+// creditLines works out the credit note's lines and totals from the invoice
+// lines they credit.
+func creditLines(tx *gorm.DB, inv *models.Invoice, cn *models.CreditNote) error {
+	var lines []models.InvoiceItem
+	if err := tx.Where("invoice_id = ?", inv.ID).Find(&lines).Error; err != nil {
+		return err
+	}
+	byID := map[uint]models.InvoiceItem{}
+	for _, l := range lines {
+		byID[l.ID] = l
+	}
+
+	// What earlier credit notes took off each line.
+	var earlier []models.CreditNoteItem
+	if err := tx.Joins("JOIN credit_notes ON credit_notes.id = credit_note_items.credit_note_id").
+		Where("credit_notes.invoice_id = ? AND credit_notes.status = ?", inv.ID, receivables.CreditNoteIssued).
+		Find(&earlier).Error; err != nil {
+		return err
+	}
+	type credit struct {
+		quantity float64
+		net, tax money.Amount
+	}
+	done := map[uint]credit{}
+	for _, e := range earlier {
+		c := done[e.InvoiceItemID]
+		c.quantity += e.Quantity
+		c.net += e.Net
+		c.tax += e.TaxAmount
+		done[e.InvoiceItemID] = c
+	}
+
+	round := func(a money.Amount) money.Amount { return a.Round(inv.Currency) }
+	seen := map[uint]bool{}
+	cn.Subtotal, cn.TaxTotal = 0, 0
+	for i := range cn.Items {
+		item := &cn.Items[i]
+		line, ok := byID[item.InvoiceItemID]
+		if !ok || seen[item.InvoiceItemID] {
+			return fmt.Errorf("%w: line %d must credit a line of invoice %s (once)", ErrInvalidCredit, i+1, inv.ID)
+		}
+		seen[item.InvoiceItemID] = true
+
+		c := done[line.ID]
+		left := line.Quantity - c.quantity
+		if item.Quantity == 0 {
+			item.Quantity = left
+		}
+		if item.Quantity < 0 || item.Quantity > left || left <= 0 {
+			return fmt.Errorf("%w: line %d has %g of %g left to credit", ErrOverCredited, i+1, left, line.Quantity)
+		}
+
+		if item.Quantity == left {
+			// The last of the line: credit exactly what is left of it.
+			item.Net = line.Net - c.net
+			item.TaxAmount = line.TaxAmount - c.tax
+		} else {
+			share := item.Quantity / line.Quantity
+			item.Net = round(line.Net.Mul(share))
+			item.TaxAmount = round(line.TaxAmount.Mul(share))
+		}
+		item.Total = item.Net + item.TaxAmount
+		item.ID = 0
+		if strings.TrimSpace(item.Description) == "" {
+			item.Description = line.Description
+		}
+		cn.Subtotal += item.Net
+		cn.TaxTotal += item.TaxAmount
+	}
+	cn.Amount = cn.Subtotal + cn.TaxTotal
+	if cn.Amount <= 0 {
+		return fmt.Errorf("%w: nothing to credit", ErrInvalidCredit)
+	}
+	return nil
+}
+
+// VoidInvoice voids an issued invoice that has no payments allocated to it
+// and no credit notes against it, and reverses its ledger postings. Its number stays used; the bookings it
+// billed can be billed again.
+func VoidInvoice(tx *gorm.DB, inv *models.Invoice, reason string) error {
+	if IsDraft(inv) {
+		return fmt.Errorf("%w: drafts can be deleted instead", ErrNotIssued)
+	}
+	if inv.Status == Void {
+		return fmt.Errorf("%w: already void", ErrCannotVoid)
+	}
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("%w: a reason is required", ErrCannotVoid)
+	}
+	var allocations int64
+	if err := tx.Model(&models.PaymentAllocation{}).Where("invoice_id = ?", inv.ID).
+		Count(&allocations).Error; err != nil {
+		return err
+	}
+	if allocations > 0 {
+		return fmt.Errorf("%w: remove its payment allocations first", ErrCannotVoid)
+	}
+	var vendorPayments int64
+	if err := tx.Model(&models.VendorPayment{}).Where("invoice_id = ? AND status IN ?", inv.ID,
+		[]string{"Scheduled", "Completed"}).Count(&vendorPayments).Error; err != nil {
+		return err
+	}
+	if vendorPayments > 0 {
+		return fmt.Errorf("%w: it has vendor payments", ErrCannotVoid)
+	}
+	var creditNotes int64
+	if err := tx.Model(&models.CreditNote{}).Where("invoice_id = ? AND status = ?", inv.ID,
+		receivables.CreditNoteIssued).Count(&creditNotes).Error; err != nil {
+		return err
+	}
+	if creditNotes > 0 {
+		return fmt.Errorf("%w: it has credit notes", ErrCannotVoid)
+	}
+
+	now := time.Now()
+	inv.Status = Void
+	inv.VoidedAt = &now
+	inv.VoidReason = strings.TrimSpace(reason)
+	inv.UpdatedAt = now
+	if err := tx.Model(&models.Invoice{}).Where("id = ?", inv.ID).Updates(map[string]interface{}{
+		"status": inv.Status, "voided_at": now, "void_reason": inv.VoidReason, "updated_at": now,
+	}).Error; err != nil {
+		return err
+	}
+	if err := ledger.PostInvoice(tx, inv); err != nil {
+		return err
+	}
+	return tx.Model(&models.Booking{}).Where("invoice_id = ? AND tenant_id = ?", inv.ID, inv.TenantID).
+		Update("invoice_id", nil).Error
+}
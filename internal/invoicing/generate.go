@@ -0,0 +1,285 @@
+// internal/invoicing/generate.go
+package invoicing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"travel-agency/internal/fx"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Errors returned when generating invoices.
+var (
+	ErrInvalidSchedule = errors.New("invalid payment schedule")
+	ErrAlreadyInvoiced = errors.New("already invoiced")
+	ErrNothingToBill   = errors.New("nothing to bill: no priced items")
+)
+
+// Installment is one part of a payment schedule; each becomes its own invoice.
+type Installment struct {
+	Label   string  `json:"label"`
+	Percent float64 `json:"percent"`
+	// Due this many days before travel (never before the issue date) when
+	// set, else DueInDays after the issue date.
+	DaysBeforeStart *int `json:"daysBeforeStart,omitempty"`
+	DueInDays       int  `json:"dueInDays"`
+}
+
+// DefaultSchedule is the tenant's deposit/balance split, or a single
+// invoice for the full amount due now.
+func DefaultSchedule(t models.Tenant) []Installment {
+	if t.DepositPercent <= 0 || t.DepositPercent >= 100 {
+		return []Installment{{Label: "Full payment", Percent: 100}}
+	}
+	days := t.BalanceDaysBeforeStart
+	return []Installment{
+		{Label: "Deposit", Percent: t.DepositPercent},
+		{Label: "Balance", Percent: 100 - t.DepositPercent, DaysBeforeStart: &days},
+	}
+}
+
+// ValidSchedule checks that the installments are positive and add up to 100%.
+func ValidSchedule(schedule []Installment) error {
+	if len(schedule) == 0 {
+		return fmt.Errorf("%w: no installments", ErrInvalidSchedule)
+	}
+	var total float64
+	for _, in := range schedule {
+		if strings.TrimSpace(in.Label) == "" || in.Percent <= 0 || in.DueInDays < 0 ||
+			(in.DaysBeforeStart != nil && *in.DaysBeforeStart < 0) {
+			return fmt.Errorf("%w: each installment needs a label, a positive percent and non-negative days", ErrInvalidSchedule)
+		}
+		total += in.Percent
+	}
+	if math.Abs(total-100) > 0.001 {
+		return fmt.Errorf("%w: percentages add up to %g, not 100", ErrInvalidSchedule, total)
+	}
+	return nil
+}
+
+// Options controls how invoices are generated.
+type Options struct {
+	Schedule  []Installment // Defaults to the tenant's schedule.
+	IssueDate time.Time     // Defaults to today.
+	Draft     bool          // Leave the invoices unissued (unnumbered).
+	ActorID   uint
+
+	// Matched by the tenant's tax rules.
+	PlaceOfSupply string
+	CustomerType  string
+}
+
+// FromItinerary bills an itinerary's priced items as sale invoices, one per
+// installment. Lines point at their itinerary items, the invoices at the
+// itinerary, and the itinerary's unbilled bookings at the last installment.
+func FromItinerary(tx *gorm.DB, itin *models.Itinerary, opts Options) ([]models.Invoice, error) {
+	var count int64
+	if err := tx.Model(&models.Invoice{}).
+		Where("tenant_id = ? AND itinerary_id = ? AND status NOT IN ?", itin.TenantID, itin.ID, []string{"Canceled", "Cancelled", "Void"}).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("%w: itinerary %d", ErrAlreadyInvoiced, itin.ID)
+	}
+
+	var lines []models.InvoiceItem
+	for _, item := range itin.Items {
+		if item.Price <= 0 {
+			continue
+		}
+		id := item.ID
+		desc := fmt.Sprintf("Day %d: %s", item.Day, item.Type)
+		if item.Description != "" {
+			desc += " - " + item.Description
+		}
+		lines = append(lines, models.InvoiceItem{ItineraryItemID: &id, Description: desc, Quantity: 1, UnitPrice: item.Price})
+	}
+
+	itinID := itin.ID
+	invoices, err := generate(tx, itin.TenantID, itin.StartDate, lines, opts, func(inv *models.Invoice) {
+		inv.ItineraryID = &itinID
+		inv.Currency = itin.Currency
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	last := invoices[len(invoices)-1]
+	if err := tx.Model(&models.Booking{}).
+		Where("tenant_id = ? AND itinerary_id = ? AND invoice_id IS NULL", itin.TenantID, itin.ID).
+		Update("invoice_id", last.ID).Error; err != nil {
+		return nil, err
+	}
+	return invoices, utils.LogAction(tx, itin.TenantID, opts.ActorID, "GENERATE_INVOICE", "Invoice",
+		fmt.Sprintf("Invoiced itinerary %d in %d installment(s)", itin.ID, len(invoices)))
+}
+
+// FromBooking bills a single booking that is not part of an itinerary.
+func FromBooking(tx *gorm.DB, b *models.Booking, opts Options) ([]models.Invoice, error) {
+	if b.InvoiceID != nil {
+		return nil, fmt.Errorf("%w: booking %d", ErrAlreadyInvoiced, b.ID)
+	}
+	id := b.ID
+	line := models.InvoiceItem{BookingID: &id, Description: fmt.Sprintf("Booking %d", b.ID), Quantity: 1, UnitPrice: b.Price}
+	if b.BookingRef != "" {
+		line.Description += " (" + b.BookingRef + ")"
+	}
+	var lines []models.InvoiceItem
+	if b.Price > 0 {
+		lines = append(lines, line)
+	}
+
+	invoices, err := generate(tx, b.TenantID, b.TravelDate, lines, opts, func(inv *models.Invoice) {
+		inv.Currency = b.Currency
+	})
+	if err != nil {
+		return nil, err
+	}
+	last := invoices[len(invoices)-1]
+	b.InvoiceID = &last.ID
+	if err := tx.Model(&models.Booking{}).Where("id = ?", b.ID).Update("invoice_id", last.ID).Error; err != nil {
+		return nil, err
+	}
+	return invoices, utils.LogAction(tx, b.TenantID, opts.ActorID, "GENERATE_INVOICE", "Invoice",
+		fmt.Sprintf("Invoiced booking %d in %d installment(s)", b.ID, len(invoices)))
+}
+
+// OnBookingConfirmed invoices a newly confirmed booking's trip when the
+// tenant has automatic invoicing on: the whole itinerary if the booking is
+// part of one and it is not yet invoiced, else the booking itself.
+func OnBookingConfirmed(tx *gorm.DB, b *models.Booking, actorID uint) error {
+	var tenant models.Tenant
+	if err := tx.First(&tenant, b.TenantID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !tenant.AutoInvoiceOnConfirm {
+		return nil
+	}
+
+	var err error
+	if b.ItineraryID != 0 {
+		var itin models.Itinerary
+		if err := tx.Preload("Items").Where("id = ? AND tenant_id = ?", b.ItineraryID, b.TenantID).
+			First(&itin).Error; err != nil {
+			return err
+		}
+		_, err = FromItinerary(tx, &itin, Options{ActorID: actorID})
+	} else {
+		_, err = FromBooking(tx, b, Options{ActorID: actorID})
+	}
+	if errors.Is(err, ErrAlreadyInvoiced) || errors.Is(err, ErrNothingToBill) {
+		return nil
+	}
+	return err
+}
+
+// generate splits the lines across the schedule's installments and saves
+// (and unless drafting, issues) one sale invoice per installment. Each line
+// is split by percent; the last installment takes the rounding remainder.
+func generate(tx *gorm.DB, tenantID uint, start time.Time, lines []models.InvoiceItem, opts Options, decorate func(*models.Invoice)) ([]models.Invoice, error) {
+	if len(lines) == 0 {
+		return nil, ErrNothingToBill
+	}
+	var tenant models.Tenant
+	if err := tx.First(&tenant, tenantID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	schedule := opts.Schedule
+	if len(schedule) == 0 {
+		schedule = DefaultSchedule(tenant)
+	}
+	if err := ValidSchedule(schedule); err != nil {
+		return nil, err
+	}
+	issueDate := opts.IssueDate
+	if issueDate.IsZero() {
+		issueDate = utils.TruncateDay(time.Now())
+	}
+	if err := ResolveSources(tx, tenantID, lines); err != nil {
+		return nil, err
+	}
+
+	billed := make([]money.Amount, len(lines))
+	costed := make([]money.Amount, len(lines))
+	invoices := make([]models.Invoice, 0, len(schedule))
+	for n, in := range schedule {
+		final := n == len(schedule)-1
+		inv := models.Invoice{
+			TenantID:      tenantID,
+			InvoiceType:   "sale",
+			IssueDate:     issueDate,
+			DueDate:       dueDate(in, issueDate, start),
+			Status:        Draft,
+			PlaceOfSupply: opts.PlaceOfSupply,
+			CustomerType:  opts.CustomerType,
+		}
+		if len(schedule) > 1 {
+			inv.Installment = in.Label
+		}
+		if decorate != nil {
+			decorate(&inv)
+		}
+		for i, l := range lines {
+			share := l.UnitPrice.Percent(in.Percent).Round(inv.Currency)
+			cost := l.Cost.Percent(in.Percent).Round(inv.Currency)
+			if final {
+				share = l.UnitPrice - billed[i]
+				cost = l.Cost - costed[i]
+			}
+			billed[i] += share
+			costed[i] += cost
+			line := l
+			line.UnitPrice = share
+			line.Cost = cost
+			if len(schedule) > 1 {
+				line.Description = fmt.Sprintf("%s (%g%%): %s", in.Label, in.Percent, l.Description)
+			}
+			if tenant.TaxEngine == "" && tenant.TaxRate > 0 {
+				line.Taxes = []models.InvoiceItemTax{{Name: "Tax", Rate: tenant.TaxRate}}
+			}
+			inv.Items = append(inv.Items, line)
+		}
+		if err := ApplyTaxes(tx, &inv); err != nil {
+			return nil, err
+		}
+		if err := ComputeTotals(&inv); err != nil {
+			return nil, err
+		}
+		if err := fx.RecordInvoice(tx, &inv); err != nil {
+			return nil, err
+		}
+		if err := tx.Create(&inv).Error; err != nil {
+			return nil, err
+		}
+		if !opts.Draft {
+			if err := Issue(tx, &inv); err != nil {
+				return nil, err
+			}
+		}
+		invoices = append(invoices, inv)
+	}
+	return invoices, nil
+}
+
+func dueDate(in Installment, issueDate, start time.Time) time.Time {
+	if in.DaysBeforeStart != nil && !start.IsZero() {
+		due := utils.TruncateDay(start).AddDate(0, 0, -*in.DaysBeforeStart)
+		if due.After(issueDate) {
+			return due
+		}
+		return issueDate
+	}
+	return issueDate.AddDate(0, 0, in.DueInDays)
+}
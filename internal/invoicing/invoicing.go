@@ -0,0 +1,329 @@
+// internal/invoicing/invoicing.go
+package invoicing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"travel-agency/internal/fx"
+	"travel-agency/internal/ledger"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/tax"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Errors returned while building or issuing invoices.
+var (
+	ErrInvalidLine    = errors.New("invalid invoice line")
+	ErrSourceNotFound = errors.New("billed booking or itinerary item not found")
+	ErrAlreadyIssued  = errors.New("invoice already issued")
+	ErrLocked         = errors.New("issued invoices cannot be edited; issue a credit note or void the invoice")
+)
+
+// Draft is the status of an invoice that has not been issued yet.
+const Draft = "Draft"
+
+// ComputeTotals works out each line's discount, taxes and total, and the
+// invoice totals from them, rounding each to the invoice currency's minor
+// unit. Taxes apply to the net amount, the margin over the line's cost or
+// the net plus the other taxes, by basis. Without items the invoice amount
+// is kept as entered.
+func ComputeTotals(inv *models.Invoice) error {
+	round := func(a money.Amount) money.Amount { return a.Round(inv.Currency) }
+	if len(inv.Items) == 0 {
+		inv.Amount = round(inv.Amount)
+		inv.Subtotal = inv.Amount
+		inv.DiscountTotal = 0
+		inv.TaxTotal = 0
+		return nil
+	}
+
+	var subtotal, discount, taxes money.Amount
+	for i := range inv.Items {
+		item := &inv.Items[i]
+		if err := validLine(item, i+1); err != nil {
+			return err
+		}
+		item.Position = i + 1
+		item.Gross = round(item.UnitPrice.Mul(item.Quantity))
+		item.DiscountAmount = money.Min(item.Gross, round(item.Gross.Percent(item.DiscountPercent)+item.Discount))
+		item.Net = item.Gross - item.DiscountAmount
+		item.TaxAmount = 0
+		// Taxes on the total include the others, so they come last.
+		for _, last := range []bool{false, true} {
+			others := item.TaxAmount
+			for j := range item.Taxes {
+				t := &item.Taxes[j]
+				if (t.Basis == models.TaxOnTotal) != last {
+					continue
+				}
+				t.Taxable = tax.Taxable(t.Basis, item.Net, item.Cost, others)
+				t.Amount = round(t.Taxable.Percent(t.Rate))
+				item.TaxAmount += t.Amount
+			}
+		}
+		item.Total = item.Net + item.TaxAmount
+
+		subtotal += item.Gross
+		discount += item.DiscountAmount
+		taxes += item.TaxAmount
+	}
+	inv.Subtotal = subtotal
+	inv.DiscountTotal = discount
+	inv.TaxTotal = taxes
+	inv.Amount = subtotal - discount + taxes
+	return nil
+}
+
+func validLine(item *models.InvoiceItem, n int) error {
+	switch {
+	case strings.TrimSpace(item.Description) == "":
+		return fmt.Errorf("%w %d: description is required", ErrInvalidLine, n)
+	case item.Quantity <= 0:
+		return fmt.Errorf("%w %d: quantity must be positive", ErrInvalidLine, n)
+	case item.UnitPrice < 0 || item.Cost < 0:
+		return fmt.Errorf("%w %d: unitPrice and cost must be non-negative", ErrInvalidLine, n)
+	case item.DiscountPercent < 0 || item.DiscountPercent > 100 || item.Discount < 0:
+		return fmt.Errorf("%w %d: discountPercent must be 0-100 and discount non-negative", ErrInvalidLine, n)
+	}
+	for _, t := range item.Taxes {
+		if strings.TrimSpace(t.Name) == "" || t.Rate < 0 {
+			return fmt.Errorf("%w %d: each tax needs a name and a non-negative rate", ErrInvalidLine, n)
+		}
+		if !tax.ValidBasis(t.Basis) {
+			return fmt.Errorf("%w %d: tax basis must be net, margin or total", ErrInvalidLine, n)
+		}
+	}
+	return nil
+}
+
+// ResolveSources checks that the bookings and itinerary items billed on the
+// lines belong to the tenant, and fills in a missing description, unit
+// price, service type or cost from them.
+func ResolveSources(db *gorm.DB, tenantID uint, items []models.InvoiceItem) error {
+	for i := range items {
+		item := &items[i]
+		item.TenantID = tenantID
+		if item.Quantity == 0 {
+			item.Quantity = 1
+		}
+		switch {
+		case item.BookingID != nil:
+			var b models.Booking
+			if err := db.Where("id = ? AND tenant_id = ?", *item.BookingID, tenantID).First(&b).Error; err != nil {
+				return sourceError(err, "booking", *item.BookingID)
+			}
+			if item.Description == "" {
+				item.Description = fmt.Sprintf("Booking %d", b.ID)
+				if b.BookingRef != "" {
+					item.Description += " (" + b.BookingRef + ")"
+				}
+			}
+			if item.UnitPrice == 0 {
+				item.UnitPrice = b.Price
+			}
+			if item.ServiceType == "" {
+				var vendor models.Vendor
+				if err := db.Select("id", "type").Where("id = ? AND tenant_id = ?", b.VendorID, tenantID).
+					First(&vendor).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+					return err
+				}
+				item.ServiceType = vendor.Type
+			}
+			if item.Cost == 0 {
+				item.Cost = BookingCost(b)
+			}
+		case item.ItineraryItemID != nil:
+			var it models.ItineraryItem
+			if err := db.Joins("JOIN itineraries ON itineraries.id = itinerary_items.itinerary_id").
+				Where("itinerary_items.id = ? AND itineraries.tenant_id = ?", *item.ItineraryItemID, tenantID).
+				First(&it).Error; err != nil {
+				return sourceError(err, "itinerary item", *item.ItineraryItemID)
+			}
+			if item.Description == "" {
+				item.Description = fmt.Sprintf("Day %d: %s", it.Day, it.Type)
+				if it.Description != "" {
+					item.Description += " - " + it.Description
+				}
+			}
+			if item.UnitPrice == 0 {
+				item.UnitPrice = it.Price
+			}
+			if item.ServiceType == "" {
+				item.ServiceType = it.Type
+			}
+			if item.Cost == 0 {
+				item.Cost = fx.Rated(it.Cost, it.CostRate)
+			}
+		}
+	}
+	return nil
+}
+
+// BookingCost is the booking's cost in the currency of its price, converted
+// through the base currency when the two differ. It is zero when the rates
+// to convert it were not recorded.
+func BookingCost(b models.Booking) money.Amount {
+	if b.CostCurrency == "" || b.CostCurrency == b.Currency {
+		return b.Cost
+	}
+	if b.ExchangeRate == 0 || b.CostExchangeRate == 0 {
+		return 0
+	}
+	return b.Cost.Mul(b.CostExchangeRate / b.ExchangeRate).Round(b.Currency)
+}
+
+// ApplyTaxes fills in the taxes of sale invoice lines given none (nil
+// Taxes) from the tenant's tax engine, by the line's service type and the
+// invoice's place of supply and customer type. Lines with taxes, even an
+// empty list, keep them, as do all lines when the tenant has no engine.
+func ApplyTaxes(db *gorm.DB, inv *models.Invoice) error {
+	if inv.InvoiceType != "sale" {
+		return nil
+	}
+	engine, err := tax.ForTenant(db, inv.TenantID)
+	if err != nil || engine == nil {
+		return err
+	}
+	for i := range inv.Items {
+		item := &inv.Items[i]
+		if item.Taxes != nil {
+			continue
+		}
+		item.Taxes = engine.Taxes(tax.Line{
+			ServiceType:   item.ServiceType,
+			PlaceOfSupply: inv.PlaceOfSupply,
+			CustomerType:  inv.CustomerType,
+			Date:          inv.IssueDate,
+		})
+	}
+	return nil
+}
+
+func sourceError(err error, kind string, id uint) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %s %d", ErrSourceNotFound, kind, id)
+	}
+	return err
+}
+
+// DeleteItems deletes an invoice's lines and their taxes.
+func DeleteItems(tx *gorm.DB, invoiceID uuid.UUID) error {
+	if err := tx.Where("invoice_item_id IN (?)",
+		tx.Model(&models.InvoiceItem{}).Select("id").Where("invoice_id = ?", invoiceID)).
+		Delete(&models.InvoiceItemTax{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("invoice_id = ?", invoiceID).Delete(&models.InvoiceItem{}).Error
+}
+
+// ReplaceItems deletes the invoice's lines and saves inv.Items in their place.
+func ReplaceItems(tx *gorm.DB, inv *models.Invoice) error {
+	if err := DeleteItems(tx, inv.ID); err != nil {
+		return err
+	}
+	if len(inv.Items) == 0 {
+		return nil
+	}
+	for i := range inv.Items {
+		inv.Items[i].ID = 0
+		inv.Items[i].InvoiceID = inv.ID
+		for j := range inv.Items[i].Taxes {
+			inv.Items[i].Taxes[j].ID = 0
+		}
+	}
+	return tx.Create(&inv.Items).Error
+}
+
+// LinkBookings points bookings billed on a sale invoice at it, unless they
+// are already billed elsewhere.
+func LinkBookings(tx *gorm.DB, inv *models.Invoice) error {
+	if inv.InvoiceType != "sale" {
+		return nil
+	}
+	var ids []uint
+	for _, item := range inv.Items {
+		if item.BookingID != nil {
+			ids = append(ids, *item.BookingID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Model(&models.Booking{}).
+		Where("id IN ? AND tenant_id = ? AND invoice_id IS NULL", ids, inv.TenantID).
+		Update("invoice_id", inv.ID).Error
+}
+
+// FormatNumber renders an invoice number, e.g. INV-2026-000123.
+func FormatNumber(year, seq int) string {
+	return fmt.Sprintf("INV-%d-%06d", year, seq)
+}
+
+// NextNumber takes the tenant's next invoice number for the year. The
+// sequence row stays locked until tx ends, so concurrent issues queue up and
+// a rolled-back issue releases its number.
+func NextNumber(tx *gorm.DB, tenantID uint, year int) (string, error) {
+	seq, err := next(tx, "invoice_sequences", tenantID, year)
+	if err != nil {
+		return "", err
+	}
+	return FormatNumber(year, seq), nil
+}
+
+// next takes the next value of a per-tenant, per-year sequence stored in
+// table, which has the columns of models.InvoiceSequence.
+func next(tx *gorm.DB, table string, tenantID uint, year int) (int, error) {
+	if err := tx.Table(table).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.InvoiceSequence{TenantID: tenantID, Year: year}).Error; err != nil {
+		return 0, err
+	}
+	var seq models.InvoiceSequence
+	if err := tx.Table(table).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tenant_id = ? AND year = ?", tenantID, year).First(&seq).Error; err != nil {
+		return 0, err
+	}
+	seq.Last++
+	if err := tx.Table(table).Where("id = ?", seq.ID).Update("last", seq.Last).Error; err != nil {
+		return 0, err
+	}
+	return seq.Last, nil
+}
+
+// Issue numbers a sale invoice from the tenant's sequence for its issue year,
+// moves a draft to Outstanding and posts it to the ledger. Purchase invoices
+// keep the supplier's reference and are only marked issued and posted.
+func Issue(tx *gorm.DB, inv *models.Invoice) error {
+	if inv.Number != nil || inv.IssuedAt != nil {
+		return ErrAlreadyIssued
+	}
+	now := time.Now()
+	if inv.IssueDate.IsZero() {
+		inv.IssueDate = now
+	}
+	updates := map[string]interface{}{"issued_at": now, "issue_date": inv.IssueDate, "updated_at": now}
+	if inv.InvoiceType == "sale" {
+		number, err := NextNumber(tx, inv.TenantID, inv.IssueDate.Year())
+		if err != nil {
+			return err
+		}
+		inv.Number = &number
+		updates["number"] = number
+	}
+	if inv.Status == "" || inv.Status == Draft {
+		inv.Status = "Outstanding"
+	}
+	updates["status"] = inv.Status
+	inv.IssuedAt = &now
+	inv.UpdatedAt = now
+	if err := tx.Model(&models.Invoice{}).Where("id = ?", inv.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	return ledger.PostInvoice(tx, inv)
+}
@@ -0,0 +1,277 @@
+package invoicing
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/tax"
+	"travel-agency/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestComputeTotals(t *testing.T) {
+	inv := models.Invoice{Items: []models.InvoiceItem{
+		{Description: "Hotel, 2 nights", Quantity: 2, UnitPrice: money.FromInt(150), DiscountPercent: 10,
+			Taxes: []models.InvoiceItemTax{{Name: "CGST", Rate: 6}, {Name: "SGST", Rate: 6}}},
+		{Description: "Transfer", Quantity: 1, UnitPrice: money.FromInt(40), Discount: money.FromInt(5),
+			Taxes: []models.InvoiceItemTax{{Name: "VAT", Rate: 20}}},
+	}}
+	assert.NoError(t, ComputeTotals(&inv))
+
+	hotel := inv.Items[0]
+	assert.Equal(t, money.FromInt(300), hotel.Gross)
+	assert.Equal(t, money.FromInt(30), hotel.DiscountAmount)
+	assert.Equal(t, money.FromInt(270), hotel.Net)
+	assert.Equal(t, money.FromFloat(16.2), hotel.Taxes[0].Amount)
+	assert.Equal(t, money.FromFloat(32.4), hotel.TaxAmount)
+	assert.Equal(t, money.FromFloat(302.4), hotel.Total)
+	assert.Equal(t, money.FromInt(42), inv.Items[1].Total)
+
+	assert.Equal(t, money.FromInt(340), inv.Subtotal)
+	assert.Equal(t, money.FromInt(35), inv.DiscountTotal)
+	assert.Equal(t, money.FromFloat(39.4), inv.TaxTotal)
+	assert.Equal(t, money.FromFloat(344.4), inv.Amount)
+
+	bad := models.Invoice{Items: []models.InvoiceItem{{Description: "x", Quantity: 1, DiscountPercent: 120}}}
+	assert.ErrorIs(t, ComputeTotals(&bad), ErrInvalidLine)
+}
+
+// TestIssueNumbersWithoutGaps checks that numbers run on per tenant and year
+// without gaps or repeats. SQLite has no row locks and a single connection
+// is used, so the goroutines below issue one after another: this does not
+// exercise the FOR UPDATE lock on the sequence row under real concurrency,
+// which needs Postgres.
+func TestIssueNumbersWithoutGaps(t *testing.T) {
+	db := testutil.DB(t)
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	issue := func(tenant uint, year int) *models.Invoice {
+		inv := models.Invoice{TenantID: tenant, InvoiceType: "sale", Status: Draft,
+			IssueDate: time.Date(year, 3, 1, 0, 0, 0, 0, time.UTC), DueDate: time.Date(year, 4, 1, 0, 0, 0, 0, time.UTC)}
+		assert.NoError(t, db.Create(&inv).Error)
+		assert.NoError(t, db.Transaction(func(tx *gorm.DB) error { return Issue(tx, &inv) }))
+		return &inv
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() { defer wg.Done(); issue(1, 2026) }()
+	}
+	wg.Wait()
+
+	var numbers []string
+	db.Model(&models.Invoice{}).Where("tenant_id = ?", 1).Order("number").Pluck("number", &numbers)
+	assert.Equal(t, []string{"INV-2026-000001", "INV-2026-000002", "INV-2026-000003",
+		"INV-2026-000004", "INV-2026-000005"}, numbers)
+
+	// Each tenant and year has its own sequence.
+	assert.Equal(t, "INV-2026-000001", *issue(2, 2026).Number)
+	next := issue(1, 2027)
+	assert.Equal(t, "INV-2027-000001", *next.Number)
+	assert.Equal(t, "Outstanding", next.Status)
+
+	assert.ErrorIs(t, Issue(db, next), ErrAlreadyIssued)
+}
+
+func TestFromItinerarySplitsDepositAndBalance(t *testing.T) {
+	db := testutil.DB(t)
+	db.Create(&models.Tenant{Name: "Agency", TaxRate: 5, DepositPercent: 30, BalanceDaysBeforeStart: 45})
+	itin := models.Itinerary{TenantID: 1, Name: "Goa", StartDate: time.Date(2026, 12, 20, 0, 0, 0, 0, time.UTC),
+		EndDate: time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC),
+		Items:   []models.ItineraryItem{{Day: 1, Type: "Hotel", Price: money.FromFloat(1000.01)}, {Day: 2, Type: "Tour", Price: money.FromInt(0)}}}
+	db.Create(&itin)
+	booking := models.Booking{TenantID: 1, ItineraryID: itin.ID, Price: money.FromFloat(1000.01)}
+	db.Create(&booking)
+
+	issued := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	invoices, err := FromItinerary(db, &itin, Options{IssueDate: issued})
+	assert.NoError(t, err)
+	assert.Len(t, invoices, 2)
+
+	deposit, balance := invoices[0], invoices[1]
+	assert.Equal(t, "Deposit", deposit.Installment)
+	assert.Equal(t, issued, deposit.DueDate)
+	assert.Equal(t, time.Date(2026, 11, 5, 0, 0, 0, 0, time.UTC), balance.DueDate)
+	assert.Len(t, deposit.Items, 1) // Unpriced items are not billed.
+	assert.Equal(t, money.FromInt(300), deposit.Items[0].UnitPrice)
+	assert.Equal(t, money.FromFloat(700.01), balance.Items[0].UnitPrice)
+	assert.Equal(t, itin.Items[0].ID, *balance.Items[0].ItineraryItemID)
+	assert.Equal(t, money.FromInt(315), deposit.Amount) // With 5% tax.
+	assert.Equal(t, "INV-2026-000001", *deposit.Number)
+	assert.Equal(t, "INV-2026-000002", *balance.Number)
+
+	db.First(&booking, booking.ID)
+	assert.Equal(t, balance.ID, *booking.InvoiceID)
+
+	_, err = FromItinerary(db, &itin, Options{})
+	assert.ErrorIs(t, err, ErrAlreadyInvoiced)
+
+	schedule := []Installment{{Label: "Deposit", Percent: 50}}
+	assert.ErrorIs(t, ValidSchedule(schedule), ErrInvalidSchedule)
+}
+
+func TestCreditNotesAndVoid(t *testing.T) {
+	db := testutil.DB(t)
+
+	inv := models.Invoice{TenantID: 1, InvoiceType: "sale", Status: Draft, Currency: "USD",
+		IssueDate: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), DueDate: time.Now().AddDate(0, 1, 0),
+		Items: []models.InvoiceItem{
+			{Description: "Hotel", Quantity: 3, UnitPrice: money.FromInt(100), Taxes: []models.InvoiceItemTax{{Name: "VAT", Rate: 10}}},
+			{Description: "Transfer", Quantity: 1, UnitPrice: money.FromInt(50)},
+		}}
+	assert.NoError(t, ComputeTotals(&inv))
+	assert.NoError(t, db.Create(&inv).Error)
+
+	// Drafts are edited, not credited.
+	assert.ErrorIs(t, IssueCreditNote(db, &inv, &models.CreditNote{Amount: money.FromInt(10)}), ErrNotIssued)
+	assert.NoError(t, Issue(db, &inv))
+	assert.Equal(t, money.FromInt(380), inv.Amount)
+	hotel := inv.Items[0].ID
+
+	// One of three nights, with its tax.
+	first := models.CreditNote{Reason: "Early checkout", Items: []models.CreditNoteItem{{InvoiceItemID: hotel, Quantity: 1}}}
+	assert.NoError(t, IssueCreditNote(db, &inv, &first))
+	assert.Equal(t, "CN-2026-000001", *first.Number)
+	assert.Equal(t, money.FromInt(100), first.Subtotal)
+	assert.Equal(t, money.FromInt(110), first.Amount)
+	assert.Equal(t, "Hotel", first.Items[0].Description)
+
+	// A credited invoice is not voided: that would undo the credit twice.
+	assert.ErrorIs(t, VoidInvoice(db, &inv, "Duplicate"), ErrCannotVoid)
+	assert.NotEqual(t, Void, inv.Status)
+
+	over := models.CreditNote{Items: []models.CreditNoteItem{{InvoiceItemID: hotel, Quantity: 3}}}
+	assert.ErrorIs(t, IssueCreditNote(db, &inv, &over), ErrOverCredited)
+	assert.ErrorIs(t, IssueCreditNote(db, &inv, &models.CreditNote{Amount: money.FromInt(300)}), ErrOverCredited)
+
+	// The rest of the hotel and the transfer settle the invoice.
+	rest := models.CreditNote{Items: []models.CreditNoteItem{{InvoiceItemID: hotel}, {InvoiceItemID: inv.Items[1].ID}}}
+	assert.NoError(t, IssueCreditNote(db, &inv, &rest))
+	assert.Equal(t, float64(2), rest.Items[0].Quantity)
+	assert.Equal(t, money.FromInt(270), rest.Amount)
+	assert.Equal(t, "CN-2026-000002", *rest.Number)
+	assert.Equal(t, "Credited", inv.Status)
+
+	// Voiding needs a reason and an issued invoice without payments.
+	other := models.Invoice{TenantID: 1, InvoiceType: "sale", Status: Draft, Currency: "USD", Amount: money.FromInt(90),
+		IssueDate: time.Now(), DueDate: time.Now()}
+	db.Create(&other)
+	assert.ErrorIs(t, VoidInvoice(db, &other, "typo"), ErrNotIssued)
+	assert.NoError(t, Issue(db, &other))
+	db.Create(&models.Booking{TenantID: 1, InvoiceID: &other.ID})
+	assert.ErrorIs(t, VoidInvoice(db, &other, " "), ErrCannotVoid)
+	assert.NoError(t, VoidInvoice(db, &other, "Wrong customer"))
+	assert.Equal(t, Void, other.Status)
+	assert.NotNil(t, other.VoidedAt)
+	var billed int64
+	db.Model(&models.Booking{}).Where("invoice_id = ?", other.ID).Count(&billed)
+	assert.Zero(t, billed)
+	assert.ErrorIs(t, IssueCreditNote(db, &other, &models.CreditNote{Amount: money.FromInt(10)}), ErrInvalidCredit)
+}
+
+func TestGenerateDueRecurring(t *testing.T) {
+	db := testutil.DB(t)
+	db.Create(&models.Tenant{Name: "Agency", BaseCurrency: "INR"})
+	end := time.Date(2026, 4, 30, 0, 0, 0, 0, time.UTC)
+	ri := models.RecurringInvoice{TenantID: 1, Name: "Acme retainer", Description: "Travel desk fee", Amount: money.FromInt(1000),
+		Currency: "inr", TaxName: "GST", TaxRate: 18, Cadence: models.CadenceMonthly, DueInDays: 10,
+		StartDate: time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC), EndDate: &end}
+	assert.NoError(t, ValidateRecurring(&ri))
+	assert.NoError(t, Reschedule(db, &ri, time.Time{}))
+	assert.NoError(t, db.Create(&ri).Error)
+	assert.Equal(t, ri.StartDate, *ri.NextRunAt)
+
+	// Missed periods are caught up, each billed once; short months keep the last day.
+	now := time.Date(2026, 3, 5, 6, 0, 0, 0, time.UTC)
+	n, err := GenerateDueRecurring(db, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, _ = GenerateDueRecurring(db, now)
+	assert.Zero(t, n)
+	var invoices []models.Invoice
+	db.Preload("Items").Order("issue_date").Find(&invoices)
+	assert.Len(t, invoices, 2)
+	feb := invoices[1]
+	assert.Equal(t, time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC), feb.IssueDate.UTC())
+	assert.Equal(t, "INV-2026-000002", *feb.Number)
+	assert.Equal(t, money.FromInt(1180), feb.Amount)
+	assert.Equal(t, "Travel desk fee (28 Feb 2026 - 30 Mar 2026)", feb.Items[0].Description)
+	_, err = GenerateRecurring(db, &ri, feb.IssueDate)
+	assert.ErrorIs(t, err, ErrAlreadyInvoiced)
+
+	// The schedule completes after the last period before its end date.
+	n, _ = GenerateDueRecurring(db, time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, 2, n)
+	var done models.RecurringInvoice
+	db.First(&done, ri.ID)
+	assert.Equal(t, models.RecurringCompleted, done.Status)
+	assert.Nil(t, done.NextRunAt)
+
+	bad := models.RecurringInvoice{Name: "x", Description: "x", Amount: money.FromInt(1), Currency: "INR",
+		Cadence: models.CadenceCron, CronSpec: "0 * * * *", StartDate: now}
+	assert.ErrorIs(t, ValidateRecurring(&bad), ErrInvalidRecurring)
+	bad.CronSpec = "0 0 1 */2 *"
+	assert.NoError(t, ValidateRecurring(&bad))
+}
+
+func TestApplyTaxRules(t *testing.T) {
+	db := testutil.DB(t)
+	db.Create(&models.Tenant{Name: "Agency", BaseCurrency: "INR", TaxEngine: models.TaxEngineRules})
+	for _, rule := range []models.TaxRule{
+		{Name: "GST", Components: []models.TaxRuleComponent{{Name: "IGST", Rate: 18}}},
+		{Name: "GST hotels intra-state", ServiceType: "Hotel", PlaceOfSupply: "intra-state",
+			Components: []models.TaxRuleComponent{{Name: "CGST", Rate: 6}, {Name: "SGST", Rate: 6}}},
+		{Name: "GST hotels", ServiceType: "Hotel", Components: []models.TaxRuleComponent{{Name: "IGST", Rate: 12}}},
+		{Name: "Outbound tours", ServiceType: "Package", PlaceOfSupply: "outbound", Components: []models.TaxRuleComponent{
+			{Name: "IGST", Rate: 5}, {Name: "TCS", Rate: 5, Basis: models.TaxOnTotal}}},
+		{Name: "EU margin scheme", ServiceType: "Package", PlaceOfSupply: "EU",
+			Components: []models.TaxRuleComponent{{Name: "VAT", Rate: 20, Basis: models.TaxOnMargin}}},
+	} {
+		rule.TenantID = 1
+		assert.NoError(t, tax.ValidateRule(&rule))
+		assert.NoError(t, db.Create(&rule).Error)
+	}
+	assert.ErrorIs(t, tax.ValidateRule(&models.TaxRule{Name: "Empty"}), tax.ErrInvalidRule)
+
+	taxes := func(place, service string, price, cost int64, given []models.InvoiceItemTax) []models.InvoiceItemTax {
+		inv := models.Invoice{TenantID: 1, InvoiceType: "sale", PlaceOfSupply: place, Items: []models.InvoiceItem{
+			{Description: service, Quantity: 1, UnitPrice: money.FromInt(price), Cost: money.FromInt(cost),
+				ServiceType: service, Taxes: given}}}
+		assert.NoError(t, ApplyTaxes(db, &inv))
+		assert.NoError(t, ComputeTotals(&inv))
+		return inv.Items[0].Taxes
+	}
+
+	// The most specific rule wins: CGST+SGST within the state, IGST across.
+	intra := taxes("intra-state", "Hotel", 1000, 0, nil)
+	assert.Len(t, intra, 2)
+	assert.Equal(t, "CGST", intra[0].Name)
+	assert.Equal(t, money.FromInt(60), intra[1].Amount)
+	inter := taxes("inter-state", "Hotel", 1000, 0, nil)
+	assert.Len(t, inter, 1)
+	assert.Equal(t, money.FromInt(120), inter[0].Amount)
+	assert.Equal(t, money.FromInt(180), taxes("inter-state", "Flight", 1000, 0, nil)[0].Amount)
+
+	// TCS is collected on the total including GST.
+	tour := taxes("outbound", "Package", 1000, 800, nil)
+	assert.Equal(t, money.FromInt(50), tour[0].Amount)
+	assert.Equal(t, money.FromInt(1050), tour[1].Taxable)
+	assert.Equal(t, money.FromFloat(52.5), tour[1].Amount)
+
+	// VAT under the margin scheme is on the price less the cost, never below zero.
+	margin := taxes("EU", "Package", 1000, 800, nil)
+	assert.Equal(t, money.FromInt(200), margin[0].Taxable)
+	assert.Equal(t, money.FromInt(40), margin[0].Amount)
+	assert.Equal(t, money.Amount(0), taxes("EU", "Package", 1000, 1200, nil)[0].Amount)
+
+	// Lines given their taxes keep them.
+	given := taxes("intra-state", "Hotel", 1000, 0, []models.InvoiceItemTax{})
+	assert.Empty(t, given)
+}
@@ -0,0 +1,266 @@
+// internal/invoicing/recurring.go
+package invoicing
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"travel-agency/internal/fx"
+	"travel-agency/internal/models"
+	"travel-agency/internal/utils"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidRecurring is returned for recurring invoices that cannot be saved.
+var ErrInvalidRecurring = errors.New("invalid recurring invoice")
+
+// maxCatchUp bounds how many missed periods one run bills per schedule.
+const maxCatchUp = 36
+
+// ValidateRecurring checks a recurring invoice before it is saved and
+// normalises its dates to whole days.
+func ValidateRecurring(ri *models.RecurringInvoice) error {
+	ri.Name = strings.TrimSpace(ri.Name)
+	ri.Description = strings.TrimSpace(ri.Description)
+	ri.Currency = strings.ToUpper(strings.TrimSpace(ri.Currency))
+	ri.CronSpec = strings.TrimSpace(ri.CronSpec)
+	switch {
+	case ri.Name == "" || ri.Description == "":
+		return fmt.Errorf("%w: name and description are required", ErrInvalidRecurring)
+	case ri.Amount <= 0:
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidRecurring)
+	case len(ri.Currency) != 3:
+		return fmt.Errorf("%w: currency must be a 3-letter code", ErrInvalidRecurring)
+	case ri.TaxRate < 0 || ri.TaxRate > 100:
+		return fmt.Errorf("%w: taxRate must be between 0 and 100", ErrInvalidRecurring)
+	case ri.DueInDays < 0:
+		return fmt.Errorf("%w: dueInDays cannot be negative", ErrInvalidRecurring)
+	case ri.StartDate.IsZero():
+		return fmt.Errorf("%w: startDate is required", ErrInvalidRecurring)
+	}
+	if ri.ContactEmail != "" {
+		if _, err := mail.ParseAddress(ri.ContactEmail); err != nil {
+			return fmt.Errorf("%w: invalid contact email", ErrInvalidRecurring)
+		}
+	}
+	ri.StartDate = utils.TruncateDay(ri.StartDate)
+	if ri.EndDate != nil {
+		end := utils.TruncateDay(*ri.EndDate)
+		if end.Before(ri.StartDate) {
+			return fmt.Errorf("%w: endDate is before startDate", ErrInvalidRecurring)
+		}
+		ri.EndDate = &end
+	}
+
+	switch ri.Cadence {
+	case models.CadenceMonthly, models.CadenceQuarterly:
+		ri.CronSpec = ""
+	case models.CadenceCron:
+		sched, err := cron.ParseStandard(ri.CronSpec)
+		if err != nil {
+			return fmt.Errorf("%w: cronSpec: %v", ErrInvalidRecurring, err)
+		}
+		// Invoices are dated by day, so periods must be at least a day long.
+		prev := sched.Next(ri.StartDate)
+		for i := 0; i < 10; i++ {
+			next := sched.Next(prev)
+			if next.Sub(prev) < 24*time.Hour {
+				return fmt.Errorf("%w: cronSpec must not run more than once a day", ErrInvalidRecurring)
+			}
+			prev = next
+		}
+	default:
+		return fmt.Errorf("%w: cadence must be monthly, quarterly or cron", ErrInvalidRecurring)
+	}
+	return nil
+}
+
+// NextPeriod is the start of the first period after the given time, or the
+// first period on or after the start date when after is zero. Monthly and
+// quarterly periods start on the start date's day of the month, or the last
+// day of shorter months.
+func NextPeriod(ri *models.RecurringInvoice, after time.Time) (time.Time, error) {
+	if after.Before(ri.StartDate) {
+		after = ri.StartDate.Add(-time.Second)
+	}
+	switch ri.Cadence {
+	case models.CadenceMonthly, models.CadenceQuarterly:
+		step := 1
+		if ri.Cadence == models.CadenceQuarterly {
+			step = 3
+		}
+		for n := 0; ; n += step {
+			start := addMonths(ri.StartDate, n)
+			if start.After(after) {
+				return start, nil
+			}
+		}
+	case models.CadenceCron:
+		sched, err := cron.ParseStandard(ri.CronSpec)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: cronSpec: %v", ErrInvalidRecurring, err)
+		}
+		return sched.Next(after.UTC()), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: unknown cadence %q", ErrInvalidRecurring, ri.Cadence)
+}
+
+// addMonths moves t forward n months, keeping its day of the month where the
+// month has one.
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// Reschedule sets when the recurring invoice next bills: the first period
+// after the last one billed and not before notBefore (periods a paused
+// schedule skipped are not billed on resuming). Schedules past their end
+// date are completed; paused ones stay paused.
+func Reschedule(tx *gorm.DB, ri *models.RecurringInvoice, notBefore time.Time) error {
+	var last models.RecurringInvoiceRun
+	after := time.Time{}
+	err := tx.Where("recurring_invoice_id = ?", ri.ID).Order("period_start DESC").First(&last).Error
+	switch {
+	case err == nil:
+		after = last.PeriodStart
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+	if !notBefore.IsZero() && notBefore.Add(-time.Second).After(after) {
+		after = notBefore.Add(-time.Second)
+	}
+	next, err := NextPeriod(ri, after)
+	if err != nil {
+		return err
+	}
+	if ri.EndDate != nil && next.After(ri.EndDate.Add(24*time.Hour-time.Second)) {
+		ri.Status = models.RecurringCompleted
+		ri.NextRunAt = nil
+		return nil
+	}
+	ri.NextRunAt = &next
+	if ri.Status != models.RecurringPaused {
+		ri.Status = models.RecurringActive
+	}
+	return nil
+}
+
+// GenerateRecurring bills one period of a recurring invoice: a sale invoice
+// dated the period's start for the amount plus tax, issued unless the
+// schedule drafts. A period already billed returns ErrAlreadyInvoiced.
+func GenerateRecurring(tx *gorm.DB, ri *models.RecurringInvoice, period time.Time) (*models.Invoice, error) {
+	next, err := NextPeriod(ri, period)
+	if err != nil {
+		return nil, err
+	}
+	end := utils.TruncateDay(next.AddDate(0, 0, -1))
+	if end.Before(utils.TruncateDay(period)) {
+		end = utils.TruncateDay(period)
+	}
+	run := models.RecurringInvoiceRun{TenantID: ri.TenantID, RecurringInvoiceID: ri.ID, PeriodStart: period, PeriodEnd: end}
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&run)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: recurring invoice %d for %s", ErrAlreadyInvoiced, ri.ID, period.Format("2006-01-02"))
+	}
+
+	issueDate := utils.TruncateDay(period)
+	line := models.InvoiceItem{
+		TenantID:    ri.TenantID,
+		Description: fmt.Sprintf("%s (%s - %s)", ri.Description, issueDate.Format("02 Jan 2006"), end.Format("02 Jan 2006")),
+		Quantity:    1,
+		UnitPrice:   ri.Amount,
+	}
+	if ri.TaxRate > 0 {
+		name := ri.TaxName
+		if name == "" {
+			name = "Tax"
+		}
+		line.Taxes = []models.InvoiceItemTax{{Name: name, Rate: ri.TaxRate}}
+	}
+	inv := models.Invoice{
+		TenantID:     ri.TenantID,
+		InvoiceType:  "sale",
+		Currency:     ri.Currency,
+		CustomerID:   ri.CustomerID,
+		ContactEmail: ri.ContactEmail,
+		IssueDate:    issueDate,
+		DueDate:      issueDate.AddDate(0, 0, ri.DueInDays),
+		Status:       Draft,
+		Items:        []models.InvoiceItem{line},
+	}
+	if err := ComputeTotals(&inv); err != nil {
+		return nil, err
+	}
+	if err := fx.RecordInvoice(tx, &inv); err != nil {
+		return nil, err
+	}
+	if err := tx.Create(&inv).Error; err != nil {
+		return nil, err
+	}
+	if !ri.Draft {
+		if err := Issue(tx, &inv); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Model(&run).Update("invoice_id", inv.ID).Error; err != nil {
+		return nil, err
+	}
+	return &inv, utils.LogAction(tx, ri.TenantID, 0, "GENERATE_RECURRING_INVOICE", "Invoice",
+		fmt.Sprintf("Recurring invoice %q billed %s to %s", ri.Name, issueDate.Format("2006-01-02"), end.Format("2006-01-02")))
+}
+
+// GenerateDueRecurring bills every period of the active recurring invoices
+// that has started by now, including periods missed while the job was not
+// running, and returns how many invoices it generated. Each period is billed
+// in its own transaction; a failure is logged and the schedule retried on
+// the next run.
+func GenerateDueRecurring(db *gorm.DB, now time.Time) (int, error) {
+	var due []models.RecurringInvoice
+	if err := db.Where("status = ? AND next_run_at <= ?", models.RecurringActive, now).
+		Order("id").Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	generated := 0
+	for i := range due {
+		ri := &due[i]
+		for n := 0; n < maxCatchUp && ri.Status == models.RecurringActive && ri.NextRunAt != nil && !ri.NextRunAt.After(now); n++ {
+			period, billed := *ri.NextRunAt, false
+			err := db.Transaction(func(tx *gorm.DB) error {
+				_, err := GenerateRecurring(tx, ri, period)
+				if err != nil && !errors.Is(err, ErrAlreadyInvoiced) {
+					return err
+				}
+				billed = err == nil
+				if err := Reschedule(tx, ri, time.Time{}); err != nil {
+					return err
+				}
+				return tx.Model(&models.RecurringInvoice{}).Where("id = ?", ri.ID).
+					Updates(map[string]interface{}{"next_run_at": ri.NextRunAt, "status": ri.Status, "updated_at": time.Now()}).Error
+			})
+			if err != nil {
+				log.Printf("Failed to generate recurring invoice %d for %s: %v", ri.ID, period.Format("2006-01-02"), err)
+				break
+			}
+			if billed {
+				generated++
+			}
+		}
+	}
+	return generated, nil
+}
@@ -0,0 +1,21 @@
+// internal/jobs/payment_reminders.go
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"travel-agency/internal/dunning"
+)
+
+// SendPaymentReminders emails the reminders due under each tenant's
+// reminder schedule.
+func SendPaymentReminders(reminders *dunning.Reminders) {
+	sent, err := reminders.SendDue(time.Now())
+	if err != nil {
+		log.Printf("Error sending payment reminders: %v", err)
+	}
+	if sent > 0 {
+		log.Printf("Sent %d payment reminder(s)", sent)
+	}
+}
@@ -0,0 +1,39 @@
+// internal/jobs/payment_runs.go
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/payables"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExecuteDuePaymentRuns pays scheduled runs whose date has arrived.
+func ExecuteDuePaymentRuns(db *gorm.DB) {
+	var runs []models.PaymentRun
+	if err := db.Preload("Payments").
+		Where("status = ? AND scheduled_for <= ?", models.PaymentRunScheduled, time.Now()).
+		Find(&runs).Error; err != nil {
+		log.Printf("Error fetching payment runs: %v", err)
+		return
+	}
+
+	for i := range runs {
+		run := &runs[i]
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			// Reload it locked: it may have been paid or cancelled by hand
+			// since it was listed. ExecuteRun checks the status again.
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Payments").
+				First(run, run.ID).Error; err != nil {
+				return err
+			}
+			return payables.ExecuteRun(tx, run, 0)
+		}); err != nil {
+			log.Printf("Failed to execute payment run %d: %v", run.ID, err)
+		}
+	}
+}
@@ -4,45 +4,45 @@ import (
 	"log"
 	"time"
 
+	"travel-agency/internal/dunning"
 	"travel-agency/internal/models"
+	"travel-agency/internal/receivables"
 
 	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
 )
 
 // ReconcileInvoices recalculates payment totals and updates invoice statuses.
+// Payments count through their allocations; see receivables.RefreshStatus.
 func ReconcileInvoices(db *gorm.DB) {
 	var invoices []models.Invoice
-	// Get invoices not marked as Paid or Canceled.
-	if err := db.Where("status NOT IN ?", []string{"Paid", "Canceled"}).Find(&invoices).Error; err != nil {
+	// Get invoices not marked as Paid, Credited, Canceled or Void. Drafts are
+	// left alone; they only become Outstanding when issued (and numbered).
+	if err := db.Where("status NOT IN ?", []string{"Paid", "Credited", "Canceled", "Cancelled", "Void", "Draft"}).Find(&invoices).Error; err != nil {
 		log.Printf("Error fetching invoices: %v", err)
 		return
 	}
 
-	for _, invoice := range invoices {
-		var totalPaid float64
-		db.Model(&models.Payment{}).
-			Where("invoice_id = ?", invoice.ID).
-			Select("COALESCE(SUM(amount), 0)").Row().Scan(&totalPaid)
-
-		// Reconciliation logic:
-		if totalPaid >= invoice.Amount {
-			invoice.Status = "Paid"
-		} else if time.Now().After(invoice.DueDate) {
-			invoice.Status = "Overdue"
-		} else {
-			invoice.Status = "Outstanding"
-		}
-
-		if err := db.Save(&invoice).Error; err != nil {
-			log.Printf("Failed to update invoice %d: %v", invoice.ID, err)
+	now := time.Now()
+	for i := range invoices {
+		if err := receivables.RefreshStatus(db, &invoices[i], now); err != nil {
+			log.Printf("Failed to update invoice %s: %v", invoices[i].ID, err)
 		}
 	}
 }
 
-func StartCronJobs(db *gorm.DB) {
+func StartCronJobs(db *gorm.DB, reminders *dunning.Reminders) {
 	c := cron.New()
 	// Schedule the reconciliation job to run every hour.
 	c.AddFunc("@hourly", func() { ReconcileInvoices(db) })
+	// Release unsold allotment shortly after midnight.
+	c.AddFunc("15 0 * * *", func() { ReleaseAllotments(db) })
+	// Bill recurring invoices; hourly so a missed run is caught up the same day.
+	c.AddFunc("30 * * * *", func() { GenerateRecurringInvoices(db) })
+	// Pay scheduled supplier payment runs on their date.
+	c.AddFunc("0 6 * * *", func() { ExecuteDuePaymentRuns(db) })
+	// Remind customers of due and overdue invoices once a day, after the
+	// morning's reconciliation has caught up with payments.
+	c.AddFunc("30 9 * * *", func() { SendPaymentReminders(reminders) })
 	c.Start()
 }
@@ -0,0 +1,23 @@
+// internal/jobs/recurring_invoices.go
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"travel-agency/internal/invoicing"
+
+	"gorm.io/gorm"
+)
+
+// GenerateRecurringInvoices bills the periods of recurring invoices that
+// have started.
+func GenerateRecurringInvoices(db *gorm.DB) {
+	generated, err := invoicing.GenerateDueRecurring(db, time.Now())
+	if err != nil {
+		log.Printf("Error generating recurring invoices: %v", err)
+	}
+	if generated > 0 {
+		log.Printf("Generated %d recurring invoice(s)", generated)
+	}
+}
@@ -0,0 +1,23 @@
+// internal/jobs/release_inventory.go
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"travel-agency/internal/inventory"
+
+	"gorm.io/gorm"
+)
+
+// ReleaseAllotments hands unsold allotment back to vendors once its
+// release date has passed.
+func ReleaseAllotments(db *gorm.DB) {
+	n, err := inventory.Release(db, time.Now())
+	if err != nil {
+		log.Printf("Error releasing allotments: %v", err)
+	}
+	if n > 0 {
+		log.Printf("Released %d allotment(s)", n)
+	}
+}
@@ -0,0 +1,254 @@
+// internal/ledger/documents.go
+package ledger
+
+import (
+	"fmt"
+
+	"travel-agency/internal/fx"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/receivables"
+
+	"gorm.io/gorm"
+)
+
+// Source types of entries posted for documents.
+const (
+	SourceInvoice       = "invoice"
+	SourceCreditNote    = "credit_note"
+	SourcePayment       = "payment"
+	SourceRefund        = "refund"
+	SourceVendorPayment = "vendor_payment"
+)
+
+// converter turns document amounts into base currency amounts at the rate
+// recorded on the document.
+type converter struct {
+	base string
+	rate float64
+}
+
+func newConverter(tx *gorm.DB, tenantID uint, rate float64) (converter, error) {
+	base, err := fx.BaseCurrency(tx, tenantID)
+	return converter{base: base, rate: rate}, err
+}
+
+func (c converter) amount(a money.Amount) money.Amount {
+	return fx.Rated(a, c.rate).Round(c.base)
+}
+
+func invoiceLabel(inv *models.Invoice) string {
+	switch {
+	case inv.Number != nil:
+		return *inv.Number
+	case inv.Reference != "":
+		return inv.Reference
+	default:
+		return inv.ID.String()
+	}
+}
+
+// PostInvoice brings the ledger in line with an invoice. An issued sale
+// invoice debits receivables and credits sales and output tax; a purchase
+// invoice debits cost of sales and input tax and credits payables. Drafts
+// and void invoices have no effect, so voiding reverses what was posted.
+func PostInvoice(tx *gorm.DB, inv *models.Invoice) error {
+	c, err := newConverter(tx, inv.TenantID, inv.ExchangeRate)
+	if err != nil {
+		return err
+	}
+	want := map[string]money.Amount{}
+	if !receivables.IsClosed(inv.Status) {
+		total, tax := c.amount(inv.Amount), c.amount(inv.TaxTotal)
+		if inv.InvoiceType == "purchase" {
+			want[CostOfSales] = total - tax
+			want[InputTax] = tax
+			want[Payable] = -total
+		} else {
+			want[Receivable] = total
+			want[Sales] = -(total - tax)
+			want[OutputTax] = -tax
+		}
+	}
+	memo := "Invoice " + invoiceLabel(inv)
+	if inv.InvoiceType == "purchase" {
+		memo = "Purchase invoice " + invoiceLabel(inv)
+	}
+	return sync(tx, inv.TenantID, Source{Type: SourceInvoice, ID: inv.ID.String(), Date: inv.IssueDate,
+		Memo: memo, Currency: inv.Currency, Rate: inv.ExchangeRate}, want)
+}
+
+// PostCreditNote brings the ledger in line with a credit note against inv,
+// reversing the invoice's postings in proportion: sales returns and output
+// tax against receivables, or payables against cost of sales and input tax.
+func PostCreditNote(tx *gorm.DB, inv *models.Invoice, cn *models.CreditNote) error {
+	c, err := newConverter(tx, cn.TenantID, inv.ExchangeRate)
+	if err != nil {
+		return err
+	}
+	want := map[string]money.Amount{}
+	if cn.Status == receivables.CreditNoteIssued {
+		total, tax := c.amount(cn.Amount), c.amount(cn.TaxTotal)
+		if inv.InvoiceType == "purchase" {
+			want[Payable] = total
+			want[CostOfSales] = -(total - tax)
+			want[InputTax] = -tax
+		} else {
+			want[SalesReturns] = total - tax
+			want[OutputTax] = tax
+			want[Receivable] = -total
+		}
+	}
+	label := cn.ID.String()
+	if cn.Number != nil {
+		label = *cn.Number
+	}
+	return sync(tx, cn.TenantID, Source{Type: SourceCreditNote, ID: cn.ID.String(), Date: cn.IssueDate,
+		Memo: fmt.Sprintf("Credit note %s on %s", label, invoiceLabel(inv)), Currency: cn.Currency, Rate: inv.ExchangeRate}, want)
+}
+
+// PostPayment brings the ledger in line with a customer payment: while it
+// is completed, cash is debited and receivables credited for its amount
+// less what was refunded through it.
+func PostPayment(tx *gorm.DB, p *models.Payment) error {
+	c, err := newConverter(tx, p.TenantID, p.ExchangeRate)
+	if err != nil {
+		return err
+	}
+	want := map[string]money.Amount{}
+	if p.Status == receivables.PaymentCompleted {
+		var refunded money.Amount
+		if err := tx.Model(&models.Refund{}).
+			Where("payment_id = ? AND status = ?", p.ID, receivables.PaymentCompleted).
+			Select("COALESCE(SUM(amount), 0)").Row().Scan(&refunded); err != nil {
+			return err
+		}
+		received := c.amount(p.Amount - refunded)
+		want[Cash] = received
+		want[Receivable] = -received
+	}
+	return sync(tx, p.TenantID, Source{Type: SourcePayment, ID: fmt.Sprint(p.ID), Date: p.PaymentDate,
+		Memo: fmt.Sprintf("Payment %d %s", p.ID, p.Reference), Currency: p.Currency, Rate: p.ExchangeRate}, want)
+}
+
+// PostRefund brings the ledger in line with a refund. Refunds through a
+// payment's gateway net off that payment; others credit cash and debit
+// receivables once completed, at the invoice's rate.
+func PostRefund(tx *gorm.DB, r *models.Refund) error {
+	if r.PaymentID != nil {
+		var p models.Payment
+		if err := tx.First(&p, *r.PaymentID).Error; err != nil {
+			return err
+		}
+		return PostPayment(tx, &p)
+	}
+	var inv models.Invoice
+	if err := tx.Select("id", "tenant_id", "number", "reference", "exchange_rate").
+		First(&inv, "id = ?", r.InvoiceID).Error; err != nil {
+		return err
+	}
+	c, err := newConverter(tx, r.TenantID, inv.ExchangeRate)
+	if err != nil {
+		return err
+	}
+	want := map[string]money.Amount{}
+	if r.Status == receivables.PaymentCompleted {
+		refunded := c.amount(r.Amount)
+		want[Receivable] = refunded
+		want[Cash] = -refunded
+	}
+	return sync(tx, r.TenantID, Source{Type: SourceRefund, ID: fmt.Sprint(r.ID), Date: r.UpdatedAt,
+		Memo: fmt.Sprintf("Refund %d on %s", r.ID, invoiceLabel(&inv)), Currency: r.Currency, Rate: inv.ExchangeRate}, want)
+}
+
+// PostVendorPayment brings the ledger in line with a payment to a vendor:
+// once completed, payables are debited and cash credited at the purchase
+// invoice's rate.
+func PostVendorPayment(tx *gorm.DB, vp *models.VendorPayment) error {
+	var inv models.Invoice
+	if err := tx.Select("id", "tenant_id", "number", "reference", "exchange_rate").
+		First(&inv, "id = ?", vp.InvoiceID).Error; err != nil {
+		return err
+	}
+	c, err := newConverter(tx, vp.TenantID, inv.ExchangeRate)
+	if err != nil {
+		return err
+	}
+	want := map[string]money.Amount{}
+	if vp.Status == "Completed" {
+		paid := c.amount(vp.Amount)
+		want[Payable] = paid
+		want[Cash] = -paid
+	}
+	src := Source{Type: SourceVendorPayment, ID: fmt.Sprint(vp.ID), Date: vp.UpdatedAt,
+		Memo: fmt.Sprintf("Vendor payment %d on %s", vp.ID, invoiceLabel(&inv)), Currency: vp.Currency, Rate: inv.ExchangeRate}
+	if vp.PaidAt != nil {
+		src.Date = *vp.PaidAt
+	}
+	return sync(tx, vp.TenantID, src, want)
+}
+
+// Backfill posts the tenant's documents that are not (fully) in the ledger
+// yet, e.g. those from before it existed, and returns how many it looked at.
+func Backfill(tx *gorm.DB, tenantID uint) (int, error) {
+	n := 0
+	var invoices []models.Invoice
+	if err := tx.Where("tenant_id = ?", tenantID).Find(&invoices).Error; err != nil {
+		return n, err
+	}
+	byID := map[string]*models.Invoice{}
+	for i := range invoices {
+		if err := PostInvoice(tx, &invoices[i]); err != nil {
+			return n, err
+		}
+		byID[invoices[i].ID.String()] = &invoices[i]
+		n++
+	}
+
+	var notes []models.CreditNote
+	if err := tx.Where("tenant_id = ?", tenantID).Find(&notes).Error; err != nil {
+		return n, err
+	}
+	for i := range notes {
+		if inv, ok := byID[notes[i].InvoiceID.String()]; ok {
+			if err := PostCreditNote(tx, inv, &notes[i]); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+
+	var payments []models.Payment
+	if err := tx.Where("tenant_id = ?", tenantID).Find(&payments).Error; err != nil {
+		return n, err
+	}
+	for i := range payments {
+		if err := PostPayment(tx, &payments[i]); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	var refunds []models.Refund
+	if err := tx.Where("tenant_id = ? AND payment_id IS NULL", tenantID).Find(&refunds).Error; err != nil {
+		return n, err
+	}
+	for i := range refunds {
+		if err := PostRefund(tx, &refunds[i]); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	var vendorPayments []models.VendorPayment
+	if err := tx.Where("tenant_id = ?", tenantID).Find(&vendorPayments).Error; err != nil {
+		return n, err
+	}
+	for i := range vendorPayments {
+		if err := PostVendorPayment(tx, &vendorPayments[i]); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
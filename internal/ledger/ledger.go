@@ -0,0 +1,250 @@
+// internal/ledger/ledger.go
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Codes of the system accounts documents are posted to.
+const (
+	Cash         = "1000"
+	Receivable   = "1100"
+	InputTax     = "1200"
+	Payable      = "2000"
+	OutputTax    = "2100"
+	Equity       = "3000"
+	Sales        = "4000"
+	SalesReturns = "4100"
+	CostOfSales  = "5000"
+)
+
+// Manual is the source type of entries posted by hand.
+const Manual = "manual"
+
+// Errors returned when posting entries.
+var (
+	ErrInvalidEntry  = errors.New("invalid journal entry")
+	ErrUnbalanced    = errors.New("journal entry does not balance")
+	ErrCannotReverse = errors.New("journal entry cannot be reversed")
+)
+
+// chart is the chart of accounts every tenant starts with.
+var chart = []models.Account{
+	{Code: Cash, Name: "Cash and Bank", Type: models.AccountAsset},
+	{Code: Receivable, Name: "Accounts Receivable", Type: models.AccountAsset},
+	{Code: InputTax, Name: "Input Tax", Type: models.AccountAsset},
+	{Code: Payable, Name: "Accounts Payable", Type: models.AccountLiability},
+	{Code: OutputTax, Name: "Output Tax", Type: models.AccountLiability},
+	{Code: Equity, Name: "Owner's Equity", Type: models.AccountEquity},
+	{Code: Sales, Name: "Sales", Type: models.AccountIncome},
+	{Code: SalesReturns, Name: "Sales Returns and Credits", Type: models.AccountIncome},
+	{Code: CostOfSales, Name: "Cost of Sales", Type: models.AccountExpense},
+}
+
+// ValidType reports whether t is an account type.
+func ValidType(t string) bool {
+	switch t {
+	case models.AccountAsset, models.AccountLiability, models.AccountEquity, models.AccountIncome, models.AccountExpense:
+		return true
+	}
+	return false
+}
+
+// debitNormal reports whether accounts of the type carry debit balances.
+func debitNormal(t string) bool {
+	return t == models.AccountAsset || t == models.AccountExpense
+}
+
+// EnsureChart creates whichever of the system accounts the tenant is missing.
+func EnsureChart(tx *gorm.DB, tenantID uint) error {
+	accounts := make([]models.Account, len(chart))
+	for i, a := range chart {
+		a.TenantID = tenantID
+		a.System = true
+		accounts[i] = a
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&accounts).Error
+}
+
+// Accounts returns the tenant's chart of accounts ordered by code.
+func Accounts(tx *gorm.DB, tenantID uint) ([]models.Account, error) {
+	if err := EnsureChart(tx, tenantID); err != nil {
+		return nil, err
+	}
+	var accounts []models.Account
+	err := tx.Where("tenant_id = ?", tenantID).Order("code").Find(&accounts).Error
+	return accounts, err
+}
+
+// Post validates and saves an entry. Each line must debit or credit (not
+// both) a positive amount to one of the tenant's accounts, and debits must
+// equal credits.
+func Post(tx *gorm.DB, e *models.JournalEntry) error {
+	if len(e.Lines) < 2 {
+		return fmt.Errorf("%w: an entry needs at least two lines", ErrInvalidEntry)
+	}
+	if e.Date.IsZero() {
+		e.Date = time.Now()
+	}
+	ids := make([]uint, 0, len(e.Lines))
+	var debits, credits money.Amount
+	for i := range e.Lines {
+		l := &e.Lines[i]
+		if l.Debit < 0 || l.Credit < 0 || (l.Debit == 0) == (l.Credit == 0) {
+			return fmt.Errorf("%w: line %d must have either a debit or a credit", ErrInvalidEntry, i+1)
+		}
+		l.ID = 0
+		l.TenantID = e.TenantID
+		l.Account = nil
+		debits += l.Debit
+		credits += l.Credit
+		ids = append(ids, l.AccountID)
+	}
+	if debits != credits {
+		return fmt.Errorf("%w: debits %s, credits %s", ErrUnbalanced, debits, credits)
+	}
+	var known int64
+	if err := tx.Model(&models.Account{}).Where("tenant_id = ? AND id IN ?", e.TenantID, ids).
+		Distinct("id").Count(&known).Error; err != nil {
+		return err
+	}
+	if int(known) != len(distinct(ids)) {
+		return fmt.Errorf("%w: unknown account", ErrInvalidEntry)
+	}
+	e.ID = 0
+	e.CreatedAt = time.Now()
+	return tx.Create(e).Error
+}
+
+func distinct(ids []uint) map[uint]bool {
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	return seen
+}
+
+// Reverse posts an entry that undoes a manual entry. Entries posted for
+// documents follow their documents instead.
+func Reverse(tx *gorm.DB, tenantID, entryID, actorID uint, date time.Time) (*models.JournalEntry, error) {
+	var entry models.JournalEntry
+	if err := tx.Preload("Lines").Where("id = ? AND tenant_id = ?", entryID, tenantID).
+		First(&entry).Error; err != nil {
+		return nil, err
+	}
+	if entry.SourceType != Manual || entry.ReversalOfID != nil {
+		return nil, fmt.Errorf("%w: only manual entries can be reversed", ErrCannotReverse)
+	}
+	var reversed int64
+	if err := tx.Model(&models.JournalEntry{}).Where("reversal_of_id = ?", entry.ID).
+		Count(&reversed).Error; err != nil {
+		return nil, err
+	}
+	if reversed > 0 {
+		return nil, fmt.Errorf("%w: already reversed", ErrCannotReverse)
+	}
+
+	reversal := models.JournalEntry{
+		TenantID:     tenantID,
+		Date:         date,
+		Memo:         fmt.Sprintf("Reversal of entry %d: %s", entry.ID, entry.Memo),
+		SourceType:   Manual,
+		ReversalOfID: &entry.ID,
+		CreatedByID:  actorID,
+	}
+	for _, l := range entry.Lines {
+		reversal.Lines = append(reversal.Lines, models.JournalLine{AccountID: l.AccountID, Debit: l.Credit, Credit: l.Debit})
+	}
+	return &reversal, Post(tx, &reversal)
+}
+
+// Source identifies the document an entry is posted for.
+type Source struct {
+	Type     string
+	ID       string
+	Date     time.Time // Used for the document's first entry; later ones are dated when posted.
+	Memo     string
+	Currency string
+	Rate     float64
+}
+
+// sync posts what it takes for the document's entries to add up to want,
+// the net debit (credits negative) per account code in the base currency.
+// Nothing is posted when they already do, so syncing is idempotent and a
+// changed or voided document gets an adjusting entry.
+func sync(tx *gorm.DB, tenantID uint, src Source, want map[string]money.Amount) error {
+	accounts, err := Accounts(tx, tenantID)
+	if err != nil {
+		return err
+	}
+	byCode := make(map[string]uint, len(accounts))
+	for _, a := range accounts {
+		byCode[a.Code] = a.ID
+	}
+
+	type row struct {
+		AccountID uint
+		Net       money.Amount
+	}
+	var rows []row
+	if err := tx.Table("journal_lines").
+		Select("journal_lines.account_id, SUM(journal_lines.debit) - SUM(journal_lines.credit) AS net").
+		Joins("JOIN journal_entries ON journal_entries.id = journal_lines.entry_id").
+		Where("journal_entries.tenant_id = ? AND journal_entries.source_type = ? AND journal_entries.source_id = ?",
+			tenantID, src.Type, src.ID).
+		Group("journal_lines.account_id").Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	diff := map[uint]money.Amount{}
+	for code, amount := range want {
+		id, ok := byCode[code]
+		if !ok {
+			return fmt.Errorf("%w: no account %s", ErrInvalidEntry, code)
+		}
+		diff[id] += amount
+	}
+	for _, r := range rows {
+		diff[r.AccountID] -= r.Net
+	}
+
+	entry := models.JournalEntry{
+		TenantID:     tenantID,
+		Date:         src.Date,
+		Memo:         src.Memo,
+		SourceType:   src.Type,
+		SourceID:     src.ID,
+		Currency:     src.Currency,
+		ExchangeRate: src.Rate,
+	}
+	if len(rows) > 0 {
+		entry.Date = time.Now()
+		entry.Memo = "Adjustment: " + src.Memo
+	}
+	ids := make([]uint, 0, len(diff))
+	for id := range diff {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		switch d := diff[id]; {
+		case d > 0:
+			entry.Lines = append(entry.Lines, models.JournalLine{AccountID: id, Debit: d})
+		case d < 0:
+			entry.Lines = append(entry.Lines, models.JournalLine{AccountID: id, Credit: -d})
+		}
+	}
+	if len(entry.Lines) == 0 {
+		return nil
+	}
+	return Post(tx, &entry)
+}
@@ -0,0 +1,99 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/receivables"
+	"travel-agency/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostingsAndReports(t *testing.T) {
+	db := testutil.DB(t)
+	today := time.Now()
+	balance := func(code string) money.Amount {
+		tb, err := BuildTrialBalance(db, 1, today)
+		assert.NoError(t, err)
+		assert.Equal(t, tb.TotalDebit, tb.TotalCredit)
+		for _, b := range tb.Accounts {
+			if b.Code == code {
+				return b.Balance
+			}
+		}
+		return 0
+	}
+
+	// An issued sale invoice of 1100 incl. 100 tax.
+	inv := models.Invoice{TenantID: 1, InvoiceType: "sale", Status: "Outstanding", Currency: "INR",
+		IssueDate: today, DueDate: today, Amount: money.FromInt(1100), TaxTotal: money.FromInt(100)}
+	db.Create(&inv)
+	assert.NoError(t, PostInvoice(db, &inv))
+	assert.NoError(t, PostInvoice(db, &inv)) // Posting again changes nothing.
+	assert.Equal(t, money.FromInt(1100), balance(Receivable))
+	assert.Equal(t, money.FromInt(1000), balance(Sales))
+	assert.Equal(t, money.FromInt(100), balance(OutputTax))
+
+	// A credit note for 110 and a payment of the remaining 990.
+	cn := models.CreditNote{TenantID: 1, InvoiceID: inv.ID, Status: receivables.CreditNoteIssued, Currency: "INR",
+		IssueDate: today, Amount: money.FromInt(110), TaxTotal: money.FromInt(10)}
+	db.Create(&cn)
+	assert.NoError(t, PostCreditNote(db, &inv, &cn))
+	p := models.Payment{TenantID: 1, Amount: money.FromInt(990), Currency: "INR", PaymentDate: today,
+		Status: receivables.PaymentCompleted}
+	db.Create(&p)
+	assert.NoError(t, PostPayment(db, &p))
+	assert.Equal(t, money.FromInt(0), balance(Receivable))
+	assert.Equal(t, money.FromInt(990), balance(Cash))
+	assert.Equal(t, money.FromInt(90), balance(OutputTax))
+
+	pl, err := BuildProfitAndLoss(db, 1, today.AddDate(0, 0, -1), today)
+	assert.NoError(t, err)
+	assert.Equal(t, money.FromInt(900), pl.NetProfit)
+
+	// Voiding the invoice reverses its postings with an adjusting entry.
+	inv.Status = "Void"
+	assert.NoError(t, PostInvoice(db, &inv))
+	assert.Equal(t, money.FromInt(0), balance(Sales))
+	var entries int64
+	db.Model(&models.JournalEntry{}).Where("source_type = ? AND source_id = ?", SourceInvoice, inv.ID.String()).Count(&entries)
+	assert.Equal(t, int64(2), entries)
+
+	// Posted entries cannot be changed or deleted.
+	var entry models.JournalEntry
+	db.Preload("Lines").First(&entry)
+	assert.ErrorIs(t, db.Model(&entry).Update("memo", "changed").Error, models.ErrPostedEntry)
+	assert.ErrorIs(t, db.Delete(&entry).Error, models.ErrPostedEntry)
+	assert.ErrorIs(t, db.Delete(&entry.Lines[0]).Error, models.ErrPostedEntry)
+}
+
+func TestManualEntries(t *testing.T) {
+	db := testutil.DB(t)
+	accounts, err := Accounts(db, 1)
+	assert.NoError(t, err)
+	id := map[string]uint{}
+	for _, a := range accounts {
+		id[a.Code] = a.ID
+	}
+
+	unbalanced := models.JournalEntry{TenantID: 1, SourceType: Manual, Lines: []models.JournalLine{
+		{AccountID: id[Cash], Debit: money.FromInt(100)}, {AccountID: id[Equity], Credit: money.FromInt(90)}}}
+	assert.ErrorIs(t, Post(db, &unbalanced), ErrUnbalanced)
+	foreign := models.JournalEntry{TenantID: 2, SourceType: Manual, Lines: []models.JournalLine{
+		{AccountID: id[Cash], Debit: money.FromInt(100)}, {AccountID: id[Equity], Credit: money.FromInt(100)}}}
+	assert.ErrorIs(t, Post(db, &foreign), ErrInvalidEntry)
+
+	capital := models.JournalEntry{TenantID: 1, SourceType: Manual, Memo: "Capital", Lines: []models.JournalLine{
+		{AccountID: id[Cash], Debit: money.FromInt(100)}, {AccountID: id[Equity], Credit: money.FromInt(100)}}}
+	assert.NoError(t, Post(db, &capital))
+	reversal, err := Reverse(db, 1, capital.ID, 1, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, money.FromInt(100), reversal.Lines[0].Credit)
+	_, err = Reverse(db, 1, capital.ID, 1, time.Now())
+	assert.ErrorIs(t, err, ErrCannotReverse)
+	_, err = Reverse(db, 1, reversal.ID, 1, time.Now())
+	assert.ErrorIs(t, err, ErrCannotReverse)
+}
@@ -0,0 +1,131 @@
+// internal/ledger/reports.go
+package ledger
+
+import (
+	"time"
+
+	"travel-agency/internal/fx"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// Balance is what has been posted to one account. Balance is in the
+// account's normal direction: debits less credits for assets and expenses,
+// credits less debits otherwise.
+type Balance struct {
+	AccountID uint         `json:"accountId"`
+	Code      string       `json:"code"`
+	Name      string       `json:"name"`
+	Type      string       `json:"type"`
+	Debit     money.Amount `json:"debit"`
+	Credit    money.Amount `json:"credit"`
+	Balance   money.Amount `json:"balance"`
+}
+
+// TrialBalance lists every account's totals up to and including AsOf.
+// TotalDebit and TotalCredit are equal.
+type TrialBalance struct {
+	AsOf        time.Time    `json:"asOf"`
+	Currency    string       `json:"currency"`
+	Accounts    []Balance    `json:"accounts"`
+	TotalDebit  money.Amount `json:"totalDebit"`
+	TotalCredit money.Amount `json:"totalCredit"`
+}
+
+// ProfitAndLoss is income less expenses posted between From and To
+// (inclusive days).
+type ProfitAndLoss struct {
+	From          time.Time    `json:"from"`
+	To            time.Time    `json:"to"`
+	Currency      string       `json:"currency"`
+	Income        []Balance    `json:"income"`
+	Expenses      []Balance    `json:"expenses"`
+	TotalIncome   money.Amount `json:"totalIncome"`
+	TotalExpenses money.Amount `json:"totalExpenses"`
+	NetProfit     money.Amount `json:"netProfit"`
+}
+
+// balances totals the lines of entries dated from (if not zero) up to and
+// including the day to, for every account of the tenant.
+func balances(db *gorm.DB, tenantID uint, from, to time.Time) ([]Balance, error) {
+	accounts, err := Accounts(db, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	q := db.Table("journal_lines").
+		Select("journal_lines.account_id, COALESCE(SUM(journal_lines.debit), 0) AS debit, COALESCE(SUM(journal_lines.credit), 0) AS credit").
+		Joins("JOIN journal_entries ON journal_entries.id = journal_lines.entry_id").
+		Where("journal_entries.tenant_id = ? AND journal_entries.date < ?", tenantID, to.AddDate(0, 0, 1))
+	if !from.IsZero() {
+		q = q.Where("journal_entries.date >= ?", from)
+	}
+	var rows []struct {
+		AccountID uint
+		Debit     money.Amount
+		Credit    money.Amount
+	}
+	if err := q.Group("journal_lines.account_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	posted := map[uint]int{}
+	for i, r := range rows {
+		posted[r.AccountID] = i
+	}
+
+	out := make([]Balance, 0, len(accounts))
+	for _, a := range accounts {
+		b := Balance{AccountID: a.ID, Code: a.Code, Name: a.Name, Type: a.Type}
+		if i, ok := posted[a.ID]; ok {
+			b.Debit, b.Credit = rows[i].Debit, rows[i].Credit
+		}
+		b.Balance = b.Credit - b.Debit
+		if debitNormal(a.Type) {
+			b.Balance = -b.Balance
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+// BuildTrialBalance totals the tenant's ledger up to and including asOf.
+func BuildTrialBalance(db *gorm.DB, tenantID uint, asOf time.Time) (*TrialBalance, error) {
+	accounts, err := balances(db, tenantID, time.Time{}, asOf)
+	if err != nil {
+		return nil, err
+	}
+	tb := &TrialBalance{AsOf: asOf, Accounts: accounts}
+	if tb.Currency, err = fx.BaseCurrency(db, tenantID); err != nil {
+		return nil, err
+	}
+	for _, b := range accounts {
+		tb.TotalDebit += b.Debit
+		tb.TotalCredit += b.Credit
+	}
+	return tb, nil
+}
+
+// BuildProfitAndLoss reports income and expenses posted between from and to.
+func BuildProfitAndLoss(db *gorm.DB, tenantID uint, from, to time.Time) (*ProfitAndLoss, error) {
+	accounts, err := balances(db, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	pl := &ProfitAndLoss{From: from, To: to, Income: []Balance{}, Expenses: []Balance{}}
+	if pl.Currency, err = fx.BaseCurrency(db, tenantID); err != nil {
+		return nil, err
+	}
+	for _, b := range accounts {
+		switch b.Type {
+		case models.AccountIncome:
+			pl.Income = append(pl.Income, b)
+			pl.TotalIncome += b.Balance
+		case models.AccountExpense:
+			pl.Expenses = append(pl.Expenses, b)
+			pl.TotalExpenses += b.Balance
+		}
+	}
+	pl.NetProfit = pl.TotalIncome - pl.TotalExpenses
+	return pl, nil
+}
@@ -0,0 +1,62 @@
+// internal/models/accounting.go
+package models
+
+import "time"
+
+// Accounting package export formats.
+const (
+	ExportQuickBooksIIF = "iif"
+	ExportXeroCSV       = "xero"
+	ExportTallyXML      = "tally"
+)
+
+// Kinds of accounting mappings.
+const (
+	MappingAccount = "account" // Key is an account role, e.g. "sales"; Value the account in the package.
+	MappingTax     = "tax"     // Key is a tax component name, e.g. "CGST"; Value the tax code or tax account.
+	MappingContact = "contact" // Key is a customer ID; Value the customer's name in the package.
+)
+
+// AccountingMapping maps one of our accounts, tax components or customers
+// to its name or code in an accounting package. Mappings with an empty
+// Format apply to every format unless a format has its own.
+type AccountingMapping struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TenantID  uint      `gorm:"not null;uniqueIndex:idx_accounting_mapping" json:"tenantId"`
+	Format    string    `gorm:"size:20;not null;default:'';uniqueIndex:idx_accounting_mapping" json:"format"`
+	Kind      string    `gorm:"size:20;not null;uniqueIndex:idx_accounting_mapping" json:"kind"`
+	Key       string    `gorm:"size:100;not null;uniqueIndex:idx_accounting_mapping" json:"key"`
+	Value     string    `gorm:"size:255;not null" json:"value"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// AccountingExport is one file rendered for an accounting package. The file
+// is kept so it can be downloaded again.
+type AccountingExport struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	TenantID    uint      `gorm:"not null;index" json:"tenantId"`
+	Format      string    `gorm:"size:20;not null" json:"format"`
+	From        time.Time `gorm:"not null" json:"from"`
+	To          time.Time `gorm:"not null" json:"to"`
+	Invoices    int       `json:"invoices"`
+	Bills       int       `json:"bills"`
+	Payments    int       `json:"payments"` // Customer and vendor payments.
+	Filename    string    `gorm:"size:255" json:"filename"`
+	ContentType string    `gorm:"size:100" json:"contentType"`
+	Content     []byte    `json:"-"`
+	CreatedBy   uint      `json:"createdBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// AccountingExportRecord marks a document as exported in a format, so later
+// exports in that format leave it out.
+type AccountingExportRecord struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	TenantID   uint      `gorm:"not null;uniqueIndex:idx_accounting_export_record" json:"tenantId"`
+	Format     string    `gorm:"size:20;not null;uniqueIndex:idx_accounting_export_record" json:"format"`
+	RecordType string    `gorm:"size:30;not null;uniqueIndex:idx_accounting_export_record" json:"recordType"`
+	RecordID   string    `gorm:"size:64;not null;uniqueIndex:idx_accounting_export_record" json:"recordId"`
+	ExportID   uint      `gorm:"not null;index" json:"exportId"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
@@ -1,20 +1,68 @@
 // internal/models/booking.go
 package models
 
-import "time"
+import (
+	"time"
+
+	"travel-agency/internal/money"
+
+	"github.com/google/uuid"
+)
+
+// Booking lifecycle states. See bookings.CanTransition for the allowed moves.
+const (
+	BookingPending   = "Pending"
+	BookingConfirmed = "Confirmed"
+	BookingTicketed  = "Ticketed"
+	BookingCompleted = "Completed"
+	BookingCancelled = "Cancelled"
+)
 
 // Booking represents a travel reservation or booking.
 type Booking struct {
-	ID          uint      `gorm:"primaryKey"`
-	TenantID    uint      `gorm:"not null;index"`            // Multi-tenant: associates booking with an agency.
-	ItineraryID uint      // Optional: if the booking is part of a larger itinerary.
-	VendorID    uint      `gorm:"not null;index"`            // References the vendor providing the service.
-	BookingRef  string    `gorm:"size:255"`                  // Supplier confirmation code or PNR.
-	Status      string    `gorm:"size:50;default:'Pending'"` // e.g., Pending, Confirmed, Canceled.
-	BookingDate time.Time // The date the booking is created.
-	TravelDate  time.Time // The travel date (or start date for hotels).
-	Cost        float64   `gorm:"default:0"`               // The cost charged by the vendor.
-	Price       float64   `gorm:"default:0"`               // The price charged to the client.
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID           uint         `gorm:"primaryKey"`
+	TenantID     uint         `gorm:"not null;index"` // Multi-tenant: associates booking with an agency.
+	ItineraryID  uint         // Optional: if the booking is part of a larger itinerary.
+	VendorID     uint         `gorm:"not null;index"`            // References the vendor providing the service.
+	InvoiceID    *uuid.UUID   `gorm:"type:uuid;index"`           // Optional: the sale invoice this booking is billed on.
+	BookingRef   string       `gorm:"size:255"`                  // Supplier confirmation code or PNR.
+	OfferID      string       `gorm:"size:255"`                  // Supplier offer, set when booked through a connector.
+	Status       string       `gorm:"size:50;default:'Pending'"` // Pending, Confirmed, Ticketed, Completed, Cancelled.
+	BookingDate  time.Time    // The date the booking is created.
+	TravelDate   time.Time    // The travel date (or start date for hotels).
+	Cost         money.Amount `gorm:"default:0"` // The cost charged by the vendor.
+	ContractCost money.Amount `gorm:"default:0"` // Cost per the vendor contract at booking time; 0 if none applied.
+	Price        money.Amount `gorm:"default:0"` // The price charged to the client.
+
+	// Price and the client penalty are in Currency; Cost, ContractCost and
+	// the vendor penalty in CostCurrency. The rates convert each to the
+	// tenant's base currency as of the booking date.
+	Currency         string  `gorm:"size:3"`
+	CostCurrency     string  `gorm:"size:3"`
+	ExchangeRate     float64 `gorm:"default:0"`
+	CostExchangeRate float64 `gorm:"default:0"`
+
+	// Set when the booking is cancelled.
+	CancelledAt         *time.Time
+	CancellationPenalty money.Amount `gorm:"default:0"` // Charged to the client (on Price).
+	VendorPenalty       money.Amount `gorm:"default:0"` // Retained by the vendor (on Cost).
+	CancellationReason  string       `gorm:"size:1024"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// Warnings raised while saving, e.g. a cost that differs from the contract.
+	Warnings []string `gorm:"-" json:",omitempty"`
+}
+
+// BookingTransition records every status change of a booking and who made it.
+type BookingTransition struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	TenantID   uint      `gorm:"not null;index" json:"tenantId"`
+	BookingID  uint      `gorm:"not null;index" json:"bookingId"`
+	FromStatus string    `gorm:"size:50" json:"fromStatus"` // Empty for the initial state.
+	ToStatus   string    `gorm:"size:50;not null" json:"toStatus"`
+	ActorID    uint      `json:"actorId"` // User who made the change; 0 for system jobs.
+	Reason     string    `gorm:"size:1024" json:"reason"`
+	CreatedAt  time.Time `json:"createdAt"`
 }
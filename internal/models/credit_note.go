@@ -0,0 +1,60 @@
+// internal/models/credit_note.go
+package models
+
+import (
+	"time"
+
+	"travel-agency/internal/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreditNote reduces the amount owed on an issued invoice. It either credits
+// some or all of the invoice's lines (Items) or, e.g. after a booking on that
+// invoice is cancelled, a plain amount.
+type CreditNote struct {
+	ID        uuid.UUID        `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID  uint             `gorm:"not null;index;uniqueIndex:idx_credit_note_tenant_number" json:"tenantId"`
+	Number    *string          `gorm:"size:30;uniqueIndex:idx_credit_note_tenant_number" json:"number,omitempty"` // CN-YYYY-000123, on notes against sale invoices.
+	InvoiceID uuid.UUID        `gorm:"type:uuid;not null;index" json:"invoiceId"`
+	BookingID uint             `gorm:"index" json:"bookingId,omitempty"`
+	IssueDate time.Time        `gorm:"not null" json:"issueDate"`
+	Subtotal  money.Amount     `gorm:"default:0" json:"subtotal"` // Net amount credited, before tax.
+	TaxTotal  money.Amount     `gorm:"default:0" json:"taxTotal"`
+	Amount    money.Amount     `gorm:"not null;default:0" json:"amount"`
+	Currency  string           `gorm:"size:3;not null;default:'USD'" json:"currency"`
+	Reason    string           `gorm:"size:1024" json:"reason"`
+	Status    string           `gorm:"size:50;not null;default:'Issued'" json:"status"`
+	Items     []CreditNoteItem `gorm:"foreignKey:CreditNoteID" json:"items,omitempty"`
+	CreatedAt time.Time        `json:"createdAt"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+}
+
+// CreditNoteItem credits some or all of the quantity of one invoice line,
+// with the line's discount and taxes in proportion.
+type CreditNoteItem struct {
+	ID            uint         `gorm:"primaryKey" json:"id"`
+	CreditNoteID  uuid.UUID    `gorm:"type:uuid;not null;index" json:"creditNoteId"`
+	InvoiceItemID uint         `gorm:"not null;index" json:"invoiceItemId"`
+	Description   string       `gorm:"size:1024;not null" json:"description"`
+	Quantity      float64      `gorm:"not null" json:"quantity"`
+	Net           money.Amount `gorm:"default:0" json:"net"`
+	TaxAmount     money.Amount `gorm:"default:0" json:"taxAmount"`
+	Total         money.Amount `gorm:"default:0" json:"total"`
+}
+
+// CreditNoteSequence holds the last credit note number used by a tenant in a
+// year, like InvoiceSequence does for invoices.
+type CreditNoteSequence struct {
+	ID       uint `gorm:"primaryKey"`
+	TenantID uint `gorm:"not null;uniqueIndex:idx_credit_note_sequence"`
+	Year     int  `gorm:"not null;uniqueIndex:idx_credit_note_sequence"`
+	Last     int  `gorm:"not null;default:0"`
+}
+
+// BeforeCreate assigns a new UUID.
+func (cn *CreditNote) BeforeCreate(tx *gorm.DB) (err error) {
+	cn.ID = uuid.New()
+	return
+}
@@ -0,0 +1,45 @@
+// internal/models/dunning.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outcomes of sending a reminder.
+const (
+	ReminderSent   = "Sent"
+	ReminderFailed = "Failed"
+)
+
+// ReminderRule is one step of a tenant's payment reminder schedule: a
+// reminder DaysAfterDue days after an invoice's due date (negative before
+// it, zero on it). Subject and Body are templates; empty ones use the
+// default wording.
+type ReminderRule struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	TenantID     uint      `gorm:"not null;uniqueIndex:idx_reminder_rule_days" json:"tenantId"`
+	DaysAfterDue int       `gorm:"not null;uniqueIndex:idx_reminder_rule_days" json:"daysAfterDue"`
+	Subject      string    `gorm:"size:255" json:"subject"`
+	Body         string    `gorm:"type:text" json:"body"`
+	Active       bool      `gorm:"not null" json:"active"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// InvoiceReminder logs a payment reminder sent, or tried, for an invoice.
+// RuleID is empty for reminders sent by hand.
+type InvoiceReminder struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	TenantID     uint      `gorm:"not null;index" json:"tenantId"`
+	InvoiceID    uuid.UUID `gorm:"type:uuid;not null;index" json:"invoiceId"`
+	RuleID       *uint     `gorm:"index" json:"ruleId,omitempty"`
+	DaysAfterDue int       `json:"daysAfterDue"` // Relative to the due date when sent.
+	SentTo       string    `gorm:"size:255" json:"sentTo"`
+	Subject      string    `gorm:"size:255" json:"subject"`
+	Status       string    `gorm:"size:20;not null" json:"status"`
+	Error        string    `gorm:"size:1024" json:"error,omitempty"`
+	SentBy       uint      `json:"sentBy,omitempty"` // The user who sent it by hand.
+	CreatedAt    time.Time `json:"createdAt"`
+}
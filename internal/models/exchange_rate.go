@@ -0,0 +1,19 @@
+// internal/models/exchange_rate.go
+package models
+
+import "time"
+
+// ExchangeRate is the value of one unit of FromCurrency in ToCurrency from
+// Date on, until a later rate for the pair takes over. Rates with TenantID 0
+// are shared by all tenants; a tenant's own rate wins on the same date.
+type ExchangeRate struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	TenantID     uint      `gorm:"not null;default:0;uniqueIndex:idx_fx_rate" json:"tenantId"`
+	FromCurrency string    `gorm:"size:3;not null;uniqueIndex:idx_fx_rate" json:"from"`
+	ToCurrency   string    `gorm:"size:3;not null;uniqueIndex:idx_fx_rate" json:"to"`
+	Date         time.Time `gorm:"not null;uniqueIndex:idx_fx_rate" json:"date"`
+	Rate         float64   `gorm:"not null" json:"rate"`
+	Source       string    `gorm:"size:100" json:"source,omitempty"` // e.g. a file name or "api".
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,42 @@
+// internal/models/inventory.go
+package models
+
+import (
+	"time"
+
+	"travel-agency/internal/money"
+)
+
+// Inventory is a vendor allotment of one product (room type, tour seat) on
+// one date. Units not sold by ReleaseDate go back to the vendor.
+type Inventory struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	TenantID       uint         `gorm:"not null;index" json:"tenantId"`
+	VendorID       uint         `gorm:"not null;uniqueIndex:idx_inventory_slot" json:"vendorId"`
+	Product        string       `gorm:"size:255;not null;uniqueIndex:idx_inventory_slot" json:"product"`
+	Date           time.Time    `gorm:"not null;uniqueIndex:idx_inventory_slot" json:"date"` // Midnight UTC.
+	Capacity       int          `gorm:"not null;default:0" json:"capacity"`
+	Booked         int          `gorm:"not null;default:0" json:"booked"`
+	Released       int          `gorm:"not null;default:0" json:"released"` // Unsold units handed back to the vendor.
+	ReleaseDate    time.Time    `gorm:"index" json:"releaseDate"`
+	ReleasedAt     *time.Time   `json:"releasedAt,omitempty"`
+	ContractedCost money.Amount `gorm:"default:0" json:"contractedCost"` // Per unit.
+	CreatedAt      time.Time    `json:"createdAt"`
+	UpdatedAt      time.Time    `json:"updatedAt"`
+}
+
+// Available is the number of units that can still be booked.
+func (i Inventory) Available() int {
+	return i.Capacity - i.Booked - i.Released
+}
+
+// InventoryAllocation records the units a booking took from an allotment so
+// they can be returned when the booking is cancelled.
+type InventoryAllocation struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	TenantID    uint      `gorm:"not null;index" json:"tenantId"`
+	BookingID   uint      `gorm:"not null;index" json:"bookingId"`
+	InventoryID uint      `gorm:"not null;index" json:"inventoryId"`
+	Units       int       `gorm:"not null" json:"units"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
@@ -4,24 +4,89 @@ package models
 import (
 	"time"
 
+	"travel-agency/internal/money"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 // Invoice represents a billing invoice (sale or purchase) for a given tenant.
 type Invoice struct {
-	ID          uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	TenantID    uint       `gorm:"type:uint;not null;index" json:"tenantId"`
-	InvoiceType string     `gorm:"size:20;not null" json:"invoiceType"` // "sale" or "purchase"
-	IssueDate   time.Time  `gorm:"not null" json:"issueDate"`
-	DueDate     time.Time  `gorm:"not null" json:"dueDate"`
-	Status      string     `gorm:"size:50;not null;default:'Draft'" json:"status"`
-	Amount      float64    `gorm:"not null;default:0" json:"amount"`
-	Currency    string     `gorm:"size:3;not null;default:'USD'" json:"currency"`
-	CustomerID  *uuid.UUID `gorm:"type:uuid;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"customerId,omitempty"`
-	VendorID    *uuid.UUID `gorm:"type:uuid;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"vendorId,omitempty"`
-	CreatedAt   time.Time  `json:"createdAt"`
-	UpdatedAt   time.Time  `json:"updatedAt"`
+	ID            uuid.UUID         `gorm:"type:uuid;primaryKey" json:"id"` // Set in BeforeCreate.
+	TenantID      uint              `gorm:"not null;index;uniqueIndex:idx_invoice_tenant_number" json:"tenantId"`
+	Number        *string           `gorm:"size:30;uniqueIndex:idx_invoice_tenant_number" json:"number,omitempty"` // INV-YYYY-000123, assigned when a sale invoice is issued.
+	InvoiceType   string            `gorm:"size:20;not null" json:"invoiceType"`                                   // "sale" or "purchase"
+	IssueDate     time.Time         `gorm:"not null" json:"issueDate"`
+	DueDate       time.Time         `gorm:"not null" json:"dueDate"`
+	Status        string            `gorm:"size:50;not null;default:'Draft'" json:"status"`
+	Subtotal      money.Amount      `gorm:"default:0" json:"subtotal"` // Line amounts before discounts.
+	DiscountTotal money.Amount      `gorm:"default:0" json:"discountTotal"`
+	TaxTotal      money.Amount      `gorm:"default:0" json:"taxTotal"`
+	Amount        money.Amount      `gorm:"not null;default:0" json:"amount"` // Total due; computed from the items when there are any.
+	Currency      string            `gorm:"size:3;not null;default:'USD'" json:"currency"`
+	ExchangeRate  float64           `gorm:"default:0" json:"exchangeRate"` // Currency to the tenant's base currency on the issue date.
+	CustomerID    *uuid.UUID        `gorm:"type:uuid;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"customerId,omitempty"`
+	VendorID      *uint             `gorm:"index" json:"vendorId,omitempty"`      // Supplier on purchase invoices.
+	Reference     string            `gorm:"size:100" json:"reference,omitempty"`  // Supplier's own invoice number.
+	ItineraryID   *uint             `gorm:"index" json:"itineraryId,omitempty"`   // Set on invoices generated from an itinerary.
+	Installment   string            `gorm:"size:50" json:"installment,omitempty"` // e.g. "Deposit" or "Balance" in a split schedule.
+	IssuedAt      *time.Time        `json:"issuedAt,omitempty"`                   // Issued invoices are locked; correct them with credit notes.
+	VoidedAt      *time.Time        `json:"voidedAt,omitempty"`
+	VoidReason    string            `gorm:"size:1024" json:"voidReason,omitempty"`
+	ContactEmail  string            `gorm:"size:255" json:"contactEmail,omitempty"` // Where payment reminders are sent.
+	PlaceOfSupply string            `gorm:"size:50" json:"placeOfSupply,omitempty"` // Matched by tax rules, e.g. "inter-state".
+	CustomerType  string            `gorm:"size:20" json:"customerType,omitempty"`  // Matched by tax rules, e.g. "business".
+	RemindersOff  bool              `gorm:"default:false" json:"remindersOff"`      // Stops automatic reminders, e.g. while a dispute is settled.
+	Items         []InvoiceItem     `gorm:"foreignKey:InvoiceID" json:"items,omitempty"`
+	Reminders     []InvoiceReminder `gorm:"foreignKey:InvoiceID" json:"reminders,omitempty"`
+	LegacyVendor  *uuid.UUID        `gorm:"column:legacy_vendor_uuid;type:uuid" json:"legacyVendorUuid,omitempty"` // vendorId as sent before it was numeric; see db.MigrateInvoices.
+	CreatedAt     time.Time         `json:"createdAt"`
+	UpdatedAt     time.Time         `json:"updatedAt"`
+}
+
+// InvoiceItem is one line of an invoice, optionally billing a booking or an
+// itinerary item. Amounts other than the inputs are computed server-side.
+type InvoiceItem struct {
+	ID              uint             `gorm:"primaryKey" json:"id"`
+	TenantID        uint             `gorm:"not null;index" json:"tenantId"`
+	InvoiceID       uuid.UUID        `gorm:"type:uuid;not null;index" json:"invoiceId"`
+	Position        int              `gorm:"default:0" json:"position"`
+	BookingID       *uint            `gorm:"index" json:"bookingId,omitempty"`
+	ItineraryItemID *uint            `gorm:"index" json:"itineraryItemId,omitempty"`
+	Description     string           `gorm:"size:1024;not null" json:"description"`
+	ServiceType     string           `gorm:"size:50" json:"serviceType,omitempty"` // e.g. Hotel; from the booking's vendor or the itinerary item.
+	Quantity        float64          `gorm:"default:1" json:"quantity"`
+	UnitPrice       money.Amount     `gorm:"default:0" json:"unitPrice"`
+	DiscountPercent float64          `gorm:"default:0" json:"discountPercent"`
+	Discount        money.Amount     `gorm:"default:0" json:"discount"` // Flat discount, on top of the percentage.
+	Gross           money.Amount     `gorm:"default:0" json:"gross"`    // Quantity * UnitPrice.
+	DiscountAmount  money.Amount     `gorm:"default:0" json:"discountAmount"`
+	Net             money.Amount     `gorm:"default:0" json:"net"` // Taxable amount.
+	TaxAmount       money.Amount     `gorm:"default:0" json:"taxAmount"`
+	Total           money.Amount     `gorm:"default:0" json:"total"`
+	Cost            money.Amount     `gorm:"default:0" json:"cost,omitempty"` // Supplier cost, for taxes on the margin.
+	Taxes           []InvoiceItemTax `gorm:"foreignKey:InvoiceItemID" json:"taxes,omitempty"`
+}
+
+// InvoiceItemTax is one tax component on a line, e.g. CGST 9% and SGST 9%,
+// or a single VAT rate.
+type InvoiceItemTax struct {
+	ID            uint         `gorm:"primaryKey" json:"id"`
+	InvoiceItemID uint         `gorm:"not null;index" json:"invoiceItemId"`
+	Name          string       `gorm:"size:50;not null" json:"name"`
+	Rate          float64      `gorm:"not null" json:"rate"`           // Percent of the taxable amount.
+	Basis         string       `gorm:"size:10" json:"basis,omitempty"` // net (default), margin or total; see TaxOnNet.
+	Taxable       money.Amount `gorm:"default:0" json:"taxable"`
+	Amount        money.Amount `gorm:"default:0" json:"amount"`
+}
+
+// InvoiceSequence holds the last invoice number used by a tenant in a year.
+// Rows are locked while a number is taken so the sequence has no gaps.
+type InvoiceSequence struct {
+	ID       uint `gorm:"primaryKey"`
+	TenantID uint `gorm:"not null;uniqueIndex:idx_invoice_sequence"`
+	Year     int  `gorm:"not null;uniqueIndex:idx_invoice_sequence"`
+	Last     int  `gorm:"not null;default:0"`
 }
 
 // BeforeCreate hook to ensure ID is set to a new UUID, even if client supplies one.
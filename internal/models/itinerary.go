@@ -1,34 +1,60 @@
 // internal/models/itinerary.go
 package models
 
-import "time"
+import (
+	"time"
+
+	"travel-agency/internal/money"
+)
 
 type Itinerary struct {
-    ID         uint             `gorm:"primaryKey"`
-    TenantID   uint             `gorm:"not null;index"`
-    CustomerID uint
-    Name       string           `gorm:"size:255;not null"`
-    StartDate  time.Time        `gorm:"not null"`
-    EndDate    time.Time        `gorm:"not null"`
-    Status     string           `gorm:"size:50;default:'Planned'"`
-    TotalPrice float64          `gorm:"default:0"`
-    CreatedAt  time.Time
-    UpdatedAt  time.Time
-
-    // Add this:
-    Items      []ItineraryItem  `gorm:"foreignKey:ItineraryID" json:"items"`
+	ID          uint `gorm:"primaryKey"`
+	TenantID    uint `gorm:"not null;index"`
+	CustomerID  uint
+	AssignedTo  uint         `gorm:"index"` // Agent responsible for the trip.
+	Name        string       `gorm:"size:255;not null"`
+	StartDate   time.Time    `gorm:"not null"`
+	EndDate     time.Time    `gorm:"not null"`
+	Status      string       `gorm:"size:50;default:'Planned'"`
+	Destination string       `gorm:"size:255"`  // Used to match destination markup rules.
+	Currency    string       `gorm:"size:3"`    // Prices and totals; defaults to the tenant's base currency.
+	TotalCost   money.Amount `gorm:"default:0"` // Sum of item costs, computed server-side.
+	Subtotal    money.Amount `gorm:"default:0"` // Sum of item prices before tax.
+	Margin      money.Amount `gorm:"default:0"` // Subtotal - TotalCost.
+	TaxAmount   money.Amount `gorm:"default:0"`
+	TotalPrice  money.Amount `gorm:"default:0"` // Subtotal + TaxAmount.
+
+	// Versioning: latest snapshot, and the ones sent to / accepted by the customer.
+	CurrentVersion  int `gorm:"default:0"`
+	SentVersion     int `gorm:"default:0"`
+	AcceptedVersion int `gorm:"default:0"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// Warnings from the last save, e.g. costs that differ from the vendor contract.
+	Warnings []string `gorm:"-" json:"warnings,omitempty"`
+
+	// Add this:
+	Items []ItineraryItem `gorm:"foreignKey:ItineraryID" json:"items"`
 }
 
 type ItineraryItem struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	ItineraryID uint      `gorm:"not null;index" json:"itinerary_id"`
-	Day         int       `gorm:"not null" json:"day"`
-	Type        string    `gorm:"size:50;not null" json:"type"`
-	Description string    `gorm:"size:1024" json:"description"`
-	VendorID    uint      `json:"vendor_id"`
-	Cost        float64   `gorm:"default:0" json:"cost"`
-	Price       float64   `gorm:"default:0" json:"price"`
-	Status      string    `gorm:"size:50;default:'Pending'" json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           uint         `gorm:"primaryKey" json:"id"`
+	ItineraryID  uint         `gorm:"not null;index" json:"itinerary_id"`
+	Day          int          `gorm:"not null" json:"day"`
+	Type         string       `gorm:"size:50;not null" json:"type"`
+	Description  string       `gorm:"size:1024" json:"description"`
+	VendorID     uint         `json:"vendor_id"`
+	Product      string       `gorm:"size:255" json:"product"`    // Rate card product; enables contract cost lookup.
+	Occupancy    int          `gorm:"default:0" json:"occupancy"` // Guests per unit, for the rate lookup.
+	MealPlan     string       `gorm:"size:20" json:"meal_plan"`   // For the rate lookup.
+	Cost         money.Amount `gorm:"default:0" json:"cost"`
+	ContractCost money.Amount `gorm:"default:0" json:"contract_cost"` // Set when a contract rate applies.
+	CostCurrency string       `gorm:"size:3" json:"cost_currency"`    // Currency of Cost; defaults to the itinerary's.
+	CostRate     float64      `gorm:"default:0" json:"cost_rate"`     // CostCurrency to itinerary currency, when priced.
+	Price        money.Amount `gorm:"default:0" json:"price"`         // In the itinerary's currency.
+	Status       string       `gorm:"size:50;default:'Pending'" json:"status"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
 }
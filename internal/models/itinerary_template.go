@@ -0,0 +1,38 @@
+// internal/models/itinerary_template.go
+package models
+
+import (
+	"time"
+
+	"travel-agency/internal/money"
+)
+
+// ItineraryTemplate is a reusable package (e.g. "7 days Kerala") whose items
+// are positioned relative to the trip start rather than on fixed dates.
+type ItineraryTemplate struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	TenantID     uint      `gorm:"not null;index" json:"tenantId"`
+	Name         string    `gorm:"size:255;not null" json:"name"`
+	Description  string    `gorm:"size:1024" json:"description"`
+	Destination  string    `gorm:"size:255" json:"destination"`
+	DurationDays int       `gorm:"not null;default:1" json:"durationDays"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+
+	Items []ItineraryTemplateItem `gorm:"foreignKey:TemplateID" json:"items"`
+}
+
+// ItineraryTemplateItem is a template item with a default vendor and price.
+type ItineraryTemplateItem struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	TemplateID  uint         `gorm:"not null;index" json:"templateId"`
+	DayOffset   int          `gorm:"not null;default:0" json:"dayOffset"` // 0 = first day of the trip.
+	Type        string       `gorm:"size:50;not null" json:"type"`
+	Description string       `gorm:"size:1024" json:"description"`
+	VendorID    uint         `json:"vendorId"`                // Default vendor; may be changed after instantiation.
+	Product     string       `gorm:"size:255" json:"product"` // With Cost 0, cost comes from the vendor contract on instantiation.
+	Occupancy   int          `gorm:"default:0" json:"occupancy"`
+	MealPlan    string       `gorm:"size:20" json:"mealPlan"`
+	Cost        money.Amount `gorm:"default:0" json:"cost"`
+	Price       money.Amount `gorm:"default:0" json:"price"`
+}
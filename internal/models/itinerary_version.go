@@ -0,0 +1,54 @@
+// internal/models/itinerary_version.go
+package models
+
+import (
+	"time"
+
+	"travel-agency/internal/money"
+)
+
+// ItineraryVersion is an immutable snapshot of an itinerary and its items,
+// taken every time the itinerary is saved.
+type ItineraryVersion struct {
+	ID           uint         `gorm:"primaryKey" json:"id"`
+	TenantID     uint         `gorm:"not null;index" json:"tenantId"`
+	ItineraryID  uint         `gorm:"not null;uniqueIndex:idx_itinerary_version" json:"itineraryId"`
+	Version      int          `gorm:"not null;uniqueIndex:idx_itinerary_version" json:"version"`
+	Name         string       `gorm:"size:255;not null" json:"name"`
+	StartDate    time.Time    `json:"startDate"`
+	EndDate      time.Time    `json:"endDate"`
+	Status       string       `gorm:"size:50" json:"status"`
+	Destination  string       `gorm:"size:255" json:"destination"`
+	Currency     string       `gorm:"size:3" json:"currency"`
+	TotalCost    money.Amount `json:"totalCost"`
+	Subtotal     money.Amount `json:"subtotal"`
+	Margin       money.Amount `json:"margin"`
+	TaxAmount    money.Amount `json:"taxAmount"`
+	TotalPrice   money.Amount `json:"totalPrice"`
+	RestoredFrom int          `json:"restoredFrom,omitempty"` // Version this one was restored from, if any.
+	CreatedBy    uint         `json:"createdBy"`
+	SentAt       *time.Time   `json:"sentAt,omitempty"`     // When this version was sent to the customer.
+	AcceptedAt   *time.Time   `json:"acceptedAt,omitempty"` // When the customer accepted this version.
+	CreatedAt    time.Time    `json:"createdAt"`
+
+	Items []ItineraryVersionItem `gorm:"foreignKey:VersionID" json:"items"`
+}
+
+// ItineraryVersionItem is a copy of an ItineraryItem as it was in a version.
+type ItineraryVersionItem struct {
+	ID           uint         `gorm:"primaryKey" json:"id"`
+	VersionID    uint         `gorm:"not null;index" json:"versionId"`
+	Day          int          `gorm:"not null" json:"day"`
+	Type         string       `gorm:"size:50;not null" json:"type"`
+	Description  string       `gorm:"size:1024" json:"description"`
+	VendorID     uint         `json:"vendorId"`
+	Product      string       `gorm:"size:255" json:"product"`
+	Occupancy    int          `json:"occupancy"`
+	MealPlan     string       `gorm:"size:20" json:"mealPlan"`
+	Cost         money.Amount `json:"cost"`
+	ContractCost money.Amount `json:"contractCost"`
+	CostCurrency string       `gorm:"size:3" json:"costCurrency"`
+	CostRate     float64      `json:"costRate"`
+	Price        money.Amount `json:"price"`
+	Status       string       `gorm:"size:50" json:"status"`
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"travel-agency/internal/money"
+)
 
 type Lead struct {
     ID           uint      `gorm:"primaryKey" json:"id"`
@@ -9,7 +13,8 @@ type Lead struct {
     ContactInfo  string    `json:"email"`          // Maps incoming "email" to ContactInfo
     Phone        string    `json:"phone"`
     Destination  string    `json:"destination"`
-    Budget       float64   `json:"budget"`
+    Budget       money.Amount `json:"budget"`
+    Currency     string    `gorm:"size:3" json:"currency"` // Of Budget
     TravelDate   time.Time `json:"travelDate"`     // Ensure your frontend sends a date string parseable to time.Time
     Details      string    `json:"notes"`          // Maps incoming "notes" to Details
     Status       string    `json:"status"`
@@ -0,0 +1,81 @@
+// internal/models/ledger.go
+package models
+
+import (
+	"errors"
+	"time"
+
+	"travel-agency/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// Account types. Assets and expenses carry debit balances; liabilities,
+// equity and income carry credit balances.
+const (
+	AccountAsset     = "asset"
+	AccountLiability = "liability"
+	AccountEquity    = "equity"
+	AccountIncome    = "income"
+	AccountExpense   = "expense"
+)
+
+// ErrPostedEntry is returned when a journal entry or line would be changed
+// or deleted. Posted entries are corrected by posting another entry.
+var ErrPostedEntry = errors.New("posted journal entries cannot be changed or deleted")
+
+// Account is one account in a tenant's chart of accounts. System accounts
+// are the ones documents are posted to and cannot be removed.
+type Account struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TenantID  uint      `gorm:"not null;uniqueIndex:idx_account_tenant_code" json:"tenantId"`
+	Code      string    `gorm:"size:20;not null;uniqueIndex:idx_account_tenant_code" json:"code"`
+	Name      string    `gorm:"size:255;not null" json:"name"`
+	Type      string    `gorm:"size:20;not null" json:"type"`
+	System    bool      `gorm:"not null;default:false" json:"system"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// JournalEntry is a balanced set of debits and credits in the tenant's base
+// currency. Entries posted for a document (SourceType and SourceID, e.g.
+// "invoice" and its UUID) add up to the document's current effect on the
+// books; manual entries have SourceType "manual".
+type JournalEntry struct {
+	ID           uint          `gorm:"primaryKey" json:"id"`
+	TenantID     uint          `gorm:"not null;index" json:"tenantId"`
+	Date         time.Time     `gorm:"not null;index" json:"date"`
+	Memo         string        `gorm:"size:1024" json:"memo"`
+	SourceType   string        `gorm:"size:30;not null;index:idx_journal_source" json:"sourceType"`
+	SourceID     string        `gorm:"size:64;index:idx_journal_source" json:"sourceId,omitempty"`
+	Currency     string        `gorm:"size:3" json:"currency,omitempty"` // The document's currency.
+	ExchangeRate float64       `gorm:"default:0" json:"exchangeRate,omitempty"`
+	ReversalOfID *uint         `gorm:"index" json:"reversalOfId,omitempty"`
+	CreatedByID  uint          `json:"createdById,omitempty"`
+	Lines        []JournalLine `gorm:"foreignKey:EntryID" json:"lines"`
+	CreatedAt    time.Time     `json:"createdAt"`
+}
+
+// JournalLine debits or credits one account; exactly one of Debit and
+// Credit is set.
+type JournalLine struct {
+	ID        uint         `gorm:"primaryKey" json:"id"`
+	TenantID  uint         `gorm:"not null;index" json:"tenantId"`
+	EntryID   uint         `gorm:"not null;index" json:"entryId"`
+	AccountID uint         `gorm:"not null;index" json:"accountId"`
+	Debit     money.Amount `gorm:"not null;default:0" json:"debit"`
+	Credit    money.Amount `gorm:"not null;default:0" json:"credit"`
+	Account   *Account     `gorm:"foreignKey:AccountID" json:"account,omitempty"`
+}
+
+// BeforeUpdate keeps posted entries immutable.
+func (e *JournalEntry) BeforeUpdate(tx *gorm.DB) error { return ErrPostedEntry }
+
+// BeforeDelete keeps posted entries immutable.
+func (e *JournalEntry) BeforeDelete(tx *gorm.DB) error { return ErrPostedEntry }
+
+// BeforeUpdate keeps posted lines immutable.
+func (l *JournalLine) BeforeUpdate(tx *gorm.DB) error { return ErrPostedEntry }
+
+// BeforeDelete keeps posted lines immutable.
+func (l *JournalLine) BeforeDelete(tx *gorm.DB) error { return ErrPostedEntry }
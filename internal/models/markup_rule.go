@@ -0,0 +1,26 @@
+// internal/models/markup_rule.go
+package models
+
+import (
+	"time"
+
+	"travel-agency/internal/money"
+)
+
+// MarkupRule describes how a tenant derives the selling price of an itinerary
+// item from its vendor cost. Empty/zero match fields act as wildcards.
+type MarkupRule struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	TenantID    uint         `gorm:"not null;index" json:"tenantId"`
+	Name        string       `gorm:"size:255" json:"name"`
+	VendorID    uint         `gorm:"index" json:"vendorId"`              // 0 = any vendor
+	ItemType    string       `gorm:"size:50" json:"itemType"`            // e.g., Hotel, Flight; empty = any type
+	Destination string       `gorm:"size:255" json:"destination"`        // empty = any destination
+	MarkupType  string       `gorm:"size:20;not null" json:"markupType"` // "percent" or "fixed"
+	MarkupValue float64      `gorm:"default:0" json:"markupValue"`       // percent (e.g. 15) or fixed amount
+	MinMargin   money.Amount `gorm:"default:0" json:"minMargin"`         // minimum absolute margin per item
+	Priority    int          `gorm:"default:0" json:"priority"`          // higher wins when specificity ties
+	Disabled    bool         `gorm:"default:false" json:"disabled"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	UpdatedAt   time.Time    `json:"updatedAt"`
+}
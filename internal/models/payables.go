@@ -0,0 +1,51 @@
+// internal/models/payables.go
+package models
+
+import (
+	"time"
+
+	"travel-agency/internal/money"
+
+	"github.com/google/uuid"
+)
+
+// Payment run states.
+const (
+	PaymentRunScheduled = "Scheduled"
+	PaymentRunCompleted = "Completed"
+	PaymentRunCancelled = "Cancelled"
+)
+
+// PaymentRun batches due purchase invoices into vendor payments that are
+// paid together on ScheduledFor.
+type PaymentRun struct {
+	ID           uint            `gorm:"primaryKey" json:"id"`
+	TenantID     uint            `gorm:"not null;index" json:"tenantId"`
+	ScheduledFor time.Time       `gorm:"not null;index" json:"scheduledFor"`
+	DueBy        time.Time       `json:"dueBy"` // Invoices due on or before this date were included.
+	Method       string          `gorm:"size:50" json:"method"`
+	Status       string          `gorm:"size:20;not null;default:'Scheduled'" json:"status"`
+	Total        money.Amount    `gorm:"default:0" json:"total"`
+	CreatedBy    uint            `json:"createdBy"`
+	ExecutedAt   *time.Time      `json:"executedAt,omitempty"`
+	Payments     []VendorPayment `gorm:"foreignKey:PaymentRunID" json:"payments"`
+	CreatedAt    time.Time       `json:"createdAt"`
+	UpdatedAt    time.Time       `json:"updatedAt"`
+}
+
+// VendorPayment is money paid (or scheduled to be paid) to a vendor against
+// a purchase invoice.
+type VendorPayment struct {
+	ID           uint         `gorm:"primaryKey" json:"id"`
+	TenantID     uint         `gorm:"not null;index" json:"tenantId"`
+	VendorID     uint         `gorm:"not null;index" json:"vendorId"`
+	InvoiceID    uuid.UUID    `gorm:"type:uuid;not null;index" json:"invoiceId"`
+	PaymentRunID *uint        `gorm:"index" json:"paymentRunId,omitempty"`
+	Amount       money.Amount `gorm:"not null" json:"amount"`
+	Currency     string       `gorm:"size:3" json:"currency"`
+	Method       string       `gorm:"size:50" json:"method"`
+	Status       string       `gorm:"size:20;not null;default:'Scheduled'" json:"status"` // Scheduled, Completed, Cancelled.
+	PaidAt       *time.Time   `json:"paidAt,omitempty"`
+	CreatedAt    time.Time    `json:"createdAt"`
+	UpdatedAt    time.Time    `json:"updatedAt"`
+}
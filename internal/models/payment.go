@@ -1,17 +1,54 @@
 // internal/models/payment.go
 package models
 
-import "time"
+import (
+	"time"
 
-// Payment represents a record of a payment made (or received) against an invoice.
+	"travel-agency/internal/money"
+
+	"github.com/google/uuid"
+)
+
+// Payment represents a record of a payment made (or received) against one or
+// more invoices. What is not allocated to invoices is a credit balance that
+// can be applied to later invoices. Its JSON keys are the field names, as
+// clients have always received them.
 type Payment struct {
-	ID          uint      `gorm:"primaryKey"`
-	TenantID    uint      `gorm:"not null;index"`
-	InvoiceID   uint      `gorm:"not null;index"` // The invoice this payment is for.
-	PaymentDate time.Time `gorm:"not null"`
-	Amount      float64   `gorm:"not null"`
-	Method      string    `gorm:"size:50"`                // e.g., "Credit Card", "Bank Transfer".
-	Status      string    `gorm:"size:50;default:'Pending'"` // e.g., Pending, Completed, Failed.
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID           uint                `gorm:"primaryKey"`
+	TenantID     uint                `gorm:"not null;index"`
+	InvoiceID    *uuid.UUID          `gorm:"type:uuid;index"` // The invoice the payment was made for, if just one.
+	PaymentDate  time.Time           `gorm:"not null"`
+	Amount       money.Amount        `gorm:"not null"`
+	Currency     string              `gorm:"size:3"`
+	ExchangeRate float64             `gorm:"default:0"`                        // Currency to the tenant's base currency on the payment date.
+	Method       string              `gorm:"size:50"`                          // e.g., "Credit Card", "Bank Transfer".
+	Reference    string              `gorm:"size:100" json:",omitempty"`       // Bank or card transaction reference.
+	Status       string              `gorm:"size:50;default:'Pending'"`        // e.g., Pending, Completed, Failed.
+	Unallocated  money.Amount        `gorm:"default:0"`                        // Credit balance: Amount less allocations.
+	Provider     string              `gorm:"size:50" json:",omitempty"`        // Payment gateway that took the payment, if any.
+	ProviderRef  string              `gorm:"size:255;index" json:",omitempty"` // The gateway's payment intent ID.
+	Allocations  []PaymentAllocation `gorm:"foreignKey:PaymentID" json:",omitempty"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// PaymentAllocation applies part of a payment to an invoice.
+type PaymentAllocation struct {
+	ID        uint         `gorm:"primaryKey" json:"id"`
+	TenantID  uint         `gorm:"not null;index" json:"tenantId"`
+	PaymentID uint         `gorm:"not null;index" json:"paymentId"`
+	InvoiceID uuid.UUID    `gorm:"type:uuid;not null;index" json:"invoiceId"`
+	Amount    money.Amount `gorm:"not null" json:"amount"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+// WebhookEvent records a payment gateway event once it has been handled, so
+// redelivered events are not applied twice.
+type WebhookEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Provider  string    `gorm:"size:50;not null;uniqueIndex:idx_webhook_provider_event" json:"provider"`
+	EventID   string    `gorm:"size:255;not null;uniqueIndex:idx_webhook_provider_event" json:"eventId"`
+	Type      string    `gorm:"size:100" json:"type"`
+	PaymentID *uint     `gorm:"index" json:"paymentId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
 }
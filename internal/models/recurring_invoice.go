@@ -0,0 +1,65 @@
+// internal/models/recurring_invoice.go
+package models
+
+import (
+	"time"
+
+	"travel-agency/internal/money"
+
+	"github.com/google/uuid"
+)
+
+// Recurring invoice cadences.
+const (
+	CadenceMonthly   = "monthly"
+	CadenceQuarterly = "quarterly"
+	CadenceCron      = "cron" // CronSpec gives the period starts.
+)
+
+// Recurring invoice states.
+const (
+	RecurringActive    = "Active"
+	RecurringPaused    = "Paused"
+	RecurringCompleted = "Completed" // Past its end date.
+)
+
+// RecurringInvoice bills a customer the same amount every period, e.g. a
+// corporate client's monthly management fee. Each period starting on or
+// after StartDate (and on or before EndDate, if set) gets one invoice dated
+// the period's start.
+type RecurringInvoice struct {
+	ID           uint                  `gorm:"primaryKey" json:"id"`
+	TenantID     uint                  `gorm:"not null;index" json:"tenantId"`
+	Name         string                `gorm:"size:255;not null" json:"name"`
+	CustomerID   *uuid.UUID            `gorm:"type:uuid" json:"customerId,omitempty"`
+	ContactEmail string                `gorm:"size:255" json:"contactEmail,omitempty"`
+	Description  string                `gorm:"size:1024;not null" json:"description"` // Invoice line; the period is appended.
+	Amount       money.Amount          `gorm:"not null" json:"amount"`                // Per period, before tax.
+	Currency     string                `gorm:"size:3;not null" json:"currency"`
+	TaxName      string                `gorm:"size:50" json:"taxName,omitempty"`
+	TaxRate      float64               `gorm:"default:0" json:"taxRate"`
+	Cadence      string                `gorm:"size:20;not null" json:"cadence"`
+	CronSpec     string                `gorm:"size:100" json:"cronSpec,omitempty"`
+	DueInDays    int                   `gorm:"default:0" json:"dueInDays"`
+	Draft        bool                  `gorm:"default:false" json:"draft"` // Leave generated invoices for review instead of issuing them.
+	StartDate    time.Time             `gorm:"not null" json:"startDate"`
+	EndDate      *time.Time            `json:"endDate,omitempty"`
+	Status       string                `gorm:"size:20;not null;index" json:"status"`
+	NextRunAt    *time.Time            `gorm:"index" json:"nextRunAt,omitempty"` // Start of the next period to bill.
+	CreatedBy    uint                  `json:"createdBy"`
+	Runs         []RecurringInvoiceRun `gorm:"foreignKey:RecurringInvoiceID" json:"runs,omitempty"`
+	CreatedAt    time.Time             `json:"createdAt"`
+	UpdatedAt    time.Time             `json:"updatedAt"`
+}
+
+// RecurringInvoiceRun records the invoice generated for one period. The
+// unique period keeps a period from being billed twice.
+type RecurringInvoiceRun struct {
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	TenantID           uint       `gorm:"not null;index" json:"tenantId"`
+	RecurringInvoiceID uint       `gorm:"not null;uniqueIndex:idx_recurring_period" json:"recurringInvoiceId"`
+	PeriodStart        time.Time  `gorm:"not null;uniqueIndex:idx_recurring_period" json:"periodStart"`
+	PeriodEnd          time.Time  `gorm:"not null" json:"periodEnd"`
+	InvoiceID          *uuid.UUID `gorm:"type:uuid;index" json:"invoiceId,omitempty"`
+	CreatedAt          time.Time  `json:"createdAt"`
+}
@@ -0,0 +1,26 @@
+// internal/models/refund.go
+package models
+
+import (
+	"time"
+
+	"travel-agency/internal/money"
+
+	"github.com/google/uuid"
+)
+
+// Refund is money owed back to a customer against a paid invoice.
+type Refund struct {
+	ID        uint         `gorm:"primaryKey" json:"id"`
+	TenantID  uint         `gorm:"not null;index" json:"tenantId"`
+	InvoiceID uuid.UUID    `gorm:"type:uuid;not null;index" json:"invoiceId"`
+	BookingID uint         `gorm:"index" json:"bookingId,omitempty"`
+	PaymentID *uint        `gorm:"index" json:"paymentId,omitempty"` // The payment refunded through its gateway.
+	Amount    money.Amount `gorm:"not null;default:0" json:"amount"`
+	Currency  string       `gorm:"size:3;not null;default:'USD'" json:"currency"`
+	Method    string       `gorm:"size:50" json:"method"`
+	Status    string       `gorm:"size:50;default:'Pending'" json:"status"` // Pending, Completed, Failed.
+	Reason    string       `gorm:"size:1024" json:"reason"`
+	CreatedAt time.Time    `json:"createdAt"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+}
@@ -0,0 +1,41 @@
+// internal/models/tax_rule.go
+package models
+
+import "time"
+
+// Tax bases: what a tax component's rate is applied to.
+const (
+	TaxOnNet    = "net"    // The line's net amount; the default.
+	TaxOnMargin = "margin" // Net less the line's cost, for margin schemes.
+	TaxOnTotal  = "total"  // Net plus the line's other taxes, e.g. TCS.
+)
+
+// TaxEngineRules taxes lines with the tenant's TaxRules.
+const TaxEngineRules = "rules"
+
+// TaxRule gives the tax components of invoice and booking lines matching
+// its service type, place of supply and customer type. Empty match fields
+// act as wildcards; the most specific matching rule applies.
+type TaxRule struct {
+	ID            uint               `gorm:"primaryKey" json:"id"`
+	TenantID      uint               `gorm:"not null;index" json:"tenantId"`
+	Name          string             `gorm:"size:255;not null" json:"name"`
+	ServiceType   string             `gorm:"size:50" json:"serviceType"`   // e.g. Flight, Hotel, Package; empty = any
+	PlaceOfSupply string             `gorm:"size:50" json:"placeOfSupply"` // e.g. intra-state, inter-state, outbound; empty = any
+	CustomerType  string             `gorm:"size:20" json:"customerType"`  // e.g. individual, business; empty = any
+	Priority      int                `gorm:"default:0" json:"priority"`    // higher wins when specificity ties
+	Disabled      bool               `gorm:"default:false" json:"disabled"`
+	Components    []TaxRuleComponent `gorm:"foreignKey:TaxRuleID" json:"components"`
+	CreatedAt     time.Time          `json:"createdAt"`
+	UpdatedAt     time.Time          `json:"updatedAt"`
+}
+
+// TaxRuleComponent is one tax a rule charges, e.g. CGST 9% and SGST 9%.
+type TaxRuleComponent struct {
+	ID        uint    `gorm:"primaryKey" json:"id"`
+	TaxRuleID uint    `gorm:"not null;index" json:"taxRuleId"`
+	Position  int     `gorm:"default:0" json:"position"`
+	Name      string  `gorm:"size:50;not null" json:"name"`
+	Rate      float64 `gorm:"not null" json:"rate"`
+	Basis     string  `gorm:"size:10" json:"basis"` // net (default), margin or total
+}
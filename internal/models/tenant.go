@@ -4,9 +4,35 @@ package models
 import "time"
 
 type Tenant struct {
-	ID        uint      `gorm:"primaryKey"`
-	Name      string    `gorm:"size:255;not null"`
-	Address   string    `gorm:"size:512"`
+	ID      uint    `gorm:"primaryKey"`
+	Name    string  `gorm:"size:255;not null"`
+	Address string  `gorm:"size:512"`
+	TaxRate float64 `gorm:"default:0"` // Default sales tax rate (percent) applied to itinerary totals.
+
+	// Engine that works out the taxes of sale invoice lines given none,
+	// e.g. "rules". Empty keeps TaxRate on generated invoices.
+	TaxEngine string `gorm:"size:20"`
+
+	// Currency reports are converted to. Bookings, invoices and payments
+	// record their rate to it when they are entered.
+	BaseCurrency string `gorm:"size:3;not null;default:'USD'"`
+
+	// Branding and contact details used on customer-facing documents.
+	LogoPath       string `gorm:"size:512"` // PNG/JPEG logo, relative to the assets directory.
+	PrimaryColor   string `gorm:"size:7"`   // Hex colour, e.g. "#0B5394".
+	SecondaryColor string `gorm:"size:7"`
+	FooterText     string `gorm:"size:1024"`
+	Phone          string `gorm:"size:50"`
+	Email          string `gorm:"size:255"`
+	EmergencyPhone string `gorm:"size:50"` // 24x7 number printed on travel documents.
+
+	// Invoicing of confirmed trips. With a deposit, trips are billed as a
+	// deposit due now and the balance due BalanceDaysBeforeStart days before
+	// travel; otherwise in full.
+	AutoInvoiceOnConfirm   bool    `gorm:"default:false"` // Invoice when a booking is confirmed.
+	DepositPercent         float64 `gorm:"default:0"`
+	BalanceDaysBeforeStart int     `gorm:"default:0"`
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
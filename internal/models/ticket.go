@@ -9,6 +9,7 @@ type Ticket struct {
     Subject     string    `gorm:"size:255;not null" json:"subject"`
     Description string    `gorm:"size:1024" json:"description"`
     CustomerID  uint      `json:"customer_id"`
+    BookingID   *uint     `gorm:"index" json:"booking_id,omitempty"` // Optional: the booking the ticket is about.
     AssignedTo  uint      `json:"assigned_to"`
     Status      string    `gorm:"size:50;default:Open" json:"status"`
     Priority    string    `gorm:"size:50;default:Normal" json:"priority"`
@@ -21,6 +22,7 @@ type CreateTicketRequest struct {
     Subject     string `json:"subject"     validate:"required,min=3"`
     Description string `json:"description"`
     CustomerID  uint   `json:"customer_id" validate:"required"`
+    BookingID   *uint  `json:"booking_id"`
     Priority    string `json:"priority"    validate:"oneof=Low Normal High"`
 }
 
@@ -28,6 +30,7 @@ type UpdateTicketRequest struct {
     Subject     *string `json:"subject"     validate:"omitempty,min=3"`
     Description *string `json:"description"`
     AssignedTo  *uint   `json:"assigned_to"`
+    BookingID   *uint   `json:"booking_id"`
     Status      *string `json:"status"      validate:"omitempty,oneof=Open InProgress Closed"`
     Priority    *string `json:"priority"    validate:"omitempty,oneof=Low Normal High"`
 }
@@ -12,6 +12,7 @@ type User struct {
 	Role                 string    `gorm:"size:50;not null" json:"role"`
 	IsActive             bool      `gorm:"default:true" json:"isActive"`
 	ForcePasswordChange  bool      `gorm:"default:false" json:"forcePasswordChange"`
+	CalendarTokenVersion uint      `gorm:"not null;default:0" json:"-"` // Bumped to revoke calendar feed URLs.
 	CreatedAt            time.Time `json:"createdAt"`
 	UpdatedAt            time.Time `json:"updatedAt"`
 }
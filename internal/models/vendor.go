@@ -1,7 +1,11 @@
 // internal/models/vendor.go
 package models
 
-import "time"
+import (
+	"time"
+
+	"travel-agency/internal/money"
+)
 
 // Vendor represents a service provider (e.g., airline, hotel, tour operator).
 type Vendor struct {
@@ -15,3 +19,17 @@ type Vendor struct {
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 }
+
+// CancellationRule is one tier of a vendor's cancellation policy: cancelling
+// at least MinDaysBefore days before travel costs PenaltyPercent of the
+// booking value plus FlatFee. The tier with the largest MinDaysBefore that is
+// still <= the days remaining applies.
+
+type CancellationRule struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	TenantID       uint         `gorm:"not null;index" json:"tenantId"`
+	VendorID       uint         `gorm:"not null;index" json:"vendorId"`
+	MinDaysBefore  int          `gorm:"not null;default:0" json:"minDaysBefore"`
+	PenaltyPercent float64      `gorm:"default:0" json:"penaltyPercent"`
+	FlatFee        money.Amount `gorm:"default:0" json:"flatFee"`
+}
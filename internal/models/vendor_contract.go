@@ -0,0 +1,41 @@
+// internal/models/vendor_contract.go
+package models
+
+import (
+	"time"
+
+	"travel-agency/internal/money"
+)
+
+// VendorContract is an agreement with a vendor for a validity period. Its
+// rate cards give the contracted cost per product and season.
+type VendorContract struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	TenantID     uint       `gorm:"not null;index" json:"tenantId"`
+	VendorID     uint       `gorm:"not null;index" json:"vendorId"`
+	Reference    string     `gorm:"size:100" json:"reference"`
+	ValidFrom    time.Time  `gorm:"not null" json:"validFrom"`
+	ValidTo      time.Time  `gorm:"not null" json:"validTo"`      // Inclusive.
+	PaymentTerms string     `gorm:"size:255" json:"paymentTerms"` // Overrides Vendor.PaymentTerms while valid.
+	Currency     string     `gorm:"size:10" json:"currency"`
+	Notes        string     `gorm:"size:1024" json:"notes"`
+	Rates        []RateCard `gorm:"foreignKey:ContractID" json:"rates"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+// RateCard is the contracted cost of one unit (room night, seat) of a
+// product within a season. Occupancy 0 and an empty MealPlan match any.
+type RateCard struct {
+	ID         uint         `gorm:"primaryKey" json:"id"`
+	TenantID   uint         `gorm:"not null;index" json:"tenantId"`
+	VendorID   uint         `gorm:"not null;index" json:"vendorId"`
+	ContractID uint         `gorm:"not null;index" json:"contractId"`
+	Product    string       `gorm:"size:255;not null;index" json:"product"`
+	Season     string       `gorm:"size:100" json:"season"` // Label only, e.g. "Peak".
+	StartDate  time.Time    `gorm:"not null" json:"startDate"`
+	EndDate    time.Time    `gorm:"not null" json:"endDate"`    // Inclusive.
+	Occupancy  int          `gorm:"default:0" json:"occupancy"` // Guests per unit.
+	MealPlan   string       `gorm:"size:20" json:"mealPlan"`    // EP, CP, MAP, AP...
+	Cost       money.Amount `gorm:"not null" json:"cost"`
+}
@@ -0,0 +1,366 @@
+// internal/money/money.go
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Amount is an exact amount of money in ten-thousandths of a currency unit.
+// Four decimal places cover every currency's minor unit and leave room for
+// unit prices; totals are rounded to the currency's minor unit with Round.
+// Amounts add, subtract and compare as plain integers.
+type Amount int64
+
+// Scale is the number of decimal places an Amount holds.
+const Scale = 4
+
+const unit = 10000 // 10^Scale
+
+// Zero is the zero amount.
+const Zero Amount = 0
+
+// ErrInvalidAmount is returned when a value cannot be read as an amount.
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// minorUnits lists currencies whose minor unit is not hundredths.
+var minorUnits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// MinorUnits returns the number of decimal places the currency is rounded
+// to: 0 for e.g. JPY, 3 for e.g. KWD and 2 for everything else, including
+// an unknown or empty code.
+func MinorUnits(currency string) int {
+	if d, ok := minorUnits[strings.ToUpper(strings.TrimSpace(currency))]; ok {
+		return d
+	}
+	return 2
+}
+
+// MinorUnitsMap returns the currencies whose minor unit is not hundredths,
+// for callers that need to round in SQL.
+func MinorUnitsMap() map[string]int {
+	out := make(map[string]int, len(minorUnits))
+	for k, v := range minorUnits {
+		out[k] = v
+	}
+	return out
+}
+
+// FromFloat converts f, rounding to Scale places.
+func FromFloat(f float64) Amount {
+	return Amount(math.Round(f * unit))
+}
+
+// FromInt returns a whole number of currency units.
+func FromInt(units int64) Amount {
+	return Amount(units * unit)
+}
+
+// FromMinor converts an amount in the currency's minor unit (e.g. cents),
+// as payment providers use.
+func FromMinor(minor int64, currency string) Amount {
+	return Amount(minor * pow10(Scale-MinorUnits(currency)))
+}
+
+// Minor returns the amount in the currency's minor unit, rounded.
+func (a Amount) Minor(currency string) int64 {
+	return int64(a.Round(currency)) / pow10(Scale-MinorUnits(currency))
+}
+
+// Parse reads a decimal such as "-1234.56". More than Scale decimal places
+// are rounded half away from zero; exponents are accepted but go through
+// float64.
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("%w: empty", ErrInvalidAmount)
+	}
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+		}
+		return FromFloat(f), nil
+	}
+	neg := false
+	digits := s
+	switch digits[0] {
+	case '-':
+		neg, digits = true, digits[1:]
+	case '+':
+		digits = digits[1:]
+	}
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole == "" && frac == "" || !allDigits(whole) || !allDigits(frac) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	roundUp := false
+	if len(frac) > Scale {
+		roundUp = frac[Scale] >= '5'
+		frac = frac[:Scale]
+	}
+	frac += strings.Repeat("0", Scale-len(frac))
+	if whole == "" {
+		whole = "0"
+	}
+	v, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q out of range", ErrInvalidAmount, s)
+	}
+	if roundUp {
+		v++
+	}
+	if neg {
+		v = -v
+	}
+	return Amount(v), nil
+}
+
+func allDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for ; n > 0; n-- {
+		p *= 10
+	}
+	return p
+}
+
+// Float64 returns the amount as a float, for ratios and display only.
+func (a Amount) Float64() float64 {
+	return float64(a) / unit
+}
+
+// Round rounds to the currency's minor unit, half away from zero.
+func (a Amount) Round(currency string) Amount {
+	return a.RoundTo(MinorUnits(currency))
+}
+
+// RoundTo rounds to the given number of decimal places, half away from zero.
+func (a Amount) RoundTo(places int) Amount {
+	if places >= Scale {
+		return a
+	}
+	if places < 0 {
+		places = 0
+	}
+	step := pow10(Scale - places)
+	return Amount(divRound(int64(a), step) * step)
+}
+
+// divRound divides, rounding half away from zero.
+func divRound(n, d int64) int64 {
+	q, r := n/d, n%d
+	if r < 0 {
+		r = -r
+	}
+	if 2*r >= d {
+		if n < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return q
+}
+
+// Mul multiplies by a quantity or rate, rounding to Scale places. The
+// factor is taken at its shortest decimal form (0.18, not the nearest
+// binary fraction), so the product is exact before rounding.
+func (a Amount) Mul(f float64) Amount {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	if !ok {
+		return 0
+	}
+	return Amount(roundRat(r.Mul(r, new(big.Rat).SetInt64(int64(a)))))
+}
+
+// Percent returns p percent of the amount, rounded to Scale places.
+func (a Amount) Percent(p float64) Amount {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(p, 'g', -1, 64))
+	if !ok {
+		return 0
+	}
+	r.Mul(r, new(big.Rat).SetInt64(int64(a)))
+	return Amount(roundRat(r.Quo(r, big.NewRat(100, 1))))
+}
+
+// Div divides by n, rounding to Scale places.
+func (a Amount) Div(n int64) Amount {
+	if n == 0 {
+		return 0
+	}
+	return Amount(roundRat(big.NewRat(int64(a), n)))
+}
+
+// Ratio returns a / b as a float, or 0 when b is zero.
+func (a Amount) Ratio(b Amount) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+func roundRat(r *big.Rat) int64 {
+	num, den := r.Num(), r.Denom()
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+	if m.Abs(m).Lsh(m, 1).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q.Int64()
+}
+
+// Abs returns the absolute value.
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// IsZero reports whether the amount is zero.
+func (a Amount) IsZero() bool {
+	return a == 0
+}
+
+// Min returns the smaller amount.
+func Min(a, b Amount) Amount {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Max returns the larger amount.
+func Max(a, b Amount) Amount {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Sum adds amounts.
+func Sum(amounts ...Amount) Amount {
+	var total Amount
+	for _, a := range amounts {
+		total += a
+	}
+	return total
+}
+
+// StringFixed formats the amount with exactly places decimals, rounding
+// half away from zero.
+func (a Amount) StringFixed(places int) string {
+	if places > Scale {
+		places = Scale
+	}
+	v := int64(a.RoundTo(places))
+	sign := ""
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	whole, frac := v/unit, v%unit
+	if places == 0 {
+		return fmt.Sprintf("%s%d", sign, whole)
+	}
+	f := fmt.Sprintf("%04d", frac)[:places]
+	return fmt.Sprintf("%s%d.%s", sign, whole, f)
+}
+
+// String formats the amount with at least two decimals and no trailing
+// zeros beyond them, e.g. "12.50" or "0.1234".
+func (a Amount) String() string {
+	s := a.StringFixed(Scale)
+	for strings.HasSuffix(s, "0") && len(s)-strings.IndexByte(s, '.') > 3 {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// Format formats the amount rounded to the currency's minor unit.
+func (a Amount) Format(currency string) string {
+	return a.StringFixed(MinorUnits(currency))
+}
+
+// MarshalJSON encodes the amount as a JSON number.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a numeric string.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Scan implements sql.Scanner.
+func (a *Amount) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = 0
+	case int64:
+		*a = FromInt(v)
+	case float64:
+		*a = FromFloat(v)
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidAmount, value)
+	}
+	return nil
+}
+
+func (a *Amount) scanString(s string) error {
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Value implements driver.Valuer; amounts are stored as exact decimals.
+func (a Amount) Value() (driver.Value, error) {
+	return a.StringFixed(Scale), nil
+}
+
+// GormDBDataType stores amounts as numeric(19,4) columns.
+func (Amount) GormDBDataType(*gorm.DB, *schema.Field) string {
+	return "numeric(19,4)"
+}
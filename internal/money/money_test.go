@@ -0,0 +1,60 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAmount(t *testing.T) {
+	a, err := Parse("0.1")
+	assert.NoError(t, err)
+	b, _ := Parse("0.2")
+	assert.Equal(t, Amount(3000), a+b) // exactly 0.3, unlike float64
+	assert.Equal(t, "0.30", (a + b).String())
+
+	for in, want := range map[string]string{
+		"1234.5": "1234.50", "-0.00005": "-0.0001", "1.23456": "1.2346", ".5": "0.50", "12": "12.00", "1e3": "1000.00",
+	} {
+		v, err := Parse(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, v.String(), in)
+	}
+	for _, in := range []string{"", "abc", "1.2.3", "-", "1,000"} {
+		_, err := Parse(in)
+		assert.ErrorIs(t, err, ErrInvalidAmount, in)
+	}
+
+	// Currency rounding, half away from zero.
+	v, _ := Parse("10.125")
+	assert.Equal(t, "10.13", v.Round("USD").String())
+	assert.Equal(t, "-10.13", (-v).Round("EUR").String())
+	assert.Equal(t, "10", v.Format("JPY"))
+	assert.Equal(t, "10.125", v.Format("KWD"))
+	assert.Equal(t, int64(1013), v.Minor("USD"))
+	assert.Equal(t, FromInt(10), FromMinor(10000, "KWD"))
+	assert.Equal(t, FromInt(10), FromMinor(10, "JPY"))
+
+	// Rates are applied at their decimal value.
+	assert.Equal(t, "18.00", FromInt(100).Percent(18).String())
+	assert.Equal(t, "0.0001", FromFloat(0.0005).Percent(18).String()) // 0.00009 rounds up
+	assert.Equal(t, "3.3333", FromInt(10).Div(3).String())
+	assert.Equal(t, "29.97", FromFloat(9.99).Mul(3).String())
+
+	// JSON numbers and numeric strings.
+	var x struct{ A, B Amount }
+	assert.NoError(t, json.Unmarshal([]byte(`{"A": 19.99, "B": "5.5"}`), &x))
+	assert.Equal(t, FromFloat(19.99), x.A)
+	out, _ := json.Marshal(x)
+	assert.JSONEq(t, `{"A": 19.99, "B": 5.5}`, string(out))
+
+	// Database values.
+	var s Amount
+	assert.NoError(t, s.Scan([]byte("12.3400")))
+	assert.Equal(t, FromFloat(12.34), s)
+	assert.NoError(t, s.Scan(int64(7)))
+	assert.Equal(t, FromInt(7), s)
+	dv, _ := FromFloat(12.34).Value()
+	assert.Equal(t, "12.3400", dv)
+}
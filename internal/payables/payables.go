@@ -0,0 +1,349 @@
+// internal/payables/payables.go
+package payables
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"travel-agency/internal/ledger"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Aging buckets, by days past the terms due date.
+const (
+	BucketCurrent = "current"
+	Bucket1To30   = "1-30"
+	Bucket31To60  = "31-60"
+	Bucket61To90  = "61-90"
+	BucketOver90  = "90+"
+)
+
+var termsDays = regexp.MustCompile(`(\d+)`)
+
+// ParseTerms turns payment terms such as "Net 30", "45 days" or "Due on
+// receipt" into a number of days after the invoice date.
+func ParseTerms(terms string) (int, bool) {
+	t := strings.ToLower(strings.TrimSpace(terms))
+	if t == "" {
+		return 0, false
+	}
+	if strings.Contains(t, "receipt") || strings.Contains(t, "immediate") || t == "cod" || t == "prepaid" {
+		return 0, true
+	}
+	if m := termsDays.FindString(t); m != "" {
+		days, err := strconv.Atoi(m)
+		return days, err == nil
+	}
+	return 0, false
+}
+
+// DueDate is when a purchase invoice falls due under the payment terms, or
+// the invoice's own due date when the terms cannot be parsed.
+func DueDate(inv models.Invoice, terms string) time.Time {
+	if days, ok := ParseTerms(terms); ok {
+		return inv.IssueDate.AddDate(0, 0, days)
+	}
+	return inv.DueDate
+}
+
+// Bucket names the aging bucket for a number of days overdue.
+func Bucket(daysOverdue int) string {
+	switch {
+	case daysOverdue <= 0:
+		return BucketCurrent
+	case daysOverdue <= 30:
+		return Bucket1To30
+	case daysOverdue <= 60:
+		return Bucket31To60
+	case daysOverdue <= 90:
+		return Bucket61To90
+	default:
+		return BucketOver90
+	}
+}
+
+// Payable is an open purchase invoice and what is still owed on it.
+type Payable struct {
+	InvoiceID   uuid.UUID    `json:"invoiceId"`
+	Reference   string       `json:"reference"`
+	VendorID    uint         `json:"vendorId"`
+	VendorName  string       `json:"vendorName"`
+	IssueDate   time.Time    `json:"issueDate"`
+	DueDate     time.Time    `json:"dueDate"` // From payment terms, else the invoice due date.
+	Terms       string       `json:"terms"`
+	Currency    string       `json:"currency"`
+	Amount      money.Amount `json:"amount"`
+	Paid        money.Amount `json:"paid"`
+	Scheduled   money.Amount `json:"scheduled"` // In payment runs not yet executed.
+	Outstanding money.Amount `json:"outstanding"`
+	DaysOverdue int          `json:"daysOverdue"`
+	Bucket      string       `json:"bucket"`
+}
+
+// Aging totals a vendor's outstanding payables per bucket.
+type Aging struct {
+	VendorID   uint         `json:"vendorId"`
+	VendorName string       `json:"vendorName"`
+	Current    money.Amount `json:"current"`
+	Days1To30  money.Amount `json:"days1To30"`
+	Days31To60 money.Amount `json:"days31To60"`
+	Days61To90 money.Amount `json:"days61To90"`
+	Over90     money.Amount `json:"over90"`
+	Total      money.Amount `json:"total"`
+}
+
+// TermsFor returns the vendor's payment terms on a date: those of a contract
+// valid on that date, else the vendor default.
+func TermsFor(contracts []models.VendorContract, vendor models.Vendor, date time.Time) string {
+	for _, c := range contracts {
+		if c.VendorID == vendor.ID && c.PaymentTerms != "" && !date.Before(c.ValidFrom) && !date.After(c.ValidTo) {
+			return c.PaymentTerms
+		}
+	}
+	return vendor.PaymentTerms
+}
+
+// Open lists purchase invoices with money still owed, for one vendor (or
+// all when vendorID is 0), aged as of asOf.
+func Open(db *gorm.DB, tenantID, vendorID uint, asOf time.Time) ([]Payable, error) {
+	q := db.Where("tenant_id = ? AND invoice_type = ? AND vendor_id IS NOT NULL AND status NOT IN ?",
+		tenantID, "purchase", []string{"Paid", "Credited", "Canceled", "Cancelled", "Void", "Draft"})
+	if vendorID != 0 {
+		q = q.Where("vendor_id = ?", vendorID)
+	}
+	var invoices []models.Invoice
+	if err := q.Order("issue_date, id").Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	if len(invoices) == 0 {
+		return []Payable{}, nil
+	}
+
+	var vendors []models.Vendor
+	if err := db.Where("tenant_id = ?", tenantID).Find(&vendors).Error; err != nil {
+		return nil, err
+	}
+	byID := map[uint]models.Vendor{}
+	for _, v := range vendors {
+		byID[v.ID] = v
+	}
+	var contracts []models.VendorContract
+	if err := db.Where("tenant_id = ? AND payment_terms <> ''", tenantID).
+		Order("valid_from DESC").Find(&contracts).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(invoices))
+	for _, inv := range invoices {
+		ids = append(ids, inv.ID)
+	}
+	var payments []models.VendorPayment
+	if err := db.Where("tenant_id = ? AND invoice_id IN ? AND status IN ?", tenantID, ids,
+		[]string{"Scheduled", "Completed"}).Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	paid := map[uuid.UUID]money.Amount{}
+	scheduled := map[uuid.UUID]money.Amount{}
+	for _, p := range payments {
+		if p.Status == "Completed" {
+			paid[p.InvoiceID] += p.Amount
+		} else {
+			scheduled[p.InvoiceID] += p.Amount
+		}
+	}
+
+	asOfDay := utils.TruncateDay(asOf)
+	list := make([]Payable, 0, len(invoices))
+	for _, inv := range invoices {
+		vendor := byID[*inv.VendorID]
+		terms := TermsFor(contracts, vendor, inv.IssueDate)
+		due := DueDate(inv, terms)
+		id := inv.ID
+		p := Payable{
+			InvoiceID:  id,
+			Reference:  inv.Reference,
+			VendorID:   vendor.ID,
+			VendorName: vendor.Name,
+			IssueDate:  inv.IssueDate,
+			DueDate:    due,
+			Terms:      terms,
+			Currency:   inv.Currency,
+			Amount:     inv.Amount,
+			Paid:       paid[id],
+			Scheduled:  scheduled[id],
+		}
+		p.Outstanding = inv.Amount - p.Paid
+		if p.Outstanding <= 0 {
+			continue
+		}
+		p.DaysOverdue = int(asOfDay.Sub(utils.TruncateDay(due)).Hours() / 24)
+		p.Bucket = Bucket(p.DaysOverdue)
+		list = append(list, p)
+	}
+	return list, nil
+}
+
+// Summarize totals payables per vendor and bucket.
+func Summarize(list []Payable) []Aging {
+	var out []Aging
+	index := map[uint]int{}
+	for _, p := range list {
+		i, ok := index[p.VendorID]
+		if !ok {
+			i = len(out)
+			index[p.VendorID] = i
+			out = append(out, Aging{VendorID: p.VendorID, VendorName: p.VendorName})
+		}
+		a := &out[i]
+		switch p.Bucket {
+		case BucketCurrent:
+			a.Current += p.Outstanding
+		case Bucket1To30:
+			a.Days1To30 += p.Outstanding
+		case Bucket31To60:
+			a.Days31To60 += p.Outstanding
+		case Bucket61To90:
+			a.Days61To90 += p.Outstanding
+		default:
+			a.Over90 += p.Outstanding
+		}
+		a.Total += p.Outstanding
+	}
+	return out
+}
+
+// ErrNothingDue is returned when a payment run would be empty.
+var ErrNothingDue = errors.New("no payables due")
+
+// ErrRunClosed is returned when executing or cancelling a finished run.
+var ErrRunClosed = errors.New("payment run is not scheduled")
+
+// PlanRun creates a scheduled run paying everything still unscheduled on
+// invoices due by dueBy (optionally for one vendor).
+func PlanRun(tx *gorm.DB, tenantID, vendorID, userID uint, scheduledFor, dueBy time.Time, method string) (*models.PaymentRun, error) {
+	list, err := Open(tx, tenantID, vendorID, dueBy)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	run := models.PaymentRun{
+		TenantID:     tenantID,
+		ScheduledFor: scheduledFor,
+		DueBy:        dueBy,
+		Method:       method,
+		Status:       models.PaymentRunScheduled,
+		CreatedBy:    userID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	for _, p := range list {
+		amount := p.Outstanding - p.Scheduled
+		if p.DaysOverdue < 0 || amount <= 0 {
+			continue
+		}
+		run.Payments = append(run.Payments, models.VendorPayment{
+			TenantID:  tenantID,
+			VendorID:  p.VendorID,
+			InvoiceID: p.InvoiceID,
+			Amount:    amount,
+			Currency:  p.Currency,
+			Method:    method,
+			Status:    "Scheduled",
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		run.Total += amount
+	}
+	if len(run.Payments) == 0 {
+		return nil, ErrNothingDue
+	}
+	if err := tx.Create(&run).Error; err != nil {
+		return nil, err
+	}
+	details := fmt.Sprintf("Payment run %d: %d payments, total %s, scheduled for %s",
+		run.ID, len(run.Payments), run.Total, scheduledFor.Format("2006-01-02"))
+	return &run, utils.LogAction(tx, tenantID, userID, "CREATE_PAYMENT_RUN", "PaymentRun", details)
+}
+
+// ExecuteRun marks the run's payments as paid, posts them to the ledger and
+// settles the invoices that are now fully paid.
+func ExecuteRun(tx *gorm.DB, run *models.PaymentRun, userID uint) error {
+	if run.Status != models.PaymentRunScheduled {
+		return ErrRunClosed
+	}
+	now := time.Now()
+	if err := tx.Model(&models.VendorPayment{}).
+		Where("payment_run_id = ? AND status = ?", run.ID, "Scheduled").
+		Updates(map[string]interface{}{"status": "Completed", "paid_at": now, "updated_at": now}).Error; err != nil {
+		return err
+	}
+	for i := range run.Payments {
+		vp := &run.Payments[i]
+		if vp.Status != "Scheduled" {
+			continue
+		}
+		vp.Status, vp.PaidAt, vp.UpdatedAt = "Completed", &now, now
+		if err := ledger.PostVendorPayment(tx, vp); err != nil {
+			return err
+		}
+	}
+	for _, p := range run.Payments {
+		var paid money.Amount
+		if err := tx.Model(&models.VendorPayment{}).
+			Where("invoice_id = ? AND status = ?", p.InvoiceID, "Completed").
+			Select("COALESCE(SUM(amount), 0)").Row().Scan(&paid); err != nil {
+			return err
+		}
+		var inv models.Invoice
+		if err := tx.Where("id = ? AND tenant_id = ?", p.InvoiceID, run.TenantID).First(&inv).Error; err != nil {
+			return err
+		}
+		status := "Partially Paid"
+		if paid >= inv.Amount {
+			status = "Paid"
+		}
+		if err := tx.Model(&inv).Updates(map[string]interface{}{"status": status, "updated_at": now}).Error; err != nil {
+			return err
+		}
+	}
+	run.Status = models.PaymentRunCompleted
+	run.ExecutedAt = &now
+	run.UpdatedAt = now
+	if err := tx.Model(run).Updates(map[string]interface{}{
+		"status": run.Status, "executed_at": now, "updated_at": now,
+	}).Error; err != nil {
+		return err
+	}
+	return utils.LogAction(tx, run.TenantID, userID, "EXECUTE_PAYMENT_RUN", "PaymentRun",
+		fmt.Sprintf("Payment run %d paid, total %s", run.ID, run.Total))
+}
+
+// CancelRun cancels a scheduled run; its invoices become payable again.
+func CancelRun(tx *gorm.DB, run *models.PaymentRun, userID uint) error {
+	if run.Status != models.PaymentRunScheduled {
+		return ErrRunClosed
+	}
+	now := time.Now()
+	if err := tx.Model(&models.VendorPayment{}).
+		Where("payment_run_id = ?", run.ID).
+		Updates(map[string]interface{}{"status": "Cancelled", "updated_at": now}).Error; err != nil {
+		return err
+	}
+	run.Status = models.PaymentRunCancelled
+	run.UpdatedAt = now
+	if err := tx.Model(run).Updates(map[string]interface{}{"status": run.Status, "updated_at": now}).Error; err != nil {
+		return err
+	}
+	return utils.LogAction(tx, run.TenantID, userID, "CANCEL_PAYMENT_RUN", "PaymentRun",
+		fmt.Sprintf("Payment run %d cancelled", run.ID))
+}
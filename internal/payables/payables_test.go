@@ -0,0 +1,72 @@
+package payables
+
+import (
+	"testing"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTerms(t *testing.T) {
+	cases := map[string]int{"Net 30": 30, "45 days": 45, "Due on receipt": 0, "net15": 15}
+	for terms, want := range cases {
+		days, ok := ParseTerms(terms)
+		assert.True(t, ok, terms)
+		assert.Equal(t, want, days, terms)
+	}
+	_, ok := ParseTerms("as agreed")
+	assert.False(t, ok)
+}
+
+func TestBucket(t *testing.T) {
+	assert.Equal(t, BucketCurrent, Bucket(-3))
+	assert.Equal(t, BucketCurrent, Bucket(0))
+	assert.Equal(t, Bucket1To30, Bucket(30))
+	assert.Equal(t, Bucket31To60, Bucket(31))
+	assert.Equal(t, Bucket61To90, Bucket(90))
+	assert.Equal(t, BucketOver90, Bucket(91))
+}
+
+func TestPaymentRun(t *testing.T) {
+	db := testutil.DB(t)
+	db.Create(&models.Vendor{TenantID: 1, Name: "Hotel", PaymentTerms: "Net 30"})
+	vendorID := uint(1)
+	day := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 0, 0, 0, 0, time.UTC) }
+	old := models.Invoice{TenantID: 1, InvoiceType: "purchase", VendorID: &vendorID, Status: "Outstanding",
+		IssueDate: day(1, 1), DueDate: day(1, 1), Amount: money.FromInt(500), Currency: "USD"}
+	recent := models.Invoice{TenantID: 1, InvoiceType: "purchase", VendorID: &vendorID, Status: "Outstanding",
+		IssueDate: day(3, 20), DueDate: day(3, 20), Amount: money.FromInt(200), Currency: "USD"}
+	db.Create(&old)
+	db.Create(&recent)
+
+	// Net 30 from 1 Jan is due 31 Jan: 59 days overdue on 31 Mar.
+	list, err := Open(db, 1, 0, day(3, 31))
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, Bucket31To60, list[0].Bucket)
+	assert.Equal(t, BucketCurrent, list[1].Bucket)
+	aging := Summarize(list)
+	assert.Equal(t, money.FromInt(500), aging[0].Days31To60)
+	assert.Equal(t, money.FromInt(700), aging[0].Total)
+
+	run, err := PlanRun(db, 1, 0, 9, day(3, 31), day(3, 31), "Bank Transfer")
+	assert.NoError(t, err)
+	assert.Len(t, run.Payments, 1)
+	assert.Equal(t, money.FromInt(500), run.Total)
+
+	// Already scheduled, so a second run has nothing to pay.
+	_, err = PlanRun(db, 1, 0, 9, day(3, 31), day(3, 31), "Bank Transfer")
+	assert.ErrorIs(t, err, ErrNothingDue)
+
+	assert.NoError(t, ExecuteRun(db, run, 9))
+	assert.ErrorIs(t, ExecuteRun(db, run, 9), ErrRunClosed)
+	db.First(&old, "id = ?", old.ID)
+	assert.Equal(t, "Paid", old.Status)
+
+	list, _ = Open(db, 1, 0, day(3, 31))
+	assert.Len(t, list, 1)
+}
@@ -0,0 +1,179 @@
+// internal/payables/statement.go
+package payables
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// StatementLine is one invoice (credit to the vendor) or payment (debit).
+type StatementLine struct {
+	Date      time.Time    `json:"date"`
+	Kind      string       `json:"kind"` // "invoice" or "payment"
+	Reference string       `json:"reference"`
+	Debit     money.Amount `json:"debit"`
+	Credit    money.Amount `json:"credit"`
+	Balance   money.Amount `json:"balance"` // What we owe the vendor after this line.
+}
+
+// BookingCost is a booking's cost to the vendor in the statement period.
+type BookingCost struct {
+	BookingID  uint         `json:"bookingId"`
+	BookingRef string       `json:"bookingRef"`
+	TravelDate time.Time    `json:"travelDate"`
+	Status     string       `json:"status"`
+	Cost       money.Amount `json:"cost"` // VendorPenalty for cancelled bookings.
+}
+
+// Reconciliation compares what the vendor invoiced with the cost of the
+// bookings we made with them in the period.
+type Reconciliation struct {
+	BookedCost money.Amount  `json:"bookedCost"`
+	Invoiced   money.Amount  `json:"invoiced"`
+	Difference money.Amount  `json:"difference"` // Invoiced - BookedCost.
+	Bookings   []BookingCost `json:"bookings"`
+}
+
+// Statement is a vendor account statement for a period.
+type Statement struct {
+	VendorID       uint            `json:"vendorId"`
+	VendorName     string          `json:"vendorName"`
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	OpeningBalance money.Amount    `json:"openingBalance"`
+	Lines          []StatementLine `json:"lines"`
+	ClosingBalance money.Amount    `json:"closingBalance"`
+	Reconciliation Reconciliation  `json:"reconciliation"`
+}
+
+var excludedInvoiceStatuses = []string{"Draft", "Canceled", "Cancelled", "Void"}
+
+// BuildStatement lists the vendor's purchase invoices and completed payments
+// between from and to (inclusive days) with a running balance, and
+// reconciles the invoiced total against booking costs on travel dates in
+// the same period.
+func BuildStatement(db *gorm.DB, vendor models.Vendor, from, to time.Time) (*Statement, error) {
+	end := to.AddDate(0, 0, 1)
+	st := &Statement{VendorID: vendor.ID, VendorName: vendor.Name, From: from, To: to, Lines: []StatementLine{}}
+
+	invoices := db.Model(&models.Invoice{}).
+		Where("tenant_id = ? AND vendor_id = ? AND invoice_type = ? AND status NOT IN ?",
+			vendor.TenantID, vendor.ID, "purchase", excludedInvoiceStatuses)
+	payments := db.Model(&models.VendorPayment{}).
+		Where("tenant_id = ? AND vendor_id = ? AND status = ?", vendor.TenantID, vendor.ID, "Completed")
+
+	var invoicedBefore, paidBefore money.Amount
+	if err := invoices.Session(&gorm.Session{}).Where("issue_date < ?", from).
+		Select("COALESCE(SUM(amount), 0)").Row().Scan(&invoicedBefore); err != nil {
+		return nil, err
+	}
+	if err := payments.Session(&gorm.Session{}).Where("paid_at < ?", from).
+		Select("COALESCE(SUM(amount), 0)").Row().Scan(&paidBefore); err != nil {
+		return nil, err
+	}
+	st.OpeningBalance = invoicedBefore - paidBefore
+
+	var invList []models.Invoice
+	if err := invoices.Session(&gorm.Session{}).Where("issue_date >= ? AND issue_date < ?", from, end).
+		Find(&invList).Error; err != nil {
+		return nil, err
+	}
+	var payList []models.VendorPayment
+	if err := payments.Session(&gorm.Session{}).Where("paid_at >= ? AND paid_at < ?", from, end).
+		Find(&payList).Error; err != nil {
+		return nil, err
+	}
+
+	for _, inv := range invList {
+		ref := inv.Reference
+		if ref == "" {
+			ref = inv.ID.String()
+		}
+		st.Lines = append(st.Lines, StatementLine{Date: inv.IssueDate, Kind: "invoice", Reference: ref, Credit: inv.Amount})
+		st.Reconciliation.Invoiced += inv.Amount
+	}
+	for _, p := range payList {
+		ref := fmt.Sprintf("Payment %d", p.ID)
+		if p.PaymentRunID != nil {
+			ref = fmt.Sprintf("Payment run %d", *p.PaymentRunID)
+		}
+		st.Lines = append(st.Lines, StatementLine{Date: *p.PaidAt, Kind: "payment", Reference: ref, Debit: p.Amount})
+	}
+	sort.SliceStable(st.Lines, func(i, j int) bool { return st.Lines[i].Date.Before(st.Lines[j].Date) })
+
+	balance := st.OpeningBalance
+	for i := range st.Lines {
+		balance += st.Lines[i].Credit - st.Lines[i].Debit
+		st.Lines[i].Balance = balance
+	}
+	st.ClosingBalance = balance
+
+	var bookings []models.Booking
+	if err := db.Where("tenant_id = ? AND vendor_id = ? AND travel_date >= ? AND travel_date < ?",
+		vendor.TenantID, vendor.ID, from, end).Order("travel_date, id").Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+	rec := &st.Reconciliation
+	rec.Bookings = []BookingCost{}
+	for _, b := range bookings {
+		cost := b.Cost
+		if b.Status == models.BookingCancelled {
+			cost = b.VendorPenalty
+		}
+		rec.Bookings = append(rec.Bookings, BookingCost{
+			BookingID:  b.ID,
+			BookingRef: b.BookingRef,
+			TravelDate: b.TravelDate,
+			Status:     b.Status,
+			Cost:       cost,
+		})
+		rec.BookedCost += cost
+	}
+	rec.Difference = rec.Invoiced - rec.BookedCost
+	return st, nil
+}
+
+// WriteCSV exports the statement, followed by the booking reconciliation.
+func (st *Statement) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	amount := func(v money.Amount) string { return v.String() }
+	day := func(t time.Time) string { return t.Format("2006-01-02") }
+
+	rows := [][]string{
+		{"Vendor statement", st.VendorName},
+		{"Period", day(st.From), day(st.To)},
+		{},
+		{"Date", "Type", "Reference", "Debit", "Credit", "Balance"},
+		{day(st.From), "opening", "Opening balance", "", "", amount(st.OpeningBalance)},
+	}
+	for _, l := range st.Lines {
+		rows = append(rows, []string{day(l.Date), l.Kind, l.Reference, amount(l.Debit), amount(l.Credit), amount(l.Balance)})
+	}
+	rows = append(rows,
+		[]string{day(st.To), "closing", "Closing balance", "", "", amount(st.ClosingBalance)},
+		[]string{},
+		[]string{"Booking ID", "Booking ref", "Travel date", "Status", "Cost"},
+	)
+	for _, b := range st.Reconciliation.Bookings {
+		rows = append(rows, []string{fmt.Sprint(b.BookingID), b.BookingRef, day(b.TravelDate), b.Status, amount(b.Cost)})
+	}
+	rows = append(rows,
+		[]string{},
+		[]string{"Booked cost", amount(st.Reconciliation.BookedCost)},
+		[]string{"Invoiced", amount(st.Reconciliation.Invoiced)},
+		[]string{"Difference", amount(st.Reconciliation.Difference)},
+	)
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,123 @@
+// internal/pricing/contracts.go
+package pricing
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// LoadRates returns the vendor's rate cards for the product that are in
+// season on the given date under a contract valid on that date.
+func LoadRates(db *gorm.DB, tenantID, vendorID uint, product string, date time.Time) ([]models.RateCard, error) {
+	day := utils.TruncateDay(date)
+	contracts := db.Model(&models.VendorContract{}).Select("id").
+		Where("tenant_id = ? AND vendor_id = ? AND valid_from <= ? AND valid_to >= ?", tenantID, vendorID, day, day)
+
+	var rates []models.RateCard
+	err := db.Where("tenant_id = ? AND vendor_id = ? AND LOWER(product) = ? AND start_date <= ? AND end_date >= ?",
+		tenantID, vendorID, strings.ToLower(product), day, day).
+		Where("contract_id IN (?)", contracts).
+		Order("id").
+		Find(&rates).Error
+	return rates, err
+}
+
+// BestRate picks the most specific rate for the occupancy and meal plan: an
+// exact occupancy beats a wildcard, then an exact meal plan. The newest rate
+// wins ties. Returns nil when none applies.
+func BestRate(rates []models.RateCard, occupancy int, mealPlan string) *models.RateCard {
+	var best *models.RateCard
+	bestScore := -1
+	for i := range rates {
+		rate := &rates[i]
+		score := 0
+		if rate.Occupancy != 0 {
+			if rate.Occupancy != occupancy {
+				continue
+			}
+			score += 2
+		}
+		if rate.MealPlan != "" {
+			if !strings.EqualFold(rate.MealPlan, mealPlan) {
+				continue
+			}
+			score++
+		}
+		if score > bestScore || (score == bestScore && rate.ID > best.ID) {
+			best, bestScore = rate, score
+		}
+	}
+	return best
+}
+
+// ContractCost is the contracted cost of units of a product for nights
+// consecutive dates from the start date. found is false when any night has
+// no applicable rate.
+func ContractCost(db *gorm.DB, tenantID, vendorID uint, product string, start time.Time, nights, units, occupancy int, mealPlan string) (cost money.Amount, found bool, err error) {
+	if nights < 1 {
+		nights = 1
+	}
+	if units < 1 {
+		units = 1
+	}
+	for n := 0; n < nights; n++ {
+		rates, err := LoadRates(db, tenantID, vendorID, product, start.AddDate(0, 0, n))
+		if err != nil {
+			return 0, false, err
+		}
+		rate := BestRate(rates, occupancy, mealPlan)
+		if rate == nil {
+			return 0, false, nil
+		}
+		cost += rate.Cost * money.Amount(units)
+	}
+	return cost, true, nil
+}
+
+// CostDiffers reports whether an entered cost deviates from the contract by
+// more than a cent.
+func CostDiffers(entered, contract money.Amount) bool {
+	return (entered - contract).Abs() >= money.FromFloat(0.01)
+}
+
+// CostWarning is the message shown to agents when an entered cost does not
+// match the contract.
+func CostWarning(label string, entered, contract money.Amount) string {
+	return fmt.Sprintf("%s: entered cost %s differs from contracted cost %s", label, entered, contract)
+}
+
+// ApplyContractCosts fills in the cost of items that name a product from the
+// vendor's contract, on the item's date within the itinerary. Items with a
+// cost already entered keep it; a warning is returned when it differs.
+func ApplyContractCosts(db *gorm.DB, itin *models.Itinerary, items []models.ItineraryItem) ([]string, error) {
+	var warnings []string
+	for i := range items {
+		item := &items[i]
+		item.ContractCost = 0
+		if item.Product == "" || item.VendorID == 0 {
+			continue
+		}
+		date := itin.StartDate.AddDate(0, 0, item.Day-1)
+		cost, found, err := ContractCost(db, itin.TenantID, item.VendorID, item.Product, date, 1, 1, item.Occupancy, item.MealPlan)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		item.ContractCost = cost
+		if item.Cost == 0 {
+			item.Cost = cost
+		} else if CostDiffers(item.Cost, cost) {
+			warnings = append(warnings, CostWarning(fmt.Sprintf("Day %d %s %q", item.Day, item.Type, item.Product), item.Cost, cost))
+		}
+	}
+	return warnings, nil
+}
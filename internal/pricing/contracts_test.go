@@ -0,0 +1,50 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBestRate(t *testing.T) {
+	rates := []models.RateCard{
+		{ID: 1, Cost: money.FromInt(100)},
+		{ID: 2, Occupancy: 2, Cost: money.FromInt(120)},
+		{ID: 3, Occupancy: 2, MealPlan: "MAP", Cost: money.FromInt(150)},
+		{ID: 4, MealPlan: "CP", Cost: money.FromInt(110)},
+	}
+	assert.Equal(t, uint(3), BestRate(rates, 2, "map").ID)
+	assert.Equal(t, uint(2), BestRate(rates, 2, "EP").ID)
+	assert.Equal(t, uint(4), BestRate(rates, 1, "CP").ID)
+	assert.Equal(t, uint(1), BestRate(rates, 3, "").ID)
+	assert.Nil(t, BestRate(rates[1:3], 1, ""))
+}
+
+func TestContractCost(t *testing.T) {
+	db := testutil.DB(t)
+	day := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 0, 0, 0, 0, time.UTC) }
+	db.Create(&models.VendorContract{TenantID: 1, VendorID: 5, ValidFrom: day(1, 1), ValidTo: day(12, 31),
+		Rates: []models.RateCard{
+			{TenantID: 1, VendorID: 5, Product: "Deluxe", StartDate: day(1, 1), EndDate: day(12, 19), Cost: money.FromInt(100)},
+			{TenantID: 1, VendorID: 5, Product: "Deluxe", Season: "Peak", StartDate: day(12, 20), EndDate: day(12, 31), Cost: money.FromInt(180)},
+		}})
+
+	// Two nights spanning the start of peak season, two rooms.
+	cost, found, err := ContractCost(db, 1, 5, "deluxe", day(12, 19), 2, 2, 2, "")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, money.FromInt(560), cost)
+
+	// The contract ends on 31 Dec.
+	_, found, _ = ContractCost(db, 1, 5, "Deluxe", day(12, 31), 2, 1, 0, "")
+	assert.False(t, found)
+
+	// Other tenants never see the contract.
+	_, found, _ = ContractCost(db, 2, 5, "Deluxe", day(6, 1), 1, 1, 0, "")
+	assert.False(t, found)
+}
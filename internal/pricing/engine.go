@@ -0,0 +1,196 @@
+// internal/pricing/engine.go
+package pricing
+
+import (
+	"strings"
+
+	"travel-agency/internal/fx"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+
+	"gorm.io/gorm"
+)
+
+const (
+	MarkupPercent = "percent"
+	MarkupFixed   = "fixed"
+)
+
+// LineBreakdown shows how the price of a single itinerary item was derived.
+type LineBreakdown struct {
+	ItemID      uint         `json:"itemId"`
+	Day         int          `json:"day"`
+	Type        string       `json:"type"`
+	Description string       `json:"description"`
+	VendorID    uint         `json:"vendorId"`
+	Cost        money.Amount `json:"cost"` // In the itinerary's currency.
+	Price       money.Amount `json:"price"`
+	Margin      money.Amount `json:"margin"`
+	RuleID      uint         `json:"ruleId,omitempty"` // 0 when the supplied price was kept.
+
+	// The item's own cost when it is in another currency.
+	SourceCost     money.Amount `json:"sourceCost,omitempty"`
+	SourceCurrency string       `json:"sourceCurrency,omitempty"`
+	ExchangeRate   float64      `json:"exchangeRate,omitempty"`
+}
+
+// Breakdown is the server-side price calculation for a whole itinerary.
+type Breakdown struct {
+	Lines     []LineBreakdown `json:"lines"`
+	Currency  string          `json:"currency,omitempty"`
+	TotalCost money.Amount    `json:"totalCost"`
+	Subtotal  money.Amount    `json:"subtotal"`
+	Margin    money.Amount    `json:"margin"`
+	TaxRate   float64         `json:"taxRate"`
+	TaxAmount money.Amount    `json:"taxAmount"`
+	Total     money.Amount    `json:"total"`
+	Warnings  []string        `json:"warnings,omitempty"`
+}
+
+// LoadRules returns the active markup rules for a tenant.
+func LoadRules(db *gorm.DB, tenantID uint) ([]models.MarkupRule, error) {
+	var rules []models.MarkupRule
+	err := db.Where("tenant_id = ? AND disabled = ?", tenantID, false).Find(&rules).Error
+	return rules, err
+}
+
+// LoadTaxRate returns the tenant's default tax rate (percent).
+func LoadTaxRate(db *gorm.DB, tenantID uint) (float64, error) {
+	var tenant models.Tenant
+	if err := db.Select("tax_rate").First(&tenant, tenantID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return tenant.TaxRate, nil
+}
+
+// PriceItinerary fills in contracted costs and the rates from the items'
+// cost currencies, loads the tenant's rules and tax rate, prices the items in
+// place and copies the totals and any cost warnings onto the itinerary.
+func PriceItinerary(db *gorm.DB, itin *models.Itinerary, items []models.ItineraryItem) (Breakdown, error) {
+	warnings, err := ApplyContractCosts(db, itin, items)
+	if err != nil {
+		return Breakdown{}, err
+	}
+	if err := fx.PriceItems(db, itin, items); err != nil {
+		return Breakdown{}, err
+	}
+	rules, err := LoadRules(db, itin.TenantID)
+	if err != nil {
+		return Breakdown{}, err
+	}
+	taxRate, err := LoadTaxRate(db, itin.TenantID)
+	if err != nil {
+		return Breakdown{}, err
+	}
+
+	b := Calculate(rules, taxRate, itin.Currency, itin.Destination, items)
+	itin.TotalCost = b.TotalCost
+	itin.Subtotal = b.Subtotal
+	itin.Margin = b.Margin
+	itin.TaxAmount = b.TaxAmount
+	itin.TotalPrice = b.Total
+	itin.Warnings = warnings
+	b.Warnings = warnings
+	return b, nil
+}
+
+// Calculate derives each item's Price from its Cost, converted at its
+// CostRate, using the best matching rule, then totals the itinerary. Amounts
+// are rounded to the currency's minor unit. Items without a matching rule
+// keep the price that was supplied. Items are modified in place.
+func Calculate(rules []models.MarkupRule, taxRate float64, currency, destination string, items []models.ItineraryItem) Breakdown {
+	b := Breakdown{Currency: currency, TaxRate: taxRate, Lines: make([]LineBreakdown, 0, len(items))}
+	round := func(a money.Amount) money.Amount { return a.Round(currency) }
+
+	for i := range items {
+		item := &items[i]
+		line := LineBreakdown{
+			ItemID:      item.ID,
+			Day:         item.Day,
+			Type:        item.Type,
+			Description: item.Description,
+			VendorID:    item.VendorID,
+			Cost:        round(fx.Rated(item.Cost, item.CostRate)),
+		}
+		if item.CostRate != 0 && item.CostRate != 1 {
+			line.SourceCost = item.Cost
+			line.SourceCurrency = item.CostCurrency
+			line.ExchangeRate = item.CostRate
+		}
+
+		if rule := MatchRule(rules, *item, destination); rule != nil {
+			item.Price = ApplyRule(*rule, line.Cost)
+			line.RuleID = rule.ID
+		}
+		item.Price = round(item.Price)
+
+		line.Price = item.Price
+		line.Margin = item.Price - line.Cost
+		b.Lines = append(b.Lines, line)
+
+		b.TotalCost += line.Cost
+		b.Subtotal += item.Price
+	}
+
+	b.Margin = b.Subtotal - b.TotalCost
+	b.TaxAmount = round(b.Subtotal.Percent(taxRate))
+	b.Total = b.Subtotal + b.TaxAmount
+	return b
+}
+
+// MatchRule picks the most specific rule that matches the item. Vendor is the
+// most specific criterion, then item type, then destination; Priority breaks
+// ties. Returns nil when nothing matches.
+func MatchRule(rules []models.MarkupRule, item models.ItineraryItem, destination string) *models.MarkupRule {
+	var best *models.MarkupRule
+	bestScore := -1
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Disabled {
+			continue
+		}
+		score := 0
+		if rule.VendorID != 0 {
+			if rule.VendorID != item.VendorID {
+				continue
+			}
+			score += 4
+		}
+		if rule.ItemType != "" {
+			if !strings.EqualFold(rule.ItemType, item.Type) {
+				continue
+			}
+			score += 2
+		}
+		if rule.Destination != "" {
+			if !strings.EqualFold(rule.Destination, destination) {
+				continue
+			}
+			score++
+		}
+		if best == nil || score > bestScore || (score == bestScore && rule.Priority > best.Priority) {
+			best = rule
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// ApplyRule returns the selling price for the given cost, honouring the
+// rule's minimum margin. The caller rounds it to the currency.
+func ApplyRule(rule models.MarkupRule, cost money.Amount) money.Amount {
+	var price money.Amount
+	switch rule.MarkupType {
+	case MarkupFixed:
+		price = cost + money.FromFloat(rule.MarkupValue)
+	default:
+		price = cost + cost.Percent(rule.MarkupValue)
+	}
+	if price-cost < rule.MinMargin {
+		price = cost + rule.MinMargin
+	}
+	return price
+}
@@ -0,0 +1,46 @@
+package pricing
+
+import (
+	"testing"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	rules := []models.MarkupRule{
+		{ID: 1, MarkupType: MarkupPercent, MarkupValue: 10},                                                     // catch-all
+		{ID: 2, ItemType: "Hotel", MarkupType: MarkupPercent, MarkupValue: 20},                                  // by type
+		{ID: 3, VendorID: 7, MarkupType: MarkupFixed, MarkupValue: 50},                                          // by vendor
+		{ID: 4, Destination: "Kerala", MarkupType: MarkupPercent, MarkupValue: 1, MinMargin: money.FromInt(30)}, // min margin
+	}
+	items := []models.ItineraryItem{
+		{Type: "Hotel", VendorID: 1, Cost: money.FromInt(100)},
+		{Type: "Hotel", VendorID: 7, Cost: money.FromInt(100)},
+		{Type: "Transfer", VendorID: 2, Cost: money.FromInt(100)},
+	}
+
+	b := Calculate(rules, 5, "INR", "Kerala", items)
+
+	assert.Equal(t, money.FromInt(120), items[0].Price)
+	assert.Equal(t, uint(2), b.Lines[0].RuleID)
+	assert.Equal(t, money.FromInt(150), items[1].Price)
+	assert.Equal(t, uint(3), b.Lines[1].RuleID)
+	assert.Equal(t, money.FromInt(130), items[2].Price)
+	assert.Equal(t, uint(4), b.Lines[2].RuleID)
+
+	assert.Equal(t, money.FromInt(300), b.TotalCost)
+	assert.Equal(t, money.FromInt(400), b.Subtotal)
+	assert.Equal(t, money.FromInt(100), b.Margin)
+	assert.Equal(t, money.FromInt(20), b.TaxAmount)
+	assert.Equal(t, money.FromInt(420), b.Total)
+}
+
+func TestCalculateKeepsPriceWithoutRule(t *testing.T) {
+	items := []models.ItineraryItem{{Type: "Flight", Cost: money.FromInt(80), Price: money.FromInt(95)}}
+	b := Calculate(nil, 0, "", "", items)
+	assert.Equal(t, money.FromInt(95), items[0].Price)
+	assert.Equal(t, money.FromInt(15), b.Margin)
+}
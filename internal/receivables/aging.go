@@ -0,0 +1,199 @@
+// internal/receivables/aging.go
+package receivables
+
+import (
+	"sort"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Aging buckets, by days past the due date.
+const (
+	BucketCurrent = "current"
+	Bucket1To30   = "1-30"
+	Bucket31To60  = "31-60"
+	Bucket61To90  = "61-90"
+	BucketOver90  = "90+"
+)
+
+// Bucket names the aging bucket for a number of days overdue.
+func Bucket(daysOverdue int) string {
+	switch {
+	case daysOverdue <= 0:
+		return BucketCurrent
+	case daysOverdue <= 30:
+		return Bucket1To30
+	case daysOverdue <= 60:
+		return Bucket31To60
+	case daysOverdue <= 90:
+		return Bucket61To90
+	default:
+		return BucketOver90
+	}
+}
+
+// AgedInvoice is a sale invoice with money still owed on the report date.
+type AgedInvoice struct {
+	InvoiceID   uuid.UUID    `json:"invoiceId"`
+	Number      string       `json:"number"`
+	IssueDate   time.Time    `json:"issueDate"`
+	DueDate     time.Time    `json:"dueDate"`
+	Amount      money.Amount `json:"amount"`
+	Outstanding money.Amount `json:"outstanding"`
+	DaysOverdue int          `json:"daysOverdue"`
+	Bucket      string       `json:"bucket"`
+}
+
+// AgingRow totals what one customer owes in one currency per bucket. Rows
+// for invoices without a customer have no CustomerID; currency totals have
+// neither a customer nor invoices.
+type AgingRow struct {
+	CustomerID   *uuid.UUID    `json:"customerId,omitempty"`
+	ContactEmail string        `json:"contactEmail,omitempty"`
+	Currency     string        `json:"currency"`
+	Current      money.Amount  `json:"current"`
+	Days1To30    money.Amount  `json:"days1To30"`
+	Days31To60   money.Amount  `json:"days31To60"`
+	Days61To90   money.Amount  `json:"days61To90"`
+	Over90       money.Amount  `json:"over90"`
+	Total        money.Amount  `json:"total"`
+	Invoices     []AgedInvoice `json:"invoices,omitempty"`
+}
+
+func (r *AgingRow) add(bucket string, amount money.Amount) {
+	switch bucket {
+	case BucketCurrent:
+		r.Current += amount
+	case Bucket1To30:
+		r.Days1To30 += amount
+	case Bucket31To60:
+		r.Days31To60 += amount
+	case Bucket61To90:
+		r.Days61To90 += amount
+	default:
+		r.Over90 += amount
+	}
+	r.Total += amount
+}
+
+// AgingReport is the tenant's accounts receivable by customer and age.
+type AgingReport struct {
+	AsOf      time.Time  `json:"asOf"`
+	Customers []AgingRow `json:"customers"`
+	Totals    []AgingRow `json:"totals"` // One per currency.
+}
+
+// BuildAging ages the tenant's issued sale invoices as they stood at the end
+// of asOf: what was left on each after the completed payments and issued
+// credit notes dated by then, by days past its due date.
+func BuildAging(db *gorm.DB, tenantID uint, asOf time.Time) (*AgingReport, error) {
+	day := utils.TruncateDay(asOf)
+	end := day.AddDate(0, 0, 1)
+	report := &AgingReport{AsOf: day, Customers: []AgingRow{}, Totals: []AgingRow{}}
+
+	var invoices []models.Invoice
+	if err := db.Where("tenant_id = ? AND invoice_type = ? AND issued_at IS NOT NULL AND status NOT IN ? AND issue_date < ?",
+		tenantID, "sale", closed, end).Order("due_date, issue_date").Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	if len(invoices) == 0 {
+		return report, nil
+	}
+
+	var paid []struct {
+		InvoiceID uuid.UUID
+		Total     money.Amount
+	}
+	if err := db.Model(&models.PaymentAllocation{}).
+		Joins("JOIN payments ON payments.id = payment_allocations.payment_id").
+		Where("payment_allocations.tenant_id = ? AND payments.status = ? AND payments.payment_date < ?", tenantID, PaymentCompleted, end).
+		Group("payment_allocations.invoice_id").
+		Select("payment_allocations.invoice_id AS invoice_id, COALESCE(SUM(payment_allocations.amount), 0) AS total").
+		Scan(&paid).Error; err != nil {
+		return nil, err
+	}
+	var credited []struct {
+		InvoiceID uuid.UUID
+		Total     money.Amount
+	}
+	if err := db.Model(&models.CreditNote{}).
+		Where("tenant_id = ? AND status = ? AND issue_date < ?", tenantID, CreditNoteIssued, end).
+		Group("invoice_id").Select("invoice_id, COALESCE(SUM(amount), 0) AS total").
+		Scan(&credited).Error; err != nil {
+		return nil, err
+	}
+	settled := map[uuid.UUID]money.Amount{}
+	for _, p := range paid {
+		settled[p.InvoiceID] += p.Total
+	}
+	for _, c := range credited {
+		settled[c.InvoiceID] += c.Total
+	}
+
+	type key struct {
+		customer uuid.UUID
+		currency string
+	}
+	rows := map[key]*AgingRow{}
+	totals := map[string]*AgingRow{}
+	for _, inv := range invoices {
+		left := inv.Amount - settled[inv.ID]
+		if left <= 0 {
+			continue
+		}
+		days := int(day.Sub(utils.TruncateDay(inv.DueDate)).Hours() / 24)
+		aged := AgedInvoice{InvoiceID: inv.ID, Number: inv.ID.String(), IssueDate: inv.IssueDate, DueDate: inv.DueDate,
+			Amount: inv.Amount, Outstanding: left, DaysOverdue: days, Bucket: Bucket(days)}
+		if inv.Number != nil {
+			aged.Number = *inv.Number
+		}
+
+		k := key{currency: inv.Currency}
+		if inv.CustomerID != nil {
+			k.customer = *inv.CustomerID
+		}
+		row, ok := rows[k]
+		if !ok {
+			row = &AgingRow{CustomerID: inv.CustomerID, Currency: inv.Currency}
+			rows[k] = row
+		}
+		if inv.ContactEmail != "" {
+			row.ContactEmail = inv.ContactEmail
+		}
+		row.add(aged.Bucket, left)
+		row.Invoices = append(row.Invoices, aged)
+
+		total, ok := totals[inv.Currency]
+		if !ok {
+			total = &AgingRow{Currency: inv.Currency}
+			totals[inv.Currency] = total
+		}
+		total.add(aged.Bucket, left)
+	}
+
+	for _, row := range rows {
+		report.Customers = append(report.Customers, *row)
+	}
+	// Biggest debts first.
+	sort.Slice(report.Customers, func(i, j int) bool {
+		a, b := report.Customers[i], report.Customers[j]
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.Invoices[0].InvoiceID.String() < b.Invoices[0].InvoiceID.String()
+	})
+	for _, total := range totals {
+		report.Totals = append(report.Totals, *total)
+	}
+	sort.Slice(report.Totals, func(i, j int) bool { return report.Totals[i].Currency < report.Totals[j].Currency })
+	return report, nil
+}
@@ -0,0 +1,453 @@
+// internal/receivables/receivables.go
+package receivables
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Payment states. Only completed payments count towards invoices.
+const (
+	PaymentPending   = "Pending"
+	PaymentCompleted = "Completed"
+	PaymentFailed    = "Failed"
+	PaymentRefunded  = "Refunded"
+)
+
+// Errors returned when allocating payments.
+var (
+	ErrInvalidAllocation = errors.New("invalid payment allocation")
+	ErrOverAllocated     = errors.New("allocation exceeds what is available")
+	ErrInvoiceNotOpen    = errors.New("invoice is not open for payment")
+)
+
+// closed lists invoice statuses that no longer take payments or change with them.
+var closed = []string{"Draft", "Canceled", "Cancelled", "Void"}
+
+// IsClosed reports whether an invoice status no longer takes payments.
+func IsClosed(status string) bool {
+	for _, s := range closed {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Paid returns what has been paid against an invoice: allocations of
+// completed payments and, on purchase invoices, completed vendor payments.
+func Paid(db *gorm.DB, inv models.Invoice) (money.Amount, error) {
+	var paid money.Amount
+	if err := db.Model(&models.PaymentAllocation{}).
+		Joins("JOIN payments ON payments.id = payment_allocations.payment_id").
+		Where("payment_allocations.invoice_id = ? AND payments.status = ?", inv.ID, PaymentCompleted).
+		Select("COALESCE(SUM(payment_allocations.amount), 0)").Row().Scan(&paid); err != nil {
+		return 0, err
+	}
+	if inv.InvoiceType == "purchase" {
+		var vendor money.Amount
+		if err := db.Model(&models.VendorPayment{}).
+			Where("invoice_id = ? AND status = ?", inv.ID, "Completed").
+			Select("COALESCE(SUM(amount), 0)").Row().Scan(&vendor); err != nil {
+			return 0, err
+		}
+		paid += vendor
+	}
+	return paid, nil
+}
+
+// CreditNoteIssued is the status of a credit note that counts against its
+// invoice.
+const CreditNoteIssued = "Issued"
+
+// Credited returns what issued credit notes have taken off an invoice.
+func Credited(db *gorm.DB, invoiceID uuid.UUID) (money.Amount, error) {
+	var total money.Amount
+	err := db.Model(&models.CreditNote{}).
+		Where("invoice_id = ? AND status = ?", invoiceID, CreditNoteIssued).
+		Select("COALESCE(SUM(amount), 0)").Row().Scan(&total)
+	return total, err
+}
+
+// allocated is what payments that have not failed or been refunded have put
+// against the invoice, so an invoice is not allocated twice while a payment
+// clears.
+func allocated(db *gorm.DB, invoiceID uuid.UUID) (money.Amount, error) {
+	var total money.Amount
+	err := db.Model(&models.PaymentAllocation{}).
+		Joins("JOIN payments ON payments.id = payment_allocations.payment_id").
+		Where("payment_allocations.invoice_id = ? AND payments.status NOT IN ?", invoiceID, []string{PaymentFailed, PaymentRefunded}).
+		Select("COALESCE(SUM(payment_allocations.amount), 0)").Row().Scan(&total)
+	return total, err
+}
+
+// Outstanding is what is left to collect on an invoice once credit notes
+// and the payments that have not failed or been refunded are counted,
+// including pending ones.
+func Outstanding(db *gorm.DB, inv models.Invoice) (money.Amount, error) {
+	left, err := unallocated(db, inv)
+	if err != nil {
+		return 0, err
+	}
+	return money.Max(0, left), nil
+}
+
+// unallocated is the invoice amount less its credit notes and allocations.
+// It is negative when an invoice was credited after it was paid.
+func unallocated(db *gorm.DB, inv models.Invoice) (money.Amount, error) {
+	already, err := allocated(db, inv.ID)
+	if err != nil {
+		return 0, err
+	}
+	credited, err := Credited(db, inv.ID)
+	if err != nil {
+		return 0, err
+	}
+	return inv.Amount - credited - already, nil
+}
+
+// StatusFor derives an open invoice's status from what has been paid and
+// credited. An invoice credited in full without payment is Credited.
+func StatusFor(inv models.Invoice, paid, credited money.Amount, now time.Time) string {
+	switch {
+	case paid == 0 && credited >= inv.Amount && credited > 0:
+		return "Credited"
+	case paid+credited >= inv.Amount:
+		return "Paid"
+	case now.After(inv.DueDate):
+		return "Overdue"
+	case paid > 0:
+		return "Partially Paid"
+	default:
+		return "Outstanding"
+	}
+}
+
+// RefreshStatus recomputes and saves an invoice's status from its payments.
+// Drafts and cancelled invoices are left alone.
+func RefreshStatus(db *gorm.DB, inv *models.Invoice, now time.Time) error {
+	if IsClosed(inv.Status) {
+		return nil
+	}
+	paid, err := Paid(db, *inv)
+	if err != nil {
+		return err
+	}
+	credited, err := Credited(db, inv.ID)
+	if err != nil {
+		return err
+	}
+	status := StatusFor(*inv, paid, credited, now)
+	if status == inv.Status {
+		return nil
+	}
+	inv.Status = status
+	return db.Model(&models.Invoice{}).Where("id = ?", inv.ID).
+		Updates(map[string]interface{}{"status": status, "updated_at": now}).Error
+}
+
+// refreshInvoices refreshes the statuses of the given invoices.
+func refreshInvoices(tx *gorm.DB, tenantID uint, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var invoices []models.Invoice
+	if err := tx.Where("tenant_id = ? AND id IN ?", tenantID, ids).Find(&invoices).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range invoices {
+		if err := RefreshStatus(tx, &invoices[i], now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Allocate applies part of the payment's unallocated amount to invoices. No
+// invoice may be allocated more than its amount; what is left over stays on
+// the payment as credit.
+func Allocate(tx *gorm.DB, p *models.Payment, allocs []models.PaymentAllocation, actorID uint) error {
+	var total money.Amount
+	seen := map[uuid.UUID]bool{}
+	ids := make([]uuid.UUID, 0, len(allocs))
+	for i := range allocs {
+		a := &allocs[i]
+		if a.Amount <= 0 || a.InvoiceID == uuid.Nil || seen[a.InvoiceID] {
+			return fmt.Errorf("%w: each allocation needs an invoice (once) and a positive amount", ErrInvalidAllocation)
+		}
+		seen[a.InvoiceID] = true
+
+		// Locked, so that what is left on it cannot change before the
+		// allocation is written.
+		var inv models.Invoice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND tenant_id = ?", a.InvoiceID, p.TenantID).First(&inv).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: invoice %s not found", ErrInvalidAllocation, a.InvoiceID)
+			}
+			return err
+		}
+		if IsClosed(inv.Status) {
+			return fmt.Errorf("%w: invoice %s is %s", ErrInvoiceNotOpen, inv.ID, inv.Status)
+		}
+		if p.Currency != "" && inv.Currency != "" && p.Currency != inv.Currency {
+			return fmt.Errorf("%w: payment in %s cannot pay invoice %s in %s", ErrInvalidAllocation, p.Currency, inv.ID, inv.Currency)
+		}
+		a.Amount = a.Amount.Round(inv.Currency)
+		left, err := unallocated(tx, inv)
+		if err != nil {
+			return err
+		}
+		if a.Amount > left {
+			return fmt.Errorf("%w: invoice %s has %s left to allocate", ErrOverAllocated, inv.ID, money.Max(0, left))
+		}
+
+		a.ID = 0
+		a.TenantID = p.TenantID
+		a.PaymentID = p.ID
+		a.CreatedAt = time.Now()
+		total += a.Amount
+		ids = append(ids, inv.ID)
+	}
+	if total > p.Unallocated {
+		return fmt.Errorf("%w: payment %d has %s unallocated", ErrOverAllocated, p.ID, p.Unallocated)
+	}
+	if len(allocs) == 0 {
+		return nil
+	}
+
+	if err := tx.Create(&allocs).Error; err != nil {
+		return err
+	}
+	p.Allocations = append(p.Allocations, allocs...)
+	p.Unallocated -= total
+	if err := tx.Model(&models.Payment{}).Where("id = ?", p.ID).
+		Updates(map[string]interface{}{"unallocated": p.Unallocated, "updated_at": time.Now()}).Error; err != nil {
+		return err
+	}
+	if err := refreshInvoices(tx, p.TenantID, ids); err != nil {
+		return err
+	}
+	return utils.LogAction(tx, p.TenantID, actorID, "ALLOCATE_PAYMENT", "Payment",
+		fmt.Sprintf("Allocated %s of payment %d to %d invoice(s)", total, p.ID, len(allocs)))
+}
+
+// ApplyToInvoice allocates as much of the payment's credit as the invoice
+// still needs. Anything over stays on the payment as credit.
+func ApplyToInvoice(tx *gorm.DB, p *models.Payment, invoiceID uuid.UUID, actorID uint) error {
+	var inv models.Invoice
+	if err := tx.Where("id = ? AND tenant_id = ?", invoiceID, p.TenantID).First(&inv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: invoice %s not found", ErrInvalidAllocation, invoiceID)
+		}
+		return err
+	}
+	left, err := unallocated(tx, inv)
+	if err != nil {
+		return err
+	}
+	amount := money.Min(p.Unallocated, left)
+	if amount <= 0 {
+		return nil
+	}
+	return Allocate(tx, p, []models.PaymentAllocation{{InvoiceID: inv.ID, Amount: amount}}, actorID)
+}
+
+// Unallocate removes an allocation; its amount goes back to the payment's
+// credit and the invoice's status is recomputed.
+func Unallocate(tx *gorm.DB, p *models.Payment, allocationID uint, actorID uint) error {
+	var a models.PaymentAllocation
+	if err := tx.Where("id = ? AND payment_id = ?", allocationID, p.ID).First(&a).Error; err != nil {
+		return err
+	}
+	if err := tx.Delete(&a).Error; err != nil {
+		return err
+	}
+	p.Unallocated += a.Amount
+	if err := tx.Model(&models.Payment{}).Where("id = ?", p.ID).
+		Updates(map[string]interface{}{"unallocated": p.Unallocated, "updated_at": time.Now()}).Error; err != nil {
+		return err
+	}
+	if err := refreshInvoices(tx, p.TenantID, []uuid.UUID{a.InvoiceID}); err != nil {
+		return err
+	}
+	return utils.LogAction(tx, p.TenantID, actorID, "UNALLOCATE_PAYMENT", "Payment",
+		fmt.Sprintf("Removed %s of payment %d from invoice %s", a.Amount, p.ID, a.InvoiceID))
+}
+
+// Recalculate brings a payment's credit balance and its invoices' statuses
+// in line after its amount or status changed. The amount may not drop below
+// what is already allocated and refunded.
+func Recalculate(tx *gorm.DB, p *models.Payment) error {
+	var allocs []models.PaymentAllocation
+	if err := tx.Where("payment_id = ?", p.ID).Find(&allocs).Error; err != nil {
+		return err
+	}
+	var total money.Amount
+	ids := make([]uuid.UUID, 0, len(allocs))
+	for _, a := range allocs {
+		total += a.Amount
+		ids = append(ids, a.InvoiceID)
+	}
+	// What was refunded is neither credit nor allocated; a payment refunded
+	// in full no longer counts at all.
+	var refunded money.Amount
+	if p.Status != PaymentRefunded {
+		var err error
+		if refunded, err = Refunded(tx, p.ID); err != nil {
+			return err
+		}
+	}
+	if p.Amount-refunded < total {
+		return fmt.Errorf("%w: %s is already allocated", ErrOverAllocated, total)
+	}
+	p.Allocations = allocs
+	p.Unallocated = p.Amount - refunded - total
+	if err := tx.Model(&models.Payment{}).Where("id = ?", p.ID).Update("unallocated", p.Unallocated).Error; err != nil {
+		return err
+	}
+	return refreshInvoices(tx, p.TenantID, ids)
+}
+
+// Refunded returns what completed refunds have returned of a payment.
+func Refunded(db *gorm.DB, paymentID uint) (money.Amount, error) {
+	var total money.Amount
+	err := db.Model(&models.Refund{}).
+		Where("payment_id = ? AND status = ?", paymentID, PaymentCompleted).
+		Select("COALESCE(SUM(amount), 0)").Row().Scan(&total)
+	return total, err
+}
+
+// ApplyRefunds takes what has been refunded of a payment off its credit
+// balance first, then off its allocations, latest first, so the invoices it
+// paid are owed again what was returned. Call it once the refund is saved.
+func ApplyRefunds(tx *gorm.DB, p *models.Payment, actorID uint) error {
+	refunded, err := Refunded(tx, p.ID)
+	if err != nil {
+		return err
+	}
+	var allocs []models.PaymentAllocation
+	if err := tx.Where("payment_id = ?", p.ID).Order("id DESC").Find(&allocs).Error; err != nil {
+		return err
+	}
+	var total money.Amount
+	for _, a := range allocs {
+		total += a.Amount
+	}
+
+	excess := total - money.Max(0, p.Amount-refunded)
+	var taken money.Amount
+	var ids []uuid.UUID
+	for i := range allocs {
+		if excess <= 0 {
+			break
+		}
+		a := &allocs[i]
+		cut := money.Min(a.Amount, excess)
+		a.Amount -= cut
+		excess -= cut
+		taken += cut
+		if a.Amount == 0 {
+			err = tx.Delete(a).Error
+		} else {
+			err = tx.Model(a).Update("amount", a.Amount).Error
+		}
+		if err != nil {
+			return err
+		}
+		ids = append(ids, a.InvoiceID)
+	}
+
+	if err := Recalculate(tx, p); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	// Recalculate only sees the allocations that are left.
+	if err := refreshInvoices(tx, p.TenantID, ids); err != nil {
+		return err
+	}
+	return utils.LogAction(tx, p.TenantID, actorID, "UNALLOCATE_PAYMENT", "Payment",
+		fmt.Sprintf("Took %s refunded of payment %d off %d invoice(s)", taken, p.ID, len(ids)))
+}
+
+// Reallocate puts a payment's allocations back against what its invoices
+// still need, e.g. when a payment that had failed completes after all and
+// the invoices have been paid another way meanwhile. What no longer fits
+// stays on the payment as credit.
+func Reallocate(tx *gorm.DB, p *models.Payment, actorID uint) error {
+	var allocs []models.PaymentAllocation
+	if err := tx.Where("payment_id = ?", p.ID).Order("id").Find(&allocs).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("payment_id = ?", p.ID).Delete(&models.PaymentAllocation{}).Error; err != nil {
+		return err
+	}
+	p.Allocations = nil
+	if err := Recalculate(tx, p); err != nil {
+		return err
+	}
+
+	var wanted, kept money.Amount
+	ids := make([]uuid.UUID, 0, len(allocs))
+	fits := make([]models.PaymentAllocation, 0, len(allocs))
+	at := map[uuid.UUID]int{}
+	for _, a := range allocs {
+		wanted += a.Amount
+		ids = append(ids, a.InvoiceID)
+		// Locked, so that what is left on it cannot change before the
+		// allocation is written.
+		var inv models.Invoice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND tenant_id = ?", a.InvoiceID, p.TenantID).First(&inv).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return err
+		}
+		if IsClosed(inv.Status) {
+			continue
+		}
+		left, err := unallocated(tx, inv)
+		if err != nil {
+			return err
+		}
+		i, seen := at[inv.ID]
+		if seen {
+			left -= fits[i].Amount
+		}
+		amount := money.Min(a.Amount, money.Min(left, p.Unallocated-kept))
+		if amount <= 0 {
+			continue
+		}
+		if seen {
+			fits[i].Amount += amount
+		} else {
+			at[inv.ID] = len(fits)
+			fits = append(fits, models.PaymentAllocation{InvoiceID: inv.ID, Amount: amount})
+		}
+		kept += amount
+	}
+	if err := Allocate(tx, p, fits, actorID); err != nil {
+		return err
+	}
+	if kept == wanted {
+		return nil
+	}
+	if err := refreshInvoices(tx, p.TenantID, ids); err != nil {
+		return err
+	}
+	return utils.LogAction(tx, p.TenantID, actorID, "UNALLOCATE_PAYMENT", "Payment",
+		fmt.Sprintf("Moved %s of payment %d to credit: its invoices no longer needed it", wanted-kept, p.ID))
+}
@@ -0,0 +1,140 @@
+package receivables
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocation(t *testing.T) {
+	db := testutil.DB(t)
+	due := time.Now().AddDate(0, 0, 10)
+	a := models.Invoice{TenantID: 1, InvoiceType: "sale", Status: "Outstanding", IssueDate: time.Now(), DueDate: due, Amount: money.FromInt(300)}
+	b := models.Invoice{TenantID: 1, InvoiceType: "sale", Status: "Outstanding", IssueDate: time.Now(), DueDate: due, Amount: money.FromInt(200)}
+	db.Create(&a)
+	db.Create(&b)
+	status := func(inv models.Invoice) string {
+		db.First(&inv, "id = ?", inv.ID)
+		return inv.Status
+	}
+
+	// One payment spread over both invoices: A in full, B in part.
+	p := models.Payment{TenantID: 1, Amount: money.FromInt(400), Unallocated: money.FromInt(400), Status: PaymentCompleted}
+	db.Create(&p)
+	assert.NoError(t, Allocate(db, &p, []models.PaymentAllocation{
+		{InvoiceID: a.ID, Amount: money.FromInt(300)}, {InvoiceID: b.ID, Amount: money.FromInt(100)}}, 1))
+	assert.Equal(t, money.FromInt(0), p.Unallocated)
+	assert.Equal(t, "Paid", status(a))
+	assert.Equal(t, "Partially Paid", status(b))
+
+	// An overpayment applies what B still owes and keeps the rest as credit.
+	over := models.Payment{TenantID: 1, Amount: money.FromInt(150), Unallocated: money.FromInt(150), Status: PaymentCompleted}
+	db.Create(&over)
+	assert.NoError(t, ApplyToInvoice(db, &over, b.ID, 1))
+	assert.Equal(t, money.FromInt(50), over.Unallocated)
+	assert.Equal(t, "Paid", status(b))
+
+	// Invoices cannot be allocated beyond their amount.
+	err := Allocate(db, &over, []models.PaymentAllocation{{InvoiceID: b.ID, Amount: money.FromInt(10)}}, 1)
+	assert.ErrorIs(t, err, ErrOverAllocated)
+
+	// A failed payment stops counting.
+	p.Status = PaymentFailed
+	db.Save(&p)
+	assert.NoError(t, Recalculate(db, &p))
+	assert.Equal(t, "Outstanding", status(a))
+	assert.Equal(t, "Partially Paid", status(b))
+
+	// Removing an allocation returns it to credit.
+	assert.NoError(t, Unallocate(db, &over, over.Allocations[0].ID, 1))
+	assert.Equal(t, money.FromInt(150), over.Unallocated)
+	assert.Equal(t, "Outstanding", status(b))
+
+	// A partial refund comes out of credit first, then off the invoices.
+	c := models.Invoice{TenantID: 1, InvoiceType: "sale", Status: "Outstanding", IssueDate: time.Now(), DueDate: due, Amount: money.FromInt(100)}
+	db.Create(&c)
+	card := models.Payment{TenantID: 1, Amount: money.FromInt(120), Unallocated: money.FromInt(120), Status: PaymentCompleted}
+	db.Create(&card)
+	assert.NoError(t, ApplyToInvoice(db, &card, c.ID, 1))
+	refund := func(amount int64) {
+		assert.NoError(t, db.Create(&models.Refund{TenantID: 1, InvoiceID: c.ID, PaymentID: &card.ID,
+			Amount: money.FromInt(amount), Status: PaymentCompleted}).Error)
+		assert.NoError(t, ApplyRefunds(db, &card, 1))
+	}
+	refund(15)
+	assert.Equal(t, money.FromInt(5), card.Unallocated)
+	assert.Equal(t, "Paid", status(c))
+	refund(35)
+	assert.Equal(t, money.FromInt(0), card.Unallocated)
+	assert.Equal(t, money.FromInt(70), card.Allocations[0].Amount)
+	assert.Equal(t, "Partially Paid", status(c))
+	paid, err := Paid(db, c)
+	assert.NoError(t, err)
+	assert.Equal(t, money.FromInt(70), paid)
+}
+
+func TestStatementAndAging(t *testing.T) {
+	db := testutil.DB(t)
+	db.Create(&models.Tenant{Name: "Agency"})
+	customer, other := uuid.New(), uuid.New()
+	day := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 0, 0, 0, 0, time.UTC) }
+	invoice := func(cust uuid.UUID, number string, issued time.Time, amount int64) models.Invoice {
+		inv := models.Invoice{TenantID: 1, CustomerID: &cust, Number: &number, InvoiceType: "sale", Status: "Outstanding",
+			Currency: "USD", IssueDate: issued, IssuedAt: &issued, DueDate: issued.AddDate(0, 0, 30), Amount: money.FromInt(amount)}
+		db.Create(&inv)
+		return inv
+	}
+	jan := invoice(customer, "INV-1", day(1, 10), 500)
+	mar := invoice(customer, "INV-2", day(3, 5), 800)
+	invoice(other, "INV-3", day(3, 20), 100)
+	draft := models.Invoice{TenantID: 1, CustomerID: &customer, InvoiceType: "sale", Status: "Draft", Currency: "USD",
+		IssueDate: day(3, 6), Amount: money.FromInt(999)}
+	db.Create(&draft)
+
+	// January's invoice paid in part in February; a credit note on March's.
+	p := models.Payment{TenantID: 1, InvoiceID: &jan.ID, PaymentDate: day(2, 1), Amount: money.FromInt(200),
+		Currency: "USD", Status: PaymentCompleted, Reference: "UTR-1"}
+	db.Create(&p)
+	db.Create(&models.PaymentAllocation{TenantID: 1, PaymentID: p.ID, InvoiceID: jan.ID, Amount: money.FromInt(200)})
+	cn := "CN-1"
+	db.Create(&models.CreditNote{ID: uuid.New(), TenantID: 1, Number: &cn, InvoiceID: mar.ID, IssueDate: day(3, 15),
+		Amount: money.FromInt(50), Currency: "USD", Status: CreditNoteIssued})
+
+	st, err := BuildStatement(db, 1, customer, "", day(2, 1), day(3, 31))
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", st.Currency)
+	assert.Equal(t, money.FromInt(500), st.OpeningBalance)
+	assert.Len(t, st.Lines, 3)
+	assert.Equal(t, LinePayment, st.Lines[0].Kind)
+	assert.Equal(t, "CN-1 (INV-2)", st.Lines[2].Reference)
+	assert.Equal(t, money.FromInt(1050), st.ClosingBalance)
+	assert.Equal(t, money.FromInt(800), st.Invoiced)
+	var pdf bytes.Buffer
+	assert.NoError(t, st.WritePDF(&pdf))
+	assert.True(t, bytes.HasPrefix(pdf.Bytes(), []byte("%PDF")))
+	_, err = BuildStatement(db, 1, uuid.New(), "", day(1, 1), day(3, 31))
+	assert.ErrorIs(t, err, ErrNoActivity)
+
+	// On 30 April: INV-1 is 80 days overdue, INV-2 26 days and INV-3 11 days.
+	report, err := BuildAging(db, 1, day(4, 30))
+	assert.NoError(t, err)
+	assert.Len(t, report.Customers, 2)
+	top := report.Customers[0]
+	assert.Equal(t, customer, *top.CustomerID)
+	assert.Equal(t, money.FromInt(300), top.Days61To90)
+	assert.Equal(t, money.FromInt(750), top.Days1To30)
+	assert.Equal(t, money.FromInt(1050), top.Total)
+	assert.Equal(t, money.FromInt(850), report.Totals[0].Days1To30)
+	assert.Equal(t, money.FromInt(1150), report.Totals[0].Total)
+
+	// Before the payment, all of January's invoice was owed.
+	early, _ := BuildAging(db, 1, day(1, 31))
+	assert.Equal(t, money.FromInt(500), early.Totals[0].Current)
+}
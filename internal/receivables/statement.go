@@ -0,0 +1,195 @@
+// internal/receivables/statement.go
+package receivables
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrNoActivity is returned for a statement of a customer never invoiced.
+var ErrNoActivity = errors.New("customer has no invoices")
+
+// Statement line kinds.
+const (
+	LineInvoice    = "invoice"
+	LinePayment    = "payment"
+	LineCreditNote = "credit_note"
+)
+
+// StatementLine is one invoice (debit to the customer), or payment or credit
+// note (credit).
+type StatementLine struct {
+	Date      time.Time    `json:"date"`
+	Kind      string       `json:"kind"`
+	Reference string       `json:"reference"`
+	DueDate   *time.Time   `json:"dueDate,omitempty"` // Invoices only.
+	Debit     money.Amount `json:"debit"`
+	Credit    money.Amount `json:"credit"`
+	Balance   money.Amount `json:"balance"` // What the customer owes after this line.
+}
+
+// Statement is a customer account statement for a period, in one currency.
+type Statement struct {
+	TenantName     string          `json:"tenantName"`
+	CustomerID     uuid.UUID       `json:"customerId"`
+	ContactEmail   string          `json:"contactEmail,omitempty"` // From the latest invoice.
+	Currency       string          `json:"currency"`
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	OpeningBalance money.Amount    `json:"openingBalance"`
+	Lines          []StatementLine `json:"lines"`
+	Invoiced       money.Amount    `json:"invoiced"`
+	Paid           money.Amount    `json:"paid"`
+	Credited       money.Amount    `json:"credited"`
+	ClosingBalance money.Amount    `json:"closingBalance"`
+}
+
+// BuildStatement lists the customer's issued sale invoices, completed
+// payments and issued credit notes in the currency between from and to
+// (inclusive days), with the balance brought forward and a running balance.
+// An empty currency means that of the customer's latest invoice. Payments
+// count in full when made for one of the customer's invoices, else by what
+// is allocated to them.
+func BuildStatement(db *gorm.DB, tenantID uint, customerID uuid.UUID, currency string, from, to time.Time) (*Statement, error) {
+	end := to.AddDate(0, 0, 1)
+	invoices := db.Model(&models.Invoice{}).
+		Where("tenant_id = ? AND customer_id = ? AND invoice_type = ? AND issued_at IS NOT NULL AND status NOT IN ?",
+			tenantID, customerID, "sale", closed)
+
+	var latest models.Invoice
+	if err := invoices.Session(&gorm.Session{}).Order("issue_date DESC, created_at DESC").First(&latest).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoActivity
+		}
+		return nil, err
+	}
+	if currency == "" {
+		currency = latest.Currency
+	}
+	st := &Statement{CustomerID: customerID, ContactEmail: latest.ContactEmail, Currency: currency,
+		From: from, To: to, Lines: []StatementLine{}}
+	var tenant models.Tenant
+	if err := db.Select("id", "name").First(&tenant, tenantID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	st.TenantName = tenant.Name
+
+	var invList []models.Invoice
+	if err := invoices.Session(&gorm.Session{}).Where("currency = ?", currency).
+		Order("issue_date, id").Find(&invList).Error; err != nil {
+		return nil, err
+	}
+	if len(invList) == 0 {
+		return st, nil
+	}
+	ids := make([]uuid.UUID, 0, len(invList))
+	numbers := map[uuid.UUID]string{}
+	for _, inv := range invList {
+		ids = append(ids, inv.ID)
+		numbers[inv.ID] = inv.ID.String()
+		if inv.Number != nil {
+			numbers[inv.ID] = *inv.Number
+		}
+	}
+
+	var notes []models.CreditNote
+	if err := db.Where("tenant_id = ? AND invoice_id IN ? AND status = ? AND issue_date < ?",
+		tenantID, ids, CreditNoteIssued, end).Find(&notes).Error; err != nil {
+		return nil, err
+	}
+	var allocs []models.PaymentAllocation
+	if err := db.Where("tenant_id = ? AND invoice_id IN ?", tenantID, ids).Find(&allocs).Error; err != nil {
+		return nil, err
+	}
+	allocated := map[uint]money.Amount{}
+	paymentIDs := []uint{}
+	for _, a := range allocs {
+		if _, ok := allocated[a.PaymentID]; !ok {
+			paymentIDs = append(paymentIDs, a.PaymentID)
+		}
+		allocated[a.PaymentID] += a.Amount
+	}
+	var payments []models.Payment
+	q := db.Where("tenant_id = ? AND status = ? AND currency = ? AND payment_date < ?",
+		tenantID, PaymentCompleted, currency, end)
+	if len(paymentIDs) > 0 {
+		q = q.Where("(invoice_id IN ? OR id IN ?)", ids, paymentIDs)
+	} else {
+		q = q.Where("invoice_id IN ?", ids)
+	}
+	if err := q.Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	own := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		own[id] = true
+	}
+
+	// add counts an entry towards the opening balance or lists it.
+	add := func(l StatementLine) {
+		if l.Date.Before(from) {
+			st.OpeningBalance += l.Debit - l.Credit
+			return
+		}
+		st.Lines = append(st.Lines, l)
+		st.Invoiced += l.Debit
+		if l.Kind == LinePayment {
+			st.Paid += l.Credit
+		} else {
+			st.Credited += l.Credit
+		}
+	}
+	for _, inv := range invList {
+		if !inv.IssueDate.Before(end) {
+			continue
+		}
+		due := inv.DueDate
+		add(StatementLine{Date: inv.IssueDate, Kind: LineInvoice, Reference: numbers[inv.ID], DueDate: &due, Debit: inv.Amount})
+	}
+	for _, n := range notes {
+		ref := n.ID.String()
+		if n.Number != nil {
+			ref = *n.Number
+		}
+		add(StatementLine{Date: n.IssueDate, Kind: LineCreditNote, Reference: fmt.Sprintf("%s (%s)", ref, numbers[n.InvoiceID]),
+			Credit: n.Amount})
+	}
+	for _, p := range payments {
+		amount := allocated[p.ID]
+		if p.InvoiceID != nil && own[*p.InvoiceID] {
+			amount = p.Amount
+		}
+		if amount <= 0 {
+			continue
+		}
+		ref := p.Reference
+		if ref == "" {
+			ref = fmt.Sprintf("Payment %d", p.ID)
+		}
+		add(StatementLine{Date: p.PaymentDate, Kind: LinePayment, Reference: ref, Credit: amount})
+	}
+
+	order := map[string]int{LineInvoice: 0, LineCreditNote: 1, LinePayment: 2}
+	sort.SliceStable(st.Lines, func(i, j int) bool {
+		a, b := st.Lines[i], st.Lines[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return order[a.Kind] < order[b.Kind]
+	})
+	balance := st.OpeningBalance
+	for i := range st.Lines {
+		balance += st.Lines[i].Debit - st.Lines[i].Credit
+		st.Lines[i].Balance = balance
+	}
+	st.ClosingBalance = balance
+	return st, nil
+}
@@ -0,0 +1,102 @@
+// internal/receivables/statement_pdf.go
+package receivables
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"travel-agency/internal/money"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// WritePDF prints the statement for sending to the customer.
+func (st *Statement) WritePDF(w io.Writer) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AddPage()
+	pdf.SetFont("Arial", "B", 16)
+	pdf.Cell(40, 10, "Statement of Account")
+	pdf.Ln(12)
+
+	pdf.SetFont("Arial", "", 12)
+	if st.TenantName != "" {
+		pdf.Cell(40, 10, tr(st.TenantName))
+		pdf.Ln(8)
+	}
+	pdf.Cell(40, 10, fmt.Sprintf("Customer: %s", st.CustomerID))
+	pdf.Ln(8)
+	if st.ContactEmail != "" {
+		pdf.Cell(40, 10, tr(fmt.Sprintf("Contact: %s", st.ContactEmail)))
+		pdf.Ln(8)
+	}
+	pdf.Cell(40, 10, fmt.Sprintf("Period: %s to %s", st.From.Format("2006-01-02"), st.To.Format("2006-01-02")))
+	pdf.Ln(8)
+	pdf.Cell(40, 10, fmt.Sprintf("Currency: %s", st.Currency))
+	pdf.Ln(12)
+
+	widths := []float64{24, 24, 58, 28, 28, 28}
+	pdf.SetFont("Arial", "B", 9)
+	pdf.SetFillColor(230, 238, 247)
+	for i, h := range []string{"Date", "Type", "Reference", "Debit", "Credit", "Balance"} {
+		align := "R"
+		if i < 3 {
+			align = "L"
+		}
+		pdf.CellFormat(widths[i], 7, h, "B", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	amount := func(v money.Amount) string { return v.Format(st.Currency) }
+	kinds := map[string]string{LineInvoice: "Invoice", LinePayment: "Payment", LineCreditNote: "Credit note"}
+	row := func(date, kind, ref, debit, credit, balance string) {
+		if len(ref) > 36 {
+			ref = ref[:33] + "..."
+		}
+		pdf.CellFormat(widths[0], 6, date, "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 6, kind, "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[2], 6, tr(ref), "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[3], 6, debit, "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[4], 6, credit, "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[5], 6, balance, "", 1, "R", false, 0, "")
+	}
+
+	pdf.SetFont("Arial", "I", 9)
+	row(st.From.Format("2006-01-02"), "", "Opening balance", "", "", amount(st.OpeningBalance))
+	pdf.SetFont("Arial", "", 9)
+	for _, l := range st.Lines {
+		debit, credit := "", ""
+		if l.Debit != 0 {
+			debit = amount(l.Debit)
+		}
+		if l.Credit != 0 {
+			credit = amount(l.Credit)
+		}
+		ref := l.Reference
+		if l.DueDate != nil {
+			ref += " due " + l.DueDate.Format("2006-01-02")
+		}
+		row(l.Date.Format("2006-01-02"), kinds[l.Kind], ref, debit, credit, amount(l.Balance))
+	}
+	pdf.Ln(2)
+
+	labelW := widths[0] + widths[1] + widths[2] + widths[3] + widths[4]
+	total := func(label, v string) {
+		pdf.CellFormat(labelW, 6, label, "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[5], 6, v, "", 1, "R", false, 0, "")
+	}
+	total("Invoiced", amount(st.Invoiced))
+	total("Credited", amount(-st.Credited))
+	total("Paid", amount(-st.Paid))
+	pdf.Ln(2)
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.Cell(40, 10, fmt.Sprintf("Balance Due: %s %s", amount(st.ClosingBalance), st.Currency))
+	pdf.Ln(10)
+	pdf.SetFont("Arial", "", 9)
+	pdf.Cell(40, 10, fmt.Sprintf("Generated on: %s", time.Now().Format("2006-01-02 15:04:05")))
+
+	return pdf.Output(w)
+}
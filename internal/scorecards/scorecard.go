@@ -0,0 +1,283 @@
+// internal/scorecards/scorecard.go
+package scorecards
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"travel-agency/internal/fx"
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/payables"
+	"travel-agency/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// Period bounds the data a scorecard covers. Bookings and tickets count by
+// creation date and payments by payment date; zero bounds are open.
+type Period struct {
+	From time.Time
+	To   time.Time // Inclusive (whole day).
+}
+
+func (p Period) apply(q *gorm.DB, column string) *gorm.DB {
+	if !p.From.IsZero() {
+		q = q.Where(column+" >= ?", utils.TruncateDay(p.From))
+	}
+	if !p.To.IsZero() {
+		q = q.Where(column+" < ?", utils.TruncateDay(p.To).AddDate(0, 0, 1))
+	}
+	return q
+}
+
+// Scorecard is one vendor's performance over a period.
+type Scorecard struct {
+	VendorID   uint   `json:"vendorId"`
+	VendorName string `json:"vendorName"`
+	VendorType string `json:"vendorType"`
+
+	Bookings         int          `json:"bookings"`
+	Cancelled        int          `json:"cancelled"`
+	CancellationRate float64      `json:"cancellationRate"` // Percent of bookings.
+	Revenue          money.Amount `json:"revenue"`          // Price of bookings not cancelled, in base currency.
+	Cost             money.Amount `json:"cost"`             // In base currency.
+	Margin           money.Amount `json:"margin"`           // Revenue - Cost.
+	MarginPercent    float64      `json:"marginPercent"`
+
+	Tickets     int     `json:"tickets"` // Support tickets about the vendor's bookings.
+	OpenTickets int     `json:"openTickets"`
+	TicketRate  float64 `json:"ticketRate"` // Tickets per 100 bookings.
+
+	Payments    int     `json:"payments"` // Completed vendor payments.
+	PaidOnTime  int     `json:"paidOnTime"`
+	OnTimeRate  float64 `json:"onTimeRate"` // Percent of payments made by the terms due date.
+	AvgDaysLate float64 `json:"avgDaysLate"`
+
+	// Score (0-100) weighs reliability: 50% bookings not cancelled, 30%
+	// bookings without tickets, 20% payments on time.
+	Score float64 `json:"score"`
+}
+
+// Sort keys accepted by Rank.
+var sortKeys = map[string]func(a, b Scorecard) bool{
+	"score":         func(a, b Scorecard) bool { return a.Score > b.Score },
+	"bookings":      func(a, b Scorecard) bool { return a.Bookings > b.Bookings },
+	"margin":        func(a, b Scorecard) bool { return a.Margin > b.Margin },
+	"marginPercent": func(a, b Scorecard) bool { return a.MarginPercent > b.MarginPercent },
+	"cancellationRate": func(a, b Scorecard) bool {
+		return a.CancellationRate < b.CancellationRate
+	},
+	"ticketRate": func(a, b Scorecard) bool { return a.TicketRate < b.TicketRate },
+	"onTimeRate": func(a, b Scorecard) bool { return a.OnTimeRate > b.OnTimeRate },
+}
+
+// ValidSort reports whether Rank understands the sort key.
+func ValidSort(key string) bool {
+	_, ok := sortKeys[key]
+	return ok
+}
+
+// Rank orders scorecards best first by the given key (default "score").
+// Rates of cancellations and tickets rank lowest first; ties go by bookings.
+func Rank(cards []Scorecard, key string) {
+	less, ok := sortKeys[key]
+	if !ok {
+		less = sortKeys["score"]
+	}
+	sort.SliceStable(cards, func(i, j int) bool {
+		if less(cards[i], cards[j]) {
+			return true
+		}
+		if less(cards[j], cards[i]) {
+			return false
+		}
+		return cards[i].Bookings > cards[j].Bookings
+	})
+}
+
+// Compute builds scorecards for the tenant's vendors over the period, for
+// one vendor or all when vendorID is 0. Vendors with no activity are
+// included with zero metrics.
+func Compute(db *gorm.DB, tenantID, vendorID uint, p Period) ([]Scorecard, error) {
+	vq := db.Where("tenant_id = ?", tenantID)
+	if vendorID != 0 {
+		vq = vq.Where("id = ?", vendorID)
+	}
+	var vendors []models.Vendor
+	if err := vq.Order("id").Find(&vendors).Error; err != nil {
+		return nil, err
+	}
+	cards := make([]Scorecard, len(vendors))
+	index := map[uint]*Scorecard{}
+	for i, v := range vendors {
+		cards[i] = Scorecard{VendorID: v.ID, VendorName: v.Name, VendorType: v.Type}
+		index[v.ID] = &cards[i]
+	}
+	if len(cards) == 0 {
+		return cards, nil
+	}
+
+	if err := addBookings(db, tenantID, vendorID, p, index); err != nil {
+		return nil, err
+	}
+	if err := addTickets(db, tenantID, vendorID, p, index); err != nil {
+		return nil, err
+	}
+	if err := addPayments(db, tenantID, vendorID, p, vendors, index); err != nil {
+		return nil, err
+	}
+
+	for i := range cards {
+		finish(&cards[i])
+	}
+	return cards, nil
+}
+
+func addBookings(db *gorm.DB, tenantID, vendorID uint, p Period, index map[uint]*Scorecard) error {
+	var rows []struct {
+		VendorID  uint
+		Bookings  int
+		Cancelled int
+		Revenue   money.Amount
+		Cost      money.Amount
+	}
+	q := db.Model(&models.Booking{}).
+		Select(`vendor_id,
+			COUNT(*) AS bookings,
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS cancelled,
+			COALESCE(SUM(CASE WHEN status <> ? THEN `+fx.RatedSQL("price", "exchange_rate")+` ELSE 0 END), 0) AS revenue,
+			COALESCE(SUM(CASE WHEN status <> ? THEN `+fx.RatedSQL("cost", "cost_exchange_rate")+` ELSE 0 END), 0) AS cost`,
+			models.BookingCancelled, models.BookingCancelled, models.BookingCancelled).
+		Where("tenant_id = ?", tenantID)
+	if vendorID != 0 {
+		q = q.Where("vendor_id = ?", vendorID)
+	}
+	if err := p.apply(q, "created_at").Group("vendor_id").Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if c, ok := index[row.VendorID]; ok {
+			c.Bookings = row.Bookings
+			c.Cancelled = row.Cancelled
+			c.Revenue = row.Revenue
+			c.Cost = row.Cost
+		}
+	}
+	return nil
+}
+
+func addTickets(db *gorm.DB, tenantID, vendorID uint, p Period, index map[uint]*Scorecard) error {
+	var rows []struct {
+		VendorID    uint
+		Tickets     int
+		OpenTickets int
+	}
+	q := db.Table("tickets").
+		Select(`bookings.vendor_id,
+			COUNT(tickets.id) AS tickets,
+			SUM(CASE WHEN tickets.status = ? THEN 0 ELSE 1 END) AS open_tickets`, "Closed").
+		Joins("JOIN bookings ON bookings.id = tickets.booking_id AND bookings.tenant_id = tickets.tenant_id").
+		Where("tickets.tenant_id = ?", tenantID)
+	if vendorID != 0 {
+		q = q.Where("bookings.vendor_id = ?", vendorID)
+	}
+	if err := p.apply(q, "tickets.created_at").Group("bookings.vendor_id").Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if c, ok := index[row.VendorID]; ok {
+			c.Tickets = row.Tickets
+			c.OpenTickets = row.OpenTickets
+		}
+	}
+	return nil
+}
+
+// addPayments compares each completed vendor payment with the due date of
+// the invoice it paid, under the payment terms in force when it was issued.
+func addPayments(db *gorm.DB, tenantID, vendorID uint, p Period, vendors []models.Vendor, index map[uint]*Scorecard) error {
+	q := db.Where("tenant_id = ? AND status = ? AND paid_at IS NOT NULL", tenantID, "Completed")
+	if vendorID != 0 {
+		q = q.Where("vendor_id = ?", vendorID)
+	}
+	var payments []models.VendorPayment
+	if err := p.apply(q, "paid_at").Find(&payments).Error; err != nil {
+		return err
+	}
+	if len(payments) == 0 {
+		return nil
+	}
+
+	var invoices []models.Invoice
+	ids := make([]interface{}, 0, len(payments))
+	for _, pay := range payments {
+		ids = append(ids, pay.InvoiceID)
+	}
+	if err := db.Where("tenant_id = ? AND id IN ?", tenantID, ids).Find(&invoices).Error; err != nil {
+		return err
+	}
+	byInvoice := map[string]models.Invoice{}
+	for _, inv := range invoices {
+		byInvoice[inv.ID.String()] = inv
+	}
+	var contracts []models.VendorContract
+	if err := db.Where("tenant_id = ? AND payment_terms <> ''", tenantID).
+		Order("valid_from DESC").Find(&contracts).Error; err != nil {
+		return err
+	}
+	byVendor := map[uint]models.Vendor{}
+	for _, v := range vendors {
+		byVendor[v.ID] = v
+	}
+
+	daysLate := map[uint]int{}
+	for _, pay := range payments {
+		c, ok := index[pay.VendorID]
+		inv, found := byInvoice[pay.InvoiceID.String()]
+		if !ok || !found {
+			continue
+		}
+		due := payables.DueDate(inv, payables.TermsFor(contracts, byVendor[pay.VendorID], inv.IssueDate))
+		late := int(utils.TruncateDay(*pay.PaidAt).Sub(utils.TruncateDay(due)).Hours() / 24)
+		c.Payments++
+		if late <= 0 {
+			c.PaidOnTime++
+		} else {
+			daysLate[pay.VendorID] += late
+		}
+	}
+	for id, total := range daysLate {
+		c := index[id]
+		c.AvgDaysLate = round2(float64(total) / float64(c.Payments))
+	}
+	return nil
+}
+
+// finish derives the rates, margin and score from the counts.
+func finish(c *Scorecard) {
+	c.Margin = c.Revenue - c.Cost
+	if c.Revenue > 0 {
+		c.MarginPercent = round2(c.Margin.Ratio(c.Revenue) * 100)
+	}
+
+	// Without activity a component counts as perfect, so new vendors are not
+	// ranked below ones with a poor record.
+	notCancelled, noTickets, onTime := 1.0, 1.0, 1.0
+	if c.Bookings > 0 {
+		c.CancellationRate = round2(float64(c.Cancelled) / float64(c.Bookings) * 100)
+		c.TicketRate = round2(float64(c.Tickets) / float64(c.Bookings) * 100)
+		notCancelled = 1 - float64(c.Cancelled)/float64(c.Bookings)
+		noTickets = 1 - math.Min(1, float64(c.Tickets)/float64(c.Bookings))
+	}
+	if c.Payments > 0 {
+		c.OnTimeRate = round2(float64(c.PaidOnTime) / float64(c.Payments) * 100)
+		onTime = float64(c.PaidOnTime) / float64(c.Payments)
+	}
+	c.Score = round2(50*notCancelled + 30*noTickets + 20*onTime)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
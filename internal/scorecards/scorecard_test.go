@@ -0,0 +1,72 @@
+package scorecards
+
+import (
+	"testing"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompute(t *testing.T) {
+	db := testutil.DB(t)
+	day := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 0, 0, 0, 0, time.UTC) }
+
+	good := models.Vendor{TenantID: 1, Name: "Reliable Hotels", PaymentTerms: "Net 30"}
+	poor := models.Vendor{TenantID: 1, Name: "Shaky Air"}
+	db.Create(&good)
+	db.Create(&poor)
+
+	for i := 0; i < 4; i++ {
+		db.Create(&models.Booking{TenantID: 1, VendorID: good.ID, Status: models.BookingConfirmed,
+			Price: money.FromInt(100), Cost: money.FromInt(80), CreatedAt: day(2, 1)})
+	}
+	cancelled := models.Booking{TenantID: 1, VendorID: poor.ID, Status: models.BookingCancelled,
+		Price: money.FromInt(300), Cost: money.FromInt(250), CreatedAt: day(2, 2)}
+	kept := models.Booking{TenantID: 1, VendorID: poor.ID, Status: models.BookingConfirmed,
+		Price: money.FromInt(200), Cost: money.FromInt(190), CreatedAt: day(2, 3)}
+	db.Create(&cancelled)
+	db.Create(&kept)
+	// Outside the period.
+	db.Create(&models.Booking{TenantID: 1, VendorID: poor.ID, Status: models.BookingCancelled, CreatedAt: day(5, 1)})
+	db.Create(&models.Ticket{TenantID: 1, Subject: "Missing seat", BookingID: &kept.ID, Status: "Open", CreatedAt: day(2, 10)})
+
+	// Paid 10 days after the Net 30 due date.
+	vendorID := good.ID
+	inv := models.Invoice{TenantID: 1, InvoiceType: "purchase", VendorID: &vendorID, Status: "Paid",
+		IssueDate: day(1, 1), DueDate: day(1, 1), Amount: money.FromInt(320)}
+	db.Create(&inv)
+	paidAt := day(2, 10)
+	db.Create(&models.VendorPayment{TenantID: 1, VendorID: good.ID, InvoiceID: inv.ID, Amount: money.FromInt(320),
+		Status: "Completed", PaidAt: &paidAt})
+
+	cards, err := Compute(db, 1, 0, Period{From: day(2, 1), To: day(2, 28)})
+	assert.NoError(t, err)
+	assert.Len(t, cards, 2)
+
+	g, p := cards[0], cards[1]
+	assert.Equal(t, 4, g.Bookings)
+	assert.Equal(t, money.FromInt(80), g.Margin)
+	assert.Equal(t, 20.0, g.MarginPercent)
+	assert.Equal(t, 1, g.Payments)
+	assert.Equal(t, 0.0, g.OnTimeRate)
+	assert.Equal(t, 10.0, g.AvgDaysLate)
+	assert.Equal(t, 80.0, g.Score)
+
+	assert.Equal(t, 2, p.Bookings)
+	assert.Equal(t, 50.0, p.CancellationRate)
+	assert.Equal(t, money.FromInt(10), p.Margin) // The cancelled booking does not count.
+	assert.Equal(t, 1, p.Tickets)
+	assert.Equal(t, 50.0, p.TicketRate)
+	assert.Equal(t, 60.0, p.Score)
+
+	Rank(cards, "score")
+	assert.Equal(t, good.ID, cards[0].VendorID)
+	Rank(cards, "bookings")
+	assert.Equal(t, good.ID, cards[0].VendorID)
+	Rank(cards, "cancellationRate")
+	assert.Equal(t, good.ID, cards[0].VendorID)
+}
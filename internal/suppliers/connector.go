@@ -0,0 +1,103 @@
+// internal/suppliers/connector.go
+package suppliers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"travel-agency/internal/money"
+)
+
+// Reservation states reported by suppliers.
+const (
+	StatusHeld      = "HELD"
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+var (
+	// ErrNoConnector is returned when no connector handles a vendor type.
+	ErrNoConnector = errors.New("no supplier connector for vendor type")
+	// ErrNotFound is returned for unknown offers or PNRs.
+	ErrNotFound = errors.New("supplier record not found")
+	// ErrHoldExpired is returned when confirming a hold that has lapsed.
+	ErrHoldExpired = errors.New("supplier hold expired")
+)
+
+// SearchRequest describes what the agent is looking for.
+type SearchRequest struct {
+	Product     string    `json:"product"` // e.g. "DEL-BOM", "Deluxe Room"
+	Destination string    `json:"destination"`
+	TravelDate  time.Time `json:"travelDate"`
+	Pax         int       `json:"pax"`
+}
+
+// Offer is a bookable option returned by Search.
+type Offer struct {
+	OfferID     string       `json:"offerId"`
+	Product     string       `json:"product"`
+	Description string       `json:"description"`
+	TravelDate  time.Time    `json:"travelDate"`
+	Cost        money.Amount `json:"cost"`
+	Currency    string       `json:"currency"`
+}
+
+// Reservation is the supplier's view of a booking.
+type Reservation struct {
+	PNR        string       `json:"pnr"`
+	OfferID    string       `json:"offerId"`
+	Status     string       `json:"status"`
+	Pax        int          `json:"pax"`
+	Cost       money.Amount `json:"cost"`
+	Currency   string       `json:"currency"`
+	TravelDate time.Time    `json:"travelDate"`
+	HoldUntil  *time.Time   `json:"holdUntil,omitempty"` // Set while HELD.
+}
+
+// SupplierConnector is implemented by each supplier integration (GDS, hotel
+// channel manager, tour operator API).
+type SupplierConnector interface {
+	Search(ctx context.Context, req SearchRequest) ([]Offer, error)
+	Hold(ctx context.Context, offerID string, pax int) (*Reservation, error)
+	Confirm(ctx context.Context, pnr string) (*Reservation, error)
+	Cancel(ctx context.Context, pnr string) (*Reservation, error)
+	Retrieve(ctx context.Context, pnr string) (*Reservation, error)
+}
+
+// Registry maps vendor types (models.Vendor.Type) to connectors.
+type Registry struct {
+	mu         sync.RWMutex
+	connectors map[string]SupplierConnector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{connectors: map[string]SupplierConnector{}}
+}
+
+func normalizeType(vendorType string) string {
+	return strings.ToLower(strings.TrimSpace(vendorType))
+}
+
+// Register installs the connector for a vendor type, replacing any previous one.
+func (r *Registry) Register(vendorType string, c SupplierConnector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.connectors[normalizeType(vendorType)] = c
+}
+
+// For returns the connector for a vendor type, or ErrNoConnector.
+func (r *Registry) For(vendorType string) (SupplierConnector, error) {
+	if r == nil {
+		return nil, ErrNoConnector
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.connectors[normalizeType(vendorType)]
+	if !ok {
+		return nil, ErrNoConnector
+	}
+	return c, nil
+}
@@ -0,0 +1,145 @@
+// internal/suppliers/mock.go
+package suppliers
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"travel-agency/internal/money"
+)
+
+// MockGDS is an in-process supplier used for local development and tests.
+// Offers are derived deterministically from the search request, and
+// reservations live in memory.
+type MockGDS struct {
+	HoldTTL time.Duration
+
+	mu           sync.Mutex
+	offers       map[string]Offer
+	reservations map[string]*Reservation
+	now          func() time.Time
+}
+
+func NewMockGDS() *MockGDS {
+	return &MockGDS{
+		HoldTTL:      30 * time.Minute,
+		offers:       map[string]Offer{},
+		reservations: map[string]*Reservation{},
+		now:          time.Now,
+	}
+}
+
+// Search returns three fare/rate classes for the requested product.
+func (m *MockGDS) Search(ctx context.Context, req SearchRequest) ([]Offer, error) {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s|%s|%s", req.Product, req.Destination, req.TravelDate.Format("2006-01-02"))
+	base := money.FromInt(int64(100 + h.Sum32()%400))
+	pax := req.Pax
+	if pax < 1 {
+		pax = 1
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var offers []Offer
+	for i, class := range []string{"Economy", "Standard", "Premium"} {
+		o := Offer{
+			OfferID:     fmt.Sprintf("MOCK-%08X-%d", h.Sum32(), i),
+			Product:     req.Product,
+			Description: strings.TrimSpace(fmt.Sprintf("%s %s %s", class, req.Product, req.Destination)),
+			TravelDate:  req.TravelDate,
+			Cost:        base * money.Amount((i+1)*pax),
+			Currency:    "USD",
+		}
+		m.offers[o.OfferID] = o
+		offers = append(offers, o)
+	}
+	return offers, nil
+}
+
+// Hold reserves an offer for HoldTTL and returns a PNR.
+func (m *MockGDS) Hold(ctx context.Context, offerID string, pax int) (*Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	offer, ok := m.offers[offerID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	res := &Reservation{
+		PNR:        newPNR(),
+		OfferID:    offerID,
+		Status:     StatusHeld,
+		Pax:        pax,
+		Cost:       offer.Cost,
+		Currency:   offer.Currency,
+		TravelDate: offer.TravelDate,
+	}
+	until := m.now().Add(m.HoldTTL)
+	res.HoldUntil = &until
+	m.reservations[res.PNR] = res
+	copy := *res
+	return &copy, nil
+}
+
+// Confirm tickets a held reservation. Confirming twice is a no-op.
+func (m *MockGDS) Confirm(ctx context.Context, pnr string) (*Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res, ok := m.reservations[pnr]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	switch res.Status {
+	case StatusCancelled:
+		return nil, fmt.Errorf("reservation %s is cancelled", pnr)
+	case StatusHeld:
+		if m.now().After(*res.HoldUntil) {
+			return nil, ErrHoldExpired
+		}
+		res.Status = StatusConfirmed
+		res.HoldUntil = nil
+	}
+	copy := *res
+	return &copy, nil
+}
+
+// Cancel releases a reservation. Cancelling twice is a no-op.
+func (m *MockGDS) Cancel(ctx context.Context, pnr string) (*Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res, ok := m.reservations[pnr]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	res.Status = StatusCancelled
+	copy := *res
+	return &copy, nil
+}
+
+// Retrieve returns the current state of a reservation.
+func (m *MockGDS) Retrieve(ctx context.Context, pnr string) (*Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res, ok := m.reservations[pnr]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copy := *res
+	return &copy, nil
+}
+
+// newPNR returns a 6-character record locator like real GDS systems use.
+func newPNR() string {
+	const letters = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	b := make([]byte, 6)
+	rand.Read(b)
+	for i := range b {
+		b[i] = letters[int(b[i])%len(letters)]
+	}
+	return string(b)
+}
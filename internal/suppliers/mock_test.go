@@ -0,0 +1,55 @@
+package suppliers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockGDSFlow(t *testing.T) {
+	ctx := context.Background()
+	reg := NewRegistry()
+	reg.Register("Airline", NewMockGDS())
+
+	c, err := reg.For(" airline ")
+	assert.NoError(t, err)
+	_, err = reg.For("Hotel")
+	assert.ErrorIs(t, err, ErrNoConnector)
+
+	offers, err := c.Search(ctx, SearchRequest{Product: "DEL-BOM", TravelDate: time.Now().AddDate(0, 1, 0), Pax: 2})
+	assert.NoError(t, err)
+	assert.Len(t, offers, 3)
+
+	held, err := c.Hold(ctx, offers[0].OfferID, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusHeld, held.Status)
+	assert.Len(t, held.PNR, 6)
+
+	confirmed, err := c.Confirm(ctx, held.PNR)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusConfirmed, confirmed.Status)
+
+	cancelled, err := c.Cancel(ctx, held.PNR)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCancelled, cancelled.Status)
+
+	got, err := c.Retrieve(ctx, held.PNR)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCancelled, got.Status)
+
+	_, err = c.Confirm(ctx, held.PNR)
+	assert.Error(t, err)
+}
+
+func TestMockGDSHoldExpiry(t *testing.T) {
+	ctx := context.Background()
+	m := NewMockGDS()
+	offers, _ := m.Search(ctx, SearchRequest{Product: "Deluxe Room"})
+	held, _ := m.Hold(ctx, offers[1].OfferID, 1)
+
+	m.now = func() time.Time { return time.Now().Add(time.Hour) }
+	_, err := m.Confirm(ctx, held.PNR)
+	assert.ErrorIs(t, err, ErrHoldExpired)
+}
@@ -0,0 +1,77 @@
+// internal/tax/rules.go
+package tax
+
+import (
+	"strings"
+
+	"travel-agency/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Rules is the table-driven engine: the tenant's TaxRules.
+type Rules struct {
+	Rules []models.TaxRule
+}
+
+// LoadRules loads the tenant's enabled rules with their components.
+func LoadRules(db *gorm.DB, tenantID uint) (Engine, error) {
+	var rules []models.TaxRule
+	if err := db.Preload("Components", func(tx *gorm.DB) *gorm.DB { return tx.Order("position, id") }).
+		Where("tenant_id = ? AND disabled = ?", tenantID, false).Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return &Rules{Rules: rules}, nil
+}
+
+// Taxes returns the components of the best matching rule; none when no rule
+// matches.
+func (e *Rules) Taxes(line Line) []models.InvoiceItemTax {
+	rule := e.Match(line)
+	if rule == nil {
+		return nil
+	}
+	taxes := make([]models.InvoiceItemTax, 0, len(rule.Components))
+	for _, c := range rule.Components {
+		taxes = append(taxes, models.InvoiceItemTax{Name: c.Name, Rate: c.Rate, Basis: c.Basis})
+	}
+	return taxes
+}
+
+// Match picks the most specific rule that matches the line. Service type is
+// the most specific criterion, then place of supply, then customer type;
+// Priority breaks ties. Returns nil when nothing matches.
+func (e *Rules) Match(line Line) *models.TaxRule {
+	var best *models.TaxRule
+	bestScore := -1
+	for i := range e.Rules {
+		rule := &e.Rules[i]
+		if rule.Disabled {
+			continue
+		}
+		score := 0
+		if rule.ServiceType != "" {
+			if !strings.EqualFold(rule.ServiceType, line.ServiceType) {
+				continue
+			}
+			score += 4
+		}
+		if rule.PlaceOfSupply != "" {
+			if !strings.EqualFold(rule.PlaceOfSupply, line.PlaceOfSupply) {
+				continue
+			}
+			score += 2
+		}
+		if rule.CustomerType != "" {
+			if !strings.EqualFold(rule.CustomerType, line.CustomerType) {
+				continue
+			}
+			score++
+		}
+		if best == nil || score > bestScore || (score == bestScore && rule.Priority > best.Priority) {
+			best = rule
+			bestScore = score
+		}
+	}
+	return best
+}
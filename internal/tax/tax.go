@@ -0,0 +1,154 @@
+// internal/tax/tax.go
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// Errors returned when configuring taxes.
+var (
+	ErrUnknownEngine = errors.New("unknown tax engine")
+	ErrInvalidRule   = errors.New("invalid tax rule")
+)
+
+// Line is what an engine knows of the invoice or booking line it taxes.
+type Line struct {
+	ServiceType   string
+	PlaceOfSupply string
+	CustomerType  string
+	Date          time.Time
+}
+
+// Engine works out the tax components of a line: their names, rates and
+// bases. The amounts are computed with the rest of the line's totals.
+type Engine interface {
+	Taxes(line Line) []models.InvoiceItemTax
+}
+
+// Factory loads a tenant's engine.
+type Factory func(db *gorm.DB, tenantID uint) (Engine, error)
+
+var (
+	mu      sync.RWMutex
+	engines = map[string]Factory{models.TaxEngineRules: LoadRules}
+)
+
+// Register makes an engine available to tenants under a name, e.g. one that
+// asks an external tax service. It replaces an engine of the same name.
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	engines[name] = f
+}
+
+// Engines lists the registered engine names.
+func Engines() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Known reports whether name is a registered engine; empty means none.
+func Known(name string) bool {
+	if name == "" {
+		return true
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := engines[name]
+	return ok
+}
+
+// ForTenant loads the tenant's engine, or nil when it has none.
+func ForTenant(db *gorm.DB, tenantID uint) (Engine, error) {
+	var tenant models.Tenant
+	if err := db.Select("id", "tax_engine").First(&tenant, tenantID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if tenant.TaxEngine == "" {
+		return nil, nil
+	}
+	mu.RLock()
+	f, ok := engines[tenant.TaxEngine]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEngine, tenant.TaxEngine)
+	}
+	return f(db, tenantID)
+}
+
+// ValidBasis reports whether basis is a tax basis; empty means net.
+func ValidBasis(basis string) bool {
+	switch basis {
+	case "", models.TaxOnNet, models.TaxOnMargin, models.TaxOnTotal:
+		return true
+	}
+	return false
+}
+
+// Taxable is what a component on the given basis is charged on: the line's
+// net amount, its margin over cost (never below zero), or its net plus the
+// line's other taxes.
+func Taxable(basis string, net, cost, otherTaxes money.Amount) money.Amount {
+	switch basis {
+	case models.TaxOnMargin:
+		return money.Max(0, net-cost)
+	case models.TaxOnTotal:
+		return net + otherTaxes
+	default:
+		return net
+	}
+}
+
+// ValidateRule checks a rule before it is saved and numbers its components.
+func ValidateRule(r *models.TaxRule) error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.ServiceType = strings.TrimSpace(r.ServiceType)
+	r.PlaceOfSupply = strings.TrimSpace(r.PlaceOfSupply)
+	r.CustomerType = strings.TrimSpace(r.CustomerType)
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+	if len(r.Components) == 0 {
+		return fmt.Errorf("%w: at least one component is required; use a rate of 0 to exempt", ErrInvalidRule)
+	}
+	seen := map[string]bool{}
+	for i := range r.Components {
+		c := &r.Components[i]
+		c.Name = strings.TrimSpace(c.Name)
+		c.Position = i + 1
+		switch {
+		case c.Name == "":
+			return fmt.Errorf("%w: component %d needs a name", ErrInvalidRule, i+1)
+		case c.Rate < 0 || c.Rate > 100:
+			return fmt.Errorf("%w: component %s: rate must be between 0 and 100", ErrInvalidRule, c.Name)
+		case !ValidBasis(c.Basis):
+			return fmt.Errorf("%w: component %s: basis must be net, margin or total", ErrInvalidRule, c.Name)
+		case seen[strings.ToLower(c.Name)]:
+			return fmt.Errorf("%w: component %s appears twice", ErrInvalidRule, c.Name)
+		}
+		seen[strings.ToLower(c.Name)] = true
+		if c.Basis == "" {
+			c.Basis = models.TaxOnNet
+		}
+	}
+	return nil
+}
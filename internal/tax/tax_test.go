@@ -0,0 +1,154 @@
+package tax
+
+import (
+	"testing"
+
+	"travel-agency/internal/models"
+	"travel-agency/internal/money"
+	"travel-agency/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	rules := &Rules{Rules: []models.TaxRule{
+		{ID: 1, Name: "Catch-all"},
+		{ID: 2, Name: "Business", CustomerType: "business"},
+		{ID: 3, Name: "Intra-state", PlaceOfSupply: "intra-state"},
+		{ID: 4, Name: "Intra-state business", PlaceOfSupply: "intra-state", CustomerType: "business"},
+		{ID: 5, Name: "Hotels", ServiceType: "Hotel"},
+		{ID: 6, Name: "Hotels, preferred", ServiceType: "Hotel", Priority: 10},
+		{ID: 7, Name: "Flights intra-state", ServiceType: "Flight", PlaceOfSupply: "intra-state"},
+		{ID: 8, Name: "Disabled trains", ServiceType: "Train", Disabled: true},
+	}}
+
+	tests := []struct {
+		name string
+		line Line
+		want uint
+	}{
+		{"nothing specific", Line{ServiceType: "Visa"}, 1},
+		{"customer type", Line{ServiceType: "Visa", CustomerType: "business"}, 2},
+		{"place beats customer type", Line{ServiceType: "Visa", PlaceOfSupply: "intra-state"}, 3},
+		{"place and customer type", Line{ServiceType: "Visa", PlaceOfSupply: "intra-state", CustomerType: "business"}, 4},
+		{"service type beats place and customer type", Line{ServiceType: "Hotel", PlaceOfSupply: "intra-state", CustomerType: "business"}, 6},
+		{"priority breaks ties", Line{ServiceType: "Hotel"}, 6},
+		{"matching is case-insensitive", Line{ServiceType: "flight", PlaceOfSupply: "Intra-State"}, 7},
+		{"a rule with a mismatched field is skipped", Line{ServiceType: "Flight", PlaceOfSupply: "inter-state"}, 1},
+		{"disabled rules are skipped", Line{ServiceType: "Train"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rules.Match(tt.line)
+			require.NotNil(t, got)
+			assert.Equal(t, tt.want, got.ID)
+		})
+	}
+
+	assert.Nil(t, (&Rules{Rules: []models.TaxRule{{ServiceType: "Hotel"}}}).Match(Line{ServiceType: "Flight"}))
+}
+
+func TestTaxesKeepComponentBases(t *testing.T) {
+	rules := &Rules{Rules: []models.TaxRule{{Name: "Outbound tours", Components: []models.TaxRuleComponent{
+		{Name: "IGST", Rate: 5, Basis: models.TaxOnNet},
+		{Name: "TCS", Rate: 5, Basis: models.TaxOnTotal},
+	}}}}
+	assert.Equal(t, []models.InvoiceItemTax{
+		{Name: "IGST", Rate: 5, Basis: models.TaxOnNet},
+		{Name: "TCS", Rate: 5, Basis: models.TaxOnTotal},
+	}, rules.Taxes(Line{}))
+	assert.Nil(t, (&Rules{}).Taxes(Line{}))
+}
+
+func TestTaxable(t *testing.T) {
+	net, others := money.FromInt(1000), money.FromInt(50)
+	tests := []struct {
+		name  string
+		basis string
+		cost  money.Amount
+		want  money.Amount
+	}{
+		{"default is net", "", money.FromInt(800), net},
+		{"net", models.TaxOnNet, money.FromInt(800), net},
+		{"margin is net less cost", models.TaxOnMargin, money.FromInt(800), money.FromInt(200)},
+		{"margin is never negative", models.TaxOnMargin, money.FromInt(1200), 0},
+		{"total includes the other taxes", models.TaxOnTotal, money.FromInt(800), money.FromInt(1050)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Taxable(tt.basis, net, tt.cost, others))
+		})
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	component := func(name string, rate float64, basis string) models.TaxRuleComponent {
+		return models.TaxRuleComponent{Name: name, Rate: rate, Basis: basis}
+	}
+	tests := []struct {
+		name  string
+		rule  models.TaxRule
+		valid bool
+	}{
+		{"valid", models.TaxRule{Name: "GST", Components: []models.TaxRuleComponent{component("CGST", 6, ""), component("SGST", 6, "")}}, true},
+		{"zero rate exempts", models.TaxRule{Name: "Exempt", Components: []models.TaxRuleComponent{component("GST", 0, "")}}, true},
+		{"margin and total bases", models.TaxRule{Name: "Tours", Components: []models.TaxRuleComponent{
+			component("VAT", 20, models.TaxOnMargin), component("TCS", 5, models.TaxOnTotal)}}, true},
+		{"name required", models.TaxRule{Name: "  ", Components: []models.TaxRuleComponent{component("GST", 5, "")}}, false},
+		{"components required", models.TaxRule{Name: "GST"}, false},
+		{"component name required", models.TaxRule{Name: "GST", Components: []models.TaxRuleComponent{component(" ", 5, "")}}, false},
+		{"negative rate", models.TaxRule{Name: "GST", Components: []models.TaxRuleComponent{component("GST", -1, "")}}, false},
+		{"rate over 100", models.TaxRule{Name: "GST", Components: []models.TaxRuleComponent{component("GST", 101, "")}}, false},
+		{"unknown basis", models.TaxRule{Name: "GST", Components: []models.TaxRuleComponent{component("GST", 5, "gross")}}, false},
+		{"duplicate component", models.TaxRule{Name: "GST", Components: []models.TaxRuleComponent{component("GST", 5, ""), component("gst", 5, "")}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRule(&tt.rule)
+			if !tt.valid {
+				assert.ErrorIs(t, err, ErrInvalidRule)
+				return
+			}
+			require.NoError(t, err)
+			for i, c := range tt.rule.Components {
+				assert.Equal(t, i+1, c.Position)
+				assert.NotEmpty(t, c.Basis, "an empty basis becomes net")
+			}
+		})
+	}
+}
+
+func TestForTenant(t *testing.T) {
+	db := testutil.DB(t)
+	for _, tenant := range []models.Tenant{
+		{ID: 1, Name: "No engine"},
+		{ID: 2, Name: "Rules", TaxEngine: models.TaxEngineRules},
+		{ID: 3, Name: "Unregistered engine", TaxEngine: "acme-tax"},
+	} {
+		require.NoError(t, db.Create(&tenant).Error)
+	}
+
+	tests := []struct {
+		name     string
+		tenantID uint
+		engine   bool
+		err      error
+	}{
+		{"no engine", 1, false, nil},
+		{"rules", 2, true, nil},
+		{"unknown engine", 3, false, ErrUnknownEngine},
+		{"unknown tenant", 99, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := ForTenant(db, tt.tenantID)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.engine, engine != nil)
+		})
+	}
+}
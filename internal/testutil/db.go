@@ -0,0 +1,56 @@
+// internal/testutil/db.go
+
+// Package testutil holds setup shared by the packages' tests.
+package testutil
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"travel-agency/internal/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Models lists every persisted model.
+var Models = []interface{}{
+	&models.Tenant{}, &models.User{}, &models.AuditLog{}, &models.Notification{},
+	&models.Lead{}, &models.Task{}, &models.Ticket{}, &models.TravelRequest{},
+	&models.Itinerary{}, &models.ItineraryItem{}, &models.ItineraryVersion{}, &models.ItineraryVersionItem{},
+	&models.ItineraryTemplate{}, &models.ItineraryTemplateItem{}, &models.MarkupRule{},
+	&models.Vendor{}, &models.CancellationRule{}, &models.VendorContract{}, &models.RateCard{},
+	&models.Inventory{}, &models.InventoryAllocation{}, &models.Booking{}, &models.BookingTransition{},
+	&models.Invoice{}, &models.InvoiceItem{}, &models.InvoiceItemTax{}, &models.InvoiceSequence{},
+	&models.CreditNote{}, &models.CreditNoteItem{}, &models.CreditNoteSequence{}, &models.Refund{},
+	&models.Payment{}, &models.PaymentAllocation{}, &models.WebhookEvent{},
+	&models.PaymentRun{}, &models.VendorPayment{}, &models.ExchangeRate{},
+	&models.Account{}, &models.JournalEntry{}, &models.JournalLine{},
+	&models.AccountingMapping{}, &models.AccountingExport{}, &models.AccountingExportRecord{},
+	&models.ReminderRule{}, &models.InvoiceReminder{}, &models.RecurringInvoice{}, &models.RecurringInvoiceRun{},
+	&models.TaxRule{}, &models.TaxRuleComponent{},
+}
+
+var dbCount int64
+
+// DB opens a fresh in-memory SQLite database with every model migrated.
+// Its connections share the one database, so goroutines see the same data.
+func DB(t testing.TB) *gorm.DB {
+	t.Helper()
+	name := fmt.Sprintf("file:testdb%d?mode=memory&cache=shared", atomic.AddInt64(&dbCount, 1))
+	db, err := gorm.Open(sqlite.Open(name), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(Models...); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
@@ -0,0 +1,60 @@
+// internal/utils/assets.go
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AssetsDir is the directory, relative to the working directory, that
+// tenant branding such as logos is read from.
+const AssetsDir = "assets"
+
+// ErrOutsideAssets is returned for asset paths that are absolute or leave
+// the assets directory.
+var ErrOutsideAssets = errors.New("path is outside the assets directory")
+
+// AssetPath resolves name, a path relative to AssetsDir, to the file it
+// names.
+func AssetPath(name string) (string, error) {
+	return resolveAsset(AssetsDir, name)
+}
+
+// resolveAsset joins name onto dir and rejects the result when it is not
+// inside dir, also after following symlinks.
+func resolveAsset(dir, name string) (string, error) {
+	if strings.TrimSpace(name) == "" || filepath.IsAbs(name) {
+		return "", ErrOutsideAssets
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(root, name)
+	if !within(root, path) {
+		return "", ErrOutsideAssets
+	}
+	real, err := filepath.EvalSymlinks(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return path, nil // Nothing there (yet) to point elsewhere.
+	}
+	if err != nil {
+		return "", err
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	if !within(realRoot, real) {
+		return "", ErrOutsideAssets
+	}
+	return path, nil
+}
+
+// within reports whether path is strictly inside root.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveAsset(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "acme"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "acme", "logo.png"), []byte("png"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.png"), []byte("png"), 0o644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.png"), filepath.Join(dir, "link.png")))
+
+	path, err := resolveAsset(dir, "acme/logo.png")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "acme", "logo.png"), path)
+	_, err = resolveAsset(dir, "acme/../acme/new.png")
+	assert.NoError(t, err)
+
+	for _, name := range []string{"", ".", "/etc/passwd", "../secret.png", "acme/../../secret.png", "link.png"} {
+		_, err := resolveAsset(dir, name)
+		assert.ErrorIs(t, err, ErrOutsideAssets, name)
+	}
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"travel-agency/internal/models"
+)
+
+// GenerateCreditNotePDF builds a PDF document for a credit note against the
+// given invoice and returns the PDF as a byte slice. Preload Items to print
+// the credited lines.
+func GenerateCreditNotePDF(note models.CreditNote, invoice models.Invoice) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AddPage()
+	pdf.SetFont("Arial", "B", 16)
+	pdf.Cell(40, 10, "Credit Note")
+	pdf.Ln(12)
+
+	pdf.SetFont("Arial", "", 12)
+	if note.Number != nil {
+		pdf.Cell(40, 10, fmt.Sprintf("Credit Note No: %s", *note.Number))
+	} else {
+		pdf.Cell(40, 10, fmt.Sprintf("Credit Note: %s", note.ID))
+	}
+	pdf.Ln(8)
+	if invoice.Number != nil {
+		pdf.Cell(40, 10, fmt.Sprintf("Against Invoice: %s", *invoice.Number))
+	} else {
+		pdf.Cell(40, 10, tr(fmt.Sprintf("Against Invoice: %s %s", invoice.Reference, invoice.ID)))
+	}
+	pdf.Ln(8)
+	pdf.Cell(40, 10, fmt.Sprintf("Invoice Date: %s", invoice.IssueDate.Format("2006-01-02")))
+	pdf.Ln(8)
+	pdf.Cell(40, 10, fmt.Sprintf("Issue Date: %s", note.IssueDate.Format("2006-01-02")))
+	pdf.Ln(8)
+	if note.Reason != "" {
+		pdf.MultiCell(0, 8, tr(fmt.Sprintf("Reason: %s", note.Reason)), "", "L", false)
+	}
+	pdf.Ln(4)
+
+	if len(note.Items) > 0 {
+		widths := []float64{100, 16, 24, 20, 30}
+		pdf.SetFont("Arial", "B", 9)
+		pdf.SetFillColor(230, 238, 247)
+		for i, h := range []string{"Description", "Qty", "Net", "Tax", "Total"} {
+			align := "R"
+			if i == 0 {
+				align = "L"
+			}
+			pdf.CellFormat(widths[i], 7, h, "B", 0, align, true, 0, "")
+		}
+		pdf.Ln(-1)
+
+		pdf.SetFont("Arial", "", 9)
+		for _, item := range note.Items {
+			desc := item.Description
+			if len(desc) > 60 {
+				desc = desc[:57] + "..."
+			}
+			pdf.CellFormat(widths[0], 6, tr(desc), "", 0, "L", false, 0, "")
+			pdf.CellFormat(widths[1], 6, fmt.Sprintf("%g", item.Quantity), "", 0, "R", false, 0, "")
+			pdf.CellFormat(widths[2], 6, item.Net.Format(note.Currency), "", 0, "R", false, 0, "")
+			pdf.CellFormat(widths[3], 6, item.TaxAmount.Format(note.Currency), "", 0, "R", false, 0, "")
+			pdf.CellFormat(widths[4], 6, item.Total.Format(note.Currency), "", 1, "R", false, 0, "")
+		}
+		pdf.Ln(2)
+
+		labelW := widths[0] + widths[1] + widths[2] + widths[3]
+		pdf.CellFormat(labelW, 6, "Subtotal", "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[4], 6, note.Subtotal.Format(note.Currency), "", 1, "R", false, 0, "")
+		pdf.CellFormat(labelW, 6, "Tax", "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[4], 6, note.TaxTotal.Format(note.Currency), "", 1, "R", false, 0, "")
+		pdf.Ln(2)
+	}
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.Cell(40, 10, fmt.Sprintf("Amount Credited: %s %s", note.Amount.Format(note.Currency), note.Currency))
+	pdf.Ln(10)
+	pdf.SetFont("Arial", "", 9)
+	pdf.Cell(40, 10, fmt.Sprintf("Generated on: %s", time.Now().Format("2006-01-02 15:04:05")))
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
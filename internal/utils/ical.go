@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CalendarEvent is a single VEVENT in an iCalendar feed.
+// All-day events use only the date part of Start/End; End is inclusive.
+type CalendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+}
+
+const (
+	icalDate     = "20060102"
+	icalDateTime = "20060102T150405Z"
+)
+
+// GenerateICS renders events as an RFC 5545 calendar. Timed events are written
+// in UTC so they display correctly in every client's own time zone; all-day
+// events are floating dates as the RFC recommends.
+func GenerateICS(name string, events []CalendarEvent) []byte {
+	var buf bytes.Buffer
+	stamp := time.Now().UTC().Format(icalDateTime)
+
+	writeICalLine(&buf, "BEGIN:VCALENDAR")
+	writeICalLine(&buf, "VERSION:2.0")
+	writeICalLine(&buf, "PRODID:-//travel-agency//itineraries//EN")
+	writeICalLine(&buf, "CALSCALE:GREGORIAN")
+	writeICalLine(&buf, "METHOD:PUBLISH")
+	writeICalLine(&buf, "X-WR-CALNAME:"+escapeICalText(name))
+
+	for _, ev := range events {
+		writeICalLine(&buf, "BEGIN:VEVENT")
+		writeICalLine(&buf, "UID:"+ev.UID)
+		writeICalLine(&buf, "DTSTAMP:"+stamp)
+		if ev.AllDay {
+			end := ev.End
+			if end.Before(ev.Start) {
+				end = ev.Start
+			}
+			writeICalLine(&buf, "DTSTART;VALUE=DATE:"+ev.Start.Format(icalDate))
+			// DTEND is exclusive for date values.
+			writeICalLine(&buf, "DTEND;VALUE=DATE:"+end.AddDate(0, 0, 1).Format(icalDate))
+		} else {
+			end := ev.End
+			if !end.After(ev.Start) {
+				end = ev.Start.Add(30 * time.Minute)
+			}
+			writeICalLine(&buf, "DTSTART:"+ev.Start.UTC().Format(icalDateTime))
+			writeICalLine(&buf, "DTEND:"+end.UTC().Format(icalDateTime))
+		}
+		writeICalLine(&buf, "SUMMARY:"+escapeICalText(ev.Summary))
+		if ev.Description != "" {
+			writeICalLine(&buf, "DESCRIPTION:"+escapeICalText(ev.Description))
+		}
+		if ev.Location != "" {
+			writeICalLine(&buf, "LOCATION:"+escapeICalText(ev.Location))
+		}
+		writeICalLine(&buf, "END:VEVENT")
+	}
+
+	writeICalLine(&buf, "END:VCALENDAR")
+	return buf.Bytes()
+}
+
+// IsDateOnly reports whether t carries no time-of-day (as produced by parsing
+// a "2006-01-02" date).
+func IsDateOnly(t time.Time) bool {
+	h, m, s := t.Clock()
+	return h == 0 && m == 0 && s == 0 && t.Nanosecond() == 0
+}
+
+// escapeICalText escapes TEXT values per RFC 5545 section 3.3.11.
+func escapeICalText(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+	return r.Replace(s)
+}
+
+// writeICalLine writes a content line with CRLF, folding it at 75 octets
+// without splitting UTF-8 sequences (RFC 5545 section 3.1).
+func writeICalLine(buf *bytes.Buffer, line string) {
+	const limit = 75
+	width := 0
+	for _, r := range line {
+		n := len(string(r))
+		if width+n > limit {
+			buf.WriteString("\r\n ")
+			width = 1
+		}
+		buf.WriteRune(r)
+		width += n
+	}
+	buf.WriteString("\r\n")
+}
+
+// ICalUID builds a globally unique, stable event identifier.
+func ICalUID(parts ...interface{}) string {
+	s := make([]string, len(parts))
+	for i, p := range parts {
+		s[i] = fmt.Sprint(p)
+	}
+	return strings.Join(s, "-") + "@travel-agency"
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateICS(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	out := string(GenerateICS("Trip", []CalendarEvent{
+		{UID: "a@x", Summary: "Hotel; check-in, late", Start: start, End: start.AddDate(0, 0, 2), AllDay: true},
+		{UID: "b@x", Summary: strings.Repeat("long ", 30), Start: start.Add(9 * time.Hour)},
+	}))
+
+	assert.Contains(t, out, "DTSTART;VALUE=DATE:20260301\r\n")
+	assert.Contains(t, out, "DTEND;VALUE=DATE:20260304\r\n")
+	assert.Contains(t, out, `SUMMARY:Hotel\; check-in\, late`)
+	assert.Contains(t, out, "DTSTART:20260301T090000Z\r\n")
+	assert.Contains(t, out, "DTEND:20260301T093000Z\r\n")
+
+	for _, line := range strings.Split(out, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+	}
+	assert.Contains(t, out, "lo\r\n ng")
+}
@@ -3,33 +3,69 @@ package utils
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/jung-kurt/gofpdf"
 	"travel-agency/internal/models"
+	"travel-agency/internal/money"
 )
 
 // GenerateInvoicePDF builds a PDF document for the given invoice and returns the PDF as a byte slice.
+// Preload Items.Taxes to print the lines and the tax breakdown.
 func GenerateInvoicePDF(invoice models.Invoice) ([]byte, error) {
 	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetAutoPageBreak(true, 20)
 	pdf.AddPage()
 	pdf.SetFont("Arial", "B", 16)
 	pdf.Cell(40, 10, "Invoice")
 	pdf.Ln(12)
 
 	pdf.SetFont("Arial", "", 12)
-	pdf.Cell(40, 10, fmt.Sprintf("Invoice ID: %d", invoice.ID))
+	if invoice.Number != nil {
+		pdf.Cell(40, 10, fmt.Sprintf("Invoice No: %s", *invoice.Number))
+	} else {
+		pdf.Cell(40, 10, fmt.Sprintf("Draft: %s", invoice.ID))
+	}
 	pdf.Ln(8)
+	if invoice.Reference != "" {
+		pdf.Cell(40, 10, tr(fmt.Sprintf("Reference: %s", invoice.Reference)))
+		pdf.Ln(8)
+	}
 	pdf.Cell(40, 10, fmt.Sprintf("Type: %s", invoice.InvoiceType))
 	pdf.Ln(8)
 	pdf.Cell(40, 10, fmt.Sprintf("Issue Date: %s", invoice.IssueDate.Format("2006-01-02")))
 	pdf.Ln(8)
 	pdf.Cell(40, 10, fmt.Sprintf("Due Date: %s", invoice.DueDate.Format("2006-01-02")))
 	pdf.Ln(8)
-	pdf.Cell(40, 10, fmt.Sprintf("Amount: %.2f %s", invoice.Amount, invoice.Currency))
-	pdf.Ln(8)
 	pdf.Cell(40, 10, fmt.Sprintf("Status: %s", invoice.Status))
 	pdf.Ln(8)
+	if invoice.PlaceOfSupply != "" {
+		pdf.Cell(40, 10, tr(fmt.Sprintf("Place of Supply: %s", invoice.PlaceOfSupply)))
+		pdf.Ln(8)
+	}
+	if invoice.CustomerType != "" {
+		pdf.Cell(40, 10, tr(fmt.Sprintf("Customer Type: %s", invoice.CustomerType)))
+		pdf.Ln(8)
+	}
+	if invoice.VoidedAt != nil {
+		pdf.SetFont("Arial", "B", 12)
+		pdf.SetTextColor(200, 0, 0)
+		pdf.MultiCell(0, 8, tr(fmt.Sprintf("VOID (%s): %s", invoice.VoidedAt.Format("2006-01-02"), invoice.VoidReason)), "", "L", false)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetFont("Arial", "", 12)
+	}
+	pdf.Ln(4)
+
+	if len(invoice.Items) > 0 {
+		writeInvoiceLines(pdf, tr, invoice)
+	}
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.Cell(40, 10, fmt.Sprintf("Amount: %s %s", invoice.Amount.Format(invoice.Currency), invoice.Currency))
+	pdf.Ln(10)
+	pdf.SetFont("Arial", "", 9)
 	pdf.Cell(40, 10, fmt.Sprintf("Generated on: %s", time.Now().Format("2006-01-02 15:04:05")))
 
 	var buf bytes.Buffer
@@ -38,3 +74,89 @@ func GenerateInvoicePDF(invoice models.Invoice) ([]byte, error) {
 	}
 	return buf.Bytes(), nil
 }
+
+// writeInvoiceLines prints the line table, then the subtotal, discounts and
+// tax totalled per component with what it was charged on (e.g. CGST 9% on
+// 1,000.00, VAT 20% on margin 150.00).
+func writeInvoiceLines(pdf *gofpdf.Fpdf, tr func(string) string, invoice models.Invoice) {
+	widths := []float64{78, 16, 24, 22, 20, 30}
+	pdf.SetFont("Arial", "B", 9)
+	pdf.SetFillColor(230, 238, 247)
+	for i, h := range []string{"Description", "Qty", "Unit price", "Discount", "Tax", "Total"} {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		pdf.CellFormat(widths[i], 7, h, "B", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	type component struct {
+		name  string
+		rate  float64
+		basis string
+	}
+	taxes := map[component]money.Amount{}
+	taxable := map[component]money.Amount{}
+	pdf.SetFont("Arial", "", 9)
+	for _, item := range invoice.Items {
+		desc := item.Description
+		if len(desc) > 48 {
+			desc = desc[:45] + "..."
+		}
+		pdf.CellFormat(widths[0], 6, tr(desc), "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 6, fmt.Sprintf("%g", item.Quantity), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[2], 6, item.UnitPrice.String(), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 6, item.DiscountAmount.Format(invoice.Currency), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[4], 6, item.TaxAmount.Format(invoice.Currency), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[5], 6, item.Total.Format(invoice.Currency), "", 1, "R", false, 0, "")
+		for _, t := range item.Taxes {
+			k := component{t.Name, t.Rate, t.Basis}
+			if k.basis == models.TaxOnNet {
+				k.basis = ""
+			}
+			taxes[k] += t.Amount
+			taxable[k] += t.Taxable
+		}
+	}
+	pdf.Ln(2)
+
+	keys := make([]component, 0, len(taxes))
+	for k := range taxes {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+		if keys[i].rate != keys[j].rate {
+			return keys[i].rate < keys[j].rate
+		}
+		return keys[i].basis < keys[j].basis
+	})
+
+	labelW := widths[0] + widths[1] + widths[2] + widths[3] + widths[4]
+	total := func(label string, v money.Amount) {
+		pdf.CellFormat(labelW, 6, tr(label), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[5], 6, v.Format(invoice.Currency), "", 1, "R", false, 0, "")
+	}
+	total("Subtotal", invoice.Subtotal)
+	if invoice.DiscountTotal > 0 {
+		total("Discounts", -invoice.DiscountTotal)
+	}
+	for _, k := range keys {
+		label := fmt.Sprintf("%s %g%%", k.name, k.rate)
+		if k.basis != "" {
+			label += " on " + k.basis
+		}
+		// Taxes saved before taxable amounts were recorded have none.
+		if taxable[k] != 0 {
+			if k.basis == "" {
+				label += " on"
+			}
+			label += " " + taxable[k].Format(invoice.Currency)
+		}
+		total(label, taxes[k])
+	}
+	pdf.Ln(2)
+}
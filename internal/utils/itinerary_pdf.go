@@ -0,0 +1,241 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"travel-agency/internal/models"
+)
+
+// Default branding used when the tenant has not configured its own.
+var (
+	defaultPrimaryColor   = [3]int{11, 83, 148}
+	defaultSecondaryColor = [3]int{230, 238, 247}
+)
+
+// parseHexColor turns "#RRGGBB" into RGB components, falling back to def.
+func parseHexColor(hex string, def [3]int) [3]int {
+	hex = strings.TrimPrefix(strings.TrimSpace(hex), "#")
+	if len(hex) != 6 {
+		return def
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return def
+	}
+	return [3]int{int(v >> 16 & 0xFF), int(v >> 8 & 0xFF), int(v & 0xFF)}
+}
+
+// GenerateItineraryPDF renders a branded, day-by-day travel document for the
+// itinerary, including its bookings, vendor contacts and emergency numbers.
+// vendors is keyed by vendor ID. Long itineraries flow onto further pages.
+func GenerateItineraryPDF(tenant models.Tenant, itin models.Itinerary, bookings []models.Booking, vendors map[uint]models.Vendor) ([]byte, error) {
+	primary := parseHexColor(tenant.PrimaryColor, defaultPrimaryColor)
+	secondary := parseHexColor(tenant.SecondaryColor, defaultSecondaryColor)
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pageW, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	contentW := pageW - left - right
+
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AliasNbPages("")
+
+	logo, hasLogo := "", false
+	if tenant.LogoPath != "" {
+		if path, err := AssetPath(tenant.LogoPath); err == nil {
+			if _, err := os.Stat(path); err == nil {
+				logo, hasLogo = path, true
+			}
+		}
+	}
+
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFillColor(primary[0], primary[1], primary[2])
+		pdf.Rect(0, 0, pageW, 22, "F")
+		if hasLogo {
+			pdf.ImageOptions(logo, left, 4, 0, 14, false, gofpdf.ImageOptions{ReadDpi: true}, 0, "")
+		}
+		pdf.SetTextColor(255, 255, 255)
+		pdf.SetFont("Arial", "B", 14)
+		pdf.SetXY(left, 6)
+		pdf.CellFormat(contentW, 6, tr(tenant.Name), "", 1, "R", false, 0, "")
+		pdf.SetFont("Arial", "", 9)
+		pdf.SetX(left)
+		pdf.CellFormat(contentW, 5, tr(strings.TrimSpace(tenant.Phone+"  "+tenant.Email)), "", 1, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetY(28)
+	})
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetDrawColor(primary[0], primary[1], primary[2])
+		pdf.Line(left, pdf.GetY(), pageW-right, pdf.GetY())
+		pdf.SetFont("Arial", "I", 8)
+		pdf.SetTextColor(100, 100, 100)
+		footer := tenant.FooterText
+		if footer == "" {
+			footer = tenant.Address
+		}
+		pdf.CellFormat(contentW*0.8, 8, tr(footer), "", 0, "L", false, 0, "")
+		pdf.CellFormat(contentW*0.2, 8, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+
+	sectionTitle := func(title string) {
+		pdf.Ln(4)
+		pdf.SetFont("Arial", "B", 12)
+		pdf.SetFillColor(secondary[0], secondary[1], secondary[2])
+		pdf.SetTextColor(primary[0], primary[1], primary[2])
+		pdf.CellFormat(contentW, 8, tr(title), "", 1, "L", true, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+		pdf.Ln(1)
+	}
+
+	vendorName := func(id uint) string {
+		if v, ok := vendors[id]; ok {
+			return v.Name
+		}
+		return ""
+	}
+
+	pdf.AddPage()
+
+	// Trip summary
+	pdf.SetFont("Arial", "B", 18)
+	pdf.CellFormat(contentW, 10, tr(itin.Name), "", 1, "L", false, 0, "")
+	pdf.SetFont("Arial", "", 10)
+	summary := fmt.Sprintf("%s to %s", itin.StartDate.Format("Mon, 02 Jan 2006"), itin.EndDate.Format("Mon, 02 Jan 2006"))
+	if itin.Destination != "" {
+		summary = itin.Destination + "  |  " + summary
+	}
+	pdf.CellFormat(contentW, 6, tr(summary), "", 1, "L", false, 0, "")
+	pdf.CellFormat(contentW, 6, tr(fmt.Sprintf("Status: %s    Total: %s %s", itin.Status, itin.TotalPrice.Format(itin.Currency), itin.Currency)), "", 1, "L", false, 0, "")
+
+	// Day-by-day plan; bookings are shown on the day they travel.
+	itemsByDay := map[int][]models.ItineraryItem{}
+	for _, item := range itin.Items {
+		itemsByDay[item.Day] = append(itemsByDay[item.Day], item)
+	}
+	bookingsByDay := map[int][]models.Booking{}
+	start := TruncateDay(itin.StartDate)
+	for _, b := range bookings {
+		if b.TravelDate.IsZero() {
+			continue
+		}
+		day := int(TruncateDay(b.TravelDate).Sub(start).Hours()/24) + 1
+		bookingsByDay[day] = append(bookingsByDay[day], b)
+	}
+	var days []int
+	for d := range itemsByDay {
+		days = append(days, d)
+	}
+	for d := range bookingsByDay {
+		if _, ok := itemsByDay[d]; !ok {
+			days = append(days, d)
+		}
+	}
+	sort.Ints(days)
+
+	for _, day := range days {
+		date := start.AddDate(0, 0, day-1)
+		sectionTitle(fmt.Sprintf("Day %d - %s", day, date.Format("Monday, 02 January 2006")))
+
+		for _, item := range itemsByDay[day] {
+			pdf.SetFont("Arial", "B", 10)
+			heading := item.Type
+			if name := vendorName(item.VendorID); name != "" {
+				heading += " - " + name
+			}
+			pdf.CellFormat(contentW, 6, tr(heading), "", 1, "L", false, 0, "")
+			if item.Description != "" {
+				pdf.SetFont("Arial", "", 10)
+				pdf.MultiCell(contentW, 5, tr(item.Description), "", "L", false)
+			}
+			pdf.Ln(1)
+		}
+		for _, b := range bookingsByDay[day] {
+			pdf.SetFont("Arial", "", 9)
+			line := fmt.Sprintf("Booking %s with %s (%s)", orDash(b.BookingRef), orDash(vendorName(b.VendorID)), b.Status)
+			pdf.CellFormat(contentW, 5, tr(line), "", 1, "L", false, 0, "")
+		}
+	}
+
+	// Booking references
+	if len(bookings) > 0 {
+		sectionTitle("Booking references")
+		cols := []float64{contentW * 0.25, contentW * 0.35, contentW * 0.2, contentW * 0.2}
+		pdf.SetFont("Arial", "B", 9)
+		for i, h := range []string{"Reference", "Vendor", "Travel date", "Status"} {
+			pdf.CellFormat(cols[i], 6, h, "B", 0, "L", false, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Arial", "", 9)
+		for _, b := range bookings {
+			travel := "-"
+			if !b.TravelDate.IsZero() {
+				travel = b.TravelDate.Format("2006-01-02")
+			}
+			pdf.CellFormat(cols[0], 6, tr(orDash(b.BookingRef)), "", 0, "L", false, 0, "")
+			pdf.CellFormat(cols[1], 6, tr(orDash(vendorName(b.VendorID))), "", 0, "L", false, 0, "")
+			pdf.CellFormat(cols[2], 6, travel, "", 0, "L", false, 0, "")
+			pdf.CellFormat(cols[3], 6, tr(b.Status), "", 1, "L", false, 0, "")
+		}
+	}
+
+	// Vendor contacts
+	if len(vendors) > 0 {
+		sectionTitle("Vendor contacts")
+		ids := make([]int, 0, len(vendors))
+		for id := range vendors {
+			ids = append(ids, int(id))
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			v := vendors[uint(id)]
+			pdf.SetFont("Arial", "B", 10)
+			pdf.CellFormat(contentW, 5, tr(fmt.Sprintf("%s (%s)", v.Name, orDash(v.Type))), "", 1, "L", false, 0, "")
+			pdf.SetFont("Arial", "", 9)
+			pdf.CellFormat(contentW, 5, tr(strings.TrimSpace(v.ContactPerson+"  "+v.ContactInfo)), "", 1, "L", false, 0, "")
+			pdf.Ln(1)
+		}
+	}
+
+	// Emergency numbers
+	sectionTitle("Emergency contacts")
+	pdf.SetFont("Arial", "", 10)
+	if tenant.EmergencyPhone != "" {
+		pdf.CellFormat(contentW, 6, tr(fmt.Sprintf("%s 24x7 assistance: %s", tenant.Name, tenant.EmergencyPhone)), "", 1, "L", false, 0, "")
+	} else if tenant.Phone != "" {
+		pdf.CellFormat(contentW, 6, tr(fmt.Sprintf("%s: %s", tenant.Name, tenant.Phone)), "", 1, "L", false, 0, "")
+	}
+	pdf.CellFormat(contentW, 6, "International emergency number (mobile): 112", "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+	pdf.SetFont("Arial", "I", 8)
+	pdf.CellFormat(contentW, 5, fmt.Sprintf("Generated on: %s", time.Now().Format("2006-01-02 15:04:05")), "", 1, "L", false, 0, "")
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// TruncateDay returns midnight UTC of t's calendar day.
+func TruncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}